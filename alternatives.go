@@ -0,0 +1,114 @@
+package pkgbuild
+
+import (
+	"sort"
+	"strings"
+)
+
+// Alternatives is a requirement satisfied by any of its dependencies, like
+// "java-runtime>=11 | jre11", e.g. when several packages provide the same
+// name. Earlier dependencies are preferred.
+type Alternatives []*Dependency
+
+// ParseAlternatives parses alternatives separated by "|", each a dependency
+// as in a PKGBUILD, e.g. "java-runtime>=11|jre11". Like exclusions they are
+// not supported by pacman.
+func ParseAlternatives(s string) (Alternatives, error) {
+	var alternatives Alternatives
+	for _, alternative := range strings.Split(s, "|") {
+		alternative = strings.TrimSpace(alternative)
+		deps, err := parseDependency(alternative, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(deps) == 0 {
+			return nil, &InvalidDependencyError{Dep: s, Err: ErrMissingAlternative}
+		}
+		alternatives = append(alternatives, deps[0])
+	}
+	return alternatives, nil
+}
+
+func (a Alternatives) String() string {
+	alternatives := make([]string, 0, len(a))
+	for _, dep := range a {
+		if !dep.versioned() {
+			alternatives = append(alternatives, dep.Name)
+		} else {
+			alternatives = append(alternatives, dep.String())
+		}
+	}
+	return strings.Join(alternatives, " | ")
+}
+
+// SatisfiedBy reports whether any of the alternatives is satisfied by the
+// package name, see Dependency.SatisfiedBy.
+func (a Alternatives) SatisfiedBy(name string, version *CompleteVersion, provides []string, policy ProvidePolicy) bool {
+	return a.satisfiedBy(name, version, provides, policy) >= 0
+}
+
+// satisfiedBy returns the index of the first alternative satisfied by the
+// package name, -1 if there is none.
+func (a Alternatives) satisfiedBy(name string, version *CompleteVersion, provides []string, policy ProvidePolicy) int {
+	for i, dep := range a {
+		if dep.SatisfiedBy(name, version, provides, policy) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Satisfying returns the candidates satisfying any of the alternatives.
+// They are ordered by the first alternative they satisfy and then by less,
+// which defaults to DefaultProviderLess, so the first one is the preferred
+// provider. Unlike SelectProvider this lets resolvers fall back to other
+// providers.
+func (a Alternatives) Satisfying(candidates []*Candidate, policy ProvidePolicy, less ProviderLess) []*Candidate {
+	if less == nil {
+		less = DefaultProviderLess
+	}
+
+	var satisfying []*Candidate
+	index := make(map[*Candidate]int)
+	for _, c := range candidates {
+		if i := a.satisfiedBy(c.Name, c.Version, c.Provides, policy); i >= 0 {
+			satisfying = append(satisfying, c)
+			index[c] = i
+		}
+	}
+
+	sort.SliceStable(satisfying, func(i, j int) bool {
+		ci, cj := satisfying[i], satisfying[j]
+		if index[ci] != index[cj] {
+			return index[ci] < index[cj]
+		}
+		return less(a[index[ci]], ci, cj)
+	})
+	return satisfying
+}
+
+// Select returns the preferred of the candidates satisfying any of the
+// alternatives, nil if none does, see Satisfying.
+func (a Alternatives) Select(candidates []*Candidate, policy ProvidePolicy, less ProviderLess) *Candidate {
+	if satisfying := a.Satisfying(candidates, policy, less); len(satisfying) > 0 {
+		return satisfying[0]
+	}
+	return nil
+}
+
+// WhatSatisfiesAny returns the packages of s satisfying any of the
+// alternatives, ordered by the alternative satisfied. Packages satisfying
+// several alternatives are only returned once.
+func (s *PkgbuildSet) WhatSatisfiesAny(a Alternatives, policy ProvidePolicy) []Provider {
+	var providers []Provider
+	seen := make(map[string]bool)
+	for _, dep := range a {
+		for _, provider := range s.WhatSatisfies(dep, policy) {
+			if !seen[provider.Pkgname] {
+				seen[provider.Pkgname] = true
+				providers = append(providers, provider)
+			}
+		}
+	}
+	return providers
+}
@@ -0,0 +1,86 @@
+package pkgbuild
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAlternatives(t *testing.T) {
+	a, err := ParseAlternatives("java-runtime>=11 | jre11|jdk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.String() != "java-runtime>=11 | jre11 | jdk" {
+		t.Errorf("unexpected alternatives: %s", a)
+	}
+
+	for _, s := range []string{"", "foo|", "foo|-bar", "foo>=a:b:c"} {
+		if _, err := ParseAlternatives(s); err == nil {
+			t.Errorf("expected error parsing %q", s)
+		}
+	}
+}
+
+func TestAlternativesSatisfying(t *testing.T) {
+	a, err := ParseAlternatives("java-runtime>=11|jre11")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	version := func(v string) *CompleteVersion {
+		version, err := NewCompleteVersion(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return version
+	}
+
+	candidates := []*Candidate{
+		{Name: "jre11", Version: version("11.0.2-1")},
+		{Name: "jre8", Version: version("8-1"), Provides: []string{"java-runtime=8"}},
+		{Name: "jdk17", Version: version("17-1"), Provides: []string{"java-runtime=17"}},
+		{Name: "jre17", Version: version("17-1"), Provides: []string{"java-runtime=17"}, Priority: 1},
+	}
+
+	var names []string
+	for _, c := range a.Satisfying(candidates, ProvidesStrict, nil) {
+		names = append(names, c.Name)
+	}
+	expected := []string{"jdk17", "jre17", "jre11"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+
+	if c := a.Select(candidates, ProvidesStrict, nil); c == nil || c.Name != "jdk17" {
+		t.Errorf("expected jdk17, got %v", c)
+	}
+	if c := a.Select(candidates[1:2], ProvidesStrict, nil); c != nil {
+		t.Errorf("expected no candidate, got %s", c.Name)
+	}
+
+	if !a.SatisfiedBy("jre11", nil, nil, ProvidesStrict) || a.SatisfiedBy("jre8", version("8-1"), []string{"java-runtime=8"}, ProvidesStrict) {
+		t.Error("unexpected SatisfiedBy result")
+	}
+}
+
+func TestWhatSatisfiesAny(t *testing.T) {
+	jdk := testPKGBUILD("jdk17", []string{"jdk17"})
+	jdk.Provides = []string{"java-runtime=17"}
+	jre := testPKGBUILD("jre11", []string{"jre11"})
+	jre.Provides = []string{"java-runtime=11"}
+	s := NewPkgbuildSet(jdk, jre)
+
+	a, err := ParseAlternatives("java-runtime>=17|jre11|java-runtime")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, provider := range s.WhatSatisfiesAny(a, ProvidesStrict) {
+		names = append(names, provider.Pkgname)
+	}
+	expected := []string{"jdk17", "jre11"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+}
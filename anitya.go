@@ -0,0 +1,117 @@
+package pkgbuild
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultAnityaURL is the URL of the API of release-monitoring.org.
+const DefaultAnityaURL = "https://release-monitoring.org/api/v2"
+
+// Anitya is a client of the API of Anitya, the upstream release monitoring
+// of release-monitoring.org.
+type Anitya struct {
+	URL          string       // DefaultAnityaURL if empty
+	Client       *http.Client // http.DefaultClient if nil
+	Distribution string       // distribution the packages are mapped for, "Arch Linux" if empty
+}
+
+// UpdateReport compares the version of a package with the latest upstream
+// release.
+type UpdateReport struct {
+	Pkgbase  string
+	Packaged Version // pkgver of the package
+	Upstream Version
+}
+
+// Outdated returns true if the upstream version is newer than the packaged
+// one.
+func (r *UpdateReport) Outdated() bool {
+	return rpmvercmp(r.Upstream, r.Packaged) > 0
+}
+
+// String returns the report like "foo: upstream 2.1.0 vs packaged 2.0.3".
+func (r *UpdateReport) String() string {
+	return fmt.Sprintf("%s: upstream %s vs packaged %s", r.Pkgbase, r.Upstream, r.Packaged)
+}
+
+// Latest returns the latest stable upstream version of the package name.
+// The project is looked up by the mapping of the package in the
+// distribution first, then by the project name.
+func (c *Anitya) Latest(ctx context.Context, name string) (Version, error) {
+	base := c.URL
+	if base == "" {
+		base = DefaultAnityaURL
+	}
+	base = strings.TrimSuffix(base, "/")
+
+	distribution := c.Distribution
+	if distribution == "" {
+		distribution = "Arch Linux"
+	}
+
+	var packages struct {
+		Items []struct {
+			Name          string `json:"name"`
+			Project       string `json:"project"`
+			Version       string `json:"version"`
+			StableVersion string `json:"stable_version"`
+		} `json:"items"`
+	}
+	query := url.Values{"name": {name}, "distribution": {distribution}}
+	if err := getJSON(ctx, c.Client, base+"/packages/?"+query.Encode(), &packages); err != nil {
+		return "", err
+	}
+	for _, item := range packages.Items {
+		if item.StableVersion != "" {
+			return upstreamVersion(item.StableVersion), nil
+		}
+		if item.Version != "" {
+			return upstreamVersion(item.Version), nil
+		}
+	}
+
+	var projects struct {
+		Items []struct {
+			Name           string   `json:"name"`
+			Version        string   `json:"version"`
+			StableVersions []string `json:"stable_versions"`
+		} `json:"items"`
+	}
+	query = url.Values{"name": {name}}
+	if err := getJSON(ctx, c.Client, base+"/projects/?"+query.Encode(), &projects); err != nil {
+		return "", err
+	}
+	for _, item := range projects.Items {
+		if len(item.StableVersions) > 0 {
+			return upstreamVersion(item.StableVersions[0]), nil
+		}
+		if item.Version != "" {
+			return upstreamVersion(item.Version), nil
+		}
+	}
+
+	return "", fmt.Errorf("no upstream release of %s found", name)
+}
+
+// upstreamVersion returns version without a v prefix, as in tag names.
+func upstreamVersion(version string) Version {
+	if len(version) > 1 && (version[0] == 'v' || version[0] == 'V') && isDigit(rune(version[1])) {
+		version = version[1:]
+	}
+	return Version(version)
+}
+
+// Check compares the pkgver of p with the latest upstream release of its
+// pkgbase, without the VCS suffix of e.g. -git packages.
+func (c *Anitya) Check(ctx context.Context, p *PKGBUILD) (*UpdateReport, error) {
+	name, _ := VCSBaseName(p.Pkgbase)
+	upstream, err := c.Latest(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return &UpdateReport{Pkgbase: p.Pkgbase, Packaged: p.Pkgver, Upstream: upstream}, nil
+}
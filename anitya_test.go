@@ -0,0 +1,57 @@
+package pkgbuild
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAnitya(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("name")
+		switch {
+		case r.URL.Path == "/api/v2/packages/" && r.URL.Query().Get("distribution") != "Arch Linux":
+			http.Error(w, "unexpected distribution", http.StatusBadRequest)
+		case r.URL.Path == "/api/v2/packages/" && name == "foo":
+			w.Write([]byte(`{"items": [{"name": "foo", "project": "Foo", "version": "2.2.0rc1", "stable_version": "2.1.0"}]}`))
+		case r.URL.Path == "/api/v2/packages/":
+			w.Write([]byte(`{"items": []}`))
+		case r.URL.Path == "/api/v2/projects/" && name == "bar":
+			w.Write([]byte(`{"items": [{"name": "bar", "version": "v1.1", "stable_versions": ["v1.0", "0.9"]}]}`))
+		case r.URL.Path == "/api/v2/projects/":
+			w.Write([]byte(`{"items": []}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	c := &Anitya{URL: server.URL + "/api/v2"}
+
+	report, err := c.Check(context.Background(), &PKGBUILD{Pkgbase: "foo", Pkgver: "2.0.3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.String() != "foo: upstream 2.1.0 vs packaged 2.0.3" {
+		t.Errorf("unexpected report: %s", report)
+	}
+	if !report.Outdated() {
+		t.Error("expected foo to be outdated")
+	}
+
+	report, err = c.Check(context.Background(), &PKGBUILD{Pkgbase: "bar-git", Pkgver: "1.0.r5.gabcdef"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Upstream != "1.0" {
+		t.Errorf("expected upstream 1.0 of bar, got %s", report.Upstream)
+	}
+	if report.Outdated() {
+		t.Error("expected bar-git to be up to date")
+	}
+
+	if _, err := c.Latest(context.Background(), "baz"); err == nil {
+		t.Error("expected error for unknown project")
+	}
+}
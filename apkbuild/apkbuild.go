@@ -0,0 +1,161 @@
+// Package apkbuild parses Alpine Linux APKBUILD files.
+//
+// APKBUILDs are shell scripts like PKGBUILDs, so they are parsed with the
+// shell parser of the pkgbuild package without running them. List
+// variables like depends are plain strings of whitespace separated values
+// in APKBUILDs, they are split into slices.
+package apkbuild
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	pkgbuild "github.com/mikkeloscar/gopkgbuild"
+)
+
+// APKBUILD is a parsed APKBUILD file.
+// Required fields are:
+//
+//	pkgname
+//	pkgver
+//	pkgrel
+type APKBUILD struct {
+	Pkgname      string
+	Pkgver       string // Alpine version e.g. "1.2.3_rc1"
+	Pkgrel       int
+	Pkgdesc      string
+	URL          string
+	Arch         []string // e.g. "all" or "x86_64", "!armhf" excludes an arch
+	License      string   // SPDX license expression
+	Depends      []string
+	Makedepends  []string
+	Checkdepends []string
+	Install      []string
+	Subpackages  []Subpackage
+	Provides     []string
+	Replaces     []string
+	Triggers     []string
+	Options      []string
+	Source       []string
+	Sha512sums   map[string]string // checksum by file name
+}
+
+// Subpackage is an entry of subpackages e.g. "$pkgname-doc" or
+// "py3-foo:_py3:noarch".
+type Subpackage struct {
+	Name string
+	Func string // function building the subpackage, if not the default
+	Arch string // arch of the subpackage, if not the one of the package
+}
+
+// ParseFile parses the APKBUILD file given by path.
+func ParseFile(path string) (*APKBUILD, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read file: %s, %s", path, err.Error())
+	}
+
+	return Parse(content)
+}
+
+// Parse parses the APKBUILD content. The variables assigned at the top level
+// are evaluated with a pkgbuild.Expander.
+func Parse(content []byte) (*APKBUILD, error) {
+	file, err := pkgbuild.ParseAST(content)
+	if err != nil {
+		return nil, err
+	}
+
+	e := pkgbuild.NewExpander(file)
+	value := func(name string) string {
+		return strings.TrimSpace(strings.Join(e.Values(name), " "))
+	}
+	list := func(name string) []string {
+		if fields := strings.Fields(value(name)); len(fields) > 0 {
+			return fields
+		}
+		return nil
+	}
+
+	a := &APKBUILD{
+		Pkgname:      value("pkgname"),
+		Pkgver:       value("pkgver"),
+		Pkgdesc:      value("pkgdesc"),
+		URL:          value("url"),
+		Arch:         list("arch"),
+		License:      value("license"),
+		Depends:      list("depends"),
+		Makedepends:  list("makedepends"),
+		Checkdepends: list("checkdepends"),
+		Install:      list("install"),
+		Provides:     list("provides"),
+		Replaces:     list("replaces"),
+		Triggers:     list("triggers"),
+		Options:      list("options"),
+		Source:       list("source"),
+	}
+
+	if a.Pkgname == "" {
+		return nil, fmt.Errorf("pkgname missing")
+	}
+	if a.Pkgver == "" {
+		return nil, fmt.Errorf("pkgver missing")
+	}
+
+	if a.Pkgrel, err = strconv.Atoi(value("pkgrel")); err != nil || a.Pkgrel < 0 {
+		return nil, fmt.Errorf("invalid pkgrel: %q", value("pkgrel"))
+	}
+
+	for _, entry := range list("subpackages") {
+		parts := strings.SplitN(entry, ":", 3)
+		s := Subpackage{Name: parts[0]}
+		if len(parts) > 1 {
+			s.Func = parts[1]
+		}
+		if len(parts) > 2 {
+			s.Arch = parts[2]
+		}
+		a.Subpackages = append(a.Subpackages, s)
+	}
+
+	if a.Sha512sums, err = parseSums(value("sha512sums")); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// parseSums parses checksum lines like "<checksum>  <file name>".
+func parseSums(sums string) (map[string]string, error) {
+	fields := strings.Fields(sums)
+	if len(fields)%2 != 0 {
+		return nil, fmt.Errorf("invalid sha512sums: %q", sums)
+	}
+
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	checksums := make(map[string]string, len(fields)/2)
+	for i := 0; i < len(fields); i += 2 {
+		checksums[fields[i+1]] = fields[i]
+	}
+	return checksums, nil
+}
+
+// Version returns the full version of a e.g. "1.2.3-r0".
+func (a *APKBUILD) Version() string {
+	return a.Pkgver + "-r" + strconv.Itoa(a.Pkgrel)
+}
+
+// SourceFileNames returns the local file names of the sources of a, which
+// the checksums of Sha512sums refer to.
+func (a *APKBUILD) SourceFileNames() []string {
+	names := make([]string, 0, len(a.Source))
+	for _, source := range a.Source {
+		names = append(names, pkgbuild.Source(source).FileName())
+	}
+	return names
+}
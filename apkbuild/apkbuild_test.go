@@ -0,0 +1,97 @@
+package apkbuild
+
+import (
+	"reflect"
+	"testing"
+)
+
+const sample = `# Contributor: Foo Bar <foo@example.org>
+# Maintainer: Foo Bar <foo@example.org>
+pkgname=foo
+pkgver=1.2.3_rc1
+pkgrel=2
+pkgdesc="A foo library"
+url="https://example.org/foo"
+arch="all !armhf"
+license="MIT OR Apache-2.0"
+depends="libbar so:libc.musl-x86_64.so.1"
+makedepends="cmake
+	samurai
+	"
+checkdepends="bats"
+subpackages="$pkgname-dev $pkgname-doc py3-$pkgname:_py3:noarch"
+source="https://example.org/foo-$pkgver.tar.gz
+	fix-build.patch
+	"
+
+build() {
+	cmake -B build -G Ninja
+	cmake --build build
+}
+
+package() {
+	DESTDIR="$pkgdir" cmake --install build
+}
+
+sha512sums="
+abc123  foo-1.2.3_rc1.tar.gz
+def456  fix-build.patch
+"
+`
+
+func TestParse(t *testing.T) {
+	a, err := Parse([]byte(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &APKBUILD{
+		Pkgname:      "foo",
+		Pkgver:       "1.2.3_rc1",
+		Pkgrel:       2,
+		Pkgdesc:      "A foo library",
+		URL:          "https://example.org/foo",
+		Arch:         []string{"all", "!armhf"},
+		License:      "MIT OR Apache-2.0",
+		Depends:      []string{"libbar", "so:libc.musl-x86_64.so.1"},
+		Makedepends:  []string{"cmake", "samurai"},
+		Checkdepends: []string{"bats"},
+		Subpackages: []Subpackage{
+			{Name: "foo-dev"},
+			{Name: "foo-doc"},
+			{Name: "py3-foo", Func: "_py3", Arch: "noarch"},
+		},
+		Source: []string{"https://example.org/foo-1.2.3_rc1.tar.gz", "fix-build.patch"},
+		Sha512sums: map[string]string{
+			"foo-1.2.3_rc1.tar.gz": "abc123",
+			"fix-build.patch":      "def456",
+		},
+	}
+
+	if !reflect.DeepEqual(a, expected) {
+		t.Errorf("expected %+v, got %+v", expected, a)
+	}
+
+	if a.Version() != "1.2.3_rc1-r2" {
+		t.Errorf("expected version 1.2.3_rc1-r2, got %s", a.Version())
+	}
+
+	names := a.SourceFileNames()
+	if !reflect.DeepEqual(names, []string{"foo-1.2.3_rc1.tar.gz", "fix-build.patch"}) {
+		t.Errorf("unexpected source file names %v", names)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, content := range []string{
+		"pkgver=1\npkgrel=0\n",
+		"pkgname=foo\npkgrel=0\n",
+		"pkgname=foo\npkgver=1\n",
+		"pkgname=foo\npkgver=1\npkgrel=r1\n",
+		"pkgname=foo\npkgver=1\npkgrel=0\nsha512sums=\"abc\"\n",
+	} {
+		if _, err := Parse([]byte(content)); err == nil {
+			t.Errorf("expected error for %q", content)
+		}
+	}
+}
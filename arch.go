@@ -0,0 +1,481 @@
+package pkgbuild
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// Arch is an architecture of the arch array e.g. "x86_64", or "any" for
+// architecture independent packages.
+type Arch string
+
+// Common architectures
+const (
+	Any     Arch = "any"
+	I686    Arch = "i686"
+	X8664   Arch = "x86_64"
+	ARMv6h  Arch = "armv6h"
+	ARMv7h  Arch = "armv7h"
+	AArch64 Arch = "aarch64"
+	RISCV64 Arch = "riscv64"
+	ARM     Arch = "arm" // ARMv5
+)
+
+func (a Arch) String() string {
+	return string(a)
+}
+
+// ParseArch parses the architecture name s. Like makepkg any name made of
+// alphanumeric characters and underscores is accepted, not only the
+// predefined ones.
+func ParseArch(s string) (Arch, error) {
+	if s == "" {
+		return "", fmt.Errorf("empty architecture")
+	}
+
+	for _, c := range s {
+		if !isAlphaNumericUnderscore(c) {
+			return "", fmt.Errorf("invalid architecture: %s", s)
+		}
+	}
+
+	return Arch(s), nil
+}
+
+// ArchSet is a set of architectures like the arch array of a PKGBUILD. A set
+// including Any contains every architecture.
+type ArchSet []Arch
+
+// ParseArchSet parses the architecture names archs, see ParseArch.
+func ParseArchSet(archs []string) (ArchSet, error) {
+	set := make(ArchSet, 0, len(archs))
+	for _, s := range archs {
+		a, err := ParseArch(s)
+		if err != nil {
+			return nil, err
+		}
+		if !set.has(a) {
+			set = append(set, a)
+		}
+	}
+	return set, nil
+}
+
+// has reports whether a is literally in s.
+func (s ArchSet) has(a Arch) bool {
+	for _, arch := range s {
+		if arch == a {
+			return true
+		}
+	}
+	return false
+}
+
+// Contains reports whether packages for the architectures s can be used on
+// a, i.e. whether s includes a or Any.
+func (s ArchSet) Contains(a Arch) bool {
+	return s.has(a) || s.has(Any)
+}
+
+// Intersect returns the architectures in both s and s2, in the order of s.
+// If either set includes Any the other one is returned as is.
+func (s ArchSet) Intersect(s2 ArchSet) ArchSet {
+	switch {
+	case s.has(Any):
+		return s2
+	case s2.has(Any):
+		return s
+	}
+
+	var set ArchSet
+	for _, a := range s {
+		if s2.has(a) {
+			set = append(set, a)
+		}
+	}
+	return set
+}
+
+// Archs returns the architectures of the arch array of p. Invalid names are
+// skipped, they are reported by Lint.
+func (p *PKGBUILD) Archs() ArchSet {
+	set := make(ArchSet, 0, len(p.Arch))
+	for _, s := range p.Arch {
+		if a, err := ParseArch(s); err == nil && !set.has(a) {
+			set = append(set, a)
+		}
+	}
+	return set
+}
+
+// SupportsArch reports whether p can be built for or installed on a, which
+// is the case if the arch array includes a or "any".
+func (p *PKGBUILD) SupportsArch(a Arch) bool {
+	return p.Archs().Contains(a)
+}
+
+var (
+	hostArchOnce sync.Once
+	hostArch     Arch
+)
+
+// HostArch returns the architecture of the running system as makepkg sees
+// it: the CARCH of makepkg.conf if set, otherwise derived from "uname -m" or
+// GOARCH. Machine names differing from the architecture names are mapped,
+// e.g. "armv7l" to armv7h. The result is computed once.
+func HostArch() Arch {
+	hostArchOnce.Do(func() {
+		hostArch = detectArch(makepkgConfPaths(), uname)
+	})
+	return hostArch
+}
+
+// detectArch returns the architecture given by the CARCH of the last of the
+// makepkg.conf files confs setting it, by uname or by GOARCH.
+func detectArch(confs []string, uname func() string) Arch {
+	for i := len(confs) - 1; i >= 0; i-- {
+		f, err := ParseASTFile(confs[i])
+		if err != nil {
+			continue
+		}
+		if values := NewExpander(f).Values("CARCH"); len(values) > 0 {
+			if a, err := ParseArch(values[0]); err == nil {
+				return a
+			}
+		}
+	}
+
+	if a, ok := machineArch(uname()); ok {
+		return a
+	}
+
+	a, _ := machineArch(runtime.GOARCH)
+	return a
+}
+
+// makepkgConfPaths returns the makepkg.conf files in the order makepkg reads
+// them, later ones override earlier ones.
+func makepkgConfPaths() []string {
+	confs := []string{"/etc/makepkg.conf"}
+	if conf := os.Getenv("MAKEPKG_CONF"); conf != "" {
+		confs[0] = conf
+	}
+
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		confs = append(confs, filepath.Join(dir, "pacman", "makepkg.conf"))
+	} else if home := os.Getenv("HOME"); home != "" {
+		confs = append(confs, filepath.Join(home, ".config", "pacman", "makepkg.conf"))
+	}
+	if home := os.Getenv("HOME"); home != "" {
+		confs = append(confs, filepath.Join(home, ".makepkg.conf"))
+	}
+
+	return confs
+}
+
+// uname returns the machine name of the running system, empty if unknown.
+func uname() string {
+	out, err := exec.Command("uname", "-m").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// machineArch maps the machine name of uname or GOARCH to the architecture
+// name used by makepkg.
+func machineArch(machine string) (Arch, bool) {
+	switch machine {
+	case "":
+		return "", false
+	case "x86_64", "amd64":
+		return X8664, true
+	case "i386", "i486", "i586", "i686", "386":
+		return I686, true
+	case "aarch64", "arm64":
+		return AArch64, true
+	case "armv7l", "armv8l", "arm":
+		// armv8l is a 32 bit userspace on a 64 bit kernel, GOARCH arm
+		// doesn't tell the version so the common one is assumed
+		return ARMv7h, true
+	case "armv6l":
+		return ARMv6h, true
+	case "armv5tel", "armv5tejl":
+		return ARM, true
+	}
+
+	a, err := ParseArch(machine)
+	return a, err == nil
+}
+
+// ResolveFor returns p as makepkg sees it when building on a: the arch
+// specific variables of a like depends_x86_64 are appended to their base
+// arrays, those of other architectures are dropped. The arch array of the
+// result is the arch of the built packages, a or "any". An error is
+// returned if p doesn't support a. p is not modified.
+func (p *PKGBUILD) ResolveFor(a Arch) (*PKGBUILD, error) {
+	if !p.SupportsArch(a) {
+		return nil, fmt.Errorf("unsupported arch: %s", a)
+	}
+
+	r := *p
+	r.ArchSpecific = nil
+	r.base = nil
+	r.Arch = []string{a.String()}
+	if contains(p.Arch, Any.String()) {
+		r.Arch = []string{Any.String()}
+	}
+
+	resolved := p.archIndependent().copy()
+	if specific, ok := p.ArchSpecific[a.String()]; ok {
+		resolved.extend(specific)
+	}
+	r.setArchVariables(resolved)
+
+	r.Pkgnames = copyStrings(p.Pkgnames)
+	r.License = copyStrings(p.License)
+	r.Groups = copyStrings(p.Groups)
+	r.Backup = copyStrings(p.Backup)
+	r.Options = copyStrings(p.Options)
+	r.Noextract = copyStrings(p.Noextract)
+	r.Validpgpkeys = copyStrings(p.Validpgpkeys)
+	r.Comments = copyStrings(p.Comments)
+
+	if p.Extra != nil {
+		r.Extra = make(map[string][]string, len(p.Extra))
+		for name, values := range p.Extra {
+			r.Extra[name] = copyStrings(values)
+		}
+	}
+
+	return &r, nil
+}
+
+// archVariables returns the values of the variables which can be arch
+// specific, e.g. Depends with the values of all depends_<arch> included.
+func (p *PKGBUILD) archVariables() *ArchSpecific {
+	a := &ArchSpecific{
+		Depends:      p.Depends,
+		Optdepends:   p.Optdepends,
+		Makedepends:  p.Makedepends,
+		Checkdepends: p.Checkdepends,
+		Provides:     p.Provides,
+		Conflicts:    p.Conflicts,
+		Replaces:     p.Replaces,
+		Source:       p.Source,
+	}
+	for algo, sums := range p.sums() {
+		*a.checksumArray(algo) = sums
+	}
+	return a
+}
+
+// setArchVariables sets the variables which can be arch specific to the
+// values of a.
+func (p *PKGBUILD) setArchVariables(a *ArchSpecific) {
+	p.Depends = a.Depends
+	p.Optdepends = a.Optdepends
+	p.Makedepends = a.Makedepends
+	p.Checkdepends = a.Checkdepends
+	p.Provides = a.Provides
+	p.Conflicts = a.Conflicts
+	p.Replaces = a.Replaces
+	p.Source = a.Source
+	for algo, sums := range a.sums() {
+		*p.checksumArray(algo) = sums
+	}
+}
+
+// archIndependent returns the values of the variables which can be arch
+// specific without the values of ArchSpecific, e.g. the values of depends
+// without those of depends_x86_64. These are the values kept apart when
+// parsing, with the changes made to the variables since applied. A
+// PKGBUILD built by hand has the arch specific values only in
+// ArchSpecific, so its variables are returned as they are.
+func (p *PKGBUILD) archIndependent() *ArchSpecific {
+	if p.base == nil {
+		return p.archVariables()
+	}
+
+	a := p.base.copy()
+	a.applyChanges(p.mergeArchSpecific(p.base), p.archVariables())
+	return a
+}
+
+// setArchIndependent sets the values kept apart of the variables which can
+// be arch specific to a and the variables to a followed by the values of
+// ArchSpecific, the way they are parsed.
+func (p *PKGBUILD) setArchIndependent(a *ArchSpecific) {
+	p.base = a.copy()
+	p.setArchVariables(p.mergeArchSpecific(a))
+}
+
+// mergeArchSpecific returns a copy of a extended with the values of every
+// arch of ArchSpecific.
+func (p *PKGBUILD) mergeArchSpecific(a *ArchSpecific) *ArchSpecific {
+	merged := a.copy()
+	for _, arch := range p.archSpecificOrder() {
+		merged.extend(p.ArchSpecific[arch])
+	}
+	return merged
+}
+
+// allArchs returns the values of the variables which can be arch specific
+// including those of every arch of the arch array. Unlike archVariables it
+// includes arch specific values missing from the variables, as in a
+// PKGBUILD built by hand.
+func (p *PKGBUILD) allArchs() *ArchSpecific {
+	a := p.archIndependent().copy()
+	for _, arch := range p.Arch {
+		if specific, ok := p.ArchSpecific[arch]; ok {
+			a.extend(specific)
+		}
+	}
+	return a
+}
+
+// archSpecificOrder returns the architectures of ArchSpecific in the order
+// of the arch array, followed by unknown ones sorted.
+func (p *PKGBUILD) archSpecificOrder() []string {
+	archs := make([]string, 0, len(p.ArchSpecific))
+	unknown := make(map[string]bool)
+	for arch := range p.ArchSpecific {
+		unknown[arch] = true
+	}
+	for _, arch := range p.Arch {
+		if unknown[arch] {
+			archs = append(archs, arch)
+			delete(unknown, arch)
+		}
+	}
+	return append(archs, sortedKeys(unknown)...)
+}
+
+// stringArrays returns pointers to the arrays of a which aren't
+// dependencies.
+func (a *ArchSpecific) stringArrays() []*[]string {
+	arrays := []*[]string{&a.Optdepends, &a.Provides, &a.Conflicts, &a.Replaces, &a.Source}
+	for _, algo := range checksumAlgorithms {
+		arrays = append(arrays, a.checksumArray(algo))
+	}
+	return arrays
+}
+
+// dependencyArrays returns pointers to the dependency arrays of a.
+func (a *ArchSpecific) dependencyArrays() []*[]*Dependency {
+	return []*[]*Dependency{&a.Depends, &a.Makedepends, &a.Checkdepends}
+}
+
+// extend appends the values of b to a. Dependencies on a name already in a
+// are merged, like when parsing.
+func (a *ArchSpecific) extend(b *ArchSpecific) {
+	arrays, more := a.stringArrays(), b.stringArrays()
+	for i, values := range arrays {
+		*values = append(*values, *more[i]...)
+	}
+
+	deps, moreDeps := a.dependencyArrays(), b.dependencyArrays()
+	for i, values := range deps {
+	Deps:
+		for _, dep := range *moreDeps[i] {
+			for j, d := range *values {
+				if d.Name == dep.Name {
+					(*values)[j] = d.Restrict(dep)
+					continue Deps
+				}
+			}
+			*values = append(*values, dep)
+		}
+	}
+}
+
+// applyChanges applies the changes made to the arrays from, resulting in
+// the arrays to, to the arrays of a. The values of a still in to and the
+// values added are kept, in the order of to.
+func (a *ArchSpecific) applyChanges(from, to *ArchSpecific) {
+	arrays, before, after := a.stringArrays(), from.stringArrays(), to.stringArrays()
+	for i, values := range arrays {
+		*values = applyChanges(*values, *before[i], *after[i])
+	}
+
+	deps, beforeDeps, afterDeps := a.dependencyArrays(), from.dependencyArrays(), to.dependencyArrays()
+	for i, values := range deps {
+		*values = applyDependencyChanges(*values, *beforeDeps[i], *afterDeps[i])
+	}
+}
+
+// applyChanges applies the changes from before to after to values, see
+// ArchSpecific.applyChanges.
+func applyChanges(values, before, after []string) []string {
+	if stringsEqual(before, after) {
+		return values
+	}
+
+	added := added(before, after)
+	kept := make(map[string]int, len(values))
+	for _, value := range values {
+		kept[value]++
+	}
+
+	changed := make([]string, 0, len(after))
+	for _, value := range after {
+		switch {
+		case added[value] > 0:
+			added[value]--
+		case kept[value] > 0:
+			kept[value]--
+		default:
+			continue
+		}
+		changed = append(changed, value)
+	}
+	return changed
+}
+
+// applyDependencyChanges is applyChanges for dependencies. The dependencies
+// of values are matched by name, as they might have been merged with arch
+// specific ones, e.g. "foo" with "foo>=2".
+func applyDependencyChanges(values, before, after []*Dependency) []*Dependency {
+	beforeStrs, afterStrs := dependencyStrings(before), dependencyStrings(after)
+	if stringsEqual(beforeStrs, afterStrs) {
+		return values
+	}
+
+	added := added(beforeStrs, afterStrs)
+	kept := make(map[string][]*Dependency, len(values))
+	for _, dep := range values {
+		kept[dep.Name] = append(kept[dep.Name], dep)
+	}
+
+	changed := make([]*Dependency, 0, len(after))
+	for i, dep := range after {
+		if added[afterStrs[i]] > 0 {
+			added[afterStrs[i]]--
+			changed = append(changed, dep)
+		} else if deps := kept[dep.Name]; len(deps) > 0 {
+			kept[dep.Name] = deps[1:]
+			changed = append(changed, deps[0])
+		}
+	}
+	return changed
+}
+
+// added returns the number of times each value of after was added to
+// before.
+func added(before, after []string) map[string]int {
+	counts := make(map[string]int, len(after))
+	for _, value := range after {
+		counts[value]++
+	}
+	for _, value := range before {
+		if counts[value] > 0 {
+			counts[value]--
+		}
+	}
+	return counts
+}
@@ -0,0 +1,244 @@
+package pkgbuild
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseArch(t *testing.T) {
+	for s, valid := range map[string]bool{
+		"x86_64":  true,
+		"any":     true,
+		"armv7h":  true,
+		"":        false,
+		"x86-64":  false,
+		"x86 64":  false,
+		"aarch64": true,
+	} {
+		a, err := ParseArch(s)
+		if valid != (err == nil) {
+			t.Errorf("expected %q valid %t, got %v", s, valid, err)
+		}
+		if valid && a.String() != s {
+			t.Errorf("expected %q, got %q", s, a)
+		}
+	}
+}
+
+func TestArchSet(t *testing.T) {
+	set, err := ParseArchSet([]string{"x86_64", "aarch64", "x86_64"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(set, ArchSet{X8664, AArch64}) {
+		t.Errorf("unexpected set %v", set)
+	}
+
+	if !set.Contains(X8664) || set.Contains(I686) {
+		t.Errorf("unexpected Contains for %v", set)
+	}
+	if !(ArchSet{Any}).Contains(RISCV64) {
+		t.Error("any should contain every architecture")
+	}
+
+	for _, test := range []struct {
+		a, b, expected ArchSet
+	}{
+		{ArchSet{X8664, AArch64}, ArchSet{AArch64, I686}, ArchSet{AArch64}},
+		{ArchSet{X8664}, ArchSet{I686}, nil},
+		{ArchSet{Any}, ArchSet{I686, X8664}, ArchSet{I686, X8664}},
+		{ArchSet{ARMv7h}, ArchSet{Any}, ArchSet{ARMv7h}},
+	} {
+		if s := test.a.Intersect(test.b); !reflect.DeepEqual(s, test.expected) {
+			t.Errorf("expected %v and %v to intersect as %v, got %v", test.a, test.b, test.expected, s)
+		}
+	}
+
+	if _, err := ParseArchSet([]string{"x86_64", "arm-v7"}); err == nil {
+		t.Error("expected error for invalid architecture")
+	}
+}
+
+func TestSupportsArch(t *testing.T) {
+	p := &PKGBUILD{Arch: []string{"x86_64", "i686"}}
+	if !p.SupportsArch(X8664) || p.SupportsArch(AArch64) {
+		t.Errorf("unexpected SupportsArch for %v", p.Arch)
+	}
+
+	p.Arch = []string{"any"}
+	if !p.SupportsArch(AArch64) {
+		t.Error("any package should support every architecture")
+	}
+}
+
+func TestLintArch(t *testing.T) {
+	p := &PKGBUILD{Pkgnames: []string{"foo"}, Pkgver: "1", Pkgrel: "1", Arch: []string{"x86-64"}}
+	for _, issue := range Lint(p) {
+		if issue.Field == "arch" && issue.Severity == SeverityError {
+			return
+		}
+	}
+	t.Error("expected an error for the invalid architecture")
+}
+
+func TestDetectArch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopkgbuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	system := filepath.Join(dir, "makepkg.conf")
+	user := filepath.Join(dir, "user.conf")
+	if err = ioutil.WriteFile(system, []byte("CARCH=\"armv7h\"\nCHOST=\"armv7l-unknown-linux-gnueabihf\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(user, []byte("PACKAGER=\"Foo <foo@example.org>\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	unknown := func() string { return "" }
+	for _, test := range []struct {
+		confs    []string
+		uname    func() string
+		expected Arch
+	}{
+		{[]string{system, user}, unknown, ARMv7h},
+		{[]string{filepath.Join(dir, "missing")}, func() string { return "armv7l" }, ARMv7h},
+		{nil, func() string { return "armv6l" }, ARMv6h},
+		{nil, func() string { return "i586" }, I686},
+		{nil, func() string { return "riscv64" }, RISCV64},
+	} {
+		if a := detectArch(test.confs, test.uname); a != test.expected {
+			t.Errorf("expected %s, got %s", test.expected, a)
+		}
+	}
+
+	if a := detectArch(nil, unknown); a == "" {
+		t.Error("expected the architecture from GOARCH")
+	}
+	if HostArch() == "" {
+		t.Error("expected a host architecture")
+	}
+}
+
+func TestResolveFor(t *testing.T) {
+	pkg, err := ParseSRCINFOContent([]byte(`pkgbase = foo
+	pkgver = 1.0
+	pkgrel = 1
+	arch = x86_64
+	arch = aarch64
+	depends = glibc
+	depends_x86_64 = lib32-glibc
+	source = foo.tar.gz
+	source_x86_64 = foo-x86_64.bin
+	source_aarch64 = foo-aarch64.bin
+	sha256sums = aaaa
+	sha256sums_x86_64 = bbbb
+	sha256sums_aarch64 = cccc
+
+pkgname = foo
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := pkg.ResolveFor(X8664)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(r.Arch, []string{"x86_64"}) || r.ArchSpecific != nil {
+		t.Errorf("unexpected arch %v, %v", r.Arch, r.ArchSpecific)
+	}
+	if !reflect.DeepEqual(dependencyStrings(r.Depends), []string{"glibc", "lib32-glibc"}) {
+		t.Errorf("unexpected depends %v", dependencyStrings(r.Depends))
+	}
+	if !reflect.DeepEqual(r.Source, []string{"foo.tar.gz", "foo-x86_64.bin"}) {
+		t.Errorf("unexpected sources %v", r.Source)
+	}
+	if !reflect.DeepEqual(r.Sha256sums, []string{"aaaa", "bbbb"}) {
+		t.Errorf("unexpected checksums %v", r.Sha256sums)
+	}
+
+	// p is not modified
+	r.Source[0] = "bar.tar.gz"
+	if pkg.Source[0] != "foo.tar.gz" || len(pkg.Depends) != 2 {
+		t.Error("expected the PKGBUILD to be unmodified")
+	}
+
+	r, err = pkg.ResolveFor(AArch64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(r.Source, []string{"foo.tar.gz", "foo-aarch64.bin"}) || len(r.Depends) != 1 {
+		t.Errorf("unexpected sources %v or depends %v", r.Source, r.Depends)
+	}
+
+	if _, err = pkg.ResolveFor(I686); err == nil {
+		t.Error("expected error for unsupported arch")
+	}
+
+	pkg.Arch = []string{"any"}
+	pkg.ArchSpecific = nil
+	if r, err = pkg.ResolveFor(I686); err != nil || !reflect.DeepEqual(r.Arch, []string{"any"}) {
+		t.Errorf("expected arch any, got %v, %v", r, err)
+	}
+}
+
+func TestArchIndependent(t *testing.T) {
+	pkg, err := ParseSRCINFOContent([]byte(`pkgbase = foo
+	pkgver = 1.0
+	pkgrel = 1
+	arch = x86_64
+	depends = glibc
+	depends = bar
+	depends_x86_64 = bar>=2
+	depends_x86_64 = lib32-glibc
+	source = foo.tar.gz
+	source_x86_64 = foo.tar.gz
+	sha256sums = aaaa
+	sha256sums_x86_64 = aaaa
+
+pkgname = foo
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	common := pkg.archIndependent()
+	if !reflect.DeepEqual(dependencyStrings(common.Depends), []string{"glibc", "bar"}) {
+		t.Errorf("unexpected depends %v", dependencyStrings(common.Depends))
+	}
+	if !reflect.DeepEqual(common.Source, []string{"foo.tar.gz"}) || !reflect.DeepEqual(common.Sha256sums, []string{"aaaa"}) {
+		t.Errorf("unexpected sources %v or checksums %v", common.Source, common.Sha256sums)
+	}
+
+	// changes to the variables apply to the arch independent values
+	deps, err := ParseDeps([]string{"zlib"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg.Depends = append(pkg.Depends, deps...)
+	pkg.Source = append([]string{"bar.tar.gz"}, pkg.Source...)
+
+	vars := pkg.Vars()
+	if !reflect.DeepEqual(vars["depends"], []string{"glibc", "bar", "zlib"}) {
+		t.Errorf("unexpected depends %v", vars["depends"])
+	}
+	if !reflect.DeepEqual(vars["depends_x86_64"], []string{"bar>=2", "lib32-glibc"}) {
+		t.Errorf("unexpected depends_x86_64 %v", vars["depends_x86_64"])
+	}
+	if !reflect.DeepEqual(vars["source"], []string{"bar.tar.gz", "foo.tar.gz"}) {
+		t.Errorf("unexpected sources %v", vars["source"])
+	}
+
+	// removing a merged dependency removes the arch independent one
+	pkg.Depends = pkg.Depends[:1]
+	if depends := dependencyStrings(pkg.archIndependent().Depends); !reflect.DeepEqual(depends, []string{"glibc"}) {
+		t.Errorf("unexpected depends %v", depends)
+	}
+}
@@ -0,0 +1,128 @@
+package pkgbuild
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+// ArchivePackage is a package directory found in an archive.
+type ArchivePackage struct {
+	Dir      string // directory in the archive, "." for the top level
+	File     string // the file parsed, .SRCINFO or PKGBUILD below Dir
+	PKGBUILD *PKGBUILD
+}
+
+// ParseArchive parses the packages in the archive at the path archive, like an AUR
+// snapshot, a CI artifact or the output of git archive, without extracting
+// it. The archive is read as by ReadPKGINFO, so tar archives compressed with
+// gzip, bzip2, xz or zstd and zip archives are supported.
+//
+// Every directory containing a .SRCINFO or PKGBUILD is a package, hidden
+// directories like .git are skipped. The .SRCINFO is parsed if present,
+// otherwise the PKGBUILD is evaluated without bash as by File.PKGBUILD,
+// since it can't be run without extracting the archive. The packages are
+// returned sorted by Dir, the error of the first package failing to parse
+// is returned. The limits of WithLimits apply to each file.
+func ParseArchive(archive string, opts ...ParseOption) ([]*ArchivePackage, error) {
+	config := newParseConfig(opts)
+
+	srcinfos := make(map[string][]byte)
+	pkgbuilds := make(map[string][]byte)
+	err := walkArchive(archive, func(name string, r io.Reader) (bool, error) {
+		files := srcinfos
+		switch path.Base(name) {
+		case ".SRCINFO":
+		case "PKGBUILD":
+			files = pkgbuilds
+		default:
+			return true, nil
+		}
+
+		dir := path.Dir(name)
+		if hiddenDir(dir) {
+			return true, nil
+		}
+
+		content, err := config.read(r)
+		if err != nil {
+			return false, fmt.Errorf("%s: %s", name, err)
+		}
+		files[dir] = content
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := make([]string, 0, len(srcinfos)+len(pkgbuilds))
+	for dir := range pkgbuilds {
+		dirs = append(dirs, dir)
+	}
+	for dir := range srcinfos {
+		if _, ok := pkgbuilds[dir]; !ok {
+			dirs = append(dirs, dir)
+		}
+	}
+	sort.Strings(dirs)
+
+	packages := make([]*ArchivePackage, 0, len(dirs))
+	for _, dir := range dirs {
+		pkg, err := parseArchivePackage(dir, srcinfos[dir], pkgbuilds[dir], opts)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", archive, pkg.File, err)
+		}
+		packages = append(packages, pkg)
+	}
+	return packages, nil
+}
+
+// parseArchivePackage parses the package in dir from the content of its
+// .SRCINFO or PKGBUILD, nil if missing.
+func parseArchivePackage(dir string, srcinfo, pkgbuild []byte, opts []ParseOption) (*ArchivePackage, error) {
+	pkg := &ArchivePackage{Dir: dir}
+
+	var err error
+	if srcinfo != nil {
+		pkg.File = path.Join(dir, ".SRCINFO")
+
+		config := newParseConfig(opts)
+		// srcinfo isn't used by anything else
+		config.zeroCopy = true
+		pkg.PKGBUILD, err = parsePKGBUILD(srcinfo, config)
+		if err != nil {
+			return pkg, err
+		}
+
+		// the header is informational, like for ParsePKGBUILD
+		if pkgbuild != nil {
+			pkg.PKGBUILD.Maintainers, pkg.PKGBUILD.Contributors = parseHeader(string(pkgbuild))
+		}
+		return pkg, nil
+	}
+
+	pkg.File = path.Join(dir, "PKGBUILD")
+	f, err := ParseAST(pkgbuild)
+	if err != nil {
+		return pkg, err
+	}
+	pkg.PKGBUILD, err = f.PKGBUILD(opts...)
+	if err != nil {
+		return pkg, err
+	}
+	pkg.PKGBUILD.Maintainers, pkg.PKGBUILD.Contributors = f.Header()
+	return pkg, nil
+}
+
+// hiddenDir returns true if the archive directory dir or one of its parents
+// is hidden.
+func hiddenDir(dir string) bool {
+	for _, name := range strings.Split(dir, "/") {
+		if name != "." && strings.HasPrefix(name, ".") {
+			return true
+		}
+	}
+	return false
+}
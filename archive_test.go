@@ -0,0 +1,139 @@
+package pkgbuild
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testArchiveFiles(t *testing.T) map[string]string {
+	files := make(map[string]string)
+	for name, file := range map[string]string{
+		"sudo/.SRCINFO":       "SRCINFO_sudo",
+		"sudo/PKGBUILD":       "PKGBUILD_sudo",
+		"openssh/PKGBUILD":    "PKGBUILD_openssh",
+		"linux/.SRCINFO":      "SRCINFO_linux",
+		".github/PKGBUILD":    "PKGBUILD_teamviewer",
+		"PKGBUILD":            "PKGBUILD_shaman-git",
+		"sudo/sudo.pam":       "PKGBUILD_sudo",
+		"openssh/sshd.conf":   "PKGBUILD_openssh",
+		"linux/.git/HEAD":     "SRCINFO_linux",
+		"linux/.git/PKGBUILD": "PKGBUILD_linux",
+	} {
+		content, err := ioutil.ReadFile("./test_pkgbuilds/" + file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[name] = string(content)
+	}
+	return files
+}
+
+func writeTestZip(t *testing.T, path string, files map[string]string) {
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopkgbuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := testArchiveFiles(t)
+
+	tarball := filepath.Join(dir, "packages.tar")
+	writeTestPackage(t, tarball, files, false)
+	gzipped := filepath.Join(dir, "packages.tar.gz")
+	writeTestPackage(t, gzipped, files, true)
+	zipped := filepath.Join(dir, "packages.zip")
+	writeTestZip(t, zipped, files)
+	paths := []string{tarball, gzipped, zipped}
+
+	if _, err := exec.LookPath("xz"); err == nil {
+		xz := tarball + ".xz"
+		if err := exec.Command("xz", "-kq", tarball).Run(); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, xz)
+	}
+
+	expected := []struct {
+		dir, file, pkgbase string
+	}{
+		{".", "PKGBUILD", "shaman-git"},
+		{"linux", "linux/.SRCINFO", "linux"},
+		{"openssh", "openssh/PKGBUILD", "openssh"},
+		{"sudo", "sudo/.SRCINFO", "sudo"},
+	}
+
+	for _, path := range paths {
+		packages, err := ParseArchive(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(packages) != len(expected) {
+			t.Fatalf("%s: expected %d packages, got %d", path, len(expected), len(packages))
+		}
+		for i, e := range expected {
+			pkg := packages[i]
+			if pkg.Dir != e.dir || pkg.File != e.file || pkg.PKGBUILD.pkgbase() != e.pkgbase {
+				t.Errorf("%s: expected %s parsed from %s, got %s from %s in %s", path, e.pkgbase, e.file, pkg.PKGBUILD.pkgbase(), pkg.File, pkg.Dir)
+			}
+		}
+
+		// the maintainers of the PKGBUILD are kept next to the .SRCINFO
+		sudo := packages[3].PKGBUILD
+		if len(sudo.Maintainers) == 0 {
+			t.Errorf("%s: expected maintainers of the sudo PKGBUILD", path)
+		}
+	}
+}
+
+func TestParseArchiveError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopkgbuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "packages.tar.gz")
+	writeTestPackage(t, path, map[string]string{
+		"foo/.SRCINFO": "pkgbase = foo\n\tpkgver = 1.0\n",
+	}, true)
+
+	_, err = ParseArchive(path)
+	if err == nil {
+		t.Fatal("expected error for the invalid .SRCINFO")
+	}
+	if expected := path + ": foo/.SRCINFO: "; !strings.HasPrefix(err.Error(), expected) {
+		t.Errorf("expected error for %s, got: %s", expected, err)
+	}
+
+	if _, err := ParseArchive(filepath.Join(dir, "missing.tar.gz")); err == nil {
+		t.Error("expected error for a missing archive")
+	}
+}
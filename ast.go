@@ -0,0 +1,211 @@
+package pkgbuild
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// Node is a node of the syntax tree of a PKGBUILD.
+type Node interface {
+	Pos() Position // position of the first character of the node
+	End() Position // position of the character immediately after the node
+}
+
+// node holds the position of a Node.
+type node struct {
+	pos Position
+	end Position
+}
+
+// Pos returns the position of the first character of the node.
+func (n node) Pos() Position { return n.pos }
+
+// End returns the position of the character immediately after the node.
+func (n node) End() Position { return n.end }
+
+// File is the syntax tree of a PKGBUILD file.
+type File struct {
+	node
+	Stmts []Node // *Assignment, *Function, *Comment or *Command
+
+	content string
+}
+
+// Word is a single shell word e.g. "$pkgname-$pkgver.tar.gz".
+type Word struct {
+	node
+	Raw   string // the word as written, including quotes
+	Value string // the word with quotes removed, expansions are kept as is
+}
+
+// Assignment is a variable assignment: name=value, name=(...) or name+=...
+type Assignment struct {
+	node
+	Name   string
+	Append bool  // the assignment uses +=
+	Value  *Word // the value of a scalar assignment, nil for arrays
+	Array  *Array
+}
+
+// Array is the value of an array assignment.
+type Array struct {
+	node     // from ( to )
+	Elements []*Word
+}
+
+// Function is a function definition e.g. package() { ... }.
+type Function struct {
+	node
+	Name    string
+	Body    string   // raw bash between the braces
+	BodyPos Position // position of the first character of Body
+}
+
+// Comment is a comment line.
+type Comment struct {
+	node
+	Text string // comment text without the leading #
+}
+
+// Command is any other top level command e.g. an if statement, kept as raw
+// bash.
+type Command struct {
+	node
+	Raw string
+}
+
+// ParseAST parses the PKGBUILD content into a syntax tree.
+func ParseAST(content []byte) (*File, error) {
+	input := string(content)
+	stmts, err := parseShell(input)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &File{
+		node:  node{offsetPosition(input, 0), offsetPosition(input, len(input))},
+		Stmts: make([]Node, 0, len(stmts)),
+
+		content: input,
+	}
+
+	for _, stmt := range stmts {
+		file.Stmts = append(file.Stmts, stmt.node(input))
+	}
+
+	return file, nil
+}
+
+// ParseASTFile parses the PKGBUILD file given by path into a syntax tree.
+func ParseASTFile(path string) (*File, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read file: %s, %s", path, err.Error())
+	}
+
+	return ParseAST(content)
+}
+
+// spanNode returns the node covering s in input.
+func spanNode(input string, s span) node {
+	return node{offsetPosition(input, s.start), offsetPosition(input, s.end)}
+}
+
+// word returns the Word covering s in input.
+func word(input string, s span) *Word {
+	raw := input[s.start:s.end]
+	return &Word{
+		node:  spanNode(input, s),
+		Raw:   raw,
+		Value: unquoteWord(raw),
+	}
+}
+
+// node converts the statement to its syntax tree node.
+func (stmt statement) node(input string) Node {
+	n := spanNode(input, stmt.span)
+
+	switch stmt.kind {
+	case stmtAssignment:
+		a := &Assignment{node: n, Name: stmt.name, Append: stmt.append}
+		if !stmt.array {
+			a.Value = word(input, stmt.value)
+			return a
+		}
+
+		a.Array = &Array{
+			node:     spanNode(input, span{stmt.value.start - 1, stmt.end}),
+			Elements: make([]*Word, 0, len(stmt.elements)),
+		}
+		for _, element := range stmt.elements {
+			a.Array.Elements = append(a.Array.Elements, word(input, element))
+		}
+		return a
+	case stmtFunction:
+		return &Function{
+			node:    n,
+			Name:    stmt.name,
+			Body:    input[stmt.value.start:stmt.value.end],
+			BodyPos: offsetPosition(input, stmt.value.start),
+		}
+	case stmtComment:
+		return &Comment{node: n, Text: input[stmt.start+1 : stmt.end]}
+	}
+
+	return &Command{node: n, Raw: input[stmt.start:stmt.end]}
+}
+
+// Assignments returns the top level assignments of f in order.
+func (f *File) Assignments() []*Assignment {
+	var assignments []*Assignment
+	for _, stmt := range f.Stmts {
+		if a, ok := stmt.(*Assignment); ok {
+			assignments = append(assignments, a)
+		}
+	}
+	return assignments
+}
+
+// Functions returns the function definitions of f in order.
+func (f *File) Functions() []*Function {
+	var functions []*Function
+	for _, stmt := range f.Stmts {
+		if fn, ok := stmt.(*Function); ok {
+			functions = append(functions, fn)
+		}
+	}
+	return functions
+}
+
+// Function returns the definition of the function name, or nil if f doesn't
+// define it. If the function is defined more than once the last definition
+// is returned, like bash does.
+func (f *File) Function(name string) *Function {
+	var function *Function
+	for _, fn := range f.Functions() {
+		if fn.Name == name {
+			function = fn
+		}
+	}
+	return function
+}
+
+// HasFunction reports whether f defines the function name, e.g. "check".
+func (f *File) HasFunction(name string) bool {
+	return f.Function(name) != nil
+}
+
+// PackageFunctions returns the package functions by package name: "" for
+// package() and the pkgname for package_<pkgname>() of split packages.
+func (f *File) PackageFunctions() map[string]*Function {
+	functions := make(map[string]*Function)
+	for _, fn := range f.Functions() {
+		if fn.Name == "package" {
+			functions[""] = fn
+		} else if strings.HasPrefix(fn.Name, "package_") {
+			functions[strings.TrimPrefix(fn.Name, "package_")] = fn
+		}
+	}
+	return functions
+}
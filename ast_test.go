@@ -0,0 +1,172 @@
+package pkgbuild
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseAST(t *testing.T) {
+	file, err := ParseASTFile("./test_pkgbuilds/PKGBUILD_sudo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	comment, ok := file.Stmts[1].(*Comment)
+	if !ok || comment.Text != " Maintainer: Evangelos Foutras <evangelos@foutrelis.com>" {
+		t.Errorf("unexpected comment: %#v", file.Stmts[1])
+	}
+
+	var sums *Assignment
+	for _, a := range file.Assignments() {
+		if a.Name == "sha256sums" {
+			sums = a
+		}
+	}
+
+	if sums == nil || sums.Array == nil || len(sums.Array.Elements) != 4 {
+		t.Fatalf("unexpected sha256sums: %#v", sums)
+	}
+
+	if pos := sums.Pos(); pos.Line != 21 || pos.Column != 1 {
+		t.Errorf("unexpected position of sha256sums: %s", pos)
+	}
+
+	skip := sums.Array.Elements[1]
+	if skip.Raw != "'SKIP'" || skip.Value != "SKIP" || skip.Pos().String() != "22:13" {
+		t.Errorf("unexpected element: %#v", skip)
+	}
+
+	if end := sums.Array.End(); end != sums.End() || end.Line != 24 {
+		t.Errorf("unexpected end of sha256sums: %s", end)
+	}
+
+	functions := file.Functions()
+	if len(functions) != 3 || functions[0].Name != "build" || functions[2].Name != "package" {
+		t.Fatalf("unexpected functions: %#v", functions)
+	}
+
+	build := functions[0]
+	if !strings.HasPrefix(build.Body, "\n  cd \"$srcdir/$pkgname-$_sudover\"\n") || build.BodyPos.String() != "26:10" {
+		t.Errorf("unexpected body of build: %q at %s", build.Body, build.BodyPos)
+	}
+}
+
+func TestParseASTNodes(t *testing.T) {
+	file, err := ParseAST([]byte(`pkgname=foo
+depends+=("bar>=1")
+if [[ $CARCH = x86_64 ]]; then
+  depends+=(baz)
+fi
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(file.Stmts) != 3 {
+		t.Fatalf("expected 3 statements, got %d", len(file.Stmts))
+	}
+
+	if a, ok := file.Stmts[0].(*Assignment); !ok || a.Value == nil || a.Value.Value != "foo" || a.Array != nil {
+		t.Errorf("unexpected assignment: %#v", file.Stmts[0])
+	}
+
+	if a, ok := file.Stmts[1].(*Assignment); !ok || !a.Append || a.Array.Elements[0].Value != "bar>=1" {
+		t.Errorf("unexpected assignment: %#v", file.Stmts[1])
+	}
+
+	if c, ok := file.Stmts[2].(*Command); !ok || !strings.HasSuffix(c.Raw, "\nfi") || c.End().Line != 5 {
+		t.Errorf("unexpected command: %#v", file.Stmts[2])
+	}
+
+	if _, err = ParseAST([]byte("source=(foo\n")); err == nil {
+		t.Error("expected error for unterminated array")
+	}
+}
+
+func TestParseASTArrayQuoting(t *testing.T) {
+	file, err := ParseAST([]byte(`_ver=1.0
+source=("foo \"bar\"" 'single item' unquoted 'it'\''s'
+  mixed"double"'single' esc\ aped "\$_ver" '$_ver' $_ver # comment
+  "multi
+line" ')' "(")
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := []string{
+		`foo "bar"`,
+		"single item",
+		"unquoted",
+		"it's",
+		"mixeddoublesingle",
+		"esc aped",
+		"$_ver",
+		"$_ver",
+		"$_ver",
+		"multi\nline",
+		")",
+		"(",
+	}
+
+	source := file.Assignments()[1]
+	if source.Array == nil || len(source.Array.Elements) != len(values) {
+		t.Fatalf("unexpected source: %#v", source)
+	}
+	for i, element := range source.Array.Elements {
+		if element.Value != values[i] {
+			t.Errorf("expected element %d to be %q, got %q from %s", i, values[i], element.Value, element.Raw)
+		}
+	}
+
+	// only the unquoted and double quoted references are expanded
+	expanded := NewExpander(file).Values("source")
+	if len(expanded) != len(values) || expanded[6] != "$_ver" || expanded[7] != "$_ver" || expanded[8] != "1.0" {
+		t.Errorf("unexpected expanded source: %q", expanded)
+	}
+}
+
+func TestFileFunctions(t *testing.T) {
+	file, err := ParseASTFile("./test_pkgbuilds/PKGBUILD_sudo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, defined := range map[string]bool{"prepare": false, "build": true, "check": true, "package": true, "pkgver": false} {
+		if file.HasFunction(name) != defined {
+			t.Errorf("HasFunction(%s) should be %t", name, defined)
+		}
+	}
+
+	check := file.Function("check")
+	if check.Body != "\n  cd \"$srcdir/$pkgname-$_sudover\"\n  make check\n" {
+		t.Errorf("unexpected body of check: %q", check.Body)
+	}
+
+	file, err = ParseASTFile("./test_pkgbuilds/PKGBUILD_systemd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	functions := file.PackageFunctions()
+	for _, pkgname := range []string{"systemd", "libsystemd", "systemd-sysvcompat"} {
+		if functions[pkgname] == nil {
+			t.Errorf("missing package function of %s", pkgname)
+		}
+	}
+
+	if len(functions) != 3 {
+		t.Errorf("expected 3 package functions, got %d", len(functions))
+	}
+
+	for _, name := range []string{"shaman-git", "biicode"} {
+		file, err = ParseASTFile("./test_pkgbuilds/PKGBUILD_" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if functions = file.PackageFunctions(); functions[""] == nil {
+			t.Errorf("missing package function of %s", name)
+		}
+	}
+}
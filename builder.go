@@ -0,0 +1,196 @@
+package pkgbuild
+
+import "fmt"
+
+// Builder builds a PKGBUILD from scratch. The setters can be chained and
+// the first invalid value is reported by Build:
+//
+//	pkg, err := NewBuilder("foo").
+//		Pkgver("1.0").
+//		Arch("x86_64").
+//		Depends("glibc", "bar>=1.0").
+//		Build()
+type Builder struct {
+	p   *PKGBUILD
+	err error
+}
+
+// NewBuilder returns a Builder for the package pkgname.
+func NewBuilder(pkgname string) *Builder {
+	return &Builder{p: &PKGBUILD{Pkgnames: []string{pkgname}}}
+}
+
+// NewPKGBUILD returns a PKGBUILD for the package pkgname with the defaults
+// of Build applied.
+func NewPKGBUILD(pkgname string, pkgver Version) (*PKGBUILD, error) {
+	return NewBuilder(pkgname).Pkgver(pkgver).Build()
+}
+
+// fail records err unless an error was already recorded.
+func (b *Builder) fail(err error) *Builder {
+	if b.err == nil {
+		b.err = err
+	}
+	return b
+}
+
+// Pkgbase sets pkgbase, by default the first pkgname.
+func (b *Builder) Pkgbase(pkgbase string) *Builder {
+	if !validPkgname(pkgbase) {
+		return b.fail(fmt.Errorf("invalid pkgbase: %s", pkgbase))
+	}
+	b.p.Pkgbase = pkgbase
+	return b
+}
+
+// Pkgname adds additional packages for a split package.
+func (b *Builder) Pkgname(pkgnames ...string) *Builder {
+	b.p.Pkgnames = append(b.p.Pkgnames, pkgnames...)
+	return b
+}
+
+// Pkgver sets pkgver.
+func (b *Builder) Pkgver(pkgver Version) *Builder {
+	if err := b.p.SetPkgver(pkgver); err != nil {
+		return b.fail(err)
+	}
+	return b
+}
+
+// Pkgrel sets pkgrel, by default "1".
+func (b *Builder) Pkgrel(pkgrel Version) *Builder {
+	if err := b.p.SetPkgrel(pkgrel); err != nil {
+		return b.fail(err)
+	}
+	return b
+}
+
+// Epoch sets epoch.
+func (b *Builder) Epoch(epoch int) *Builder {
+	if epoch < 0 {
+		return b.fail(fmt.Errorf("invalid epoch: %d", epoch))
+	}
+	b.p.Epoch = epoch
+	return b
+}
+
+// Pkgdesc sets pkgdesc.
+func (b *Builder) Pkgdesc(pkgdesc string) *Builder {
+	b.p.Pkgdesc = pkgdesc
+	return b
+}
+
+// URL sets url.
+func (b *Builder) URL(url string) *Builder {
+	b.p.URL = url
+	return b
+}
+
+// Arch adds architectures, by default "any".
+func (b *Builder) Arch(arch ...string) *Builder {
+	b.p.Arch = append(b.p.Arch, arch...)
+	return b
+}
+
+// License adds licenses.
+func (b *Builder) License(license ...string) *Builder {
+	b.p.License = append(b.p.License, license...)
+	return b
+}
+
+// Depends adds dependencies e.g. "foo>=1.0".
+func (b *Builder) Depends(deps ...string) *Builder {
+	return b.dependencies(&b.p.Depends, deps)
+}
+
+// Makedepends adds make dependencies.
+func (b *Builder) Makedepends(deps ...string) *Builder {
+	return b.dependencies(&b.p.Makedepends, deps)
+}
+
+// Checkdepends adds check dependencies.
+func (b *Builder) Checkdepends(deps ...string) *Builder {
+	return b.dependencies(&b.p.Checkdepends, deps)
+}
+
+// dependencies adds deps to the dependency array.
+func (b *Builder) dependencies(array *[]*Dependency, deps []string) *Builder {
+	for _, dep := range deps {
+		var err error
+		if *array, err = parseDependencyKeep(dep, *array); err != nil {
+			return b.fail(err)
+		}
+	}
+	return b
+}
+
+// Optdepends adds optional dependencies e.g. "foo: for bar support".
+func (b *Builder) Optdepends(deps ...string) *Builder {
+	b.p.Optdepends = append(b.p.Optdepends, deps...)
+	return b
+}
+
+// Provides adds provides.
+func (b *Builder) Provides(provides ...string) *Builder {
+	for _, provide := range provides {
+		if err := b.p.AddProvide(provide); err != nil {
+			return b.fail(err)
+		}
+	}
+	return b
+}
+
+// Conflicts adds conflicts.
+func (b *Builder) Conflicts(conflicts ...string) *Builder {
+	b.p.Conflicts = append(b.p.Conflicts, conflicts...)
+	return b
+}
+
+// Source adds source entries.
+func (b *Builder) Source(sources ...string) *Builder {
+	b.p.Source = append(b.p.Source, sources...)
+	return b
+}
+
+// Checksums adds checksums of algo e.g. "sha256" for the source entries.
+func (b *Builder) Checksums(algo string, sums ...string) *Builder {
+	array := b.p.checksumArray(algo)
+	if array == nil {
+		return b.fail(fmt.Errorf("unsupported checksum algorithm: %s", algo))
+	}
+	*array = append(*array, sums...)
+	return b
+}
+
+// Build applies the defaults and returns the PKGBUILD, or the first error
+// found. Besides the values rejected by the parser, checksum arrays not
+// matching the number of source entries are an error. Every call returns a
+// new copy, so b can be used further, e.g. to build variants.
+func (b *Builder) Build() (*PKGBUILD, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	p := b.p.Copy()
+	if p.Pkgbase == "" && len(p.Pkgnames) > 0 {
+		p.Pkgbase = p.Pkgnames[0]
+	}
+	if p.Pkgrel == "" {
+		p.Pkgrel = "1"
+	}
+	if len(p.Arch) == 0 {
+		p.Arch = []string{"any"}
+	}
+
+	if errs := p.validate(); len(errs) > 0 {
+		return nil, errs[0]
+	}
+
+	for _, algo := range checksumAlgorithms {
+		if sums := *p.checksumArray(algo); len(sums) > 0 && len(sums) != len(p.Source) {
+			return nil, fmt.Errorf("%d %ssums for %d sources", len(sums), algo, len(p.Source))
+		}
+	}
+
+	return p, nil
+}
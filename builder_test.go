@@ -0,0 +1,85 @@
+package pkgbuild
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuilder(t *testing.T) {
+	pkg, err := NewBuilder("foo").
+		Pkgver("1.0").
+		Arch("x86_64").
+		License("MIT").
+		Depends("glibc", "bar>=1.0").
+		Makedepends("go").
+		Source("foo-1.0.tar.gz", "foo.service").
+		Checksums("sha256", "aaaa", "bbbb").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pkg.Pkgbase != "foo" || pkg.Version() != "1.0-1" {
+		t.Errorf("expected defaults pkgbase foo and version 1.0-1, got %s %s", pkg.Pkgbase, pkg.Version())
+	}
+
+	if deps := dependencyStrings(pkg.Depends); !reflect.DeepEqual(deps, []string{"glibc", "bar>=1.0"}) {
+		t.Errorf("unexpected depends: %v", deps)
+	}
+
+	checksums := pkg.SourceChecksums()
+	if len(checksums) != 2 || checksums[1].Sums["sha256"] != "bbbb" {
+		t.Errorf("unexpected checksums: %v", checksums)
+	}
+
+	pkg, err = NewPKGBUILD("bar", "2.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(pkg.Arch, []string{"any"}) {
+		t.Errorf("expected default arch any, got %v", pkg.Arch)
+	}
+}
+
+func TestBuilderReuse(t *testing.T) {
+	b := NewBuilder("foo").Pkgver("1.0").Depends("glibc")
+
+	first, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.Depends[0].Name = "musl"
+	first.Pkgrel = "2"
+
+	second, err := b.Depends("bar").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second || second.Version() != "1.0-1" {
+		t.Errorf("expected a new PKGBUILD at 1.0-1, got %s", second.Version())
+	}
+	if deps := dependencyStrings(second.Depends); !reflect.DeepEqual(deps, []string{"glibc", "bar"}) {
+		t.Errorf("unexpected depends: %v", deps)
+	}
+	if len(first.Depends) != 1 {
+		t.Errorf("expected the first PKGBUILD to be unchanged, got %v", dependencyStrings(first.Depends))
+	}
+}
+
+func TestBuilderInvalid(t *testing.T) {
+	builders := map[string]*Builder{
+		"missing pkgver":   NewBuilder("foo"),
+		"invalid pkgname":  NewBuilder("-foo").Pkgver("1.0"),
+		"invalid pkgver":   NewBuilder("foo").Pkgver("1.0-1"),
+		"invalid epoch":    NewBuilder("foo").Pkgver("1.0").Epoch(-1),
+		"invalid depend":   NewBuilder("foo").Pkgver("1.0").Depends("-bar"),
+		"unknown checksum": NewBuilder("foo").Pkgver("1.0").Checksums("crc32", "aaaa"),
+		"missing checksum": NewBuilder("foo").Pkgver("1.0").Source("a", "b").Checksums("md5", "aaaa"),
+	}
+
+	for name, builder := range builders {
+		if _, err := builder.Build(); err == nil {
+			t.Errorf("expected error for %s", name)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package pkgbuild
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// BUILDINFO is the .BUILDINFO metadata makepkg stores in a built package,
+// describing the environment it was built in.
+type BUILDINFO struct {
+	Format            int
+	Pkgname           string
+	Pkgbase           string
+	Pkgver            string // full version e.g. "1:1.0-1"
+	Pkgarch           string
+	PkgbuildSha256sum string
+	Packager          string
+	BuildDate         int64 // unix time
+	BuildDir          string
+	StartDir          string
+	BuildTool         string // e.g. "devtools"
+	BuildToolVer      string
+	BuildEnv          []string // e.g. "!distcc" or "check"
+	Options           []string // e.g. "strip" or "!lto"
+	Installed         []string // installed packages e.g. "glibc-2.38-1-x86_64"
+}
+
+// ParseBUILDINFO parses the content of a .BUILDINFO file.
+func ParseBUILDINFO(content []byte) (*BUILDINFO, error) {
+	info := &BUILDINFO{}
+
+	arrays := map[string]*[]string{
+		"buildenv":  &info.BuildEnv,
+		"options":   &info.Options,
+		"installed": &info.Installed,
+	}
+	scalars := map[string]*string{
+		"pkgname":            &info.Pkgname,
+		"pkgbase":            &info.Pkgbase,
+		"pkgver":             &info.Pkgver,
+		"pkgarch":            &info.Pkgarch,
+		"pkgbuild_sha256sum": &info.PkgbuildSha256sum,
+		"packager":           &info.Packager,
+		"builddir":           &info.BuildDir,
+		"startdir":           &info.StartDir,
+		"buildtool":          &info.BuildTool,
+		"buildtoolver":       &info.BuildToolVer,
+	}
+
+	err := parseKeyValues(content, func(key, value string) error {
+		var err error
+		switch {
+		case arrays[key] != nil:
+			if value != "" {
+				*arrays[key] = append(*arrays[key], value)
+			}
+		case scalars[key] != nil:
+			*scalars[key] = value
+		case key == "format":
+			info.Format, err = strconv.Atoi(value)
+		case key == "builddate":
+			info.BuildDate, err = strconv.ParseInt(value, 10, 64)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Pkgname == "" || info.Pkgver == "" {
+		return nil, fmt.Errorf("pkgname or pkgver missing")
+	}
+
+	return info, nil
+}
+
+// ReadBUILDINFO reads the .BUILDINFO of the built package file at path,
+// see ReadPKGINFO.
+func ReadBUILDINFO(path string) (*BUILDINFO, error) {
+	content, err := readPackageFile(path, ".BUILDINFO")
+	if err != nil {
+		return nil, err
+	}
+	return ParseBUILDINFO(content)
+}
+
+// BuiltPackage is the metadata of a built package.
+type BuiltPackage struct {
+	PKGINFO   *PKGINFO
+	BUILDINFO *BUILDINFO
+}
+
+// ReadBuiltPackage reads the .PKGINFO and .BUILDINFO of the built package
+// file at path.
+func ReadBuiltPackage(path string) (*BuiltPackage, error) {
+	pkginfo, err := ReadPKGINFO(path)
+	if err != nil {
+		return nil, err
+	}
+
+	buildinfo, err := ReadBUILDINFO(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BuiltPackage{pkginfo, buildinfo}, nil
+}
+
+// CompareBuilds returns the differences between two builds of a package
+// which may explain why they aren't reproducible, like different versions
+// of installed packages, build environments or options. Builds made from
+// the same PKGBUILD in the same environment have no differences, given
+// SOURCE_DATE_EPOCH was set to the same build date.
+func CompareBuilds(a, b *BuiltPackage) Changes {
+	var changes Changes
+
+	scalar := func(field, old, new string) {
+		if old != new {
+			changes = append(changes, Change{Field: field, Old: old, New: new})
+		}
+	}
+	array := func(field string, old, new []string) {
+		added := difference(new, old)
+		removed := difference(old, new)
+		if len(added) > 0 || len(removed) > 0 {
+			changes = append(changes, Change{Field: field, Added: added, Removed: removed})
+		}
+	}
+
+	ba, bb := a.BUILDINFO, b.BUILDINFO
+	scalar("pkgname", ba.Pkgname, bb.Pkgname)
+	scalar("pkgver", ba.Pkgver, bb.Pkgver)
+	scalar("pkgarch", ba.Pkgarch, bb.Pkgarch)
+	scalar("pkgbuild_sha256sum", ba.PkgbuildSha256sum, bb.PkgbuildSha256sum)
+	scalar("builddate", strconv.FormatInt(ba.BuildDate, 10), strconv.FormatInt(bb.BuildDate, 10))
+	scalar("builddir", ba.BuildDir, bb.BuildDir)
+	scalar("startdir", ba.StartDir, bb.StartDir)
+	scalar("buildtool", ba.BuildTool, bb.BuildTool)
+	scalar("buildtoolver", ba.BuildToolVer, bb.BuildToolVer)
+	array("buildenv", ba.BuildEnv, bb.BuildEnv)
+	array("options", ba.Options, bb.Options)
+	array("installed", ba.Installed, bb.Installed)
+
+	pa, pb := a.PKGINFO, b.PKGINFO
+	scalar("size", strconv.FormatInt(pa.Size, 10), strconv.FormatInt(pb.Size, 10))
+	array("depend", pa.Depends, pb.Depends)
+	array("provides", pa.Provides, pb.Provides)
+
+	return changes
+}
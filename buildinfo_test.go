@@ -0,0 +1,106 @@
+package pkgbuild
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testBUILDINFO = `format = 2
+pkgname = sudo
+pkgbase = sudo
+pkgver = 1.8.11.p2-1
+pkgarch = x86_64
+pkgbuild_sha256sum = 0123456789abcdef
+packager = Foo Bar <foo@example.org>
+builddate = 1427201013
+builddir = /build
+startdir = /startdir
+buildtool = devtools
+buildtoolver = 1:1.0.0-1-any
+buildenv = !distcc
+buildenv = color
+options = strip
+options = !lto
+installed = glibc-2.38-1-x86_64
+installed = gcc-13.2.1-3-x86_64
+`
+
+func TestParseBUILDINFO(t *testing.T) {
+	info, err := ParseBUILDINFO([]byte(testBUILDINFO))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &BUILDINFO{
+		Format:            2,
+		Pkgname:           "sudo",
+		Pkgbase:           "sudo",
+		Pkgver:            "1.8.11.p2-1",
+		Pkgarch:           "x86_64",
+		PkgbuildSha256sum: "0123456789abcdef",
+		Packager:          "Foo Bar <foo@example.org>",
+		BuildDate:         1427201013,
+		BuildDir:          "/build",
+		StartDir:          "/startdir",
+		BuildTool:         "devtools",
+		BuildToolVer:      "1:1.0.0-1-any",
+		BuildEnv:          []string{"!distcc", "color"},
+		Options:           []string{"strip", "!lto"},
+		Installed:         []string{"glibc-2.38-1-x86_64", "gcc-13.2.1-3-x86_64"},
+	}
+	if !reflect.DeepEqual(info, expected) {
+		t.Errorf("expected %+v, got %+v", expected, info)
+	}
+
+	if _, err := ParseBUILDINFO([]byte("format = two\npkgname = foo\npkgver = 1-1\n")); err == nil {
+		t.Error("expected error for invalid format")
+	}
+}
+
+func TestCompareBuilds(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopkgbuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "sudo-1.8.11.p2-1-x86_64.pkg.tar.gz")
+	writeTestPackage(t, path, map[string]string{".PKGINFO": testPKGINFO, ".BUILDINFO": testBUILDINFO}, true)
+
+	a, err := ReadBuiltPackage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ReadBuiltPackage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if changes := CompareBuilds(a, b); len(changes) != 0 {
+		t.Errorf("expected no changes, got %s", changes)
+	}
+
+	b.BUILDINFO.Installed[0] = "glibc-2.39-1-x86_64"
+	b.BUILDINFO.BuildEnv = []string{"!distcc"}
+	b.BUILDINFO.BuildDir = "/tmp/build"
+	b.PKGINFO.Size++
+
+	expected := strings.Join([]string{
+		`builddir: "/build" -> "/tmp/build"`,
+		`buildenv: -color`,
+		`installed: -glibc-2.38-1-x86_64 +glibc-2.39-1-x86_64`,
+		`size: "3186688" -> "3186689"`,
+		``,
+	}, "\n")
+	if changes := CompareBuilds(a, b).String(); changes != expected {
+		t.Errorf("expected changes:\n%s\ngot:\n%s", expected, changes)
+	}
+
+	if _, err = ReadBuiltPackage(filepath.Join(dir, "missing.pkg.tar")); err == nil {
+		t.Error("expected error for missing package")
+	}
+}
@@ -0,0 +1,128 @@
+package pkgbuild
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ChangelogEntry is an entry of a changelog file. Changelogs have no fixed
+// format, an entry starts at each line which isn't indented, like
+// "2015-01-01 Foo Bar <foo@example.org>" or "1.2.3-1:", and continues up to
+// the next one.
+type ChangelogEntry struct {
+	Header  string // the first line of the entry
+	Version string // first version in the entry e.g. "1.2.3-1", if any
+	Date    string // date of the header in YYYY-MM-DD format, if any
+	Text    string // the lines after the header with common indentation removed
+}
+
+// Changelog is a parsed changelog file, in the order of the file.
+type Changelog []ChangelogEntry
+
+var (
+	changelogDateRegex    = regexp.MustCompile(`\b\d{4}-\d{2}-\d{2}\b`)
+	changelogVersionRegex = regexp.MustCompile(`\b(?:\d+:)?\d+(?:\.[0-9A-Za-z_+~]+)+(?:-\d+(?:\.\d+)?)?`)
+	changelogRuleRegex    = regexp.MustCompile(`^(?:=+|-+|\*+)$`)
+)
+
+// ParseChangelog splits the changelog content into entries and finds their
+// version and date.
+func ParseChangelog(content []byte) Changelog {
+	var changelog Changelog
+	var lines []string
+
+	flush := func() {
+		if len(changelog) == 0 {
+			return
+		}
+		entry := &changelog[len(changelog)-1]
+		entry.Text = strings.TrimSpace(dedent(lines))
+		lines = nil
+
+		if entry.Version == "" {
+			entry.Version = changelogVersion(entry.Text)
+		}
+	}
+
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		trimmed := strings.TrimSpace(line)
+
+		if line == "" || line != trimmed || changelogRuleRegex.MatchString(trimmed) ||
+			strings.HasPrefix(trimmed, "*") || strings.HasPrefix(trimmed, "-") {
+			lines = append(lines, line)
+			continue
+		}
+
+		flush()
+		changelog = append(changelog, ChangelogEntry{
+			Header:  trimmed,
+			Version: changelogVersion(trimmed),
+			Date:    changelogDateRegex.FindString(trimmed),
+		})
+	}
+	flush()
+
+	return changelog
+}
+
+// changelogVersion returns the first version in s, ignoring dates.
+func changelogVersion(s string) string {
+	return changelogVersionRegex.FindString(changelogDateRegex.ReplaceAllString(s, ""))
+}
+
+// dedent joins lines, removing the indentation common to the non-empty ones.
+func dedent(lines []string) string {
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		n := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent == -1 || n < indent {
+			indent = n
+		}
+	}
+
+	for i, line := range lines {
+		if len(line) >= indent && indent > 0 {
+			lines[i] = line[indent:]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ParseChangelogFile parses the changelog file given by path.
+func ParseChangelogFile(path string) (Changelog, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read file: %s, %s", path, err.Error())
+	}
+
+	return ParseChangelog(content), nil
+}
+
+// ReadChangelog parses the changelog file of p in the package directory
+// dir. nil is returned if p has no changelog.
+func (p *PKGBUILD) ReadChangelog(dir string) (Changelog, error) {
+	if p.Changelog == "" {
+		return nil, nil
+	}
+	return ParseChangelogFile(filepath.Join(dir, p.Changelog))
+}
+
+// Since returns the entries of c with a version newer than version, e.g.
+// the changes of an update from version. Entries without a version are
+// left out.
+func (c Changelog) Since(version string) Changelog {
+	var entries Changelog
+	for _, entry := range c {
+		if entry.Version != "" && VerCmp(entry.Version, version) > 0 {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
@@ -0,0 +1,86 @@
+package pkgbuild
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testChangelog = `2015-03-02 Foo Bar <foo@example.org>
+
+	* 1.2.0-1 :
+	  new upstream release
+	  - fixes crash on startup
+
+2014-12-24 Foo Bar <foo@example.org>
+	* 1.1.0-2 :
+	  rebuild against openssl 1.0.2
+
+1.0.0-1:
+* initial release
+`
+
+func TestParseChangelog(t *testing.T) {
+	changelog := ParseChangelog([]byte(testChangelog))
+
+	expected := Changelog{
+		{
+			Header:  "2015-03-02 Foo Bar <foo@example.org>",
+			Version: "1.2.0-1",
+			Date:    "2015-03-02",
+			Text:    "* 1.2.0-1 :\n  new upstream release\n  - fixes crash on startup",
+		},
+		{
+			Header:  "2014-12-24 Foo Bar <foo@example.org>",
+			Version: "1.1.0-2",
+			Date:    "2014-12-24",
+			Text:    "* 1.1.0-2 :\n  rebuild against openssl 1.0.2",
+		},
+		{
+			Header:  "1.0.0-1:",
+			Version: "1.0.0-1",
+			Text:    "* initial release",
+		},
+	}
+
+	if len(changelog) != len(expected) {
+		t.Fatalf("expected %d entries, got %d: %+v", len(expected), len(changelog), changelog)
+	}
+	for i := range expected {
+		if changelog[i] != expected[i] {
+			t.Errorf("expected entry %+v, got %+v", expected[i], changelog[i])
+		}
+	}
+
+	since := changelog.Since("1.1.0-1")
+	if len(since) != 2 || since[0].Version != "1.2.0-1" || since[1].Version != "1.1.0-2" {
+		t.Errorf("unexpected entries since 1.1.0-1: %+v", since)
+	}
+}
+
+func TestReadChangelog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopkgbuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	p := &PKGBUILD{}
+	if c, err := p.ReadChangelog(dir); c != nil || err != nil {
+		t.Errorf("expected no changelog, got %v, %v", c, err)
+	}
+
+	p.Changelog = "ChangeLog"
+	if _, err = p.ReadChangelog(dir); err == nil {
+		t.Error("expected error for missing changelog")
+	}
+
+	if err = ioutil.WriteFile(filepath.Join(dir, "ChangeLog"), []byte(testChangelog), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := p.ReadChangelog(dir)
+	if err != nil || len(c) != 3 {
+		t.Errorf("expected 3 entries, got %v, %v", c, err)
+	}
+}
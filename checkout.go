@@ -0,0 +1,159 @@
+package pkgbuild
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Checkout is the packaging repository of an official package, e.g. a clone
+// of https://gitlab.archlinux.org/archlinux/packaging/packages/<pkgbase>,
+// with everything distro tooling needs from it.
+type Checkout struct {
+	Dir       string // the directory containing the PKGBUILD
+	PKGBUILD  *PKGBUILD
+	Install   map[string]*InstallScript // by file name e.g. "foo.install"
+	Changelog Changelog                 // nil if the PKGBUILD has none
+	Keys      []CheckoutKey             // sorted by fingerprint
+}
+
+// CheckoutKey is a public key stored below keys/pgp of a checkout.
+type CheckoutKey struct {
+	Fingerprint string // of the primary key
+	File        string // relative to the checkout e.g. "keys/pgp/<fingerprint>.asc"
+	Key         []byte // the content of File
+}
+
+// ReadCheckout reads the packaging checkout dir. The layout of the git
+// repositories, with the PKGBUILD at the top level, and of the old svntogit
+// repositories, with the PKGBUILD in trunk, are supported.
+//
+// The .SRCINFO is parsed if present, otherwise the PKGBUILD is parsed with
+// ParsePKGBUILD and opts, which runs makepkg unless WithEmbeddedEvaluation
+// is given. The install script of the PKGBUILD and all other .install files,
+// e.g. of split packages, are parsed, as well as the changelog and the keys
+// below keys/pgp.
+func ReadCheckout(dir string, opts ...ParseOption) (*Checkout, error) {
+	if _, err := os.Stat(filepath.Join(dir, "PKGBUILD")); os.IsNotExist(err) {
+		if _, err := os.Stat(filepath.Join(dir, "trunk", "PKGBUILD")); err == nil {
+			dir = filepath.Join(dir, "trunk")
+		}
+	}
+
+	c := &Checkout{Dir: dir, Install: make(map[string]*InstallScript)}
+
+	var err error
+	c.PKGBUILD, err = readCheckoutPKGBUILD(dir, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	installs, err := filepath.Glob(filepath.Join(dir, "*.install"))
+	if err != nil {
+		return nil, err
+	}
+	if c.PKGBUILD.Install != "" {
+		installs = append(installs, filepath.Join(dir, c.PKGBUILD.Install))
+	}
+	for _, path := range installs {
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return nil, err
+		}
+		if c.Install[name] != nil {
+			continue
+		}
+
+		c.Install[name], err = ParseInstallScriptFile(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	c.Changelog, err = c.PKGBUILD.ReadChangelog(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Keys, err = readCheckoutKeys(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// readCheckoutPKGBUILD parses the .SRCINFO or PKGBUILD in dir.
+func readCheckoutPKGBUILD(dir string, opts []ParseOption) (*PKGBUILD, error) {
+	srcinfo := filepath.Join(dir, ".SRCINFO")
+	if _, err := os.Stat(srcinfo); err != nil {
+		return ParsePKGBUILD(filepath.Join(dir, "PKGBUILD"), opts...)
+	}
+
+	p, err := ParseSRCINFO(srcinfo, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// the header is informational, like for ParsePKGBUILD
+	if content, err := ioutil.ReadFile(filepath.Join(dir, "PKGBUILD")); err == nil {
+		p.Maintainers, p.Contributors = parseHeader(string(content))
+	}
+	return p, nil
+}
+
+// readCheckoutKeys reads the public keys below keys/pgp in dir.
+func readCheckoutKeys(dir string) ([]CheckoutKey, error) {
+	files, err := ioutil.ReadDir(filepath.Join(dir, "keys", "pgp"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []CheckoutKey
+	for _, file := range files {
+		if file.IsDir() || strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+
+		name := filepath.Join("keys", "pgp", file.Name())
+		content, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		entities, err := readKeyRing(bytes.NewReader(content))
+		if err != nil {
+			return nil, fmt.Errorf("unable to read key: %s, %s", name, err.Error())
+		}
+		for _, entity := range entities {
+			keys = append(keys, CheckoutKey{
+				Fingerprint: fmt.Sprintf("%X", entity.PrimaryKey.Fingerprint),
+				File:        name,
+				Key:         content,
+			})
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Fingerprint < keys[j].Fingerprint
+	})
+	return keys, nil
+}
+
+// MissingKeys returns the fingerprints listed in validpgpkeys without a key
+// in the checkout, see PKGBUILD.MissingKeys.
+func (c *Checkout) MissingKeys() ([]string, error) {
+	keyrings := make([]io.Reader, 0, len(c.Keys))
+	for _, key := range c.Keys {
+		keyrings = append(keyrings, bytes.NewReader(key.Key))
+	}
+	return c.PKGBUILD.MissingKeys(keyrings...)
+}
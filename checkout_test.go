@@ -0,0 +1,143 @@
+package pkgbuild
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+const testInstall = `post_install() {
+	echo installed
+}
+`
+
+func writeTestCheckout(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReadCheckout(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopkgbuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	key, err := openpgp.NewEntity("test", "", "test@example.org", &packet.Config{RSABits: 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fingerprint := fmt.Sprintf("%X", key.PrimaryKey.Fingerprint)
+
+	var armored bytes.Buffer
+	w, err := armor.Encode(&armored, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := key.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	srcinfo, err := ioutil.ReadFile("./test_pkgbuilds/SRCINFO_sudo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkgbuild, err := ioutil.ReadFile("./test_pkgbuilds/PKGBUILD_sudo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeTestCheckout(t, dir, map[string]string{
+		"PKGBUILD":                         string(pkgbuild),
+		".SRCINFO":                         string(srcinfo),
+		"sudo.install":                     testInstall,
+		"sudo-ldap.install":                testInstall,
+		"keys/pgp/" + fingerprint + ".asc": armored.String(),
+	})
+
+	c, err := ReadCheckout(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Dir != dir || c.PKGBUILD.Pkgbase != "sudo" {
+		t.Errorf("expected sudo in %s, got %s in %s", dir, c.PKGBUILD.Pkgbase, c.Dir)
+	}
+	if len(c.PKGBUILD.Maintainers) == 0 {
+		t.Error("expected maintainers of the PKGBUILD")
+	}
+
+	if len(c.Install) != 2 || c.Install["sudo.install"] == nil || !c.Install["sudo-ldap.install"].HasHook("post_install") {
+		t.Errorf("expected both install scripts, got %v", c.Install)
+	}
+	if c.Changelog != nil {
+		t.Errorf("expected no changelog, got %v", c.Changelog)
+	}
+
+	if len(c.Keys) != 1 || c.Keys[0].Fingerprint != fingerprint || c.Keys[0].File != filepath.Join("keys", "pgp", fingerprint+".asc") {
+		t.Fatalf("expected key %s, got %v", fingerprint, c.Keys)
+	}
+
+	missing := "0123456789ABCDEF0123456789ABCDEF01234567"
+	c.PKGBUILD.Validpgpkeys = []string{fingerprint, missing}
+	keys, err := c.MissingKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(keys, []string{missing}) {
+		t.Errorf("expected missing key %s, got %v", missing, keys)
+	}
+}
+
+func TestReadCheckoutTrunk(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopkgbuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	pkgbuild, err := ioutil.ReadFile("./test_pkgbuilds/PKGBUILD_openssh")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeTestCheckout(t, dir, map[string]string{
+		"trunk/PKGBUILD": string(pkgbuild),
+		"trunk/install":  testInstall,
+	})
+
+	c, err := ReadCheckout(dir, WithEmbeddedEvaluation())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Dir != filepath.Join(dir, "trunk") || c.PKGBUILD.Pkgbase != "openssh" {
+		t.Errorf("expected openssh in trunk, got %s in %s", c.PKGBUILD.Pkgbase, c.Dir)
+	}
+	if len(c.Install) != 1 || c.Install["install"] == nil {
+		t.Errorf("expected the install script of the PKGBUILD, got %v", c.Install)
+	}
+	if len(c.Keys) != 0 {
+		t.Errorf("expected no keys, got %v", c.Keys)
+	}
+
+	if _, err := ReadCheckout(filepath.Join(dir, "missing"), WithEmbeddedEvaluation()); err == nil {
+		t.Error("expected error for a missing checkout")
+	}
+}
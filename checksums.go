@@ -0,0 +1,385 @@
+package pkgbuild
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// checksumAlgorithms lists the checksum algorithms supported by makepkg.
+var checksumAlgorithms = []string{
+	"md5",
+	"sha1",
+	"sha224",
+	"sha256",
+	"sha384",
+	"sha512",
+	"b2",
+}
+
+// SourceChecksum describes a source entry along with all the checksums
+// declared for it.
+type SourceChecksum struct {
+	Source string
+	Arch   string            // empty if the source is not arch specific
+	Sums   map[string]string // digest by algorithm e.g. "sha256"
+}
+
+// SourceChecksums returns, per source entry, the digests declared in the
+// md5sums, sha1sums, ..., b2sums arrays. Arch specific sources are paired
+// with the checksums declared for the same arch.
+func (p *PKGBUILD) SourceChecksums() []SourceChecksum {
+	common := p.archIndependent()
+	checksums := pairChecksums(common.Source, common.sums(), "")
+
+	for _, arch := range p.Arch {
+		if a, ok := p.ArchSpecific[arch]; ok {
+			checksums = append(checksums, pairChecksums(a.Source, a.sums(), arch)...)
+		}
+	}
+
+	return checksums
+}
+
+// ChecksumsFor returns the checksum arrays makepkg uses when building for
+// arch by algorithm, e.g. sha256sums followed by sha256sums_<arch>.
+// Algorithms without any checksums are left out.
+func (p *PKGBUILD) ChecksumsFor(arch string) map[string][]string {
+	checksums := make(map[string][]string)
+
+	for algo, sums := range p.archIndependent().sums() {
+		merged := append([]string{}, sums...)
+		if a, ok := p.ArchSpecific[arch]; ok {
+			merged = append(merged, *a.checksumArray(algo)...)
+		}
+
+		if len(merged) > 0 {
+			checksums[algo] = merged
+		}
+	}
+
+	return checksums
+}
+
+// pairChecksums pairs each source with the digest at the same index in each
+// of the checksum arrays.
+func pairChecksums(sources []string, sums map[string][]string, arch string) []SourceChecksum {
+	checksums := make([]SourceChecksum, 0, len(sources))
+
+	for i, source := range sources {
+		checksum := SourceChecksum{
+			Source: source,
+			Arch:   arch,
+			Sums:   make(map[string]string),
+		}
+
+		for algo, digests := range sums {
+			if i < len(digests) {
+				checksum.Sums[algo] = digests[i]
+			}
+		}
+
+		checksums = append(checksums, checksum)
+	}
+
+	return checksums
+}
+
+// sums returns the checksum arrays of p by algorithm.
+func (p *PKGBUILD) sums() map[string][]string {
+	sums := make(map[string][]string, len(checksumAlgorithms))
+	for _, algo := range checksumAlgorithms {
+		sums[algo] = *p.checksumArray(algo)
+	}
+	return sums
+}
+
+// checksumArray returns a pointer to the checksum array of p for algo.
+func (p *PKGBUILD) checksumArray(algo string) *[]string {
+	switch algo {
+	case "md5":
+		return &p.Md5sums
+	case "sha1":
+		return &p.Sha1sums
+	case "sha224":
+		return &p.Sha224sums
+	case "sha256":
+		return &p.Sha256sums
+	case "sha384":
+		return &p.Sha384sums
+	case "sha512":
+		return &p.Sha512sums
+	case "b2":
+		return &p.B2sums
+	}
+	return nil
+}
+
+// sums returns the checksum arrays of a by algorithm.
+func (a *ArchSpecific) sums() map[string][]string {
+	sums := make(map[string][]string, len(checksumAlgorithms))
+	for _, algo := range checksumAlgorithms {
+		sums[algo] = *a.checksumArray(algo)
+	}
+	return sums
+}
+
+// checksumArray returns a pointer to the checksum array of a for algo.
+func (a *ArchSpecific) checksumArray(algo string) *[]string {
+	switch algo {
+	case "md5":
+		return &a.Md5sums
+	case "sha1":
+		return &a.Sha1sums
+	case "sha224":
+		return &a.Sha224sums
+	case "sha256":
+		return &a.Sha256sums
+	case "sha384":
+		return &a.Sha384sums
+	case "sha512":
+		return &a.Sha512sums
+	case "b2":
+		return &a.B2sums
+	}
+	return nil
+}
+
+// Checksums holds freshly generated checksums of a single algorithm.
+type Checksums struct {
+	Algorithm string
+	Sums      []string
+	ArchSums  map[string][]string // checksums of the arch specific sources
+}
+
+// GenerateChecksums computes the algo digests of the source files found in
+// sourcesDir, like makepkg -g. VCS sources get the digest SKIP. Local
+// sources are looked up in the directory given with WithStartDir if any.
+func (p *PKGBUILD) GenerateChecksums(sourcesDir, algo string, opts ...SourceOption) (*Checksums, error) {
+	if _, err := newHash(algo); err != nil {
+		return nil, err
+	}
+
+	config := newSourceConfig(opts)
+	sums, err := generateChecksums(config, sourcesDir, algo, p.archIndependent().Source)
+	if err != nil {
+		return nil, err
+	}
+
+	checksums := &Checksums{
+		Algorithm: algo,
+		Sums:      sums,
+		ArchSums:  make(map[string][]string),
+	}
+
+	for _, arch := range p.Arch {
+		a, ok := p.ArchSpecific[arch]
+		if !ok || len(a.Source) == 0 {
+			continue
+		}
+
+		sums, err = generateChecksums(config, sourcesDir, algo, a.Source)
+		if err != nil {
+			return nil, err
+		}
+		checksums.ArchSums[arch] = sums
+	}
+
+	return checksums, nil
+}
+
+// UpdateChecksums generates the algo checksums of the sources in sourcesDir
+// and replaces the corresponding checksum arrays of p, like updpkgsums. The
+// options are those of GenerateChecksums.
+func (p *PKGBUILD) UpdateChecksums(sourcesDir, algo string, opts ...SourceOption) error {
+	checksums, err := p.GenerateChecksums(sourcesDir, algo, opts...)
+	if err != nil {
+		return err
+	}
+
+	common := p.archIndependent()
+	*common.checksumArray(algo) = checksums.Sums
+	for arch, sums := range checksums.ArchSums {
+		*p.archSpecific(arch).checksumArray(algo) = sums
+	}
+	p.setArchIndependent(common)
+
+	return nil
+}
+
+// generateChecksums computes the algo digests of sources.
+func generateChecksums(config *sourceConfig, sourcesDir, algo string, sources []string) ([]string, error) {
+	sums := make([]string, 0, len(sources))
+
+	for _, source := range sources {
+		if vcsProtocols[Source(source).Protocol()] {
+			sums = append(sums, "SKIP")
+			continue
+		}
+
+		digests, err := fileDigests(config.sourcePath(sourcesDir, Source(source)), []string{algo})
+		if err != nil {
+			return nil, err
+		}
+		sums = append(sums, digests[algo])
+	}
+
+	return sums, nil
+}
+
+// VerifyStatus is the outcome of verifying a source file.
+type VerifyStatus int
+
+// Verify statuses
+const (
+	VerifyPassed   VerifyStatus = iota // all declared digests match
+	VerifySkipped                      // all declared digests are SKIP
+	VerifyFailed                       // at least one digest did not match
+	VerifyNotFound                     // the source file does not exist
+)
+
+func (s VerifyStatus) String() string {
+	switch s {
+	case VerifyPassed:
+		return "Passed"
+	case VerifySkipped:
+		return "Skipped"
+	case VerifyFailed:
+		return "FAILED"
+	case VerifyNotFound:
+		return "NOT FOUND"
+	}
+	return fmt.Sprintf("VerifyStatus(%d)", int(s))
+}
+
+// VerifyResult is the result of verifying a single source file.
+type VerifyResult struct {
+	Source   string
+	Arch     string
+	FileName string
+	Status   VerifyStatus
+	Failed   []string // algorithms with a mismatching digest
+}
+
+// Verify computes the digests of the local source files found in sourcesDir
+// and compares them to the declared checksums. Digests declared as SKIP are
+// not checked. Local sources are looked up in the directory given with
+// WithStartDir if any.
+func (p *PKGBUILD) Verify(sourcesDir string, opts ...SourceOption) ([]VerifyResult, error) {
+	config := newSourceConfig(opts)
+	checksums := p.SourceChecksums()
+	results := make([]VerifyResult, 0, len(checksums))
+
+	for _, checksum := range checksums {
+		result, err := verifySource(config, sourcesDir, checksum)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// verifySource verifies a single source file against its checksums.
+func verifySource(config *sourceConfig, sourcesDir string, checksum SourceChecksum) (VerifyResult, error) {
+	result := VerifyResult{
+		Source:   checksum.Source,
+		Arch:     checksum.Arch,
+		FileName: Source(checksum.Source).FileName(),
+		Status:   VerifySkipped,
+	}
+
+	var algos []string
+	for _, algo := range checksumAlgorithms {
+		if digest, ok := checksum.Sums[algo]; ok && digest != "SKIP" {
+			algos = append(algos, algo)
+		}
+	}
+
+	if len(algos) == 0 {
+		return result, nil
+	}
+
+	digests, err := fileDigests(config.sourcePath(sourcesDir, Source(checksum.Source)), algos)
+	if os.IsNotExist(err) {
+		result.Status = VerifyNotFound
+		return result, nil
+	}
+	if err != nil {
+		return result, err
+	}
+
+	result.Status = VerifyPassed
+	for _, algo := range algos {
+		if digests[algo] != strings.ToLower(checksum.Sums[algo]) {
+			result.Status = VerifyFailed
+			result.Failed = append(result.Failed, algo)
+		}
+	}
+
+	return result, nil
+}
+
+// fileDigests computes the hex encoded digests of the file at path for each
+// of the algorithms in algos.
+func fileDigests(path string, algos []string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	hashes := make(map[string]hash.Hash, len(algos))
+	writers := make([]io.Writer, 0, len(algos))
+	for _, algo := range algos {
+		h, err := newHash(algo)
+		if err != nil {
+			return nil, err
+		}
+		hashes[algo] = h
+		writers = append(writers, h)
+	}
+
+	if _, err := io.Copy(io.MultiWriter(writers...), f); err != nil {
+		return nil, fmt.Errorf("unable to read file: %s, %s", path, err.Error())
+	}
+
+	digests := make(map[string]string, len(hashes))
+	for algo, h := range hashes {
+		digests[algo] = hex.EncodeToString(h.Sum(nil))
+	}
+
+	return digests, nil
+}
+
+// newHash returns a new hash for the checksum algorithm algo.
+func newHash(algo string) (hash.Hash, error) {
+	switch algo {
+	case "md5":
+		return md5.New(), nil
+	case "sha1":
+		return sha1.New(), nil
+	case "sha224":
+		return sha256.New224(), nil
+	case "sha256":
+		return sha256.New(), nil
+	case "sha384":
+		return sha512.New384(), nil
+	case "sha512":
+		return sha512.New(), nil
+	case "b2":
+		return blake2b.New512(nil)
+	}
+
+	return nil, fmt.Errorf("unsupported checksum algorithm: %s", algo)
+}
@@ -0,0 +1,229 @@
+package pkgbuild
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSourceChecksums(t *testing.T) {
+	pkg, err := ParseSRCINFOContent([]byte(`pkgbase = foo
+	pkgver = 1.0
+	pkgrel = 1
+	arch = i686
+	arch = x86_64
+	source = foo.tar.gz
+	source = foo.patch
+	md5sums = aaaa
+	md5sums = bbbb
+	sha256sums = cccc
+	sha256sums = SKIP
+	source_x86_64 = foo-x86_64.bin
+	sha256sums_x86_64 = dddd
+
+pkgname = foo
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []SourceChecksum{
+		{"foo.tar.gz", "", map[string]string{"md5": "aaaa", "sha256": "cccc"}},
+		{"foo.patch", "", map[string]string{"md5": "bbbb", "sha256": "SKIP"}},
+		{"foo-x86_64.bin", "x86_64", map[string]string{"sha256": "dddd"}},
+	}
+
+	checksums := pkg.SourceChecksums()
+	if len(checksums) != len(expected) {
+		t.Fatalf("expected %d checksums, got %d", len(expected), len(checksums))
+	}
+
+	for i, checksum := range checksums {
+		e := expected[i]
+		if checksum.Source != e.Source || checksum.Arch != e.Arch {
+			t.Errorf("expected %s (%s), got %s (%s)", e.Source, e.Arch, checksum.Source, checksum.Arch)
+		}
+
+		if len(checksum.Sums) != len(e.Sums) {
+			t.Errorf("expected sums %v for %s, got %v", e.Sums, e.Source, checksum.Sums)
+		}
+
+		for algo, digest := range e.Sums {
+			if checksum.Sums[algo] != digest {
+				t.Errorf("expected %s digest %s for %s, got %s", algo, digest, e.Source, checksum.Sums[algo])
+			}
+		}
+	}
+}
+
+func TestSourceChecksumsArchSpecific(t *testing.T) {
+	pkg, err := ParseSRCINFO("./test_pkgbuilds/SRCINFO_teamviewer")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// like the arch specific dependencies, the arch specific sources are
+	// part of Source too
+	if len(pkg.Source) != 4 {
+		t.Errorf("arch specific sources should be part of Source: %v", pkg.Source)
+	}
+	if len(pkg.ArchSpecific["x86_64"].Source) != 2 {
+		t.Errorf("expected 2 x86_64 sources, got %v", pkg.ArchSpecific["x86_64"].Source)
+	}
+
+	checksums := pkg.SourceChecksums()
+	if len(checksums) != 4 {
+		t.Fatalf("expected 4 checksums, got %d", len(checksums))
+	}
+
+	if checksums[2].Arch != "x86_64" || checksums[2].Sums["sha256"] != "a30bfaa0ddfa7f6ab03141f0deb8fd0a26760e1b18ea1cb9e54b5b54bf2c0131" {
+		t.Errorf("unexpected checksum: %+v", checksums[2])
+	}
+}
+
+func TestVerify(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopkgbuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"hello.txt", "bad.txt", "skip.txt"} {
+		err = ioutil.WriteFile(filepath.Join(dir, name), []byte("hello\n"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pkg := &PKGBUILD{
+		Source: []string{
+			"hello.txt",
+			"https://example.org/bad.txt",
+			"skip.txt",
+			"missing.txt",
+		},
+		Md5sums: []string{
+			"b1946ac92492d2347c6235b4d2611184",
+			"b1946ac92492d2347c6235b4d2611184",
+			"SKIP",
+			"b1946ac92492d2347c6235b4d2611184",
+		},
+		Sha256sums: []string{
+			"5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03",
+			"0000000000000000000000000000000000000000000000000000000000000000",
+			"SKIP",
+			"SKIP",
+		},
+		B2sums: []string{
+			"f60ce482e5cc1229f39d71313171a8d9f4ca3a87d066bf4b205effb528192a75f14f3271e2c1a90e1de53f275b4d4793eef2f5e31ea90d2ce29d2e481c36435f",
+		},
+	}
+
+	results, err := pkg.Verify(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []VerifyStatus{VerifyPassed, VerifyFailed, VerifySkipped, VerifyNotFound}
+	for i, result := range results {
+		if result.Status != expected[i] {
+			t.Errorf("expected %s to be %s, got %s", result.Source, expected[i], result.Status)
+		}
+	}
+
+	if len(results[1].Failed) != 1 || results[1].Failed[0] != "sha256" {
+		t.Errorf("expected sha256 to fail for %s, got %v", results[1].Source, results[1].Failed)
+	}
+
+	// with a start dir only the local sources are looked up there
+	startDir, err := ioutil.TempDir("", "gopkgbuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(startDir)
+
+	if err = os.Rename(filepath.Join(dir, "hello.txt"), filepath.Join(startDir, "hello.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err = pkg.Verify(dir, WithStartDir(startDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Status != VerifyPassed || results[1].Status != VerifyFailed {
+		t.Errorf("expected %s to pass and %s to fail, got %s and %s", results[0].Source, results[1].Source, results[0].Status, results[1].Status)
+	}
+}
+
+func TestUpdateChecksums(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopkgbuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"hello.txt", "hello-x86_64.txt"} {
+		err = ioutil.WriteFile(filepath.Join(dir, name), []byte("hello\n"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pkg := &PKGBUILD{
+		Arch:       []string{"x86_64"},
+		Source:     []string{"hello.txt", "git+https://github.com/foo/bar.git"},
+		Sha256sums: []string{"outdated", "SKIP"},
+		ArchSpecific: map[string]*ArchSpecific{
+			"x86_64": {Source: []string{"https://example.org/hello-x86_64.txt"}},
+		},
+	}
+
+	if _, err = pkg.GenerateChecksums(dir, "sha3"); err == nil {
+		t.Error("sha3 should not be a supported algorithm")
+	}
+
+	err = pkg.UpdateChecksums(dir, "sha256")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	digest := "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"
+	if len(pkg.Sha256sums) != 3 || pkg.Sha256sums[0] != digest || pkg.Sha256sums[1] != "SKIP" || pkg.Sha256sums[2] != digest {
+		t.Errorf("unexpected sha256sums: %v", pkg.Sha256sums)
+	}
+
+	sums := pkg.ArchSpecific["x86_64"].Sha256sums
+	if len(sums) != 1 || sums[0] != digest {
+		t.Errorf("unexpected sha256sums_x86_64: %v", sums)
+	}
+}
+
+func TestChecksumsFor(t *testing.T) {
+	pkg := &PKGBUILD{
+		Arch:       []string{"i686", "x86_64"},
+		Source:     []string{"foo.tar.gz"},
+		Sha256sums: []string{"aaaa"},
+		ArchSpecific: map[string]*ArchSpecific{
+			"x86_64": {
+				Source:     []string{"foo-x86_64.bin"},
+				Sha256sums: []string{"bbbb"},
+				B2sums:     []string{"cccc"},
+			},
+		},
+	}
+
+	checksums := pkg.ChecksumsFor("x86_64")
+	if len(checksums) != 2 {
+		t.Errorf("expected 2 algorithms, got %v", checksums)
+	}
+
+	if sums := checksums["sha256"]; len(sums) != 2 || sums[0] != "aaaa" || sums[1] != "bbbb" {
+		t.Errorf("unexpected sha256sums for x86_64: %v", sums)
+	}
+
+	checksums = pkg.ChecksumsFor("i686")
+	if len(checksums) != 1 || len(checksums["sha256"]) != 1 {
+		t.Errorf("unexpected checksums for i686: %v", checksums)
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	pkgbuild "github.com/mikkeloscar/gopkgbuild"
+)
+
+const bumpUsage = `usage: gopkgbuild bump -pkgver <version> [flags] [dir]
+
+Update the PKGBUILD in dir, by default the current directory, to a new
+pkgver: set pkgver, reset pkgrel, download the http and https sources
+and refresh the declared checksums, then regenerate .SRCINFO. Formatting
+and comments of the PKGBUILD are kept.
+
+flags:
+`
+
+// bumpChecksumAlgorithms are the checksum algorithms refreshed by bump.
+var bumpChecksumAlgorithms = []string{"md5", "sha1", "sha224", "sha256", "sha384", "sha512", "b2"}
+
+// runBump updates a PKGBUILD to a new pkgver.
+func runBump(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("bump", bumpUsage, stderr)
+	pkgver := flags.String("pkgver", "", "the new `version`")
+	pkgrel := flags.String("pkgrel", "1", "the new `release`")
+	skipChecksums := flags.Bool("skip-checksums", false, "don't download sources and refresh checksums")
+	srcdest := flags.String("srcdest", "", "`directory` to download the sources to, by default dir; local sources are always read from dir")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	if *pkgver == "" {
+		flags.Usage()
+		return 2
+	}
+
+	dir := "."
+	if flags.NArg() > 0 {
+		dir = flags.Arg(0)
+	}
+	if *srcdest == "" {
+		*srcdest = dir
+	}
+
+	path := filepath.Join(dir, "PKGBUILD")
+	e, err := pkgbuild.NewEditorFile(path)
+	if err != nil {
+		return fail(stderr, "bump", err)
+	}
+
+	old, err := evalPKGBUILD(e)
+	if err != nil {
+		return fail(stderr, "bump", err)
+	}
+
+	if err := e.SetPkgver(pkgbuild.Version(*pkgver)); err != nil {
+		return fail(stderr, "bump", err)
+	}
+	if err := e.SetPkgrel(pkgbuild.Version(*pkgrel)); err != nil {
+		return fail(stderr, "bump", err)
+	}
+
+	if !*skipChecksums {
+		if err := refreshChecksums(e, dir, *srcdest); err != nil {
+			return fail(stderr, "bump", err)
+		}
+	}
+
+	file, err := pkgbuild.ParseAST(e.Bytes())
+	if err != nil {
+		return fail(stderr, "bump", err)
+	}
+
+	pkg, err := file.PKGBUILD()
+	if err != nil {
+		return fail(stderr, "bump", err)
+	}
+
+	if err := e.WriteFile(path); err != nil {
+		return fail(stderr, "bump", err)
+	}
+
+	if err := writeSRCINFOFile(file, filepath.Join(dir, ".SRCINFO")); err != nil {
+		return fail(stderr, "bump", err)
+	}
+
+	fmt.Fprintf(stdout, "%s %s -> %s\n", pkg.Pkgbase, old.Version(), pkg.Version())
+	return 0
+}
+
+// evalPKGBUILD evaluates the PKGBUILD being edited by e.
+func evalPKGBUILD(e *pkgbuild.Editor) (*pkgbuild.PKGBUILD, error) {
+	file, err := pkgbuild.ParseAST(e.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return file.PKGBUILD()
+}
+
+// refreshChecksums downloads the sources of the PKGBUILD being edited by e
+// to srcdest and replaces the declared checksums with the ones of the
+// downloaded files and the local sources in dir.
+func refreshChecksums(e *pkgbuild.Editor, dir, srcdest string) error {
+	pkg, err := evalPKGBUILD(e)
+	if err != nil {
+		return err
+	}
+
+	if err := pkg.DownloadSources(context.Background(), srcdest); err != nil {
+		return err
+	}
+
+	vars := pkg.Vars()
+	for _, algo := range bumpChecksumAlgorithms {
+		declared := false
+		for name := range vars {
+			if name == algo+"sums" || strings.HasPrefix(name, algo+"sums_") {
+				declared = true
+			}
+		}
+		if !declared {
+			continue
+		}
+
+		checksums, err := pkg.GenerateChecksums(srcdest, algo, pkgbuild.WithStartDir(dir))
+		if err != nil {
+			return err
+		}
+
+		if _, ok := vars[algo+"sums"]; ok {
+			if err := e.SetChecksums(algo, checksums.Sums); err != nil {
+				return err
+			}
+		}
+
+		for arch, sums := range checksums.ArchSums {
+			name := algo + "sums_" + arch
+			if _, ok := vars[name]; !ok {
+				continue
+			}
+			if err := e.SetArray(name, sums); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// writeSRCINFOFile writes the .SRCINFO of file to path, including the
+// package sections of split packages like the srcinfo command.
+func writeSRCINFOFile(file *pkgbuild.File, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = file.WriteSRCINFO(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBump(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopkgbuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	err = ioutil.WriteFile(filepath.Join(dir, "PKGBUILD"), []byte(`# Maintainer: foo
+pkgname=foo
+pkgver=1.0
+pkgrel=3
+arch=(any)
+source=(foo.patch)
+sha256sums=('0000000000000000000000000000000000000000000000000000000000000000')
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "foo.patch"), []byte("foo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, stderr := runCommand("bump", "-pkgver", "1.1", dir)
+	if code != 0 {
+		t.Fatalf("bump failed with exit code %d: %s", code, stderr)
+	}
+	if stdout != "foo 1.0-3 -> 1.1-1\n" {
+		t.Errorf("unexpected output: %s", stdout)
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "PKGBUILD"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// sha256 of "foo"
+	expected := `# Maintainer: foo
+pkgname=foo
+pkgver=1.1
+pkgrel=1
+arch=(any)
+source=(foo.patch)
+sha256sums=('2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae')
+`
+	if string(content) != expected {
+		t.Errorf("expected PKGBUILD:\n%s\ngot:\n%s", expected, content)
+	}
+
+	srcinfo, err := ioutil.ReadFile(filepath.Join(dir, ".SRCINFO"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(srcinfo), "\tpkgver = 1.1\n\tpkgrel = 1\n") {
+		t.Errorf("unexpected .SRCINFO:\n%s", srcinfo)
+	}
+
+	srcdest, err := ioutil.TempDir("", "gopkgbuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(srcdest)
+
+	// local sources are read from dir regardless of -srcdest
+	if code, _, stderr := runCommand("bump", "-pkgver", "1.2", "-srcdest", srcdest, dir); code != 0 {
+		t.Errorf("bump with -srcdest failed with exit code %d: %s", code, stderr)
+	}
+
+	if code, _, _ := runCommand("bump", dir); code != 2 {
+		t.Errorf("expected exit code 2 without -pkgver, got %d", code)
+	}
+
+	if code, _, _ := runCommand("bump", "-pkgver", "1.1-1", dir); code != 4 {
+		t.Errorf("expected exit code 4 for an invalid pkgver, got %d", code)
+	}
+}
+
+func TestBumpSplitPackage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopkgbuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	err = ioutil.WriteFile(filepath.Join(dir, "PKGBUILD"), []byte(`pkgbase=foo
+pkgname=(foo-a foo-b)
+pkgver=1.0
+pkgrel=1
+pkgdesc='foo'
+arch=(any)
+
+package_foo-a() {
+  pkgdesc='foo a'
+  depends=(bar)
+}
+
+package_foo-b() {
+  pkgdesc='foo b'
+  depends=(baz)
+}
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, _, stderr := runCommand("bump", "-pkgver", "2.0", "-skip-checksums", dir)
+	if code != 0 {
+		t.Fatalf("bump failed with exit code %d: %s", code, stderr)
+	}
+
+	srcinfo, err := ioutil.ReadFile(filepath.Join(dir, ".SRCINFO"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, expected, _ := runCommand("srcinfo", filepath.Join(dir, "PKGBUILD"))
+	if string(srcinfo) != expected {
+		t.Errorf("expected .SRCINFO:\n%s\ngot:\n%s", expected, srcinfo)
+	}
+
+	for _, section := range []string{
+		"pkgbase = foo\n\tpkgdesc = foo\n\tpkgver = 2.0\n",
+		"pkgname = foo-a\n\tpkgdesc = foo a\n\tdepends = bar\n",
+		"pkgname = foo-b\n\tpkgdesc = foo b\n\tdepends = baz\n",
+	} {
+		if !strings.Contains(string(srcinfo), section) {
+			t.Errorf("expected .SRCINFO to contain:\n%s\ngot:\n%s", section, srcinfo)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	pkgbuild "github.com/mikkeloscar/gopkgbuild"
+)
+
+const diffUsage = `usage: gopkgbuild diff [flags] <old> <new>
+
+Print the semantic changes between two PKGBUILD or .SRCINFO files, or
+package directories: version bumps, added and removed dependencies,
+changed sources and checksums etc.
+
+The exit status is 1 if one of the variables given with -fail-on changed,
+otherwise 0.
+
+flags:
+`
+
+// runDiff prints the changes between two PKGBUILDs.
+func runDiff(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("diff", diffUsage, stderr)
+	format := flags.String("format", "text", "output format: text or json")
+	failOn := flags.String("fail-on", "", "comma separated `variables`, e.g. source,validpgpkeys, which must not change; arch specific variants are included")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(stderr, "gopkgbuild diff: unknown format: %s\n", *format)
+		return 2
+	}
+
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return 2
+	}
+
+	oldPkg, err := load(flags.Arg(0))
+	if err != nil {
+		return fail(stderr, "diff", err)
+	}
+
+	newPkg, err := load(flags.Arg(1))
+	if err != nil {
+		return fail(stderr, "diff", err)
+	}
+
+	changes := pkgbuild.Diff(oldPkg, newPkg)
+
+	if *format == "json" {
+		if changes == nil {
+			changes = pkgbuild.Changes{}
+		}
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(changes); err != nil {
+			return fail(stderr, "diff", err)
+		}
+	} else {
+		fmt.Fprint(stdout, changes)
+	}
+
+	if *failOn == "" {
+		return 0
+	}
+
+	code := 0
+	for _, field := range strings.Split(*failOn, ",") {
+		field = strings.TrimSpace(field)
+		for _, change := range changes {
+			if change.Field == field || strings.HasPrefix(change.Field, field+"_") {
+				fmt.Fprintf(stderr, "gopkgbuild diff: %s changed\n", change.Field)
+				code = 1
+			}
+		}
+	}
+
+	return code
+}
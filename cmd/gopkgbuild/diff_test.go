@@ -0,0 +1,71 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopkgbuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	oldPath := filepath.Join(dir, "PKGBUILD.old")
+	newPath := filepath.Join(dir, "PKGBUILD.new")
+
+	err = ioutil.WriteFile(oldPath, []byte(`pkgname=foo
+pkgver=1.0
+pkgrel=2
+arch=(x86_64)
+depends=(glibc)
+source=("https://example.org/foo-$pkgver.tar.gz")
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ioutil.WriteFile(newPath, []byte(`pkgname=foo
+pkgver=1.1
+pkgrel=1
+arch=(x86_64)
+depends=(glibc zlib)
+source=("https://example.org/foo-$pkgver.tar.gz")
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, stderr := runCommand("diff", oldPath, newPath)
+	if code != 0 {
+		t.Fatalf("diff failed with exit code %d: %s", code, stderr)
+	}
+
+	expected := `pkgver: "1.0" -> "1.1"
+pkgrel: "2" -> "1"
+depends: +zlib
+source: -https://example.org/foo-1.0.tar.gz +https://example.org/foo-1.1.tar.gz
+`
+	if stdout != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, stdout)
+	}
+
+	if code, _, _ := runCommand("diff", "-fail-on", "validpgpkeys,source", oldPath, newPath); code != 1 {
+		t.Errorf("expected exit code 1 for changed sources, got %d", code)
+	}
+
+	if code, _, _ := runCommand("diff", "-fail-on", "url", oldPath, newPath); code != 0 {
+		t.Errorf("expected exit code 0 for unchanged url, got %d", code)
+	}
+
+	if code, stdout, _ := runCommand("diff", "-format", "json", oldPath, oldPath); code != 0 || stdout != "[]\n" {
+		t.Errorf("expected no changes, got exit code %d: %s", code, stdout)
+	}
+
+	if code, _, _ := runCommand("diff", oldPath); code != 2 {
+		t.Errorf("expected exit code 2 for a missing argument, got %d", code)
+	}
+}
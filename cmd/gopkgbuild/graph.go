@@ -0,0 +1,232 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	pkgbuild "github.com/mikkeloscar/gopkgbuild"
+)
+
+const graphUsage = `usage: gopkgbuild graph [flags] [dir]
+
+Print the dependency graph of the packages in the subdirectories of dir,
+by default the current directory. Each subdirectory must contain a
+PKGBUILD or .SRCINFO file. Only dependencies between the found packages
+are included unless -external is given. Make and check dependencies are
+drawn dashed.
+
+flags:
+`
+
+// edge is a dependency of the package from on the package to.
+type edge struct {
+	from, to string
+	make     bool // a make or check dependency
+}
+
+// graph is a package dependency graph.
+type graph struct {
+	nodes []string
+	edges []edge
+}
+
+// runGraph prints the dependency graph of a directory of packages.
+func runGraph(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("graph", graphUsage, stderr)
+	format := flags.String("format", "dot", "output format: dot or mermaid")
+	makedepends := flags.Bool("makedepends", false, "include make and check dependencies")
+	collapse := flags.Bool("collapse", false, "draw split packages as a single pkgbase node")
+	external := flags.Bool("external", false, "include dependencies on packages not found in dir")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	if *format != "dot" && *format != "mermaid" {
+		fmt.Fprintf(stderr, "gopkgbuild graph: unknown format: %s\n", *format)
+		return 2
+	}
+
+	dir := "."
+	if flags.NArg() > 0 {
+		dir = flags.Arg(0)
+	}
+
+	pkgs, err := loadDir(dir)
+	if err != nil {
+		return fail(stderr, "graph", err)
+	}
+
+	g := newGraph(pkgs, *makedepends, *collapse, *external)
+	if *format == "mermaid" {
+		err = g.writeMermaid(stdout)
+	} else {
+		err = g.writeDOT(stdout)
+	}
+	if err != nil {
+		return fail(stderr, "graph", err)
+	}
+
+	return 0
+}
+
+// loadDir loads the packages in the subdirectories of dir.
+func loadDir(dir string) ([]*pkgbuild.PKGBUILD, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var pkgs []*pkgbuild.PKGBUILD
+	for _, info := range infos {
+		if !info.IsDir() || strings.HasPrefix(info.Name(), ".") {
+			continue
+		}
+
+		path := filepath.Join(dir, info.Name())
+		if _, err := resolvePath(path); err != nil {
+			// not a package directory
+			continue
+		}
+
+		pkg, err := load(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+		pkgs = append(pkgs, pkg)
+	}
+
+	return pkgs, nil
+}
+
+// newGraph returns the dependency graph of pkgs.
+func newGraph(pkgs []*pkgbuild.PKGBUILD, makedepends, collapse, external bool) *graph {
+	// node of each package name, including provided names
+	nodeOf := make(map[string]string)
+	for _, pkg := range pkgs {
+		for _, name := range pkg.Pkgnames {
+			nodeOf[name] = name
+			if collapse {
+				nodeOf[name] = pkg.Pkgbase
+			}
+		}
+	}
+	for _, pkg := range pkgs {
+		for _, provide := range pkg.Provides {
+			name := provide
+			if i := strings.IndexAny(name, "<>="); i >= 0 {
+				name = name[:i]
+			}
+			if _, ok := nodeOf[name]; !ok {
+				nodeOf[name] = nodeOf[pkg.Pkgnames[0]]
+			}
+		}
+	}
+
+	nodes := make(map[string]bool)
+	edges := make(map[edge]bool)
+
+	for _, pkg := range pkgs {
+		froms := pkg.Pkgnames
+		if collapse {
+			froms = []string{pkg.Pkgbase}
+		}
+
+		deps := map[bool][]*pkgbuild.Dependency{false: pkg.Depends}
+		if makedepends {
+			deps[true] = append(append([]*pkgbuild.Dependency{}, pkg.Makedepends...), pkg.Checkdepends...)
+		}
+
+		for _, from := range froms {
+			nodes[from] = true
+			for isMake, deps := range deps {
+				for _, dep := range deps {
+					to, ok := nodeOf[dep.Name]
+					if !ok {
+						if !external {
+							continue
+						}
+						to = dep.Name
+					}
+					if to == from {
+						continue
+					}
+					nodes[to] = true
+					edges[edge{from, to, isMake}] = true
+				}
+			}
+		}
+	}
+
+	g := &graph{}
+	for node := range nodes {
+		g.nodes = append(g.nodes, node)
+	}
+	sort.Strings(g.nodes)
+
+	for e := range edges {
+		// a runtime dependency implies the make dependency
+		if e.make && edges[edge{e.from, e.to, false}] {
+			continue
+		}
+		g.edges = append(g.edges, e)
+	}
+	sort.Slice(g.edges, func(i, j int) bool {
+		a, b := g.edges[i], g.edges[j]
+		if a.from != b.from {
+			return a.from < b.from
+		}
+		return a.to < b.to
+	})
+
+	return g
+}
+
+// writeDOT writes the graph in the Graphviz DOT language.
+func (g *graph) writeDOT(w io.Writer) error {
+	var b strings.Builder
+
+	b.WriteString("digraph packages {\n")
+	for _, node := range g.nodes {
+		fmt.Fprintf(&b, "\t%s;\n", strconv.Quote(node))
+	}
+	for _, e := range g.edges {
+		fmt.Fprintf(&b, "\t%s -> %s", strconv.Quote(e.from), strconv.Quote(e.to))
+		if e.make {
+			b.WriteString(" [style=dashed]")
+		}
+		b.WriteString(";\n")
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeMermaid writes the graph as a Mermaid flowchart.
+func (g *graph) writeMermaid(w io.Writer) error {
+	var b strings.Builder
+
+	// package names can't be used as Mermaid ids
+	ids := make(map[string]string, len(g.nodes))
+
+	b.WriteString("graph LR\n")
+	for i, node := range g.nodes {
+		ids[node] = fmt.Sprintf("n%d", i)
+		fmt.Fprintf(&b, "\t%s[\"%s\"]\n", ids[node], strings.ReplaceAll(node, `"`, "#quot;"))
+	}
+	for _, e := range g.edges {
+		arrow := "-->"
+		if e.make {
+			arrow = "-.->"
+		}
+		fmt.Fprintf(&b, "\t%s %s %s\n", ids[e.from], arrow, ids[e.to])
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
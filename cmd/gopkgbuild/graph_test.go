@@ -0,0 +1,82 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGraph(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopkgbuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	pkgbuilds := map[string]string{
+		"foo": "pkgbase=foo\npkgname=(foo foo-docs)\npkgver=1\npkgrel=1\narch=(any)\ndepends=(bar glibc)\nmakedepends=(cc)\n",
+		"bar": "pkgname=bar\npkgver=1\npkgrel=1\narch=(any)\ndepends=(glibc)\n",
+		"baz": "pkgname=baz\npkgver=1\npkgrel=1\narch=(any)\nprovides=(cc=1)\n",
+	}
+	for name, content := range pkgbuilds {
+		if err := os.Mkdir(filepath.Join(dir, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, name, "PKGBUILD"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{
+			[]string{"graph", dir},
+			`digraph packages {
+	"bar";
+	"baz";
+	"foo";
+	"foo-docs";
+	"foo" -> "bar";
+	"foo-docs" -> "bar";
+}
+`,
+		},
+		{
+			[]string{"graph", "-collapse", "-makedepends", dir},
+			`digraph packages {
+	"bar";
+	"baz";
+	"foo";
+	"foo" -> "bar";
+	"foo" -> "baz" [style=dashed];
+}
+`,
+		},
+		{
+			[]string{"graph", "-collapse", "-external", "-format", "mermaid", dir},
+			`graph LR
+	n0["bar"]
+	n1["baz"]
+	n2["foo"]
+	n3["glibc"]
+	n0 --> n3
+	n2 --> n0
+	n2 --> n3
+`,
+		},
+	}
+
+	for _, test := range tests {
+		code, stdout, stderr := runCommand(test.args...)
+		if code != 0 {
+			t.Errorf("%v failed with exit code %d: %s", test.args, code, stderr)
+			continue
+		}
+		if stdout != test.expected {
+			t.Errorf("%v: expected:\n%s\ngot:\n%s", test.args, test.expected, stdout)
+		}
+	}
+}
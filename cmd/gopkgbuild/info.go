@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	pkgbuild "github.com/mikkeloscar/gopkgbuild"
+)
+
+const infoUsage = `usage: gopkgbuild info [-f format] [-l separator] [path...]
+
+Print the metadata of PKGBUILD or .SRCINFO files, or package directories,
+one line per path. The format is an expac style format string like
+"%n %v" or a Go template like "{{.Pkgbase}}-{{.Version}}". The path
+defaults to the current directory.
+
+flags:
+`
+
+// runInfo prints the metadata of the given files using a format string.
+func runInfo(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("info", infoUsage, stderr)
+	format := flags.String("f", "%e %v", "format string")
+	separator := flags.String("l", "  ", "separator of array values")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	tmpl, err := pkgbuild.NewTemplate(*format)
+	if err != nil {
+		return fail(stderr, "info", err)
+	}
+	tmpl.ListSeparator = *separator
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	code := 0
+	for _, path := range paths {
+		pkg, err := load(path)
+		if err != nil {
+			code = fail(stderr, "info", err)
+			continue
+		}
+
+		if err := tmpl.Execute(stdout, pkg); err != nil {
+			code = fail(stderr, "info", err)
+			continue
+		}
+		fmt.Fprintln(stdout)
+	}
+
+	return code
+}
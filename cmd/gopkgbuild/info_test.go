@@ -0,0 +1,22 @@
+package main
+
+import "testing"
+
+func TestInfo(t *testing.T) {
+	code, stdout, stderr := runCommand("info", "../../test_pkgbuilds/SRCINFO_sudo")
+	if code != 0 {
+		t.Fatalf("info failed with exit code %d: %s", code, stderr)
+	}
+	if stdout != "sudo 1.8.11.p2-1\n" {
+		t.Errorf("unexpected output %q", stdout)
+	}
+
+	code, stdout, _ = runCommand("info", "-f", "%n: %D", "-l", ",", "../../test_pkgbuilds/SRCINFO_sudo")
+	if code != 0 || stdout != "sudo: glibc,pam,libldap\n" {
+		t.Errorf("unexpected output %q (exit code %d)", stdout, code)
+	}
+
+	if code, _, _ = runCommand("info", "-f", "%x", "../../test_pkgbuilds/SRCINFO_sudo"); code != 4 {
+		t.Errorf("expected exit code 4 for invalid format, got %d", code)
+	}
+}
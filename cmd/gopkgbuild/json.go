@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+
+	pkgbuild "github.com/mikkeloscar/gopkgbuild"
+)
+
+const jsonUsage = `usage: gopkgbuild json [path]
+
+Print the metadata of a PKGBUILD or .SRCINFO file, or a package directory,
+as a JSON object keyed by variable name. Scalars like pkgver are strings,
+arrays like depends or source_x86_64 are lists of strings. The path
+defaults to the current directory.
+`
+
+// scalarVars are the variables printed as strings instead of lists.
+var scalarVars = map[string]bool{
+	"pkgbase":   true,
+	"pkgver":    true,
+	"pkgrel":    true,
+	"epoch":     true,
+	"pkgdesc":   true,
+	"url":       true,
+	"install":   true,
+	"changelog": true,
+}
+
+// runJSON prints the parsed metadata as JSON.
+func runJSON(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("json", jsonUsage, stderr)
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	path := "."
+	if flags.NArg() > 0 {
+		path = flags.Arg(0)
+	}
+
+	pkg, err := load(path)
+	if err != nil {
+		return fail(stderr, "json", err)
+	}
+
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(metadata(pkg)); err != nil {
+		return fail(stderr, "json", err)
+	}
+
+	return 0
+}
+
+// metadata returns the variables of pkg as a JSON object.
+func metadata(pkg *pkgbuild.PKGBUILD) map[string]interface{} {
+	object := make(map[string]interface{})
+	for name, values := range pkg.Vars() {
+		if scalarVars[name] {
+			object[name] = values[0]
+		} else {
+			object[name] = values
+		}
+	}
+	return object
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestJSON(t *testing.T) {
+	code, stdout, stderr := runCommand("json", "../../test_pkgbuilds/SRCINFO_sudo")
+	if code != 0 {
+		t.Fatalf("json failed with exit code %d: %s", code, stderr)
+	}
+
+	var object map[string]interface{}
+	if err := json.Unmarshal([]byte(stdout), &object); err != nil {
+		t.Fatal(err)
+	}
+
+	if object["pkgver"] != "1.8.11.p2" {
+		t.Errorf("expected pkgver 1.8.11.p2, got %v", object["pkgver"])
+	}
+
+	expected := []interface{}{"glibc", "pam", "libldap"}
+	if !reflect.DeepEqual(object["depends"], expected) {
+		t.Errorf("expected depends %v, got %v", expected, object["depends"])
+	}
+
+	if _, ok := object["epoch"]; ok {
+		t.Errorf("expected unset epoch to be left out")
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	pkgbuild "github.com/mikkeloscar/gopkgbuild"
+)
+
+const lintUsage = `usage: gopkgbuild lint [-format text|json] [path...]
+
+Check PKGBUILD or .SRCINFO files, or package directories, for common
+mistakes. The path defaults to the current directory.
+
+The exit status is 0 if no warnings or errors are found, 1 if the worst
+issue is a warning and 3 if it is an error.
+
+flags:
+`
+
+// fileIssue is a lint issue of a single file.
+type fileIssue struct {
+	File string `json:"file"`
+	pkgbuild.Issue
+}
+
+// runLint lints the given files and prints the issues.
+func runLint(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("lint", lintUsage, stderr)
+	format := flags.String("format", "text", "output format: text or json")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(stderr, "gopkgbuild lint: unknown format: %s\n", *format)
+		return 2
+	}
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	issues := []fileIssue{}
+	for _, path := range paths {
+		for _, issue := range lint(path) {
+			issues = append(issues, fileIssue{path, issue})
+		}
+	}
+
+	if *format == "json" {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(issues); err != nil {
+			return fail(stderr, "lint", err)
+		}
+	} else {
+		for _, issue := range issues {
+			fmt.Fprintf(stdout, "%s: %s\n", issue.File, issue.Issue)
+		}
+	}
+
+	worst := pkgbuild.SeverityInfo
+	for _, issue := range issues {
+		if issue.Severity > worst {
+			worst = issue.Severity
+		}
+	}
+
+	switch worst {
+	case pkgbuild.SeverityWarning:
+		return 1
+	case pkgbuild.SeverityError:
+		return 3
+	}
+	return 0
+}
+
+// lint returns the issues of the file at path, including the problems
+// found when parsing it.
+func lint(path string) []pkgbuild.Issue {
+	var issues []pkgbuild.Issue
+
+	pkg, err := load(path, pkgbuild.WithContinueOnError())
+	if errs, ok := err.(pkgbuild.ParseErrors); ok {
+		for _, err := range errs {
+			issues = append(issues, pkgbuild.Issue{Severity: pkgbuild.SeverityError, Message: err.Error()})
+		}
+	} else if err != nil {
+		return []pkgbuild.Issue{{Severity: pkgbuild.SeverityError, Message: err.Error()}}
+	}
+
+	if pkg == nil {
+		return issues
+	}
+
+	// parse errors already include the invalid required variables
+Lint:
+	for _, issue := range pkgbuild.Lint(pkg) {
+		for _, i := range issues {
+			if i == issue {
+				continue Lint
+			}
+		}
+		issues = append(issues, issue)
+	}
+
+	return issues
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopkgbuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	err = ioutil.WriteFile(filepath.Join(dir, "PKGBUILD"), []byte(`pkgname=foo
+pkgver=1.0
+pkgrel=1
+pkgdesc="Foo"
+arch=(x86_64)
+url=https://example.org
+license=(MIT)
+source=(foo.tar.gz foo.patch)
+sha256sums=(aaaa)
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, _ := runCommand("lint", dir)
+	if code != 3 {
+		t.Errorf("expected exit code 3 for errors, got %d", code)
+	}
+	if expected := dir + ": error: sha256sums: 1 checksums for 2 sources\n"; stdout != expected {
+		t.Errorf("expected output %q, got %q", expected, stdout)
+	}
+
+	code, stdout, _ = runCommand("lint", "-format", "json", dir)
+	if code != 3 {
+		t.Errorf("expected exit code 3 for errors, got %d", code)
+	}
+
+	var issues []map[string]string
+	if err := json.Unmarshal([]byte(stdout), &issues); err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 1 || issues[0]["file"] != dir || issues[0]["severity"] != "error" || issues[0]["field"] != "sha256sums" {
+		t.Errorf("unexpected JSON issues: %v", issues)
+	}
+
+	if code, stdout, _ := runCommand("lint", "../../test_pkgbuilds/SRCINFO_sudo"); code != 0 || stdout != "" {
+		t.Errorf("expected no issues, got exit code %d: %s", code, stdout)
+	}
+
+	if code, _, _ := runCommand("lint", "-format", "xml"); code != 2 {
+		t.Errorf("expected exit code 2 for unknown format, got %d", code)
+	}
+}
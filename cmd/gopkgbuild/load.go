@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	pkgbuild "github.com/mikkeloscar/gopkgbuild"
+)
+
+// isSRCINFO reports whether the file at path is a .SRCINFO file judging by
+// its name, e.g. .SRCINFO or SRCINFO_foo.
+func isSRCINFO(path string) bool {
+	name := filepath.Base(path)
+	return name == ".SRCINFO" || strings.HasPrefix(name, "SRCINFO")
+}
+
+// resolvePath returns the file to load for path. For a directory this is
+// its PKGBUILD, or its .SRCINFO if there is no PKGBUILD.
+func resolvePath(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+
+	if !info.IsDir() {
+		return path, nil
+	}
+
+	file := filepath.Join(path, "PKGBUILD")
+	if _, err := os.Stat(file); err == nil {
+		return file, nil
+	}
+
+	file = filepath.Join(path, ".SRCINFO")
+	if _, err := os.Stat(file); err != nil {
+		return "", err
+	}
+	return file, nil
+}
+
+// load parses the PKGBUILD or .SRCINFO file at path, which may also be a
+// package directory. A PKGBUILD is evaluated without running bash.
+func load(path string, opts ...pkgbuild.ParseOption) (*pkgbuild.PKGBUILD, error) {
+	path, err := resolvePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if isSRCINFO(path) {
+		return pkgbuild.ParseSRCINFO(path, opts...)
+	}
+
+	file, err := pkgbuild.ParseASTFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return file.PKGBUILD(opts...)
+}
@@ -0,0 +1,106 @@
+// Command gopkgbuild provides tools for working with PKGBUILD and .SRCINFO
+// files on top of the gopkgbuild package, e.g.
+//
+//	gopkgbuild vercmp 1.0-1 1:0.9-1
+//
+// The exit status is 2 for usage errors and 4 if a command fails, e.g.
+// because a file can't be read or parsed. The statuses 1 and 3 are left to
+// the findings of the subcommands, like lint warnings and errors.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// command is a gopkgbuild subcommand.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string, stdout, stderr io.Writer) int
+}
+
+// commands lists the available subcommands.
+var commands = []command{
+	{"vercmp", "compare two package versions like pacman's vercmp", runVercmp},
+	{"srcinfo", "print the .SRCINFO of a PKGBUILD", runSrcinfo},
+	{"lint", "check PKGBUILDs for common mistakes", runLint},
+	{"json", "print the metadata of a PKGBUILD as JSON", runJSON},
+	{"info", "print the metadata of PKGBUILDs using a format string", runInfo},
+	{"graph", "print the dependency graph of a directory of packages", runGraph},
+	{"diff", "print the semantic changes between two PKGBUILDs", runDiff},
+	{"verify", "verify the checksums of the sources of a PKGBUILD", runVerify},
+	{"bump", "update a PKGBUILD to a new pkgver", runBump},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run runs the subcommand given by args and returns the exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return 2
+	}
+
+	switch args[0] {
+	case "-h", "--help", "help":
+		usage(stdout)
+		return 0
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(args[1:], stdout, stderr)
+		}
+	}
+
+	fmt.Fprintf(stderr, "gopkgbuild: unknown command: %s\n", args[0])
+	usage(stderr)
+	return 2
+}
+
+// usage prints the list of subcommands to w.
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "usage: gopkgbuild <command> [arguments]\n\ncommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-8s %s\n", cmd.name, cmd.summary)
+	}
+}
+
+// newFlagSet returns the flag set of the subcommand name, printing usage
+// followed by the flag defaults to stderr on errors.
+func newFlagSet(name, usage string, stderr io.Writer) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// parseFlags parses args with flags. If the command should exit right away,
+// e.g. because of -h or an unknown flag, ok is false and code is the exit
+// code.
+func parseFlags(flags *flag.FlagSet, args []string) (code int, ok bool) {
+	err := flags.Parse(args)
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		return 0, false
+	case err != nil:
+		return 2, false
+	}
+	return 0, true
+}
+
+// fail prints err for the subcommand name and returns exit code 4, distinct
+// from the codes of the findings of lint, diff and verify.
+func fail(stderr io.Writer, name string, err error) int {
+	fmt.Fprintf(stderr, "gopkgbuild %s: %s\n", name, err)
+	return 4
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+// runCommand runs gopkgbuild with args and returns the exit code and output.
+func runCommand(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRunUnknownCommand(t *testing.T) {
+	if code, _, _ := runCommand("foo"); code != 2 {
+		t.Errorf("expected exit code 2 for unknown command, got %d", code)
+	}
+
+	if code, _, _ := runCommand(); code != 2 {
+		t.Errorf("expected exit code 2 without command, got %d", code)
+	}
+}
+
+func TestVercmp(t *testing.T) {
+	tests := []struct {
+		args   []string
+		output string
+	}{
+		{[]string{"1.0-1", "1.0-2"}, "-1\n"},
+		{[]string{"1.0", "1.0-2"}, "0\n"},
+		{[]string{"1:1.0", "2.0"}, "1\n"},
+		{[]string{"1.0"}, "1\n"},
+	}
+
+	for _, test := range tests {
+		code, stdout, _ := runCommand(append([]string{"vercmp"}, test.args...)...)
+		if code != 0 || stdout != test.output {
+			t.Errorf("vercmp %v: expected %q, got %q (exit code %d)", test.args, test.output, stdout, code)
+		}
+	}
+
+	if code, _, _ := runCommand("vercmp"); code != 2 {
+		t.Errorf("expected exit code 2 without versions, got %d", code)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"io"
+
+	pkgbuild "github.com/mikkeloscar/gopkgbuild"
+)
+
+const srcinfoUsage = `usage: gopkgbuild srcinfo [PKGBUILD]
+
+Print the .SRCINFO of the PKGBUILD, by default ./PKGBUILD, like makepkg
+--printsrcinfo. The PKGBUILD is evaluated without running bash, including
+the per package overrides assigned in package_*() functions.
+`
+
+// runSrcinfo prints the .SRCINFO generated from a PKGBUILD.
+func runSrcinfo(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("srcinfo", srcinfoUsage, stderr)
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	path := "PKGBUILD"
+	if flags.NArg() > 0 {
+		path = flags.Arg(0)
+	}
+
+	file, err := pkgbuild.ParseASTFile(path)
+	if err != nil {
+		return fail(stderr, "srcinfo", err)
+	}
+
+	if err := file.WriteSRCINFO(stdout); err != nil {
+		return fail(stderr, "srcinfo", err)
+	}
+
+	return 0
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"io/ioutil"
+	"regexp"
+	"testing"
+)
+
+func TestSrcinfo(t *testing.T) {
+	expected, err := ioutil.ReadFile("../../test_pkgbuilds/SRCINFO_sudo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// strip the "Generated by makepkg" comments
+	expected = regexp.MustCompile(`(?m)^#.*\n`).ReplaceAll(expected, nil)
+
+	code, stdout, stderr := runCommand("srcinfo", "../../test_pkgbuilds/PKGBUILD_sudo")
+	if code != 0 {
+		t.Fatalf("srcinfo failed with exit code %d: %s", code, stderr)
+	}
+
+	if stdout != string(expected) {
+		t.Errorf("unexpected .SRCINFO:\n%s", stdout)
+	}
+
+	if code, _, _ := runCommand("srcinfo", "../../test_pkgbuilds/PKGBUILD_missing"); code != 4 {
+		t.Errorf("expected exit code 4 for a missing PKGBUILD, got %d", code)
+	}
+}
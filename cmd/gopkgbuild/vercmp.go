@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	pkgbuild "github.com/mikkeloscar/gopkgbuild"
+)
+
+const vercmpUsage = `usage: gopkgbuild vercmp <ver1> <ver2>
+
+output values:
+  < 0 : if ver1 < ver2
+    0 : if ver1 == ver2
+  > 0 : if ver1 > ver2
+`
+
+// runVercmp compares two versions with the same arguments, output and exit
+// codes as pacman's vercmp.
+func runVercmp(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, vercmpUsage)
+		return 2
+	}
+
+	if args[0] == "-h" || args[0] == "--help" {
+		fmt.Fprint(stdout, vercmpUsage)
+		return 0
+	}
+
+	ret := 1 // like vercmp, a missing second version is older
+	if len(args) > 1 {
+		ret = pkgbuild.VerCmp(args[0], args[1])
+	}
+
+	fmt.Fprintf(stdout, "%d\n", ret)
+	return 0
+}
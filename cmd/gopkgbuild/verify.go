@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	pkgbuild "github.com/mikkeloscar/gopkgbuild"
+)
+
+const verifyUsage = `usage: gopkgbuild verify [flags] [path]
+
+Verify the declared checksums of the sources of a PKGBUILD or .SRCINFO
+file, or package directory, by default the current directory, like
+makepkg --verifysource. Local sources like patches are looked up in the
+package directory, the others in -srcdest if given.
+
+PGP signatures among the sources are verified against the public keys in
+the -keyring files, or the keys/pgp/*.asc files of the package directory,
+and must be made by a key listed in validpgpkeys. Without any keys the
+signatures are not verified.
+
+The exit status is 1 if a checksum or signature doesn't match or a source
+is missing.
+
+flags:
+`
+
+// runVerify verifies the checksums of the sources of a package.
+func runVerify(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("verify", verifyUsage, stderr)
+	download := flags.Bool("download", false, "download missing http and https sources first")
+	srcdest := flags.String("srcdest", "", "`directory` holding the sources")
+	keyring := flags.String("keyring", "", "comma separated public key `files`")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	path := "."
+	if flags.NArg() > 0 {
+		path = flags.Arg(0)
+	}
+
+	file, err := resolvePath(path)
+	if err != nil {
+		return fail(stderr, "verify", err)
+	}
+
+	pkg, err := load(file)
+	if err != nil {
+		return fail(stderr, "verify", err)
+	}
+
+	startDir := filepath.Dir(file)
+	dir := *srcdest
+	if dir == "" {
+		dir = startDir
+	}
+
+	if *download {
+		if err := pkg.DownloadSources(context.Background(), dir); err != nil {
+			return fail(stderr, "verify", err)
+		}
+	}
+
+	results, err := pkg.Verify(dir, pkgbuild.WithStartDir(startDir))
+	if err != nil {
+		return fail(stderr, "verify", err)
+	}
+
+	code := 0
+	for _, result := range results {
+		status := result.Status.String()
+		if len(result.Failed) > 0 {
+			status += " (" + strings.Join(result.Failed, ", ") + ")"
+		}
+		fmt.Fprintf(stdout, "    %s ... %s\n", result.FileName, status)
+
+		switch result.Status {
+		case pkgbuild.VerifyFailed, pkgbuild.VerifyNotFound:
+			code = 1
+		}
+	}
+
+	keyrings := strings.Split(*keyring, ",")
+	if *keyring == "" {
+		keyrings, _ = filepath.Glob(filepath.Join(startDir, "keys", "pgp", "*.asc"))
+	}
+	if len(keyrings) == 0 {
+		return code
+	}
+
+	signatures, err := verifySignatures(pkg, dir, startDir, keyrings)
+	if err != nil {
+		return fail(stderr, "verify", err)
+	}
+
+	for _, result := range signatures {
+		status := result.Status.String()
+		if result.Key != "" {
+			status += " (" + result.Key + ")"
+		}
+		name := pkgbuild.Source(result.Source).FileName()
+		fmt.Fprintf(stdout, "    %s ... %s\n", name, status)
+
+		if result.Status != pkgbuild.SignaturePassed {
+			code = 1
+		}
+	}
+
+	return code
+}
+
+// verifySignatures verifies the source signatures of pkg with the keys in
+// the keyring files, looking up local sources in startDir.
+func verifySignatures(pkg *pkgbuild.PKGBUILD, dir, startDir string, keyrings []string) ([]pkgbuild.SignatureResult, error) {
+	readers := make([]io.Reader, 0, len(keyrings))
+	for _, keyring := range keyrings {
+		f, err := os.Open(keyring)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		readers = append(readers, f)
+	}
+
+	return pkg.VerifySignatures(dir, readers, pkgbuild.WithStartDir(startDir))
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+func TestVerify(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopkgbuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// sha256 of "foo"
+	err = ioutil.WriteFile(filepath.Join(dir, "PKGBUILD"), []byte(`pkgname=foo
+pkgver=1.0
+pkgrel=1
+arch=(any)
+source=(foo.patch foo.service)
+sha256sums=('2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae' SKIP)
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "foo.patch"), []byte("foo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, stderr := runCommand("verify", dir)
+	if code != 0 {
+		t.Fatalf("verify failed with exit code %d: %s%s", code, stdout, stderr)
+	}
+
+	expected := "    foo.patch ... Passed\n    foo.service ... Skipped\n"
+	if stdout != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, stdout)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "foo.patch"), []byte("bar"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, _ = runCommand("verify", dir)
+	if code != 1 {
+		t.Errorf("expected exit code 1 for a mismatching checksum, got %d", code)
+	}
+	if expected := "    foo.patch ... FAILED (sha256)\n    foo.service ... Skipped\n"; stdout != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, stdout)
+	}
+
+	// local sources are read from the package directory regardless of -srcdest
+	srcdest, err := ioutil.TempDir("", "gopkgbuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(srcdest)
+
+	if _, stdout, _ = runCommand("verify", "-srcdest", srcdest, dir); !strings.HasPrefix(stdout, "    foo.patch ... FAILED (sha256)\n") {
+		t.Errorf("expected foo.patch to be read from %s, got:\n%s", dir, stdout)
+	}
+}
+
+func TestVerifySignatures(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopkgbuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	key, err := openpgp.NewEntity("test", "", "test@example.org", &packet.Config{RSABits: 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fingerprint := fmt.Sprintf("%X", key.PrimaryKey.Fingerprint)
+
+	if err := os.MkdirAll(filepath.Join(dir, "keys", "pgp"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(filepath.Join(dir, "keys", "pgp", fingerprint+".asc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := armor.Encode(f, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := key.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	f.Close()
+
+	var sig bytes.Buffer
+	if err := openpgp.DetachSign(&sig, key, strings.NewReader("foo"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "foo.tar.gz"), []byte("foo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "foo.tar.gz.sig"), sig.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err = ioutil.WriteFile(filepath.Join(dir, "PKGBUILD"), []byte(`pkgname=foo
+pkgver=1.0
+pkgrel=1
+arch=(any)
+source=(foo.tar.gz{,.sig})
+sha256sums=(SKIP SKIP)
+validpgpkeys=(`+fingerprint+`)
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, stderr := runCommand("verify", dir)
+	if code != 0 {
+		t.Fatalf("verify failed with exit code %d: %s%s", code, stdout, stderr)
+	}
+
+	expected := "    foo.tar.gz ... Skipped\n    foo.tar.gz.sig ... Skipped\n    foo.tar.gz.sig ... Passed (" + fingerprint + ")\n"
+	if stdout != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, stdout)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "foo.tar.gz"), []byte("bar"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if code, stdout, _ = runCommand("verify", dir); code != 1 {
+		t.Errorf("expected exit code 1 for a bad signature, got %d: %s", code, stdout)
+	}
+}
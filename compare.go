@@ -0,0 +1,119 @@
+package pkgbuild
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// VerCmpPairs compares the versions of each pair with VerCmp and returns
+// the results in the order of pairs. The pairs are split between workers
+// goroutines, runtime.GOMAXPROCS(0) if workers is not positive.
+func VerCmpPairs(pairs [][2]string, workers int) []int {
+	results := make([]int, len(pairs))
+
+	parallel(len(pairs), workers, func(start, end int) {
+		for i := start; i < end; i++ {
+			results[i] = VerCmp(pairs[i][0], pairs[i][1])
+		}
+	})
+
+	return results
+}
+
+// SortVersionStrings sorts versions from oldest to newest using VerCmp,
+// keeping the order of equal versions. Parts of versions are sorted by
+// workers goroutines in parallel and then merged, workers is as for
+// VerCmpPairs.
+func SortVersionStrings(versions []string, workers int) {
+	less := func(s []string) func(i, j int) bool {
+		return func(i, j int) bool {
+			return VerCmp(s[i], s[j]) < 0
+		}
+	}
+
+	var bounds []int
+	var mu sync.Mutex
+	parallel(len(versions), workers, func(start, end int) {
+		part := versions[start:end]
+		sort.SliceStable(part, less(part))
+
+		mu.Lock()
+		bounds = append(bounds, start)
+		mu.Unlock()
+	})
+	sort.Ints(bounds)
+	bounds = append(bounds, len(versions))
+
+	// merge neighbouring parts until one is left
+	buf := make([]string, len(versions))
+	for len(bounds) > 2 {
+		var merged []int
+		var wg sync.WaitGroup
+		for i := 0; i+1 < len(bounds); i += 2 {
+			merged = append(merged, bounds[i])
+			if i+2 >= len(bounds) {
+				// odd part out
+				continue
+			}
+
+			wg.Add(1)
+			go func(start, mid, end int) {
+				defer wg.Done()
+				mergeVersions(buf[start:end], versions[start:mid], versions[mid:end])
+				copy(versions[start:end], buf[start:end])
+			}(bounds[i], bounds[i+1], bounds[i+2])
+		}
+		wg.Wait()
+		bounds = append(merged, len(versions))
+	}
+}
+
+// mergeVersions merges the sorted versions a and b into dst, taking from a
+// first for equal versions.
+func mergeVersions(dst, a, b []string) {
+	i, j := 0, 0
+	for k := range dst {
+		if j == len(b) || i < len(a) && VerCmp(b[j], a[i]) >= 0 {
+			dst[k] = a[i]
+			i++
+		} else {
+			dst[k] = b[j]
+			j++
+		}
+	}
+}
+
+// parallel calls fn for contiguous parts of the n indices from workers
+// goroutines, runtime.GOMAXPROCS(0) if workers is not positive, and waits
+// for them to finish.
+func parallel(n, workers int, fn func(start, end int)) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > n {
+		workers = n
+	}
+	if workers <= 1 {
+		if n > 0 {
+			fn(0, n)
+		}
+		return
+	}
+
+	var wg sync.WaitGroup
+	size := (n + workers - 1) / workers
+	for start := 0; start < n; start += size {
+		end := start + size
+		if end > n {
+			end = n
+		}
+
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			fn(start, end)
+		}(start, end)
+	}
+	wg.Wait()
+}
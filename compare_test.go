@@ -0,0 +1,100 @@
+package pkgbuild
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// randomVersions returns n random versions using the seed. All of them
+// have a pkgrel, as mixing versions with and without one doesn't give a
+// consistent order: 1.0 is equal to both 1.0-1 and 1.0-2.
+func randomVersions(n int, seed int64) []string {
+	r := rand.New(rand.NewSource(seed))
+	suffixes := []string{"", "", "", "rc1", ".a", "beta2", ".r12.gabc1234"}
+
+	versions := make([]string, n)
+	for i := range versions {
+		v := fmt.Sprintf("%d.%d%s-%d", r.Intn(5), r.Intn(20), suffixes[r.Intn(len(suffixes))], 1+r.Intn(2))
+		if r.Intn(10) == 0 {
+			v = fmt.Sprintf("%d:%s", r.Intn(3), v)
+		}
+		versions[i] = v
+	}
+	return versions
+}
+
+func TestVerCmpPairs(t *testing.T) {
+	versions := randomVersions(1001, 1)
+
+	pairs := make([][2]string, len(versions)-1)
+	for i := range pairs {
+		pairs[i] = [2]string{versions[i], versions[i+1]}
+	}
+
+	for _, workers := range []int{0, 1, 3, 2000} {
+		results := VerCmpPairs(pairs, workers)
+		for i, pair := range pairs {
+			if results[i] != VerCmp(pair[0], pair[1]) {
+				t.Fatalf("%d workers: wrong result for %v: %d", workers, pair, results[i])
+			}
+		}
+	}
+
+	if results := VerCmpPairs(nil, 4); len(results) != 0 {
+		t.Errorf("expected no results, got %v", results)
+	}
+}
+
+func TestSortVersionStrings(t *testing.T) {
+	for _, workers := range []int{0, 1, 2, 3, 7, 2000} {
+		versions := randomVersions(1000, 2)
+		// equal to 1.0-1 but different strings to check the sort is stable
+		versions = append(versions, "1.0-1", "1.00-1", "0:1.0-1")
+
+		expected := append([]string{}, versions...)
+		sort.SliceStable(expected, func(i, j int) bool {
+			return VerCmp(expected[i], expected[j]) < 0
+		})
+
+		SortVersionStrings(versions, workers)
+		if !reflect.DeepEqual(versions, expected) {
+			t.Errorf("%d workers: versions are not sorted", workers)
+		}
+	}
+
+	SortVersionStrings(nil, 4)
+}
+
+func BenchmarkVerCmp(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		VerCmp("1:2.0.0.r29.g18fc492-1", "1:2.0.0.r30.g18fc492-1")
+	}
+}
+
+func BenchmarkVerCmpPairs(b *testing.B) {
+	versions := randomVersions(100001, 3)
+	pairs := make([][2]string, len(versions)-1)
+	for i := range pairs {
+		pairs[i] = [2]string{versions[i], versions[i+1]}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		VerCmpPairs(pairs, 0)
+	}
+}
+
+func BenchmarkSortVersionStrings(b *testing.B) {
+	versions := randomVersions(100000, 4)
+	s := make([]string, len(versions))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		copy(s, versions)
+		SortVersionStrings(s, 0)
+	}
+}
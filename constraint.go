@@ -0,0 +1,93 @@
+package pkgbuild
+
+import "fmt"
+
+// Operator is the operator of a version constraint of a dependency.
+type Operator string
+
+// Version constraint operators
+const (
+	OpEqual        Operator = "="
+	OpGreater      Operator = ">"
+	OpGreaterEqual Operator = ">="
+	OpLess         Operator = "<"
+	OpLessEqual    Operator = "<="
+	// OpNotEqual is not supported by pacman, see ParseConstraint.
+	OpNotEqual Operator = "!="
+)
+
+// Constraint is a single version constraint of a dependency like the ">=1.0"
+// of "foo>=1.0".
+type Constraint struct {
+	Op      Operator
+	Version *CompleteVersion
+}
+
+func (c Constraint) String() string {
+	return string(c.Op) + c.Version.String()
+}
+
+// Constraints returns the version constraints of dep in the order written
+// by String: a pin like "=1.0", or the lower and upper bound, followed by
+// the exclusions. It's empty if dep has no version.
+func (dep *Dependency) Constraints() []Constraint {
+	var constraints []Constraint
+
+	if dep.MinVer != nil && dep.MaxVer != nil && !dep.sgt && !dep.slt && dep.MinVer.cmp(dep.MaxVer) == 0 {
+		constraints = append(constraints, Constraint{OpEqual, dep.MinVer})
+	} else {
+		if dep.MinVer != nil {
+			op := OpGreaterEqual
+			if dep.sgt {
+				op = OpGreater
+			}
+			constraints = append(constraints, Constraint{op, dep.MinVer})
+		}
+
+		if dep.MaxVer != nil {
+			op := OpLessEqual
+			if dep.slt {
+				op = OpLess
+			}
+			constraints = append(constraints, Constraint{op, dep.MaxVer})
+		}
+	}
+
+	for _, v := range dep.Exclude {
+		constraints = append(constraints, Constraint{OpNotEqual, v})
+	}
+
+	return constraints
+}
+
+// NewDependency returns the dependency on name meeting all constraints.
+// Unlike parsed dependencies it can have exclusive bounds without using
+// ParseConstraint. An error is returned for unknown operators.
+func NewDependency(name string, constraints ...Constraint) (*Dependency, error) {
+	r := VersionRange{}
+	for _, c := range constraints {
+		if c.Version == nil {
+			return nil, fmt.Errorf("version missing for %s%s", name, c.Op)
+		}
+
+		var next VersionRange
+		switch c.Op {
+		case OpEqual:
+			next = VersionRange{Min: c.Version, Max: c.Version}
+		case OpGreater:
+			next = VersionRange{Min: c.Version, MinExclusive: true}
+		case OpGreaterEqual:
+			next = VersionRange{Min: c.Version}
+		case OpLess:
+			next = VersionRange{Max: c.Version, MaxExclusive: true}
+		case OpLessEqual:
+			next = VersionRange{Max: c.Version}
+		case OpNotEqual:
+			next = VersionRange{Exclude: []*CompleteVersion{c.Version}}
+		default:
+			return nil, fmt.Errorf("unknown operator: %s", c.Op)
+		}
+		r = r.Intersect(next)
+	}
+	return newDependency(name, r), nil
+}
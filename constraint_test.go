@@ -0,0 +1,71 @@
+package pkgbuild
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConstraints(t *testing.T) {
+	for _, test := range []struct {
+		constraint string
+		expected   []Operator
+	}{
+		{"foo", nil},
+		{"foo=1.0", []Operator{OpEqual}},
+		{"foo>=1.0", []Operator{OpGreaterEqual}},
+		{"foo>1.0 foo<2.0", []Operator{OpGreater, OpLess}},
+		{"foo<=2.0 foo!=1.5", []Operator{OpLessEqual, OpNotEqual}},
+	} {
+		dep, err := ParseConstraint(test.constraint)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var ops []Operator
+		for _, c := range dep.Constraints() {
+			ops = append(ops, c.Op)
+		}
+		if !reflect.DeepEqual(ops, test.expected) {
+			t.Errorf("%s: expected %q, got %q", test.constraint, test.expected, ops)
+		}
+
+		rebuilt, err := NewDependency(dep.Name, dep.Constraints()...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rebuilt.String() != dep.String() {
+			t.Errorf("%s: expected %s from constraints, got %s", test.constraint, dep, rebuilt)
+		}
+	}
+
+	// pins are compared by value
+	min, _ := NewCompleteVersion("1.0-1")
+	max, _ := NewCompleteVersion("1.0-1")
+	dep := &Dependency{Name: "foo", MinVer: min, MaxVer: max}
+	if s := dep.String(); s != "foo=1.0-1" {
+		t.Errorf("expected foo=1.0-1, got %s", s)
+	}
+}
+
+func TestNewDependency(t *testing.T) {
+	v1, _ := NewCompleteVersion("1.0")
+	v2, _ := NewCompleteVersion("2.0")
+
+	dep, err := NewDependency("foo", Constraint{OpGreater, v1}, Constraint{OpLess, v2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dep.String() != "foo>1.0 foo<2.0" {
+		t.Errorf("unexpected dependency: %s", dep)
+	}
+	if dep.Range().Contains(v1) || !dep.Range().Contains(&CompleteVersion{Version: "1.5"}) {
+		t.Errorf("unexpected range of %s", dep)
+	}
+
+	if _, err := NewDependency("foo", Constraint{"~=", v1}); err == nil {
+		t.Error("expected error for unknown operator")
+	}
+	if _, err := NewDependency("foo", Constraint{Op: OpEqual}); err == nil {
+		t.Error("expected error for missing version")
+	}
+}
@@ -0,0 +1,221 @@
+package pkgbuild
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DefaultCratesIOURL is the URL of the API of crates.io.
+const DefaultCratesIOURL = "https://crates.io/api/v1"
+
+// Crate is the metadata of a release of a Rust crate.
+type Crate struct {
+	Name        string
+	Version     string
+	Description string
+	Homepage    string
+	Repository  string
+	License     string // SPDX license expression
+	Checksum    string // sha256 of the .crate file, empty if unknown
+}
+
+// ReadCargoToml reads the crate metadata from the [package] table of the
+// Cargo.toml at path. Only string values are read, values inherited from
+// the workspace are left empty.
+func ReadCargoToml(path string) (*Crate, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	crate := &Crate{}
+	fields := map[string]*string{
+		"name":        &crate.Name,
+		"version":     &crate.Version,
+		"description": &crate.Description,
+		"homepage":    &crate.Homepage,
+		"repository":  &crate.Repository,
+		"license":     &crate.License,
+	}
+
+	var table string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			table = strings.Trim(line, "[] ")
+			continue
+		}
+		if table != "package" {
+			continue
+		}
+
+		i := strings.IndexByte(line, '=')
+		if i < 0 {
+			continue
+		}
+		field, ok := fields[strings.TrimSpace(line[:i])]
+		if !ok {
+			continue
+		}
+		if value, ok := tomlString(strings.TrimSpace(line[i+1:])); ok {
+			*field = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if crate.Name == "" {
+		return nil, fmt.Errorf("package name missing: %s", path)
+	}
+	return crate, nil
+}
+
+// tomlString returns the value of the basic or literal TOML string at the
+// start of s, ok is false if s doesn't start with a string.
+func tomlString(s string) (string, bool) {
+	switch {
+	case strings.HasPrefix(s, "'"):
+		if i := strings.IndexByte(s[1:], '\''); i >= 0 {
+			return s[1 : i+1], true
+		}
+	case strings.HasPrefix(s, `"`):
+		for i := 1; i < len(s); i++ {
+			switch s[i] {
+			case '\\':
+				i++
+			case '"':
+				value, err := strconv.Unquote(s[:i+1])
+				return value, err == nil
+			}
+		}
+	}
+	return "", false
+}
+
+// CratesIO is a client of the API of crates.io.
+type CratesIO struct {
+	URL    string       // DefaultCratesIOURL if empty
+	Client *http.Client // http.DefaultClient if nil
+}
+
+// Crate returns the metadata of version of the crate name, of the latest
+// stable release if version is empty. Yanked versions aren't returned.
+func (c *CratesIO) Crate(ctx context.Context, name, version string) (*Crate, error) {
+	base := c.URL
+	if base == "" {
+		base = DefaultCratesIOURL
+	}
+
+	var response struct {
+		Crate struct {
+			Name             string `json:"name"`
+			Description      string `json:"description"`
+			Homepage         string `json:"homepage"`
+			Repository       string `json:"repository"`
+			MaxStableVersion string `json:"max_stable_version"`
+			MaxVersion       string `json:"max_version"`
+		} `json:"crate"`
+		Versions []struct {
+			Num      string `json:"num"`
+			License  string `json:"license"`
+			Checksum string `json:"checksum"`
+			Yanked   bool   `json:"yanked"`
+		} `json:"versions"`
+	}
+	u := strings.TrimSuffix(base, "/") + "/crates/" + url.PathEscape(name)
+	if err := getJSON(ctx, c.Client, u, &response); err != nil {
+		return nil, err
+	}
+
+	if version == "" {
+		version = response.Crate.MaxStableVersion
+		if version == "" {
+			version = response.Crate.MaxVersion
+		}
+	}
+
+	for _, v := range response.Versions {
+		if v.Num == version && !v.Yanked {
+			return &Crate{
+				Name:        response.Crate.Name,
+				Version:     v.Num,
+				Description: response.Crate.Description,
+				Homepage:    response.Crate.Homepage,
+				Repository:  response.Crate.Repository,
+				License:     v.License,
+				Checksum:    v.Checksum,
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("version %s of crate %s not found", version, name)
+}
+
+// licenses returns the license of the crate as the license array, the old
+// "MIT/Apache-2.0" style written as SPDX expression.
+func (c *Crate) licenses() []string {
+	if c.License == "" {
+		return nil
+	}
+	return []string{strings.Replace(c.License, "/", " OR ", -1)}
+}
+
+// NewCrateScaffold returns the scaffold of the package of crate, following
+// the Rust package guidelines. The source is the .crate file of the release
+// on crates.io, verified by Checksum if known. Empty options default to the
+// values of the crate.
+func NewCrateScaffold(crate *Crate, opts ScaffoldOptions) (*Scaffold, error) {
+	if crate.Name == "" || crate.Version == "" {
+		return nil, fmt.Errorf("crate name or version missing")
+	}
+
+	if opts.Pkgname == "" {
+		opts.Pkgname = strings.ToLower(crate.Name)
+	}
+	if opts.Pkgver == "" {
+		opts.Pkgver = Version(strings.Replace(crate.Version, "-", "_", -1))
+	}
+	if opts.Pkgdesc == "" {
+		opts.Pkgdesc = strings.Join(strings.Fields(crate.Description), " ")
+	}
+	if opts.URL == "" {
+		opts.URL = crate.Homepage
+		if opts.URL == "" {
+			opts.URL = crate.Repository
+		}
+	}
+	if len(opts.License) == 0 {
+		opts.License = crate.licenses()
+	}
+
+	sum := crate.Checksum
+	if opts.Source == "" {
+		opts.Source = fmt.Sprintf("%s-%s.tar.gz::https://static.crates.io/crates/%s/%s-%s.crate", crate.Name, crate.Version, crate.Name, crate.Name, crate.Version)
+	} else {
+		sum = ""
+	}
+
+	srcdir := `"` + crate.Name + "-" + strings.Replace(crate.Version, string(opts.Pkgver), "$pkgver", 1) + `"`
+	s, err := newScaffold(scaffoldTemplate{
+		arch:        []string{"x86_64"},
+		depends:     []string{"gcc-libs", "glibc"},
+		makedepends: []string{"cargo"},
+		functions:   rustFunctions(srcdir, quoteWord(crate.Name)),
+	}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if sum != "" {
+		s.PKGBUILD.Sha256sums = []string{sum}
+	}
+	return s, nil
+}
@@ -0,0 +1,141 @@
+package pkgbuild
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadCargoToml(t *testing.T) {
+	dir, err := ioutil.TempDir("", "crate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "Cargo.toml")
+	err = ioutil.WriteFile(path, []byte(`[package]
+name = "ripgrep" # the name
+version = "14.1.0"
+authors = ["Andrew Gallant <jamslam@gmail.com>"]
+description = """
+ripgrep is a line-oriented search tool.
+"""
+homepage = 'https://github.com/BurntSushi/ripgrep'
+license = "Unlicense OR MIT"
+edition.workspace = true
+
+[dependencies]
+version = "1.0"
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	crate, err := ReadCargoToml(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &Crate{
+		Name:     "ripgrep",
+		Version:  "14.1.0",
+		Homepage: "https://github.com/BurntSushi/ripgrep",
+		License:  "Unlicense OR MIT",
+	}
+	if !reflect.DeepEqual(crate, expected) {
+		t.Errorf("expected %+v, got %+v", expected, crate)
+	}
+
+	if _, err := ReadCargoToml(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected error for missing Cargo.toml")
+	}
+}
+
+func TestCratesIO(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/crates/foo" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{
+  "crate": {
+    "name": "foo",
+    "description": "A foo\n crate",
+    "repository": "https://github.com/foo/foo",
+    "max_stable_version": "1.1.0",
+    "max_version": "2.0.0-beta.1"
+  },
+  "versions": [
+    {"num": "2.0.0-beta.1", "license": "MIT", "checksum": "3333", "yanked": false},
+    {"num": "1.2.0", "license": "MIT", "checksum": "2222", "yanked": true},
+    {"num": "1.1.0", "license": "MIT/Apache-2.0", "checksum": "1111", "yanked": false}
+  ]
+}`))
+	}))
+	defer server.Close()
+
+	c := &CratesIO{URL: server.URL + "/api/v1"}
+	crate, err := c.Crate(context.Background(), "foo", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if crate.Version != "1.1.0" || crate.Checksum != "1111" {
+		t.Errorf("expected latest stable 1.1.0, got %+v", crate)
+	}
+
+	if _, err := c.Crate(context.Background(), "foo", "1.2.0"); err == nil {
+		t.Error("expected error for yanked version")
+	}
+	if _, err := c.Crate(context.Background(), "bar", ""); err == nil {
+		t.Error("expected error for missing crate")
+	}
+
+	s, err := NewCrateScaffold(crate, ScaffoldOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var srcinfo bytes.Buffer
+	if err := s.WriteSRCINFO(&srcinfo); err != nil {
+		t.Fatal(err)
+	}
+	pkgb, err := ParseSRCINFOContent(srcinfo.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkgb.Pkgdesc != "A foo crate" || pkgb.URL != "https://github.com/foo/foo" {
+		t.Errorf("unexpected pkgdesc or url: %q %q", pkgb.Pkgdesc, pkgb.URL)
+	}
+	if !reflect.DeepEqual(pkgb.License, []string{"MIT OR Apache-2.0"}) {
+		t.Errorf("expected license MIT OR Apache-2.0, got %q", pkgb.License)
+	}
+	if expected := []string{"foo-1.1.0.tar.gz::https://static.crates.io/crates/foo/foo-1.1.0.crate"}; !reflect.DeepEqual(pkgb.Source, expected) {
+		t.Errorf("expected source %q, got %q", expected, pkgb.Source)
+	}
+	if !reflect.DeepEqual(pkgb.Sha256sums, []string{"1111"}) {
+		t.Errorf("expected sha256sums 1111, got %q", pkgb.Sha256sums)
+	}
+
+	var pkgbuild bytes.Buffer
+	if err := s.WritePKGBUILD(&pkgbuild); err != nil {
+		t.Fatal(err)
+	}
+	f, err := ParseAST(pkgbuild.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := f.Function("check")
+	if check == nil || !bytes.Contains([]byte(check.Body), []byte("cargo test --frozen")) {
+		t.Errorf("expected cargo test in check():\n%s", pkgbuild.String())
+	}
+	if !bytes.Contains(pkgbuild.Bytes(), []byte(`cd "foo-$pkgver"`)) {
+		t.Errorf("expected build in foo-$pkgver:\n%s", pkgbuild.String())
+	}
+}
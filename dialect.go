@@ -0,0 +1,78 @@
+package pkgbuild
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dialect is a flavour of the PKGBUILD format.
+type Dialect int
+
+// Dialects
+const (
+	DialectArch  Dialect = iota // PKGBUILDs of Arch Linux
+	DialectMSYS2                // PKGBUILDs of MSYS2 using mingw_arch and msys2_* variables
+)
+
+// WithDialect sets the dialect of the PKGBUILD. Variables of a dialect,
+// like mingw_arch of MSYS2, are kept in Extra as any unknown variable of a
+// .SRCINFO file, but File.PKGBUILD only evaluates them with the dialect
+// set.
+func WithDialect(dialect Dialect) ParseOption {
+	return func(c *parseConfig) {
+		c.dialect = dialect
+	}
+}
+
+// WithMingwEnv makes File.PKGBUILD evaluate an MSYS2 PKGBUILD for the
+// environment env e.g. "ucrt64", which defines variables like
+// MINGW_PACKAGE_PREFIX, so a pkgname like ${MINGW_PACKAGE_PREFIX}-foo
+// becomes mingw-w64-ucrt-x86_64-foo. It implies DialectMSYS2.
+func WithMingwEnv(env string) ParseOption {
+	return func(c *parseConfig) {
+		c.dialect = DialectMSYS2
+		c.mingwEnv = env
+	}
+}
+
+// mingwEnvs holds the package prefix and CARCH of the MSYS2 environments.
+var mingwEnvs = map[string][2]string{
+	"mingw32":    {"mingw-w64-i686", "i686"},
+	"mingw64":    {"mingw-w64-x86_64", "x86_64"},
+	"ucrt64":     {"mingw-w64-ucrt-x86_64", "x86_64"},
+	"clang32":    {"mingw-w64-clang-i686", "i686"},
+	"clang64":    {"mingw-w64-clang-x86_64", "x86_64"},
+	"clangarm64": {"mingw-w64-clang-aarch64", "aarch64"},
+}
+
+// mingwEnvVars returns the variables makepkg-mingw defines for the MSYS2
+// environment of c, if any.
+func (c *parseConfig) mingwEnvVars() (map[string][]string, error) {
+	if c.mingwEnv == "" {
+		return nil, nil
+	}
+
+	env, ok := mingwEnvs[c.mingwEnv]
+	if !ok {
+		return nil, fmt.Errorf("unknown MSYS2 environment: %s", c.mingwEnv)
+	}
+
+	return map[string][]string{
+		"MSYSTEM":              {strings.ToUpper(c.mingwEnv)},
+		"MINGW_ARCH":           {c.mingwEnv},
+		"MINGW_PACKAGE_PREFIX": {env[0]},
+		"MINGW_PREFIX":         {"/" + c.mingwEnv},
+		"CARCH":                {env[1]},
+	}, nil
+}
+
+// isMSYS2Variable reports whether name is a variable of MSYS2 PKGBUILDs.
+func isMSYS2Variable(name string) bool {
+	return name == "mingw_arch" || strings.HasPrefix(name, "msys2_")
+}
+
+// MingwArch returns the MSYS2 environments p supports, the mingw_arch
+// variable of MSYS2 PKGBUILDs.
+func (p *PKGBUILD) MingwArch() []string {
+	return p.Extra["mingw_arch"]
+}
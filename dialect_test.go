@@ -0,0 +1,67 @@
+package pkgbuild
+
+import (
+	"reflect"
+	"testing"
+)
+
+const msys2PKGBUILD = `_realname=zstd
+pkgbase=mingw-w64-${_realname}
+pkgname=("${MINGW_PACKAGE_PREFIX}-${_realname}")
+pkgver=1.5.5
+pkgrel=1
+pkgdesc="Zstandard - Fast real-time compression algorithm (mingw-w64)"
+arch=('any')
+mingw_arch=('mingw32' 'mingw64' 'ucrt64' 'clang64' 'clangarm64')
+msys2_references=('cpe: cpe:/a:facebook:zstandard')
+url="https://facebook.github.io/zstd/"
+license=('spdx:BSD-3-Clause OR GPL-2.0-only')
+makedepends=("${MINGW_PACKAGE_PREFIX}-cc" "${MINGW_PACKAGE_PREFIX}-cmake")
+source=("https://github.com/facebook/zstd/releases/download/v${pkgver}/${_realname}-${pkgver}.tar.gz")
+sha256sums=('9c4396cc829cfae319a6e2615202e82aad41372073482fce286fac78646d3ee4')
+
+build() {
+  cmake -DCMAKE_INSTALL_PREFIX="${MINGW_PREFIX}" ../${_realname}-${pkgver}/build/cmake
+}
+`
+
+func TestMSYS2Dialect(t *testing.T) {
+	file, err := ParseAST([]byte(msys2PKGBUILD))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkg, err := file.PKGBUILD(WithMingwEnv("ucrt64"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(pkg.Pkgnames, []string{"mingw-w64-ucrt-x86_64-zstd"}) {
+		t.Errorf("unexpected pkgname: %v", pkg.Pkgnames)
+	}
+	if deps := dependencyStrings(pkg.Makedepends); !reflect.DeepEqual(deps, []string{"mingw-w64-ucrt-x86_64-cc", "mingw-w64-ucrt-x86_64-cmake"}) {
+		t.Errorf("unexpected makedepends: %v", deps)
+	}
+	if !reflect.DeepEqual(pkg.MingwArch(), []string{"mingw32", "mingw64", "ucrt64", "clang64", "clangarm64"}) {
+		t.Errorf("unexpected mingw_arch: %v", pkg.MingwArch())
+	}
+	if !reflect.DeepEqual(pkg.Extra["msys2_references"], []string{"cpe: cpe:/a:facebook:zstandard"}) {
+		t.Errorf("unexpected msys2_references: %v", pkg.Extra["msys2_references"])
+	}
+
+	// variables of the dialect are only evaluated with the dialect set
+	file, err = ParseAST([]byte("pkgname=foo\npkgver=1\npkgrel=1\narch=(any)\nmingw_arch=(ucrt64)\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg, err = file.PKGBUILD(); err != nil || pkg.MingwArch() != nil {
+		t.Errorf("expected no mingw_arch without dialect, got %v, %v", pkg.MingwArch(), err)
+	}
+	if pkg, err = file.PKGBUILD(WithDialect(DialectMSYS2)); err != nil || !reflect.DeepEqual(pkg.MingwArch(), []string{"ucrt64"}) {
+		t.Errorf("expected mingw_arch ucrt64 with dialect, got %v, %v", pkg.MingwArch(), err)
+	}
+
+	if _, err = file.PKGBUILD(WithMingwEnv("mingw128")); err == nil {
+		t.Error("expected error for an unknown environment")
+	}
+}
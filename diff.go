@@ -0,0 +1,226 @@
+package pkgbuild
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Change describes the change of a single variable between two PKGBUILDs.
+// Scalars like pkgver use Old and New, arrays like depends use Added and
+// Removed.
+type Change struct {
+	Field   string   `json:"field"` // e.g. "pkgver" or "depends_x86_64"
+	Old     string   `json:"old,omitempty"`
+	New     string   `json:"new,omitempty"`
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+func (c Change) String() string {
+	if c.Added == nil && c.Removed == nil {
+		return c.Field + ": " + strconv.Quote(c.Old) + " -> " + strconv.Quote(c.New)
+	}
+
+	values := make([]string, 0, len(c.Added)+len(c.Removed))
+	for _, value := range c.Removed {
+		values = append(values, "-"+value)
+	}
+	for _, value := range c.Added {
+		values = append(values, "+"+value)
+	}
+	return c.Field + ": " + strings.Join(values, " ")
+}
+
+// Changes is the set of changes between two PKGBUILDs as returned by Diff.
+type Changes []Change
+
+// String renders the changes as text, one change per line.
+func (c Changes) String() string {
+	var b strings.Builder
+	for _, change := range c {
+		b.WriteString(change.String())
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// Field returns the change of the variable name, if any.
+func (c Changes) Field(name string) (Change, bool) {
+	for _, change := range c {
+		if change.Field == name {
+			return change, true
+		}
+	}
+	return Change{}, false
+}
+
+// Diff returns the changes needed to go from a to b, ordered like the
+// variables of a PKGBUILD. Arrays are compared as sets, so reordering the
+// values of an array is not a change.
+func Diff(a, b *PKGBUILD) Changes {
+	var changes Changes
+
+	scalarsA, scalarsB := a.diffScalars(), b.diffScalars()
+	arraysA, arraysB := a.diffArrays(), b.diffArrays()
+
+	diff := func(name string) {
+		if old, ok := scalarsA[name]; ok {
+			if old != scalarsB[name] {
+				changes = append(changes, Change{Field: name, Old: old, New: scalarsB[name]})
+			}
+			return
+		}
+
+		added := difference(arraysB[name], arraysA[name])
+		removed := difference(arraysA[name], arraysB[name])
+		if len(added) > 0 || len(removed) > 0 {
+			changes = append(changes, Change{Field: name, Added: added, Removed: removed})
+		}
+	}
+
+	archs := make(map[string]bool)
+	for _, p := range []*PKGBUILD{a, b} {
+		for arch := range p.ArchSpecific {
+			archs[arch] = true
+		}
+	}
+
+	for _, name := range fieldOrder {
+		diff(name)
+		if archFields[name] {
+			for _, arch := range sortedKeys(archs) {
+				diff(name + "_" + arch)
+			}
+		}
+	}
+
+	extra := make(map[string]bool)
+	for _, p := range []*PKGBUILD{a, b} {
+		for name := range p.Extra {
+			extra[name] = true
+		}
+	}
+	for _, name := range sortedKeys(extra) {
+		diff(name)
+	}
+
+	return changes
+}
+
+// diffScalars returns the scalar variables of p by name.
+func (p *PKGBUILD) diffScalars() map[string]string {
+	return map[string]string{
+		"pkgbase":   p.Pkgbase,
+		"pkgver":    string(p.Pkgver),
+		"pkgrel":    string(p.Pkgrel),
+		"epoch":     strconv.Itoa(p.Epoch),
+		"pkgdesc":   p.Pkgdesc,
+		"url":       p.URL,
+		"install":   p.Install,
+		"changelog": p.Changelog,
+	}
+}
+
+// diffArrays returns the array variables of p, including the arch specific
+// and unknown ones, by name.
+func (p *PKGBUILD) diffArrays() map[string][]string {
+	common := p.archIndependent()
+	arrays := map[string][]string{
+		"pkgname":      p.Pkgnames,
+		"arch":         p.Arch,
+		"license":      p.License,
+		"groups":       p.Groups,
+		"depends":      dependencyStrings(common.Depends),
+		"makedepends":  dependencyStrings(common.Makedepends),
+		"checkdepends": dependencyStrings(common.Checkdepends),
+		"optdepends":   common.Optdepends,
+		"provides":     common.Provides,
+		"conflicts":    common.Conflicts,
+		"replaces":     common.Replaces,
+		"backup":       p.Backup,
+		"options":      p.Options,
+		"source":       common.Source,
+		"noextract":    p.Noextract,
+		"validpgpkeys": p.Validpgpkeys,
+	}
+
+	for algo, sums := range common.sums() {
+		arrays[algo+"sums"] = sums
+	}
+
+	for arch, a := range p.ArchSpecific {
+		arrays["depends_"+arch] = dependencyStrings(a.Depends)
+		arrays["makedepends_"+arch] = dependencyStrings(a.Makedepends)
+		arrays["checkdepends_"+arch] = dependencyStrings(a.Checkdepends)
+		arrays["optdepends_"+arch] = a.Optdepends
+		arrays["provides_"+arch] = a.Provides
+		arrays["conflicts_"+arch] = a.Conflicts
+		arrays["replaces_"+arch] = a.Replaces
+		arrays["source_"+arch] = a.Source
+		for algo, sums := range a.sums() {
+			arrays[algo+"sums_"+arch] = sums
+		}
+	}
+
+	for name, values := range p.Extra {
+		arrays[name] = values
+	}
+
+	return arrays
+}
+
+// dependencyStrings returns the dependencies as they are written in a
+// PKGBUILD e.g. "foo>=1.0".
+func dependencyStrings(deps []*Dependency) []string {
+	values := make([]string, 0, len(deps))
+	for _, dep := range deps {
+		if !dep.versioned() {
+			values = append(values, dep.Name)
+			continue
+		}
+		values = append(values, dep.String())
+	}
+	return values
+}
+
+// stringsEqual reports whether a and b have the same values in the same
+// order.
+func stringsEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// difference returns the values of a which are not in b, in the order of a.
+func difference(a, b []string) []string {
+	in := make(map[string]bool, len(b))
+	for _, value := range b {
+		in[value] = true
+	}
+
+	var diff []string
+	for _, value := range a {
+		if !in[value] {
+			diff = append(diff, value)
+			in[value] = true
+		}
+	}
+	return diff
+}
+
+// sortedKeys returns the keys of m in sorted order.
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,78 @@
+package pkgbuild
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	a, err := ParseSRCINFOContent([]byte(`pkgbase = foo
+	pkgver = 1.0
+	pkgrel = 2
+	arch = x86_64
+	depends = glibc
+	depends = bar>=1.0
+	depends = baz
+	options = !strip
+	source = foo-1.0.tar.gz
+	sha256sums = aaaa
+	source_x86_64 = foo.bin
+	sha256sums_x86_64 = cccc
+
+pkgname = foo
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ParseSRCINFOContent([]byte(`pkgbase = foo
+	pkgver = 1.1
+	pkgrel = 1
+	arch = x86_64
+	depends = baz
+	depends = glibc
+	depends = bar>=1.1
+	depends = qux=2.0
+	source = foo-1.1.tar.gz
+	sha256sums = bbbb
+	source_x86_64 = foo.bin
+	sha256sums_x86_64 = dddd
+
+pkgname = foo
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Changes{
+		{Field: "pkgver", Old: "1.0", New: "1.1"},
+		{Field: "pkgrel", Old: "2", New: "1"},
+		{Field: "depends", Added: []string{"bar>=1.1", "qux=2.0"}, Removed: []string{"bar>=1.0"}},
+		{Field: "options", Removed: []string{"!strip"}},
+		{Field: "source", Added: []string{"foo-1.1.tar.gz"}, Removed: []string{"foo-1.0.tar.gz"}},
+		{Field: "sha256sums", Added: []string{"bbbb"}, Removed: []string{"aaaa"}},
+		{Field: "sha256sums_x86_64", Added: []string{"dddd"}, Removed: []string{"cccc"}},
+	}
+
+	changes := Diff(a, b)
+	if !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("expected changes:\n%s\ngot:\n%s", expected, changes)
+	}
+
+	if len(Diff(a, a)) != 0 {
+		t.Errorf("expected no changes between identical PKGBUILDs")
+	}
+
+	if change, ok := changes.Field("pkgver"); !ok || change.String() != `pkgver: "1.0" -> "1.1"` {
+		t.Errorf("unexpected pkgver change: %s", change)
+	}
+
+	data, err := json.Marshal(changes[3])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"field":"options","removed":["!strip"]}` {
+		t.Errorf("unexpected JSON: %s", data)
+	}
+}
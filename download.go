@@ -0,0 +1,165 @@
+package pkgbuild
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// DownloadSources downloads the http and https sources of p, including the
+// arch specific ones, to sourcesDir. Like with makepkg, sources already
+// present in sourcesDir are not downloaded again. Sources using other
+// protocols are skipped. ctx can be used to cancel the downloads and the
+// sources downloaded are reported to the ProgressFunc of
+// WithDownloadProgress.
+func (p *PKGBUILD) DownloadSources(ctx context.Context, sourcesDir string, opts ...SourceOption) error {
+	config := newSourceConfig(opts)
+
+	var sources []Source
+	for _, source := range p.allSources() {
+		s := Source(source)
+		switch s.Protocol() {
+		case "http", "https":
+		default:
+			continue
+		}
+
+		if _, err := os.Stat(filepath.Join(sourcesDir, s.FileName())); err == nil {
+			continue
+		}
+		sources = append(sources, s)
+	}
+
+	for i, s := range sources {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		config.progress.report(i, len(sources), s.FileName())
+		if err := download(ctx, s.URL(), filepath.Join(sourcesDir, s.FileName())); err != nil {
+			return err
+		}
+	}
+	config.progress.report(len(sources), len(sources), "")
+
+	return nil
+}
+
+// download downloads url to path. The file is written to path.part first,
+// so an interrupted download doesn't leave a partial file at path.
+func download(ctx context.Context, url, path string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to download %s: %s", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unable to download %s: %s", url, resp.Status)
+	}
+
+	part := path + ".part"
+	f, err := os.Create(part)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(f, resp.Body)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(part)
+		return fmt.Errorf("unable to download %s: %s", url, err)
+	}
+
+	return os.Rename(part, path)
+}
+
+// ParseSRCINFOURL downloads the .SRCINFO at url, e.g. the raw file of an AUR
+// package on aur.archlinux.org/cgit, using client or http.DefaultClient if
+// nil, and parses it. The response is read up to the max input size, which
+// defaults to the one of DefaultLimits as the content is untrusted. ctx can
+// be used to cancel the download.
+func ParseSRCINFOURL(ctx context.Context, client *http.Client, url string, opts ...ParseOption) (*PKGBUILD, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	config := newParseConfig(opts)
+	if config.limits.MaxInputSize <= 0 {
+		config.limits.MaxInputSize = DefaultLimits.MaxInputSize
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	config.debugf("downloading %s", url)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to download %s: %s", url, err)
+	}
+	defer resp.Body.Close()
+	config.debugf("%s: %s, %d bytes", url, resp.Status, resp.ContentLength)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to download %s: %s", url, resp.Status)
+	}
+
+	if resp.ContentLength > 0 {
+		if err := config.checkInputSize(int(resp.ContentLength)); err != nil {
+			return nil, fmt.Errorf("unable to download %s: %s", url, err)
+		}
+	}
+
+	content, err := config.read(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to download %s: %s", url, err)
+	}
+
+	// content isn't used by anything else
+	config.zeroCopy = true
+	return parsePKGBUILD(content, config)
+}
+
+// getJSON decodes the JSON response of a GET request of url into v, using
+// client or http.DefaultClient if nil.
+func getJSON(ctx context.Context, client *http.Client, url string, v interface{}) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	// required by some APIs like the one of crates.io
+	req.Header.Set("User-Agent", "gopkgbuild (https://github.com/mikkeloscar/gopkgbuild)")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to download %s: %s", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unable to download %s: %s", url, resp.Status)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("unable to decode %s: %s", url, err)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package pkgbuild
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDownloadSources(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/v1.0.tar.gz" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("foo"))
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "gopkgbuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	pkg := &PKGBUILD{
+		Arch: []string{"x86_64"},
+		Source: []string{
+			"foo-1.0.tar.gz::" + server.URL + "/v1.0.tar.gz",
+			"foo.patch",
+			"git+" + server.URL + "/foo.git",
+		},
+	}
+
+	if err := pkg.DownloadSources(context.Background(), dir); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "foo-1.0.tar.gz"))
+	if err != nil || string(content) != "foo" {
+		t.Errorf("expected downloaded source, got %q (%v)", content, err)
+	}
+
+	// already downloaded sources are kept
+	if err := pkg.DownloadSources(context.Background(), dir); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 1 {
+		t.Errorf("expected 1 request, got %d", requests)
+	}
+
+	pkg.Source = []string{server.URL + "/missing.tar.gz"}
+	if err := pkg.DownloadSources(context.Background(), dir); err == nil {
+		t.Error("expected error for missing source")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "missing.tar.gz.part")); !os.IsNotExist(err) {
+		t.Error("expected no partial download")
+	}
+}
+
+func TestParseSRCINFOURL(t *testing.T) {
+	content, err := ioutil.ReadFile("./test_pkgbuilds/SRCINFO_sudo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sudo/.SRCINFO":
+			w.Write(content)
+		case "/large/.SRCINFO":
+			w.Write(make([]byte, 2<<20))
+		case "/redirect/.SRCINFO":
+			http.Redirect(w, r, "/sudo/.SRCINFO", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	pkgb, err := ParseSRCINFOURL(context.Background(), nil, server.URL+"/sudo/.SRCINFO")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkgb.Pkgbase != "sudo" {
+		t.Errorf("expected pkgbase sudo, got %s", pkgb.Pkgbase)
+	}
+
+	for _, path := range []string{"/missing/.SRCINFO", "/large/.SRCINFO"} {
+		if _, err := ParseSRCINFOURL(context.Background(), nil, server.URL+path); err == nil {
+			t.Errorf("expected error for %s", path)
+		}
+	}
+
+	// the request is made with the client given
+	if _, err = ParseSRCINFOURL(context.Background(), nil, server.URL+"/redirect/.SRCINFO"); err != nil {
+		t.Errorf("expected the redirect to be followed: %s", err)
+	}
+	noRedirect := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	if _, err = ParseSRCINFOURL(context.Background(), noRedirect, server.URL+"/redirect/.SRCINFO"); err == nil {
+		t.Error("expected error for the redirect not followed by the client")
+	}
+
+	_, err = ParseSRCINFOURL(context.Background(), nil, server.URL+"/sudo/.SRCINFO", WithLimits(Limits{MaxInputSize: 10}))
+	if err == nil {
+		t.Error("expected error for content exceeding the max input size")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := ParseSRCINFOURL(ctx, nil, server.URL+"/sudo/.SRCINFO"); err == nil {
+		t.Error("expected error for canceled context")
+	}
+}
+
+func TestDownloadSourcesProgress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("foo"))
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "gopkgbuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	pkg := &PKGBUILD{
+		Source: []string{server.URL + "/a.tar.gz", server.URL + "/b.tar.gz", "local.patch"},
+	}
+
+	var reports []string
+	err = pkg.DownloadSources(context.Background(), dir, WithDownloadProgress(func(done, total int, item string) {
+		reports = append(reports, fmt.Sprintf("%d/%d %s", done, total, item))
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"0/2 a.tar.gz", "1/2 b.tar.gz", "2/2 "}
+	if !reflect.DeepEqual(reports, expected) {
+		t.Errorf("expected progress %q, got %q", expected, reports)
+	}
+
+	pkg.Source = append(pkg.Source, server.URL+"/c.tar.gz")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := pkg.DownloadSources(ctx, dir); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
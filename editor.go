@@ -0,0 +1,230 @@
+package pkgbuild
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// Editor edits the variables of a PKGBUILD file in place. Everything that
+// isn't edited, including comments, ordering and whitespace, is kept as is.
+//
+// Only top level assignments are edited, assignments inside functions like
+// package_foo() are left untouched.
+type Editor struct {
+	content string
+	stmts   []statement
+}
+
+// NewEditor parses the PKGBUILD content for editing.
+func NewEditor(content []byte) (*Editor, error) {
+	e := &Editor{}
+	if err := e.reset(string(content)); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// NewEditorFile parses the PKGBUILD file given by path for editing.
+func NewEditorFile(path string) (*Editor, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read file: %s, %s", path, err.Error())
+	}
+
+	return NewEditor(content)
+}
+
+// reset replaces the content of the editor and parses it again.
+func (e *Editor) reset(content string) error {
+	stmts, err := parseShell(content)
+	if err != nil {
+		return err
+	}
+
+	e.content = content
+	e.stmts = stmts
+	return nil
+}
+
+// Bytes returns the edited PKGBUILD.
+func (e *Editor) Bytes() []byte {
+	return []byte(e.content)
+}
+
+// WriteFile writes the edited PKGBUILD to the file given by path.
+func (e *Editor) WriteFile(path string) error {
+	return ioutil.WriteFile(path, e.Bytes(), 0644)
+}
+
+// assignment returns the last top level assignment of the variable name not
+// using +=.
+func (e *Editor) assignment(name string) (statement, bool) {
+	for i := len(e.stmts) - 1; i >= 0; i-- {
+		stmt := e.stmts[i]
+		if stmt.kind == stmtAssignment && stmt.name == name && !stmt.append {
+			return stmt, true
+		}
+	}
+	return statement{}, false
+}
+
+// Values returns the values assigned to the top level variable name with
+// quotes removed. Variable references like $pkgver are not expanded. ok is
+// false if the variable is not assigned.
+func (e *Editor) Values(name string) (values []string, ok bool) {
+	for _, stmt := range e.stmts {
+		if stmt.kind != stmtAssignment || stmt.name != name {
+			continue
+		}
+
+		if !stmt.append {
+			values = nil
+		}
+		ok = true
+
+		if !stmt.array {
+			values = append(values, unquoteWord(e.text(stmt.value)))
+			continue
+		}
+
+		for _, element := range stmt.elements {
+			values = append(values, unquoteWord(e.text(element)))
+		}
+	}
+
+	return values, ok
+}
+
+// Set sets the variable name to value. The variable is added after the last
+// top level assignment if it's not assigned yet.
+func (e *Editor) Set(name, value string) error {
+	if !isShellName(name) {
+		return fmt.Errorf("invalid variable name: %s", name)
+	}
+
+	stmt, ok := e.assignment(name)
+	if !ok {
+		return e.insert(name + "=" + quoteWord(value))
+	}
+
+	word := quoteWord(value)
+	if !stmt.array {
+		if raw := e.text(stmt.value); raw != "" && (raw[0] == '\'' || raw[0] == '"') {
+			word = quoteWordAs(value, raw[0])
+		}
+	}
+
+	return e.replace(span{stmt.start + len(name) + 1, stmt.end}, word)
+}
+
+// SetArray sets the array variable name to values. Multi line arrays keep
+// their layout with one value per line.
+func (e *Editor) SetArray(name string, values []string) error {
+	if !isShellName(name) {
+		return fmt.Errorf("invalid variable name: %s", name)
+	}
+
+	stmt, ok := e.assignment(name)
+	if !ok {
+		words := make([]string, 0, len(values))
+		for _, value := range values {
+			words = append(words, quoteWord(value))
+		}
+		return e.insert(name + "=(" + strings.Join(words, " ") + ")")
+	}
+
+	return e.replace(span{stmt.start + len(name) + 1, stmt.end}, e.formatArray(stmt, values))
+}
+
+// SetPkgver sets pkgver.
+func (e *Editor) SetPkgver(pkgver Version) error {
+	if !validPkgver(string(pkgver)) {
+		return fmt.Errorf("%w: %s", ErrInvalidPkgver, pkgver)
+	}
+	return e.Set("pkgver", string(pkgver))
+}
+
+// SetPkgrel sets pkgrel.
+func (e *Editor) SetPkgrel(pkgrel Version) error {
+	if !validPkgver(string(pkgrel)) {
+		return fmt.Errorf("invalid pkgrel: %s", pkgrel)
+	}
+	return e.Set("pkgrel", string(pkgrel))
+}
+
+// SetChecksums sets the checksum array of algo, e.g. sha256sums for
+// "sha256".
+func (e *Editor) SetChecksums(algo string, sums []string) error {
+	if _, err := newHash(algo); err != nil {
+		return err
+	}
+	return e.SetArray(algo+"sums", sums)
+}
+
+// formatArray formats values as the array assigned by stmt, keeping its
+// layout and quoting style.
+func (e *Editor) formatArray(stmt statement, values []string) string {
+	quote := byte(0)
+	if len(stmt.elements) > 0 {
+		if first := e.text(stmt.elements[0]); first[0] == '\'' || first[0] == '"' {
+			quote = first[0]
+		}
+	}
+
+	words := make([]string, 0, len(values))
+	for _, value := range values {
+		words = append(words, quoteWordAs(value, quote))
+	}
+
+	if len(stmt.elements) == 0 || len(words) == 0 {
+		return "(" + strings.Join(words, " ") + ")"
+	}
+
+	first := stmt.elements[0]
+	last := stmt.elements[len(stmt.elements)-1]
+	prefix := e.content[stmt.value.start:first.start]
+	suffix := e.content[last.end:stmt.value.end]
+
+	sep := " "
+	if strings.Contains(e.text(stmt.value), "\n") {
+		// align with the indentation of the last element, or the first
+		// element if all elements are on the first line
+		lineStart := strings.LastIndexByte(e.content[:last.start], '\n') + 1
+		indent := e.content[lineStart:last.start]
+		if strings.TrimSpace(indent) != "" || lineStart <= stmt.value.start {
+			lineStart = strings.LastIndexByte(e.content[:first.start], '\n') + 1
+			indent = strings.Repeat(" ", first.start-lineStart)
+		}
+		sep = "\n" + indent
+	}
+
+	return "(" + prefix + strings.Join(words, sep) + suffix + ")"
+}
+
+// insert adds line after the last top level assignment.
+func (e *Editor) insert(line string) error {
+	at := len(e.content)
+	for i := len(e.stmts) - 1; i >= 0; i-- {
+		if e.stmts[i].kind == stmtAssignment {
+			at = e.stmts[i].end
+			break
+		}
+	}
+
+	if at == len(e.content) && at > 0 && e.content[at-1] == '\n' {
+		return e.replace(span{at, at}, line+"\n")
+	}
+
+	return e.replace(span{at, at}, "\n"+line)
+}
+
+// replace replaces the content of s with text.
+func (e *Editor) replace(s span, text string) error {
+	return e.reset(e.content[:s.start] + text + e.content[s.end:])
+}
+
+// text returns the content of s.
+func (e *Editor) text(s span) string {
+	return e.content[s.start:s.end]
+}
@@ -0,0 +1,183 @@
+package pkgbuild
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+var pkgbuilds = []string{
+	"sudo",
+	"pacman",
+	"openssh",
+	"grub",
+	"glibc",
+	"systemd",
+	"linux",
+	"pip2pkgbuild",
+	"biicode",
+	"teamviewer",
+	"shaman-git",
+	"bash-snippets",
+	"pulseaudio-ctl",
+}
+
+// Test that all the PKGBUILDs can be read by the editor
+func TestEditorPKGBUILDs(t *testing.T) {
+	for _, name := range pkgbuilds {
+		content, err := ioutil.ReadFile("./test_pkgbuilds/PKGBUILD_" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		e, err := NewEditor(content)
+		if err != nil {
+			t.Errorf("PKGBUILD for %s did not parse: %s", name, err)
+			continue
+		}
+
+		if string(e.Bytes()) != string(content) {
+			t.Errorf("PKGBUILD for %s was changed without edits", name)
+		}
+
+		if values, ok := e.Values("pkgrel"); !ok || len(values) != 1 {
+			t.Errorf("PKGBUILD for %s should have a pkgrel, got %v", name, values)
+		}
+	}
+}
+
+func TestEditorSet(t *testing.T) {
+	e, err := NewEditorFile("./test_pkgbuilds/PKGBUILD_sudo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values, _ := e.Values("pkgdesc")
+	if len(values) != 1 || values[0] != "Give certain users the ability to run some commands as root" {
+		t.Errorf("unexpected pkgdesc: %v", values)
+	}
+
+	values, _ = e.Values("source")
+	if len(values) != 3 || values[0] != "http://www.sudo.ws/sudo/dist/$pkgname-$_sudover.tar.gz{,.sig}" {
+		t.Errorf("unexpected source: %v", values)
+	}
+
+	original := string(e.Bytes())
+
+	if err = e.SetPkgver("1.8.12"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = e.SetPkgrel("3"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = e.Set("pkgdesc", `Say "hi" for $5`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = e.SetChecksums("sha256", []string{"aaaa", "SKIP", "bbbb", "cccc"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = e.SetPkgver("1.0-1"); err == nil {
+		t.Error("expected error setting an invalid pkgver")
+	}
+
+	expected := strings.NewReplacer(
+		"pkgver=${_sudover/p/.p}\n", "pkgver=1.8.12\n",
+		"pkgrel=1\n", "pkgrel=3\n",
+		`pkgdesc="Give certain users the ability to run some commands as root"`, `pkgdesc="Say \"hi\" for \$5"`,
+		`sha256sums=('8133849418fa18cf6b6bb6893d1855ff7afe21db8923234a00bf045c90fba1ad'
+            'SKIP'
+            '080dd97111b3149f8d140ffac68c88acd63da9eacc81fbcc7c43591be13b42fe'
+            'd1738818070684a5d2c9b26224906aad69a4fea77aabd960fc2675aee2df1fa2')`,
+		`sha256sums=('aaaa'
+            'SKIP'
+            'bbbb'
+            'cccc')`,
+	).Replace(original)
+
+	if string(e.Bytes()) != expected {
+		t.Errorf("unexpected result:\n%s", e.Bytes())
+	}
+
+	values, _ = e.Values("pkgdesc")
+	if len(values) != 1 || values[0] != `Say "hi" for $5` {
+		t.Errorf("unexpected pkgdesc: %v", values)
+	}
+}
+
+func TestEditorSetArray(t *testing.T) {
+	e, err := NewEditor([]byte(`# Maintainer: foo
+pkgname=foo
+pkgver=1.0
+pkgrel=1
+arch=(x86_64)
+depends=('bar') # comment
+source=(
+  "foo-$pkgver.tar.gz"
+  foo.patch
+)
+sha256sums=()
+
+package() {
+  depends=(baz)
+}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = e.SetArray("depends", []string{"bar", "baz>=1.0"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = e.SetArray("source", []string{"foo-1.1.tar.gz", "new file"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = e.SetArray("sha256sums", []string{"SKIP", "SKIP"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = e.SetArray("b2sums", []string{"SKIP", "SKIP"}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `# Maintainer: foo
+pkgname=foo
+pkgver=1.0
+pkgrel=1
+arch=(x86_64)
+depends=('bar' 'baz>=1.0') # comment
+source=(
+  "foo-1.1.tar.gz"
+  "new file"
+)
+sha256sums=(SKIP SKIP)
+b2sums=(SKIP SKIP)
+
+package() {
+  depends=(baz)
+}
+`
+
+	if string(e.Bytes()) != expected {
+		t.Errorf("unexpected result:\n%s", e.Bytes())
+	}
+}
+
+func TestEditorErrors(t *testing.T) {
+	inputs := []string{
+		"pkgname=(foo\n",
+		"pkgdesc=\"foo\n",
+		"build() {\n  make\n",
+	}
+
+	for _, input := range inputs {
+		if _, err := NewEditor([]byte(input)); err == nil {
+			t.Errorf("expected error parsing %q", input)
+		}
+	}
+}
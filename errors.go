@@ -0,0 +1,59 @@
+package pkgbuild
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned when parsing or validating a PKGBUILD. They are wrapped
+// with the offending value, use errors.Is to check for them.
+var (
+	ErrMissingPkgbase = errors.New("missing pkgbase")
+	ErrMissingPkgname = errors.New("missing pkgname")
+	ErrInvalidPkgname = errors.New("invalid pkgname")
+	ErrInvalidPkgver  = errors.New("invalid pkgver")
+	ErrInvalidPkgrel  = errors.New("invalid pkgrel")
+	ErrInvalidEpoch   = errors.New("invalid epoch")
+	ErrInvalidVersion = errors.New("invalid version")
+	ErrMissingArch    = errors.New("Arch missing")
+	ErrTooManyValues  = errors.New("too many values for variable")
+	ErrInputTooLarge  = errors.New("input exceeds max size")
+
+	ErrMissingAlternative = errors.New("missing alternative")
+)
+
+// ConflictError is returned when combining dependencies which can't both
+// be satisfied, e.g. "foo=1.2" and "foo=1.3".
+type ConflictError struct {
+	A, B *Dependency
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("conflicting dependencies: %s and %s", e.A, e.B)
+}
+
+// InvalidDependencyError is returned for a dependency like "foo>=" which
+// can't be parsed.
+type InvalidDependencyError struct {
+	Dep string
+	Err error // cause, e.g. an ErrInvalidVersion
+}
+
+func (e *InvalidDependencyError) Error() string {
+	return fmt.Sprintf("invalid dependency: %s, %s", e.Dep, e.Err)
+}
+
+func (e *InvalidDependencyError) Unwrap() error {
+	return e.Err
+}
+
+// PanicError is returned by the hardened parsing mode of WithLimits if the
+// parser panics, which is a bug. Stack holds the stack trace of the panic.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("unable to parse input: %v", e.Value)
+}
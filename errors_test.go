@@ -0,0 +1,51 @@
+package pkgbuild
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseErrorCauses(t *testing.T) {
+	for input, target := range map[string]error{
+		"pkgbase = foo\n\tpkgver = 1.0\n\tarch = any\n":                                ErrMissingPkgname,
+		"pkgbase = foo\n\tpkgver = 1.0\n\npkgname = foo\n":                             ErrMissingArch,
+		"pkgbase = foo\n\tpkgver = 1-0\n\tarch = any\n\npkgname = foo\n":               ErrInvalidPkgver,
+		"pkgbase = foo\n\tpkgver = 1.0\n\tarch = any\n\tepoch = -1\n\npkgname = foo\n": ErrInvalidEpoch,
+		"pkgbase = foo\n\tpkgver = 1.0\n\tarch = any\n\tepoch = x\n\npkgname = foo\n":  ErrInvalidEpoch,
+		"# no pkgbase\n": ErrMissingPkgbase,
+	} {
+		_, err := ParseSRCINFOContent([]byte(input))
+		if !errors.Is(err, target) {
+			t.Errorf("expected %q for %q, got %v", target, input, err)
+		}
+	}
+
+	_, err := ParseSRCINFOContent([]byte("pkgbase = foo\n\tpkgver = 1.0\n\tarch = any\n\tdepends = bar>=1:2:3\n\npkgname = foo\n"))
+	var depErr *InvalidDependencyError
+	if !errors.As(err, &depErr) || depErr.Dep != "bar>=1:2:3" || !errors.Is(err, ErrInvalidVersion) {
+		t.Errorf("expected invalid dependency error, got %v", err)
+	}
+
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || parseErr.Pos.Line != 4 {
+		t.Errorf("expected parse error at line 4, got %v", err)
+	}
+
+	_, err = ParseSRCINFOContent([]byte("pkgbase = foo\n\tpkgver = 1-0\n\tdepends = -bar\n"), WithContinueOnError())
+	if _, ok := err.(ParseErrors); !ok {
+		t.Fatalf("expected ParseErrors, got %v", err)
+	}
+	for _, target := range []error{ErrInvalidPkgver, ErrMissingArch, ErrMissingPkgname} {
+		if !errors.Is(err, target) {
+			t.Errorf("expected %q in %v", target, err)
+		}
+	}
+	if !errors.As(err, &depErr) || depErr.Dep != "-bar" {
+		t.Errorf("expected invalid dependency error in %v", err)
+	}
+
+	_, err = ParseSRCINFOContent([]byte("pkgbase = foo\n"), WithLimits(Limits{MaxInputSize: 4}))
+	if !errors.Is(err, ErrInputTooLarge) {
+		t.Errorf("expected %q, got %v", ErrInputTooLarge, err)
+	}
+}
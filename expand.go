@@ -0,0 +1,604 @@
+package pkgbuild
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Expander expands variable references like $pkgver or ${pkgname} in shell
+// words of a PKGBUILD without running bash.
+//
+// The parameter expansions commonly used in PKGBUILDs are supported:
+// ${var:-default}, ${var:+alt}, ${var#pattern}, ${var%pattern},
+// ${var/pattern/replacement}, ${var^^}, ${var,,}, ${var:offset:length},
+// ${#var} and ${array[n]}, as well as brace expansion like foo{,.sig}.
+// Command substitutions can't be expanded.
+type Expander struct {
+	// Vars holds the (already expanded) values of the variables.
+	Vars map[string][]string
+
+	// KeepUnresolved leaves references to unknown variables and command
+	// substitutions as is instead of returning an error.
+	KeepUnresolved bool
+}
+
+// NewExpander returns an Expander with the variables assigned at the top
+// level of f, evaluated in order. References that can't be resolved, like
+// $srcdir, are kept as is in the values.
+func NewExpander(f *File) *Expander {
+	return newExpander(f, nil)
+}
+
+// newExpander is like NewExpander but with the variables vars defined before
+// the ones of f are evaluated, like the environment makepkg sets.
+func newExpander(f *File, vars map[string][]string) *Expander {
+	e := &Expander{
+		Vars:           make(map[string][]string, len(vars)),
+		KeepUnresolved: true,
+	}
+
+	for name, values := range vars {
+		e.Vars[name] = values
+	}
+
+	for _, a := range f.Assignments() {
+		var values []string
+		if a.Array == nil {
+			// no brace expansion in scalar assignments
+			value, _ := e.expandWord(a.Value.Raw)
+			values = []string{value}
+		} else {
+			for _, element := range a.Array.Elements {
+				expanded, _ := e.Expand(element.Raw)
+				values = append(values, expanded...)
+			}
+		}
+
+		if a.Append {
+			old := e.Vars[a.Name]
+			if a.Array == nil && len(old) > 0 {
+				values = append([]string{old[0] + values[0]}, old[1:]...)
+			} else {
+				values = append(append([]string{}, old...), values...)
+			}
+		}
+
+		e.Vars[a.Name] = values
+	}
+
+	e.KeepUnresolved = false
+	return e
+}
+
+// Expand performs brace and parameter expansion of the shell word raw and
+// removes quotes. Brace expansion can turn a single word into several.
+func (e *Expander) Expand(raw string) ([]string, error) {
+	words := braceExpand(raw)
+
+	expanded := make([]string, 0, len(words))
+	for _, word := range words {
+		value, err := e.expandWord(word)
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, value)
+	}
+
+	return expanded, nil
+}
+
+// Values returns the expanded values of the variable name.
+func (e *Expander) Values(name string) []string {
+	return e.Vars[name]
+}
+
+// wordPartEnd returns the end of the quoted string, escape or expansion
+// starting at word[i].
+func wordPartEnd(word string, i int) int {
+	s := &shellScanner{input: word, pos: i}
+
+	switch word[i] {
+	case '\\':
+		s.pos += 2
+	case '\'':
+		s.scanSingleQuoted()
+	case '"':
+		s.scanDoubleQuoted()
+	case '`':
+		s.scanBackquoted()
+	case '$':
+		s.pos++
+		if s.pos < len(word) {
+			switch c := word[s.pos]; {
+			case c == '{' || c == '(' || c == '\'':
+				s.pos--
+				s.scanDollar()
+			case strings.IndexByte("@*#?$!-", c) >= 0 || isDigit(rune(c)):
+				s.pos++
+			default:
+				for s.pos < len(word) && isNameChar(word[s.pos]) {
+					s.pos++
+				}
+			}
+		}
+	default:
+		s.pos++
+	}
+
+	if s.pos > len(word) {
+		return len(word)
+	}
+	return s.pos
+}
+
+// isNameChar reports whether c can be part of a variable name.
+func isNameChar(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+// braceExpand performs brace expansion of word e.g. foo{,.sig} becomes foo
+// and foo.sig.
+func braceExpand(word string) []string {
+	for i := 0; i < len(word); {
+		if word[i] != '{' {
+			i = wordPartEnd(word, i)
+			continue
+		}
+
+		// find the matching brace and the top level commas
+		depth := 0
+		commas := []int{}
+		j := i
+		for j < len(word) {
+			switch word[j] {
+			case '{':
+				depth++
+			case '}':
+				depth--
+			case ',':
+				if depth == 1 {
+					commas = append(commas, j)
+				}
+			}
+
+			if depth == 0 {
+				break
+			}
+			j = wordPartEnd(word, j)
+		}
+
+		if depth != 0 || len(commas) == 0 {
+			i++
+			continue
+		}
+
+		prefix, suffix := word[:i], word[j+1:]
+		bounds := append(append([]int{i}, commas...), j)
+
+		var words []string
+		for k := 0; k < len(bounds)-1; k++ {
+			alt := word[bounds[k]+1 : bounds[k+1]]
+			words = append(words, braceExpand(prefix+alt+suffix)...)
+		}
+		return words
+	}
+
+	return []string{word}
+}
+
+// expandWord performs parameter expansion of word and removes quotes.
+func (e *Expander) expandWord(word string) (string, error) {
+	var b strings.Builder
+
+	for i := 0; i < len(word); {
+		end := wordPartEnd(word, i)
+
+		switch word[i] {
+		case '\\':
+			b.WriteString(word[i+1 : end])
+		case '\'':
+			if end-1 > i {
+				b.WriteString(word[i+1 : end-1])
+			}
+		case '"':
+			if end-1 > i {
+				value, err := e.expandQuoted(word[i+1 : end-1])
+				if err != nil {
+					return "", err
+				}
+				b.WriteString(value)
+			}
+		case '$', '`':
+			value, err := e.expandDollar(word[i:end])
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(value)
+		default:
+			b.WriteString(word[i:end])
+		}
+
+		i = end
+	}
+
+	return b.String(), nil
+}
+
+// expandQuoted expands the contents of a double quoted string.
+func (e *Expander) expandQuoted(s string) (string, error) {
+	var b strings.Builder
+
+	for i := 0; i < len(s); {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) && strings.IndexByte("$`\"\\\n", s[i+1]) >= 0 {
+				if s[i+1] != '\n' {
+					b.WriteByte(s[i+1])
+				}
+				i += 2
+				continue
+			}
+			b.WriteByte('\\')
+			i++
+		case '$', '`':
+			end := wordPartEnd(s, i)
+			value, err := e.expandDollar(s[i:end])
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(value)
+			i = end
+		default:
+			b.WriteByte(s[i])
+			i++
+		}
+	}
+
+	return b.String(), nil
+}
+
+// unresolved returns ref as is if unresolved references are kept, an error
+// otherwise.
+func (e *Expander) unresolved(ref string) (string, error) {
+	if e.KeepUnresolved {
+		return ref, nil
+	}
+	return "", fmt.Errorf("unable to expand %s", ref)
+}
+
+// expandDollar expands a single expansion e.g. $pkgver or ${pkgver//./_}.
+func (e *Expander) expandDollar(ref string) (string, error) {
+	switch {
+	case ref == "$":
+		return ref, nil
+	case strings.HasPrefix(ref, "`"), strings.HasPrefix(ref, "$("):
+		return e.unresolved(ref)
+	case strings.HasPrefix(ref, "$'"):
+		return strings.TrimSuffix(ref[2:], "'"), nil
+	case strings.HasPrefix(ref, "${"):
+		return e.expandParameter(ref, strings.TrimSuffix(ref[2:], "}"))
+	}
+
+	values, ok := e.Vars[ref[1:]]
+	if !ok || len(values) == 0 {
+		return e.unresolved(ref)
+	}
+	return values[0], nil
+}
+
+// expandParameter expands the parameter expansion ref with the contents
+// body, i.e. without ${ and }.
+func (e *Expander) expandParameter(ref, body string) (string, error) {
+	length := false
+	if len(body) > 1 && body[0] == '#' {
+		length = true
+		body = body[1:]
+	}
+
+	i := 0
+	for i < len(body) && isNameChar(body[i]) {
+		i++
+	}
+	name := body[:i]
+	if name == "" {
+		return e.unresolved(ref)
+	}
+
+	values, set := e.Vars[name]
+
+	// array subscript
+	all := false
+	if i < len(body) && body[i] == '[' {
+		end := strings.IndexByte(body[i:], ']')
+		if end < 0 {
+			return e.unresolved(ref)
+		}
+
+		switch index := body[i+1 : i+end]; index {
+		case "@", "*":
+			all = true
+		default:
+			n, err := strconv.Atoi(index)
+			if err != nil {
+				return e.unresolved(ref)
+			}
+			if n >= 0 && n < len(values) {
+				values = values[n : n+1]
+			} else {
+				values, set = nil, false
+			}
+		}
+		i += end + 1
+	}
+
+	if !all && len(values) > 1 {
+		values = values[:1]
+	}
+	if len(values) == 0 {
+		set = false
+	}
+
+	op := body[i:]
+
+	if length {
+		if op != "" || !set {
+			return e.unresolved(ref)
+		}
+		if all {
+			return strconv.Itoa(len(values)), nil
+		}
+		return strconv.Itoa(len(values[0])), nil
+	}
+
+	value := strings.Join(values, " ")
+
+	switch {
+	case op == "":
+		if !set {
+			return e.unresolved(ref)
+		}
+		return value, nil
+	case strings.HasPrefix(op, ":-"), strings.HasPrefix(op, "-"):
+		if set && (op[0] == '-' || value != "") {
+			return value, nil
+		}
+		return e.expandWord(strings.TrimPrefix(strings.TrimPrefix(op, ":"), "-"))
+	case strings.HasPrefix(op, ":+"), strings.HasPrefix(op, "+"):
+		if set && (op[0] == '+' || value != "") {
+			return e.expandWord(strings.TrimPrefix(strings.TrimPrefix(op, ":"), "+"))
+		}
+		return "", nil
+	}
+
+	if !set {
+		return e.unresolved(ref)
+	}
+
+	// operations applied to each element
+	var apply func(string) string
+
+	switch {
+	case op == "^^":
+		apply = strings.ToUpper
+	case op == ",,":
+		apply = strings.ToLower
+	case op == "^":
+		apply = func(s string) string { return strings.ToUpper(s[:min(1, len(s))]) + s[min(1, len(s)):] }
+	case op == ",":
+		apply = func(s string) string { return strings.ToLower(s[:min(1, len(s))]) + s[min(1, len(s)):] }
+	case op[0] == '#' || op[0] == '%':
+		n := 1
+		if len(op) > 1 && op[1] == op[0] {
+			n = 2
+		}
+		pattern, err := e.expandWord(op[n:])
+		if err != nil {
+			return "", err
+		}
+		re := globRegexp(pattern)
+		apply = func(s string) string { return trimPattern(s, re, op[0] == '#', n == 2) }
+	case op[0] == '/':
+		op = op[1:]
+		mode := byte(0)
+		if op != "" && (op[0] == '/' || op[0] == '#' || op[0] == '%') {
+			mode = op[0]
+			op = op[1:]
+		}
+
+		// split pattern and replacement at the first unquoted /
+		j := 0
+		for j < len(op) && op[j] != '/' {
+			j = wordPartEnd(op, j)
+		}
+
+		pattern, err := e.expandWord(op[:j])
+		if err != nil {
+			return "", err
+		}
+
+		replacement := ""
+		if j < len(op) {
+			replacement, err = e.expandWord(op[j+1:])
+			if err != nil {
+				return "", err
+			}
+		}
+
+		re := globRegexp(pattern)
+		apply = func(s string) string { return replacePattern(s, re, replacement, mode) }
+	case op[0] == ':':
+		var err error
+		apply, err = substring(op[1:])
+		if err != nil {
+			return e.unresolved(ref)
+		}
+	default:
+		return e.unresolved(ref)
+	}
+
+	expanded := make([]string, 0, len(values))
+	for _, v := range values {
+		expanded = append(expanded, apply(v))
+	}
+	return strings.Join(expanded, " "), nil
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// substring returns a function applying the substring expansion
+// offset[:length].
+func substring(spec string) (func(string) string, error) {
+	parts := strings.SplitN(spec, ":", 2)
+
+	offset, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return nil, err
+	}
+
+	length := -1
+	if len(parts) == 2 {
+		length, err = strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return func(s string) string {
+		start := offset
+		if start < 0 {
+			start += len(s)
+		}
+		if start < 0 || start > len(s) {
+			return ""
+		}
+
+		end := len(s)
+		if length >= 0 {
+			end = min(start+length, len(s))
+		} else if len(parts) == 2 {
+			end = len(s) + length
+		}
+		if end < start {
+			return ""
+		}
+
+		return s[start:end]
+	}, nil
+}
+
+// globRegexp converts the bash pattern to an anchored regular expression.
+func globRegexp(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^(?s:")
+
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+				b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+			}
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.Replace(class, `\`, `\\`, -1) + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	b.WriteString(")$")
+
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return regexp.MustCompile("^" + regexp.QuoteMeta(pattern) + "$")
+	}
+	return re
+}
+
+// trimPattern removes the shortest or longest prefix or suffix of s
+// matching re.
+func trimPattern(s string, re *regexp.Regexp, prefix, longest bool) string {
+	for k := 0; k <= len(s); k++ {
+		n := k
+		if longest {
+			n = len(s) - k
+		}
+
+		if prefix && re.MatchString(s[:n]) {
+			return s[n:]
+		}
+		if !prefix && re.MatchString(s[len(s)-n:]) {
+			return s[:len(s)-n]
+		}
+	}
+
+	return s
+}
+
+// replacePattern replaces the longest match of re in s with replacement.
+// mode is '/' to replace all matches, '#' or '%' to only match at the start
+// or end of s, and 0 to replace the first match.
+func replacePattern(s string, re *regexp.Regexp, replacement string, mode byte) string {
+	switch mode {
+	case '#':
+		for n := len(s); n >= 0; n-- {
+			if re.MatchString(s[:n]) {
+				return replacement + s[n:]
+			}
+		}
+		return s
+	case '%':
+		for n := 0; n <= len(s); n++ {
+			if re.MatchString(s[n:]) {
+				return s[:n] + replacement
+			}
+		}
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		matched := false
+		for j := len(s); j > i; j-- {
+			if re.MatchString(s[i:j]) {
+				b.WriteString(replacement)
+				i = j
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			b.WriteByte(s[i])
+			i++
+			continue
+		}
+
+		if mode != '/' {
+			b.WriteString(s[i:])
+			return b.String()
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,97 @@
+package pkgbuild
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpanderPKGBUILD(t *testing.T) {
+	file, err := ParseASTFile("./test_pkgbuilds/PKGBUILD_sudo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := NewExpander(file)
+
+	if pkgver := e.Values("pkgver"); !reflect.DeepEqual(pkgver, []string{"1.8.11.p2"}) {
+		t.Errorf("unexpected pkgver: %v", pkgver)
+	}
+
+	expected := []string{
+		"http://www.sudo.ws/sudo/dist/sudo-1.8.11p2.tar.gz",
+		"http://www.sudo.ws/sudo/dist/sudo-1.8.11p2.tar.gz.sig",
+		"sudo.tmpfiles.conf",
+		"sudo.pam",
+	}
+	if source := e.Values("source"); !reflect.DeepEqual(source, expected) {
+		t.Errorf("unexpected source: %v", source)
+	}
+}
+
+func TestExpand(t *testing.T) {
+	e := &Expander{
+		Vars: map[string][]string{
+			"pkgname": {"foo"},
+			"pkgver":  {"1.2.3"},
+			"pkgbase": {"linux-lts"},
+			"empty":   {""},
+			"arch":    {"i686", "x86_64"},
+		},
+	}
+
+	words := map[string][]string{
+		`$pkgname-$pkgver.tar.gz`:        {"foo-1.2.3.tar.gz"},
+		`"${pkgname}"_'$pkgver'`:         {"foo_$pkgver"},
+		`\$pkgname`:                      {"$pkgname"},
+		`"\$pkgname \"x\""`:              {`$pkgname "x"`},
+		`${pkgbase#linux}`:               {"-lts"},
+		`${pkgver%.*}`:                   {"1.2"},
+		`${pkgver%%.*}`:                  {"1"},
+		`${pkgver##*.}`:                  {"3"},
+		`${pkgver//./_}`:                 {"1_2_3"},
+		`${pkgver/./_}`:                  {"1_2.3"},
+		`${pkgver/#1/v1}`:                {"v1.2.3"},
+		`${pkgname^^}`:                   {"FOO"},
+		`${pkgname^}`:                    {"Foo"},
+		`${pkgver:2}`:                    {"2.3"},
+		`${pkgver:0:3}`:                  {"1.2"},
+		`${#pkgname}`:                    {"3"},
+		`${empty:-default}`:              {"default"},
+		`${empty-default}`:               {""},
+		`${unset:-$pkgname}`:             {"foo"},
+		`${pkgname:+set}`:                {"set"},
+		`${arch[1]}`:                     {"x86_64"},
+		`${arch[@]}`:                     {"i686 x86_64"},
+		`$pkgname{,.sig}`:                {"foo", "foo.sig"},
+		`{a,b{c,d}}.patch`:               {"a.patch", "bc.patch", "bd.patch"},
+		`"{a,b}"`:                        {"{a,b}"},
+		`${pkgname}{}`:                   {"foo{}"},
+		`$'tab'`:                         {"tab"},
+		`git+https://x.org/$pkgname.git`: {"git+https://x.org/foo.git"},
+	}
+
+	for word, expected := range words {
+		expanded, err := e.Expand(word)
+		if err != nil {
+			t.Errorf("unable to expand %s: %s", word, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(expanded, expected) {
+			t.Errorf("%s should expand to %q, got %q", word, expected, expanded)
+		}
+	}
+
+	unresolved := []string{"$srcdir/foo", "${unset}", "$(date)", "`date`", "${unset#x}"}
+	for _, word := range unresolved {
+		if _, err := e.Expand(word); err == nil {
+			t.Errorf("expected error expanding %s", word)
+		}
+	}
+
+	e.KeepUnresolved = true
+	expanded, err := e.Expand(`"$srcdir/$pkgname-$(date)"`)
+	if err != nil || len(expanded) != 1 || expanded[0] != "$srcdir/foo-$(date)" {
+		t.Errorf("unexpected expansion: %q, %v", expanded, err)
+	}
+}
@@ -0,0 +1,129 @@
+package pkgbuild
+
+import "fmt"
+
+// Reason is why a version does or doesn't satisfy a dependency.
+type Reason int
+
+// Reasons of an Explanation
+const (
+	ReasonSatisfied Reason = iota
+	// ReasonBelowMin means the version is older than the min version.
+	ReasonBelowMin
+	// ReasonAboveMax means the version is newer than the max version.
+	ReasonAboveMax
+	// ReasonStrictBound means the version is equal to a bound excluding
+	// it, e.g. 1.0 for "<1.0".
+	ReasonStrictBound
+	// ReasonEpochMismatch means the bound not met has a different epoch
+	// than the version, e.g. 1.2 for ">=1:1.0".
+	ReasonEpochMismatch
+	// ReasonExcluded means the version is excluded, e.g. 1.2 for "!=1.2".
+	ReasonExcluded
+)
+
+func (r Reason) String() string {
+	switch r {
+	case ReasonSatisfied:
+		return "satisfied"
+	case ReasonBelowMin:
+		return "below min"
+	case ReasonAboveMax:
+		return "above max"
+	case ReasonStrictBound:
+		return "strict bound"
+	case ReasonEpochMismatch:
+		return "epoch mismatch"
+	case ReasonExcluded:
+		return "excluded"
+	}
+	return "unknown"
+}
+
+// Explanation tells whether a version satisfies a dependency and if not
+// which bound it doesn't meet.
+type Explanation struct {
+	Reason  Reason
+	Version *CompleteVersion
+	Bound   string // the bound not met e.g. ">=1.2", empty if satisfied
+}
+
+// Satisfied reports whether the version satisfies the dependency.
+func (e Explanation) Satisfied() bool {
+	return e.Reason == ReasonSatisfied
+}
+
+// String returns the explanation in a form suitable for users like
+// "requires >=1.2, found 1.1".
+func (e Explanation) String() string {
+	switch e.Reason {
+	case ReasonSatisfied:
+		return fmt.Sprintf("found %s", e.Version)
+	case ReasonEpochMismatch:
+		return fmt.Sprintf("requires %s, found %s with epoch %d", e.Bound, e.Version, e.Version.Epoch)
+	}
+	return fmt.Sprintf("requires %s, found %s", e.Bound, e.Version)
+}
+
+// Explain is like Satisfies but returns why version does or doesn't
+// satisfy dep.
+func (version *CompleteVersion) Explain(dep *Dependency) Explanation {
+	return dep.Range().Explain(version)
+}
+
+// Explain is like Contains but returns why version is or isn't in r.
+func (r VersionRange) Explain(version *CompleteVersion) Explanation {
+	pinned := r.Min != nil && r.Max != nil && !r.MinExclusive && !r.MaxExclusive && r.Min.cmp(r.Max) == 0
+
+	if r.Min != nil {
+		op := ">="
+		if r.MinExclusive {
+			op = ">"
+		} else if pinned {
+			op = "="
+		}
+
+		cmp := version.cmp(r.Min)
+		switch {
+		case cmp == -1:
+			return unsatisfied(ReasonBelowMin, version, op, r.Min)
+		case cmp == 0 && r.MinExclusive:
+			return unsatisfied(ReasonStrictBound, version, op, r.Min)
+		}
+	}
+
+	if r.Max != nil {
+		op := "<="
+		if r.MaxExclusive {
+			op = "<"
+		} else if pinned {
+			op = "="
+		}
+
+		cmp := version.cmp(r.Max)
+		switch {
+		case cmp == 1:
+			return unsatisfied(ReasonAboveMax, version, op, r.Max)
+		case cmp == 0 && r.MaxExclusive:
+			return unsatisfied(ReasonStrictBound, version, op, r.Max)
+		}
+	}
+
+	for _, v := range r.Exclude {
+		if version.cmp(v) == 0 {
+			return Explanation{Reason: ReasonExcluded, Version: version, Bound: "!=" + v.String()}
+		}
+	}
+
+	return Explanation{Reason: ReasonSatisfied, Version: version}
+}
+
+// unsatisfied returns the explanation of version not meeting the bound op
+// e.g. ">=" with the version bound. The reason is ReasonEpochMismatch if
+// the epochs differ.
+func unsatisfied(reason Reason, version *CompleteVersion, op string, bound *CompleteVersion) Explanation {
+	if bound.Epoch != version.Epoch {
+		reason = ReasonEpochMismatch
+	}
+	return Explanation{Reason: reason, Version: version, Bound: op + bound.String()}
+}
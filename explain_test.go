@@ -0,0 +1,45 @@
+package pkgbuild
+
+import "testing"
+
+func TestExplain(t *testing.T) {
+	for _, test := range []struct {
+		dep     string
+		version string
+		reason  Reason
+		str     string
+	}{
+		{"foo>=1.2", "1.1", ReasonBelowMin, "requires >=1.2, found 1.1"},
+		{"foo>=1.2", "1.2", ReasonSatisfied, "found 1.2"},
+		{"foo<2", "2.1", ReasonAboveMax, "requires <2, found 2.1"},
+		{"foo<=2", "2.1", ReasonAboveMax, "requires <=2, found 2.1"},
+		{"foo>1.0 foo<2", "1.0", ReasonStrictBound, "requires >1.0, found 1.0"},
+		{"foo<2", "2", ReasonStrictBound, "requires <2, found 2"},
+		{"foo=1.2", "1.3", ReasonAboveMax, "requires =1.2, found 1.3"},
+		{"foo>=1:1.0", "1.2", ReasonEpochMismatch, "requires >=1:1.0, found 1.2 with epoch 0"},
+		{"foo", "1.0", ReasonSatisfied, "found 1.0"},
+	} {
+		version, err := NewCompleteVersion(test.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dep := parseRange(t, test.dep)
+
+		e := dep.Explain(version)
+		if e.Reason != test.reason || e.String() != test.str {
+			t.Errorf("%s with %s: expected %s %q, got %s %q", test.dep, test.version, test.reason, test.str, e.Reason, e.String())
+		}
+		if e.Satisfied() != dep.Contains(version) {
+			t.Errorf("%s with %s: explanation disagrees with Contains", test.dep, test.version)
+		}
+	}
+
+	deps, err := ParseDeps([]string{"foo>=1.2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	version, _ := NewCompleteVersion("1.1")
+	if e := version.Explain(deps[0]); e.Reason != ReasonBelowMin || version.Satisfies(deps[0]) {
+		t.Errorf("expected 1.1 to be below min of %s, got %s", deps[0], e.Reason)
+	}
+}
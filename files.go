@@ -0,0 +1,224 @@
+package pkgbuild
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PackageFiles is the file list of a built package.
+type PackageFiles struct {
+	PKGINFO *PKGINFO
+	Files   []string // relative to /, directories ending with /
+}
+
+// ReadPackageFiles reads the .PKGINFO and the file list from the .MTREE of
+// the built package file at path.
+func ReadPackageFiles(path string) (*PackageFiles, error) {
+	info, err := ReadPKGINFO(path)
+	if err != nil {
+		return nil, err
+	}
+
+	mtree, err := readPackageFile(path, ".MTREE")
+	if err != nil {
+		return nil, err
+	}
+	files, err := ParseMTREE(mtree)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+
+	return &PackageFiles{PKGINFO: info, Files: files}, nil
+}
+
+// ParseMTREE returns the files listed in the content of a .MTREE file,
+// which may be gzip compressed as stored in packages. The package metadata
+// files like .PKGINFO are skipped.
+func ParseMTREE(content []byte) ([]string, error) {
+	if bytes.HasPrefix(content, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(bytes.NewReader(content))
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+
+		content, err = ioutil.ReadAll(gz)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	defaultType := "file"
+	var files []string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for n := 1; scanner.Scan(); n++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		typ := ""
+		for _, keyword := range fields[1:] {
+			if strings.HasPrefix(keyword, "type=") {
+				typ = keyword[len("type="):]
+			}
+		}
+
+		switch fields[0] {
+		case "/set":
+			if typ != "" {
+				defaultType = typ
+			}
+			continue
+		case "/unset":
+			continue
+		}
+		if typ == "" {
+			typ = defaultType
+		}
+
+		name, err := unvis(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", n, err)
+		}
+		name = strings.TrimPrefix(name, "./")
+		if name == "." || strings.HasPrefix(name, ".") && !strings.Contains(name, "/") {
+			continue
+		}
+
+		if typ == "dir" {
+			name += "/"
+		}
+		files = append(files, name)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// unvis decodes the octal escapes like \040 of mtree path names.
+func unvis(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+4 > len(s) {
+			return "", fmt.Errorf("invalid escape in %q", s)
+		}
+		c, err := strconv.ParseUint(s[i+1:i+4], 8, 8)
+		if err != nil {
+			return "", fmt.Errorf("invalid escape in %q", s)
+		}
+		b.WriteByte(byte(c))
+		i += 3
+	}
+	return b.String(), nil
+}
+
+// ParseFilesList returns the files listed in the content of the files
+// entry of a package in a sync database or the local database, following
+// %FILES%.
+func ParseFilesList(content []byte) []string {
+	var files []string
+	inFiles := false
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "%") && strings.HasSuffix(line, "%"):
+			inFiles = line == "%FILES%"
+		case inFiles && line != "":
+			files = append(files, line)
+		}
+	}
+	return files
+}
+
+// FileConflict is a path shipped by two packages.
+type FileConflict struct {
+	Path string
+	A, B string // names of the packages
+}
+
+func (c FileConflict) String() string {
+	return fmt.Sprintf("%s exists in both '%s' and '%s'", c.Path, c.A, c.B)
+}
+
+// PredictFileConflicts returns the paths shipped by more than one of the
+// packages, e.g. the packages of a repository, which pacman would refuse
+// to install together. Directories are shared, unless one package ships a
+// file where another ships a directory. Packages declaring a conflict with
+// each other can't be installed together and aren't checked. The conflicts
+// are ordered by path.
+func PredictFileConflicts(packages []*PackageFiles) []FileConflict {
+	type owner struct {
+		pkg *PackageFiles
+		dir bool
+	}
+
+	owners := make(map[string][]owner)
+	for _, pkg := range packages {
+		for _, file := range pkg.Files {
+			path := strings.TrimSuffix(file, "/")
+			owners[path] = append(owners[path], owner{pkg, strings.HasSuffix(file, "/")})
+		}
+	}
+
+	var conflicts []FileConflict
+	for path, owners := range owners {
+		for i, a := range owners {
+			for _, b := range owners[i+1:] {
+				if a.dir && b.dir || a.pkg == b.pkg || exclusive(a.pkg.PKGINFO, b.pkg.PKGINFO) {
+					continue
+				}
+
+				conflict := FileConflict{Path: path, A: a.pkg.PKGINFO.Pkgname, B: b.pkg.PKGINFO.Pkgname}
+				if conflict.B < conflict.A {
+					conflict.A, conflict.B = conflict.B, conflict.A
+				}
+				conflicts = append(conflicts, conflict)
+			}
+		}
+	}
+
+	sort.Slice(conflicts, func(i, j int) bool {
+		if conflicts[i].Path != conflicts[j].Path {
+			return conflicts[i].Path < conflicts[j].Path
+		}
+		if conflicts[i].A != conflicts[j].A {
+			return conflicts[i].A < conflicts[j].A
+		}
+		return conflicts[i].B < conflicts[j].B
+	})
+	return conflicts
+}
+
+// exclusive returns true if a or b declares a conflict with the other
+// package or something it provides.
+func exclusive(a, b *PKGINFO) bool {
+	conflicts := func(a, b *PKGINFO) bool {
+		version, _ := NewCompleteVersion(b.Pkgver)
+		for _, conflict := range a.Conflicts {
+			deps, err := ParseDeps([]string{conflict})
+			if err == nil && len(deps) == 1 && deps[0].SatisfiedBy(b.Pkgname, version, b.Provides, ProvidesStrict) {
+				return true
+			}
+		}
+		return false
+	}
+	return conflicts(a, b) || conflicts(b, a)
+}
@@ -0,0 +1,105 @@
+package pkgbuild
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testMTREE = `#mtree
+/set type=file uid=0 gid=0 mode=644
+./.BUILDINFO time=1427201013.0 size=4924 md5digest=1 sha256digest=1
+./.PKGINFO time=1427201013.0 size=608 md5digest=1 sha256digest=1
+./usr time=1427201013.0 mode=755 type=dir
+./usr/bin time=1427201013.0 mode=755 type=dir
+./usr/bin/sudo time=1427201013.0 mode=4755 size=145656 md5digest=1 sha256digest=1
+./usr/share/doc/sudo/read\040me time=1427201013.0 size=10 md5digest=1 sha256digest=1
+./usr/bin/sudoedit time=1427201013.0 type=link link=sudo
+`
+
+func TestParseMTREE(t *testing.T) {
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write([]byte(testMTREE))
+	w.Close()
+
+	expected := []string{"usr/", "usr/bin/", "usr/bin/sudo", "usr/share/doc/sudo/read me", "usr/bin/sudoedit"}
+	for _, content := range [][]byte{[]byte(testMTREE), gz.Bytes()} {
+		files, err := ParseMTREE(content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(files, expected) {
+			t.Errorf("expected %q, got %q", expected, files)
+		}
+	}
+
+	if _, err := ParseMTREE([]byte(`./usr/bin/foo\04`)); err == nil {
+		t.Error("expected error for invalid escape")
+	}
+}
+
+func TestReadPackageFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopkgbuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "sudo-1.8.11.p2-1-x86_64.pkg.tar.gz")
+	writeTestPackage(t, path, map[string]string{".PKGINFO": testPKGINFO, ".MTREE": testMTREE}, true)
+
+	pkg, err := ReadPackageFiles(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg.PKGINFO.Pkgname != "sudo" || len(pkg.Files) != 5 {
+		t.Errorf("unexpected package files: %s %q", pkg.PKGINFO.Pkgname, pkg.Files)
+	}
+}
+
+func TestParseFilesList(t *testing.T) {
+	files := ParseFilesList([]byte("%FILES%\nusr/\nusr/bin/\nusr/bin/foo\n\n%BACKUP%\netc/foo.conf\tabc\n"))
+	if expected := []string{"usr/", "usr/bin/", "usr/bin/foo"}; !reflect.DeepEqual(files, expected) {
+		t.Errorf("expected %q, got %q", expected, files)
+	}
+}
+
+func TestPredictFileConflicts(t *testing.T) {
+	packages := []*PackageFiles{
+		{
+			PKGINFO: &PKGINFO{Pkgname: "foo", Pkgver: "1.0-1"},
+			Files:   []string{"usr/", "usr/bin/", "usr/bin/foo", "usr/lib/libfoo.so", "usr/share/foo"},
+		},
+		{
+			PKGINFO: &PKGINFO{Pkgname: "foo-git", Pkgver: "1.0.r1-1", Provides: []string{"foo=1.0"}, Conflicts: []string{"foo"}},
+			Files:   []string{"usr/", "usr/bin/", "usr/bin/foo"},
+		},
+		{
+			PKGINFO: &PKGINFO{Pkgname: "bar", Pkgver: "1.0-1"},
+			Files:   []string{"usr/", "usr/bin/", "usr/bin/foo", "usr/share/foo/"},
+		},
+		{
+			PKGINFO: &PKGINFO{Pkgname: "baz", Pkgver: "1.0-1"},
+			Files:   []string{"usr/", "usr/lib/libfoo.so"},
+		},
+	}
+
+	conflicts := PredictFileConflicts(packages)
+	expected := []FileConflict{
+		{Path: "usr/bin/foo", A: "bar", B: "foo"},
+		{Path: "usr/bin/foo", A: "bar", B: "foo-git"},
+		{Path: "usr/lib/libfoo.so", A: "baz", B: "foo"},
+		{Path: "usr/share/foo", A: "bar", B: "foo"},
+	}
+	if !reflect.DeepEqual(conflicts, expected) {
+		t.Errorf("expected %v, got %v", expected, conflicts)
+	}
+	if conflicts[0].String() != "usr/bin/foo exists in both 'bar' and 'foo'" {
+		t.Errorf("unexpected conflict: %s", conflicts[0])
+	}
+}
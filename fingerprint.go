@@ -0,0 +1,87 @@
+package pkgbuild
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// unorderedFields are the array variables, and their arch specific
+// variants, whose order and duplicates carry no meaning. The order of the
+// other arrays matters, e.g. checksums are paired with sources by index.
+var unorderedFields = map[string]bool{
+	"arch":         true,
+	"license":      true,
+	"groups":       true,
+	"depends":      true,
+	"makedepends":  true,
+	"checkdepends": true,
+	"optdepends":   true,
+	"provides":     true,
+	"conflicts":    true,
+	"replaces":     true,
+	"backup":       true,
+	"noextract":    true,
+	"validpgpkeys": true,
+}
+
+// normalized returns the variables of p in a normalized form: unset
+// variables and defaults like epoch 0 are left out, pkgbase is always set
+// and the values of unordered arrays are sorted and deduplicated. Comments
+// are not included.
+func (p *PKGBUILD) normalized() map[string][]string {
+	vars := p.Vars()
+	if base := p.pkgbase(); base != "" {
+		vars["pkgbase"] = []string{base}
+	}
+
+	for name, values := range vars {
+		field := name
+		if i := strings.Index(name, "_"); i >= 0 && archFields[name[:i]] {
+			field = name[:i]
+		}
+		if !unorderedFields[field] {
+			continue
+		}
+
+		set := make(map[string]bool, len(values))
+		for _, value := range values {
+			set[value] = true
+		}
+		vars[name] = sortedKeys(set)
+	}
+
+	return vars
+}
+
+// Equal reports whether a and b are the same PKGBUILD, ignoring differences
+// without meaning like the order of dependencies, an explicit pkgbase equal
+// to the first pkgname or comments.
+func Equal(a, b *PKGBUILD) bool {
+	return reflect.DeepEqual(a.normalized(), b.normalized())
+}
+
+// Fingerprint returns a stable hex encoded SHA-256 hash of the normalized
+// variables of p. PKGBUILDs have the same fingerprint if they are Equal.
+func (p *PKGBUILD) Fingerprint() string {
+	vars := p.normalized()
+
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// length prefixes keep the encoding unambiguous
+	h := sha256.New()
+	for _, name := range names {
+		h.Write([]byte(strconv.Itoa(len(name)) + ":" + name + strconv.Itoa(len(vars[name])) + ";"))
+		for _, value := range vars[name] {
+			h.Write([]byte(strconv.Itoa(len(value)) + ":" + value))
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
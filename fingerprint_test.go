@@ -0,0 +1,48 @@
+package pkgbuild
+
+import "testing"
+
+func TestEqualAndFingerprint(t *testing.T) {
+	a, err := ParseSRCINFO("./test_pkgbuilds/SRCINFO_sudo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ParseSRCINFO("./test_pkgbuilds/SRCINFO_sudo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !Equal(a, b) || a.Fingerprint() != b.Fingerprint() {
+		t.Fatal("expected the same PKGBUILD to be equal")
+	}
+
+	// changes without meaning
+	b.Depends[0], b.Depends[1] = b.Depends[1], b.Depends[0]
+	b.Arch = append(b.Arch, b.Arch[0])
+	b.Comments = []string{"# Generated by mksrcinfo v8"}
+	b.Pkgbase = ""
+	b.Pkgnames = []string{"sudo"}
+	a.Pkgbase = "sudo"
+	if !Equal(a, b) || a.Fingerprint() != b.Fingerprint() {
+		t.Errorf("expected reordered PKGBUILD to be equal: %s", Diff(a, b))
+	}
+
+	fingerprint := a.Fingerprint()
+	for _, change := range []func(p *PKGBUILD){
+		func(p *PKGBUILD) { p.Pkgrel = "2" },
+		func(p *PKGBUILD) { p.Epoch = 1 },
+		func(p *PKGBUILD) { p.Source[0], p.Source[1] = p.Source[1], p.Source[0] },
+		func(p *PKGBUILD) { p.Depends = p.Depends[1:] },
+		func(p *PKGBUILD) { p.Extra = map[string][]string{"_foo": {"bar"}} },
+	} {
+		c, err := ParseSRCINFO("./test_pkgbuilds/SRCINFO_sudo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		change(c)
+
+		if Equal(a, c) || c.Fingerprint() == fingerprint {
+			t.Errorf("expected change to be detected: %s", Diff(a, c))
+		}
+	}
+}
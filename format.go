@@ -0,0 +1,630 @@
+package pkgbuild
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// fieldOrder is the canonical order of the PKGBUILD variables.
+var fieldOrder = []string{
+	"pkgbase",
+	"pkgname",
+	"pkgver",
+	"pkgrel",
+	"epoch",
+	"pkgdesc",
+	"arch",
+	"url",
+	"license",
+	"groups",
+	"depends",
+	"makedepends",
+	"checkdepends",
+	"optdepends",
+	"provides",
+	"conflicts",
+	"replaces",
+	"backup",
+	"options",
+	"install",
+	"changelog",
+	"source",
+	"noextract",
+	"validpgpkeys",
+	"md5sums",
+	"sha1sums",
+	"sha224sums",
+	"sha256sums",
+	"sha384sums",
+	"sha512sums",
+	"b2sums",
+}
+
+// archFields lists the variables which can have an _<arch> suffix.
+var archFields = map[string]bool{
+	"depends":      true,
+	"makedepends":  true,
+	"checkdepends": true,
+	"optdepends":   true,
+	"provides":     true,
+	"conflicts":    true,
+	"replaces":     true,
+	"source":       true,
+	"md5sums":      true,
+	"sha1sums":     true,
+	"sha224sums":   true,
+	"sha256sums":   true,
+	"sha384sums":   true,
+	"sha512sums":   true,
+	"b2sums":       true,
+}
+
+// maxLineLength is the length up to which arrays are kept on a single line.
+const maxLineLength = 80
+
+// Format rewrites the PKGBUILD content into the canonical style:
+//
+//   - the variables at the top are ordered like in the PKGBUILD prototype,
+//     unless moving them would change what they expand to
+//   - literal array elements are single quoted, literal scalars are double
+//     quoted if needed
+//   - arrays are kept on one line if they fit in 80 columns, otherwise one
+//     element per line aligned to the first element
+//   - function bodies are indented with two spaces per level
+//   - blank lines are collapsed and functions are separated by a blank line
+//
+// Anything the formatter can't safely rewrite, like arrays containing
+// comments, top level if statements or heredocs, is kept as is.
+func Format(content []byte) ([]byte, error) {
+	input := string(content)
+
+	stmts, err := parseShell(input)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &formatter{input: input, stmts: stmts}
+	return []byte(f.format()), nil
+}
+
+// formatter holds the state of Format.
+type formatter struct {
+	input string
+	stmts []statement
+	block [2]int // range of the reordered header statements
+}
+
+func (f *formatter) format() string {
+	if len(f.stmts) == 0 {
+		return ""
+	}
+
+	order := f.order()
+
+	var b strings.Builder
+	for k, i := range order {
+		if k > 0 {
+			b.WriteString(f.separator(order[k-1], i))
+		}
+		b.WriteString(f.formatStmt(f.stmts[i]))
+	}
+
+	last := f.stmts[len(f.stmts)-1]
+	if rest := f.input[last.end:]; strings.TrimSpace(rest) != "" {
+		// e.g. a heredoc body
+		b.WriteString(strings.TrimRight(rest, " \t\r\n"))
+	}
+	b.WriteString("\n")
+
+	return b.String()
+}
+
+// separator returns the text between the statements prev and cur.
+func (f *formatter) separator(prev, cur int) string {
+	inBlock := func(i int) bool { return i >= f.block[0] && i < f.block[1] }
+
+	if cur != prev+1 {
+		switch {
+		case !inBlock(prev):
+			// first statement of the reordered block
+			prev, cur = f.block[0]-1, f.block[0]
+		case !inBlock(cur):
+			// first statement after the reordered block
+			prev, cur = f.block[1]-1, f.block[1]
+		default:
+			return "\n"
+		}
+	}
+
+	gap := f.input[f.stmts[prev].end:f.stmts[cur].start]
+	if !strings.Contains(gap, "\n") || strings.TrimSpace(gap) != "" {
+		return gap
+	}
+
+	switch {
+	case inBlock(prev) && inBlock(cur):
+		return "\n"
+	case f.stmts[prev].kind == stmtFunction:
+		return "\n\n"
+	case f.stmts[cur].kind == stmtFunction && f.stmts[prev].kind != stmtComment:
+		return "\n\n"
+	case strings.Count(gap, "\n") > 1:
+		return "\n\n"
+	}
+
+	return "\n"
+}
+
+// unit is an assignment together with its comments.
+type unit struct {
+	stmts []int
+	name  string
+	rank  int
+	arch  bool
+}
+
+// order returns the order of the statements, with the variables at the top
+// in the canonical order if possible.
+func (f *formatter) order() []int {
+	order := make([]int, len(f.stmts))
+	for i := range order {
+		order[i] = i
+	}
+
+	start := 0
+	for start < len(f.stmts) && f.stmts[start].kind != stmtAssignment {
+		start++
+	}
+
+	end := start
+	for end < len(f.stmts) && (f.stmts[end].kind == stmtAssignment || f.stmts[end].kind == stmtComment) {
+		end++
+	}
+	for end > start && f.stmts[end-1].kind == stmtComment {
+		end--
+	}
+
+	if end-start < 2 {
+		return order
+	}
+	f.block = [2]int{start, end}
+
+	var units []*unit
+	names := make(map[string]bool)
+	var pending []int
+
+	for i := start; i < end; i++ {
+		stmt := f.stmts[i]
+
+		sameLine := false
+		if i > start {
+			gap := f.input[f.stmts[i-1].end:stmt.start]
+			if strings.TrimSpace(gap) != "" {
+				return order
+			}
+			sameLine = !strings.Contains(gap, "\n")
+		}
+
+		switch {
+		case stmt.kind == stmtComment && sameLine && len(pending) == 0:
+			// trailing comment of the previous assignment
+			units[len(units)-1].stmts = append(units[len(units)-1].stmts, i)
+		case sameLine:
+			// e.g. a=1; b=2
+			return order
+		case stmt.kind == stmtComment:
+			pending = append(pending, i)
+		default:
+			if stmt.append || names[stmt.name] {
+				return order
+			}
+			names[stmt.name] = true
+
+			u := &unit{stmts: append(pending, i), name: stmt.name, rank: -1}
+			pending = nil
+
+			base := stmt.name
+			if i := strings.IndexByte(base, '_'); i > 0 && archFields[base[:i]] {
+				base = base[:i]
+				u.arch = true
+			}
+			for rank, field := range fieldOrder {
+				if field == base {
+					u.rank = rank
+				}
+			}
+
+			units = append(units, u)
+		}
+	}
+
+	sorted := append([]*unit{}, units...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].rank != sorted[j].rank {
+			return sorted[i].rank < sorted[j].rank
+		}
+		return !sorted[i].arch && sorted[j].arch
+	})
+
+	// only reorder if no reference moves across the referenced assignment
+	before := func(units []*unit) map[string]int {
+		pos := make(map[string]int, len(units))
+		for i, u := range units {
+			pos[u.name] = i
+		}
+		return pos
+	}
+	oldPos, newPos := before(units), before(sorted)
+
+	for _, u := range units {
+		for _, ref := range f.references(u) {
+			if _, ok := names[ref]; !ok || ref == u.name {
+				continue
+			}
+			if (oldPos[ref] < oldPos[u.name]) != (newPos[ref] < newPos[u.name]) {
+				return order
+			}
+		}
+	}
+
+	k := start
+	for _, u := range sorted {
+		for _, i := range u.stmts {
+			order[k] = i
+			k++
+		}
+	}
+
+	return order
+}
+
+var referenceRegexp = regexp.MustCompile(`\$\{?[#!]?([A-Za-z_][A-Za-z0-9_]*)`)
+
+// references returns the names of the variables referenced by the
+// assignment of u.
+func (f *formatter) references(u *unit) []string {
+	stmt := f.stmts[u.stmts[len(u.stmts)-1]]
+
+	var refs []string
+	for _, match := range referenceRegexp.FindAllStringSubmatch(f.input[stmt.start:stmt.end], -1) {
+		refs = append(refs, match[1])
+	}
+	return refs
+}
+
+// formatStmt formats a single statement.
+func (f *formatter) formatStmt(stmt statement) string {
+	switch stmt.kind {
+	case stmtAssignment:
+		return f.formatAssignment(stmt)
+	case stmtFunction:
+		return f.formatFunction(stmt)
+	}
+
+	return f.input[stmt.start:stmt.end]
+}
+
+// formatAssignment formats a top level assignment.
+func (f *formatter) formatAssignment(stmt statement) string {
+	raw := f.input[stmt.start:stmt.end]
+
+	prefix := stmt.name + "="
+	if stmt.append {
+		prefix = stmt.name + "+="
+	}
+
+	if !stmt.array {
+		value, ok := literalWord(f.input[stmt.value.start:stmt.value.end])
+		if !ok {
+			return raw
+		}
+		if quoteWord(value) == value {
+			return prefix + value
+		}
+		return prefix + quoteWordAs(value, '"')
+	}
+
+	// keep arrays containing comments as is
+	at := stmt.value.start
+	for _, element := range stmt.elements {
+		if strings.TrimSpace(f.input[at:element.start]) != "" {
+			return raw
+		}
+		at = element.end
+	}
+	if strings.TrimSpace(f.input[at:stmt.value.end]) != "" {
+		return raw
+	}
+
+	words := make([]string, 0, len(stmt.elements))
+	for _, element := range stmt.elements {
+		word := f.input[element.start:element.end]
+		if value, ok := literalWord(word); ok {
+			word = quoteWordAs(value, '\'')
+		}
+		words = append(words, word)
+	}
+
+	line := prefix + "(" + strings.Join(words, " ") + ")"
+	if len(line) <= maxLineLength || len(words) < 2 {
+		return line
+	}
+
+	indent := "\n" + strings.Repeat(" ", len(prefix)+1)
+	return prefix + "(" + strings.Join(words, indent) + ")"
+}
+
+// literalWord returns the value of the shell word raw if it doesn't contain
+// any expansions.
+func literalWord(raw string) (string, bool) {
+	for i := 0; i < len(raw); {
+		end := wordPartEnd(raw, i)
+
+		switch c := raw[i]; {
+		case c == '$' || c == '`':
+			return "", false
+		case c == '"':
+			for j := i + 1; j < end-1; j++ {
+				if raw[j] == '\\' {
+					j++
+				} else if raw[j] == '$' || raw[j] == '`' {
+					return "", false
+				}
+			}
+		case strings.IndexByte("{}*?[]~", c) >= 0:
+			return "", false
+		}
+
+		i = end
+	}
+
+	return unquoteWord(raw), true
+}
+
+// formatFunction formats a top level function definition.
+func (f *formatter) formatFunction(stmt statement) string {
+	body := f.input[stmt.value.start:stmt.value.end]
+
+	firstLine := body
+	if i := strings.IndexByte(body, '\n'); i >= 0 {
+		firstLine = body[:i]
+	}
+	if strings.TrimSpace(firstLine) != "" || !strings.Contains(body, "\n") {
+		// e.g. foo() { bar; }
+		return f.input[stmt.start:stmt.end]
+	}
+
+	return stmt.name + "() {\n" + f.indentBody(stmt.value) + "}"
+}
+
+// lineInfo is the indentation of a line of a function body.
+type lineInfo struct {
+	depth     int
+	recorded  bool // the line starts with a token
+	cont      bool // continuation of the previous line
+	protected bool // inside a multi line string or heredoc
+}
+
+// indentBody reindents the lines of the function body s, starting with the
+// line after the opening brace.
+func (f *formatter) indentBody(s span) string {
+	// line start offsets, line 0 is the rest of the line of the brace
+	starts := []int{s.start}
+	for i := s.start; i < s.end; i++ {
+		if f.input[i] == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	lineOf := func(offset int) int {
+		return sort.SearchInts(starts, offset+1) - 1
+	}
+
+	info := make([]lineInfo, len(starts))
+
+	depth := 1
+	parens := 0
+	commandStart := true
+	sawNewline := true
+
+	const (
+		casePreIn = iota
+		casePattern
+		caseBody
+	)
+	type frame struct {
+		kind  string
+		state int
+	}
+	var stack []frame
+	top := func() *frame {
+		if len(stack) == 0 {
+			return &frame{}
+		}
+		return &stack[len(stack)-1]
+	}
+	pop := func() {
+		if len(stack) > 0 {
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	record := func(t shellToken, depth int) {
+		l := lineOf(t.start)
+		if !info[l].recorded {
+			info[l].recorded = true
+			info[l].depth = depth
+			info[l].cont = !sawNewline || parens > 0
+		}
+		sawNewline = false
+	}
+
+	scanner := &shellScanner{input: f.input[:s.end], pos: s.start}
+	for {
+		t := scanner.next()
+		if t.typ == shellEOF {
+			break
+		}
+
+		// lines inside the token
+		for l := lineOf(t.start) + 1; l < len(starts) && starts[l] < t.end; l++ {
+			info[l].protected = true
+		}
+
+		switch t.typ {
+		case shellNewline:
+			sawNewline = true
+			if parens == 0 {
+				commandStart = true
+			}
+		case shellComment:
+			record(t, depth)
+		case shellOperator:
+			switch {
+			case t.val == "(" && commandStart:
+				record(t, depth)
+				stack = append(stack, frame{kind: "("})
+				depth++
+			case t.val == "(":
+				record(t, depth)
+				parens++
+			case t.val == ")" && top().kind == "(":
+				pop()
+				depth--
+				record(t, depth)
+			case t.val == ")" && top().kind == "case" && top().state == casePattern:
+				record(t, depth)
+				top().state = caseBody
+				depth++
+			case t.val == ")":
+				record(t, depth)
+				if parens > 0 {
+					parens--
+				}
+			case (t.val == ";;" || t.val == ";&" || t.val == ";;&") && top().kind == "case" && top().state == caseBody:
+				depth--
+				record(t, depth)
+				top().state = casePattern
+			default:
+				record(t, depth)
+			}
+
+			switch t.val {
+			case "<", ">", ">>", "<<", "<<-", "<<<", "<&", ">&", "<>", "&>", ">|", ")":
+				commandStart = false
+			default:
+				commandStart = true
+			}
+		case shellWord:
+			if c := top(); c.kind == "case" && c.state != caseBody {
+				switch {
+				case c.state == casePreIn && t.val == "in":
+					record(t, depth)
+					c.state = casePattern
+					depth++
+				case c.state == casePattern && t.val == "esac":
+					depth--
+					pop()
+					record(t, depth)
+				default:
+					record(t, depth)
+				}
+				commandStart = false
+				continue
+			}
+
+			if !commandStart {
+				record(t, depth)
+				continue
+			}
+
+			switch t.val {
+			case "{", "if", "for", "while", "until", "select", "case":
+				record(t, depth)
+				stack = append(stack, frame{kind: t.val})
+				if t.val == "{" {
+					depth++
+				}
+			case "then", "do":
+				record(t, depth)
+				depth++
+			case "elif":
+				depth--
+				record(t, depth)
+			case "else":
+				record(t, depth-1)
+			case "}", "fi", "done":
+				depth--
+				pop()
+				record(t, depth)
+			case "esac":
+				depth -= 2
+				pop()
+				record(t, depth)
+			default:
+				record(t, depth)
+			}
+
+			switch t.val {
+			case "then", "do", "else", "elif", "{", "!", "time", "if", "while", "until":
+				commandStart = true
+			default:
+				commandStart = false
+			}
+		}
+	}
+
+	var b strings.Builder
+	delta := 0
+
+	for l := 1; l < len(starts); l++ {
+		end := s.end
+		if l+1 < len(starts) {
+			end = starts[l+1]
+		}
+		line := f.input[starts[l]:end]
+		text := strings.TrimLeft(line, " \t")
+		origWidth := indentWidth(line[:len(line)-len(text)])
+
+		switch {
+		case info[l].protected:
+			b.WriteString(line)
+			continue
+		case strings.TrimSpace(line) == "":
+			if l+1 < len(starts) {
+				b.WriteString("\n")
+			}
+			continue
+		case !info[l].recorded:
+			b.WriteString(line)
+			continue
+		case info[l].cont:
+			b.WriteString(strings.Repeat(" ", max(0, origWidth+delta)))
+		default:
+			width := 2 * max(0, info[l].depth)
+			delta = width - origWidth
+			b.WriteString(strings.Repeat(" ", width))
+		}
+
+		b.WriteString(text)
+	}
+
+	out := b.String()
+	if out != "" && !strings.HasSuffix(out, "\n") {
+		out += "\n"
+	}
+	return out
+}
+
+// indentWidth returns the width of the indentation, counting tabs as two
+// spaces.
+func indentWidth(indent string) int {
+	return len(indent) + strings.Count(indent, "\t")
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
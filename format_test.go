@@ -0,0 +1,162 @@
+package pkgbuild
+
+import (
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Test that formatting keeps the meaning of the PKGBUILDs and is idempotent
+func TestFormatPKGBUILDs(t *testing.T) {
+	for _, name := range pkgbuilds {
+		content, err := ioutil.ReadFile("./test_pkgbuilds/PKGBUILD_" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		formatted, err := Format(content)
+		if err != nil {
+			t.Errorf("PKGBUILD for %s did not format: %s", name, err)
+			continue
+		}
+
+		again, err := Format(formatted)
+		if err != nil {
+			t.Errorf("formatted PKGBUILD for %s did not format: %s", name, err)
+			continue
+		}
+
+		if string(again) != string(formatted) {
+			t.Errorf("formatting PKGBUILD for %s is not idempotent:\n%s\n---\n%s", name, formatted, again)
+		}
+
+		before, _ := ParseAST(content)
+		after, err := ParseAST(formatted)
+		if err != nil {
+			t.Errorf("formatted PKGBUILD for %s did not parse: %s", name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(NewExpander(before).Vars, NewExpander(after).Vars) {
+			t.Errorf("formatting PKGBUILD for %s changed the variables", name)
+		}
+
+		functions := after.Functions()
+		for i, fn := range before.Functions() {
+			if i >= len(functions) || functions[i].Name != fn.Name ||
+				strings.Join(strings.Fields(functions[i].Body), " ") != strings.Join(strings.Fields(fn.Body), " ") {
+				t.Errorf("formatting PKGBUILD for %s changed the function %s", name, fn.Name)
+			}
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	input := `# Maintainer: foo
+
+
+pkgname=foo
+pkgrel=1
+pkgver=1.0
+_commit=abc # upstream commit
+arch=(x86_64)
+
+# runtime deps
+depends=("glibc" bar 'baz>=1.0' "lib$pkgname")
+makedepends=(aaaaaaaaaaaaaaaaaaaa bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb cccccccccccccccccccc dddddddddd)
+pkgdesc='A "foo" tool'
+source=("https://example.org/$pkgname-$pkgver.tar.gz" # release
+        foo.patch)
+url=https://example.org
+build ()
+{
+	cd "$srcdir"
+	if [ -f foo ]; then
+	make \
+	  V=1
+	else
+		case $CARCH in
+		x86_64)
+		echo 64;;
+		*) echo 32
+		esac
+	fi
+	cat > foo <<EOT
+	  keep
+EOT
+}
+package() {
+    make DESTDIR="$pkgdir" install
+}
+`
+
+	expected := `# Maintainer: foo
+
+_commit=abc # upstream commit
+pkgname=foo
+pkgver=1.0
+pkgrel=1
+pkgdesc="A \"foo\" tool"
+arch=('x86_64')
+url=https://example.org
+# runtime deps
+depends=('glibc' 'bar' 'baz>=1.0' "lib$pkgname")
+makedepends=('aaaaaaaaaaaaaaaaaaaa'
+             'bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb'
+             'cccccccccccccccccccc'
+             'dddddddddd')
+source=("https://example.org/$pkgname-$pkgver.tar.gz" # release
+        foo.patch)
+
+build() {
+  cd "$srcdir"
+  if [ -f foo ]; then
+    make \
+      V=1
+  else
+    case $CARCH in
+      x86_64)
+        echo 64;;
+      *) echo 32
+    esac
+  fi
+  cat > foo <<EOT
+	  keep
+EOT
+}
+
+package() {
+  make DESTDIR="$pkgdir" install
+}
+`
+
+	formatted, err := Format([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(formatted) != expected {
+		t.Errorf("unexpected formatting:\n%s\nexpected:\n%s", formatted, expected)
+	}
+}
+
+func TestFormatKeepsReferencedOrder(t *testing.T) {
+	input := "pkgver=1.0\n_pkgver=${pkgver//./_}\npkgname=foo\n"
+
+	formatted, err := Format([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(formatted) != input {
+		t.Errorf("unexpected formatting:\n%s", formatted)
+	}
+}
+
+func TestFormatError(t *testing.T) {
+	_, err := Format([]byte("pkgname=(foo\n"))
+	if err == nil {
+		t.Error("expected error for unterminated array")
+	}
+}
@@ -0,0 +1,91 @@
+//go:build go1.16
+// +build go1.16
+
+package pkgbuild
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// ParseSRCINFOFS parses the .SRCINFO file given by name in fsys, e.g. an
+// embed.FS of test fixtures or a zip.Reader.
+func ParseSRCINFOFS(fsys fs.FS, name string, opts ...ParseOption) (*PKGBUILD, error) {
+	config := newParseConfig(opts)
+
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read file: %s, %s", name, err.Error())
+	}
+	defer f.Close()
+
+	content, err := config.read(f)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read file: %s, %s", name, err.Error())
+	}
+
+	// content isn't used by anything else
+	config.zeroCopy = true
+	return parsePKGBUILD(content, config)
+}
+
+// WalkSRCINFOFunc is called by ParseSRCINFODirFS for each .SRCINFO file
+// found with its path and the result of parsing it. Returning an error
+// stops the walk.
+type WalkSRCINFOFunc func(name string, pkgb *PKGBUILD, err error) error
+
+// ParseSRCINFODirFS parses every .SRCINFO file below root in fsys, calling
+// fn for each in lexical order. Hidden directories like .git are skipped.
+// The error returned by fn or by reading a directory is returned. The
+// progress is reported to the ProgressFunc of WithProgress.
+func ParseSRCINFODirFS(fsys fs.FS, root string, fn WalkSRCINFOFunc, opts ...ParseOption) error {
+	return ParseSRCINFODirFSContext(context.Background(), fsys, root, fn, opts...)
+}
+
+// ParseSRCINFODirFSContext is like ParseSRCINFODirFS but stops and returns
+// the error of ctx once ctx is done.
+func ParseSRCINFODirFSContext(ctx context.Context, fsys fs.FS, root string, fn WalkSRCINFOFunc, opts ...ParseOption) error {
+	var names []string
+	err := fs.WalkDir(fsys, root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if name != root && strings.HasPrefix(d.Name(), ".") {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		if path.Base(name) == ".SRCINFO" {
+			names = append(names, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	progress := newParseConfig(opts).progress
+	for i, name := range names {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		progress.report(i, len(names), name)
+		pkgb, err := ParseSRCINFOFS(fsys, name, opts...)
+		if err := fn(name, pkgb, err); err != nil {
+			return err
+		}
+	}
+	progress.report(len(names), len(names), "")
+
+	return nil
+}
@@ -0,0 +1,114 @@
+//go:build go1.16
+// +build go1.16
+
+package pkgbuild
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParseSRCINFOFS(t *testing.T) {
+	expected, err := ParseSRCINFO("./test_pkgbuilds/SRCINFO_linux")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkgb, err := ParseSRCINFOFS(os.DirFS("test_pkgbuilds"), "SRCINFO_linux")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(pkgb, expected) {
+		t.Error("parsing from fs.FS differs from parsing the file")
+	}
+
+	if _, err = ParseSRCINFOFS(os.DirFS("test_pkgbuilds"), "SRCINFO_missing"); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestParseSRCINFODirFS(t *testing.T) {
+	content, err := ioutil.ReadFile("./test_pkgbuilds/SRCINFO_sudo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fsys := fstest.MapFS{
+		"aur/sudo/.SRCINFO":        {Data: content},
+		"aur/sudo/PKGBUILD":        {Data: []byte("pkgname=sudo\n")},
+		"aur/broken/.SRCINFO":      {Data: []byte("pkgname = broken\n")},
+		"aur/.git/foo/.SRCINFO":    {Data: content},
+		"other/linux/.SRCINFO":     {Data: content},
+		"aur/nested/deep/.SRCINFO": {Data: content},
+	}
+
+	parsed := make(map[string]bool)
+	err = ParseSRCINFODirFS(fsys, "aur", func(name string, pkgb *PKGBUILD, err error) error {
+		parsed[name] = err == nil && pkgb.Pkgbase == "sudo"
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]bool{
+		"aur/sudo/.SRCINFO":        true,
+		"aur/broken/.SRCINFO":      false,
+		"aur/nested/deep/.SRCINFO": true,
+	}
+	if !reflect.DeepEqual(parsed, expected) {
+		t.Errorf("expected %v, got %v", expected, parsed)
+	}
+
+	stop := errors.New("stop")
+	err = ParseSRCINFODirFS(fsys, ".", func(name string, pkgb *PKGBUILD, err error) error {
+		return stop
+	})
+	if err != stop {
+		t.Errorf("expected the error of fn, got %v", err)
+	}
+}
+
+func TestParseSRCINFODirFSProgress(t *testing.T) {
+	content, err := ioutil.ReadFile("./test_pkgbuilds/SRCINFO_sudo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fsys := fstest.MapFS{
+		"a/.SRCINFO": {Data: content},
+		"b/.SRCINFO": {Data: content},
+	}
+
+	var reports []string
+	progress := func(done, total int, item string) {
+		reports = append(reports, fmt.Sprintf("%d/%d %s", done, total, item))
+	}
+	err = ParseSRCINFODirFS(fsys, ".", func(name string, pkgb *PKGBUILD, err error) error {
+		return err
+	}, WithProgress(progress))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"0/2 a/.SRCINFO", "1/2 b/.SRCINFO", "2/2 "}
+	if !reflect.DeepEqual(reports, expected) {
+		t.Errorf("expected progress %q, got %q", expected, reports)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = ParseSRCINFODirFSContext(ctx, fsys, ".", func(name string, pkgb *PKGBUILD, err error) error {
+		t.Errorf("unexpected call for %s", name)
+		return nil
+	})
+	if err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
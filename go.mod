@@ -1,3 +1,5 @@
 module github.com/mikkeloscar/gopkgbuild
 
 go 1.15
+
+require golang.org/x/crypto v0.0.0-20210921155107-089bfa567519
@@ -0,0 +1,280 @@
+package pkgbuild
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GoPackage is the kind of package generated for a Go module.
+type GoPackage int
+
+// Go package kinds
+const (
+	GoSource GoPackage = iota // built from the release sources
+	GoBin                     // prebuilt binary, pkgname suffixed with -bin
+	GoGit                     // built from the latest commit, pkgname suffixed with -git
+)
+
+// GoModule is the information about a Go module needed to package it.
+type GoModule struct {
+	Path        string // module path e.g. "github.com/foo/bar/v2"
+	GoVersion   string // go directive of the go.mod, empty if missing
+	LicenseFile string // relative to the module root, empty if missing
+	License     string // SPDX identifier guessed from LicenseFile, empty if unknown
+}
+
+// majorSuffix matches the major version suffix of module paths.
+var majorSuffix = regexp.MustCompile(`[/.]v[0-9]+$`)
+
+// ReadGoModule reads the go.mod and the license file of the module rooted at
+// dir.
+func ReadGoModule(dir string) (*GoModule, error) {
+	content, err := ioutil.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return nil, err
+	}
+
+	m := &GoModule{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+
+		switch fields[0] {
+		case "module":
+			m.Path = fields[1]
+			if unquoted, err := strconv.Unquote(fields[1]); err == nil {
+				m.Path = unquoted
+			}
+		case "go":
+			m.GoVersion = fields[1]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if m.Path == "" {
+		return nil, fmt.Errorf("module path missing: %s", filepath.Join(dir, "go.mod"))
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		name := strings.ToLower(file.Name())
+		if !file.IsDir() && (strings.HasPrefix(name, "license") || strings.HasPrefix(name, "licence") || strings.HasPrefix(name, "copying")) {
+			names = append(names, file.Name())
+		}
+	}
+	sort.Strings(names)
+
+	if len(names) > 0 {
+		m.LicenseFile = names[0]
+		license, err := ioutil.ReadFile(filepath.Join(dir, m.LicenseFile))
+		if err != nil {
+			return nil, err
+		}
+		m.License = guessLicense(string(license))
+	}
+
+	return m, nil
+}
+
+// guessLicense returns the SPDX identifier of the license text, or an empty
+// string if not recognized.
+func guessLicense(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	contains := func(s string) bool {
+		return strings.Contains(text, s)
+	}
+
+	switch {
+	case contains("Apache License") && contains("Version 2.0"):
+		return "Apache-2.0"
+	case contains("Mozilla Public License Version 2.0"):
+		return "MPL-2.0"
+	case contains("GNU LESSER GENERAL PUBLIC LICENSE") && contains("Version 3"):
+		return "LGPL-3.0-or-later"
+	case contains("GNU AFFERO GENERAL PUBLIC LICENSE") && contains("Version 3"):
+		return "AGPL-3.0-or-later"
+	case contains("GNU GENERAL PUBLIC LICENSE") && contains("Version 3"):
+		return "GPL-3.0-or-later"
+	case contains("GNU GENERAL PUBLIC LICENSE") && contains("Version 2"):
+		return "GPL-2.0-or-later"
+	case contains("Permission to use, copy, modify, and/or distribute this software for any purpose"):
+		return "ISC"
+	case contains("Permission is hereby granted, free of charge"):
+		return "MIT"
+	case contains("Redistribution and use in source and binary forms"):
+		if contains("Neither the name") || contains("may be used to endorse or promote") {
+			return "BSD-3-Clause"
+		}
+		return "BSD-2-Clause"
+	case contains("This is free and unencumbered software released into the public domain"):
+		return "Unlicense"
+	}
+	return ""
+}
+
+// Pkgname returns the name of the package of the module, the last element
+// of its path without the major version suffix.
+func (m *GoModule) Pkgname() string {
+	return strings.ToLower(path.Base(majorSuffix.ReplaceAllString(m.Path, "")))
+}
+
+// repository returns the host, owner and name of the repository of the
+// module for the hosts providing source archives, ok is false for other
+// hosts.
+func (m *GoModule) repository() (host, owner, name string, ok bool) {
+	parts := strings.Split(majorSuffix.ReplaceAllString(m.Path, ""), "/")
+	if len(parts) < 3 {
+		return "", "", "", false
+	}
+	switch parts[0] {
+	case "github.com", "gitlab.com":
+		return parts[0], parts[1], parts[2], true
+	}
+	return "", "", "", false
+}
+
+// URL returns the URL of the module, its repository if hosted on GitHub or
+// GitLab.
+func (m *GoModule) URL() string {
+	if host, owner, name, ok := m.repository(); ok {
+		return "https://" + host + "/" + owner + "/" + name
+	}
+	return "https://" + majorSuffix.ReplaceAllString(m.Path, "")
+}
+
+// source returns the source entry of version of the module and the
+// directory it's extracted to as a quoted shell word. Modules not hosted on
+// GitHub or GitLab are downloaded from the Go module proxy.
+func (m *GoModule) source(version Version) (source, srcdir string) {
+	host, owner, name, ok := m.repository()
+	switch {
+	case ok && host == "github.com":
+		return fmt.Sprintf("%s-%s.tar.gz::https://github.com/%s/%s/archive/v%s.tar.gz", name, version, owner, name, version),
+			`"` + name + `-$pkgver"`
+	case ok && host == "gitlab.com":
+		return fmt.Sprintf("https://gitlab.com/%s/%s/-/archive/v%s/%s-v%s.tar.gz", owner, name, version, name, version),
+			`"` + name + `-v$pkgver"`
+	}
+
+	// the module proxy escapes upper case letters as ! followed by the
+	// lower case letter
+	var escaped strings.Builder
+	for _, r := range m.Path {
+		if 'A' <= r && r <= 'Z' {
+			escaped.WriteByte('!')
+			r += 'a' - 'A'
+		}
+		escaped.WriteRune(r)
+	}
+	return fmt.Sprintf("%s-%s.zip::https://proxy.golang.org/%s/@v/v%s.zip", m.Pkgname(), version, escaped.String(), version),
+		`"` + m.Path + `@v$pkgver"`
+}
+
+// NewGoScaffold returns the scaffold of a package of kind for the module m,
+// following the Go package guidelines. Empty options default to the values
+// of the module: pkgname, url, license and the source of the release
+// Pkgver. GoBin packages require Source, the binary to install. GoGit
+// packages get a pkgver() function, and Pkgver defaults to "0".
+func NewGoScaffold(m *GoModule, kind GoPackage, opts ScaffoldOptions) (*Scaffold, error) {
+	name := m.Pkgname()
+	opts.Pkgver = Version(strings.TrimPrefix(string(opts.Pkgver), "v"))
+	if opts.URL == "" {
+		opts.URL = m.URL()
+	}
+	if len(opts.License) == 0 && m.License != "" {
+		opts.License = []string{m.License}
+	}
+
+	var install string
+	if m.LicenseFile != "" {
+		install = `install -Dm644 ` + quoteWord(m.LicenseFile) + ` -t "$pkgdir/usr/share/licenses/$pkgname"` + "\n"
+	}
+
+	template := scaffoldTemplate{
+		arch:        []string{"x86_64"},
+		depends:     []string{"glibc"},
+		makedepends: []string{"go"},
+	}
+
+	var srcdir string
+	switch kind {
+	case GoSource:
+		if opts.Pkgname == "" {
+			opts.Pkgname = name
+		}
+		var source string
+		source, srcdir = m.source(opts.Pkgver)
+		if opts.Source == "" {
+			opts.Source = source
+		}
+	case GoBin:
+		if opts.Pkgname == "" {
+			opts.Pkgname = name + "-bin"
+		}
+		if opts.Source == "" {
+			return nil, fmt.Errorf("source of the %s binary missing", name)
+		}
+		template.depends = nil
+		template.makedepends = nil
+		template.provides = []string{name}
+		template.conflicts = []string{name}
+		template.functions = []*Function{
+			{Name: "package", Body: "\ninstall -Dm755 " + quoteWord(name) + ` -t "$pkgdir/usr/bin"` + "\n"},
+		}
+	case GoGit:
+		if opts.Pkgname == "" {
+			opts.Pkgname = name + "-git"
+		}
+		if opts.Pkgver == "" {
+			opts.Pkgver = "0"
+		}
+		url := opts.URL
+		if _, _, _, ok := m.repository(); ok {
+			url += ".git"
+		}
+		if opts.Source == "" {
+			opts.Source = "git+" + url
+		}
+		srcdir = `"` + Source(opts.Source).FileName() + `"`
+		template.makedepends = append(template.makedepends, "git")
+		template.provides = []string{name}
+		template.conflicts = []string{name}
+		template.functions = []*Function{
+			{Name: "pkgver", Body: `
+cd ` + srcdir + `
+git describe --long --tags --abbrev=7 | sed 's/^v//;s/\([^-]*-g\)/r\1/;s/-/./g'
+`},
+		}
+	default:
+		return nil, fmt.Errorf("unknown Go package kind: %d", kind)
+	}
+
+	if srcdir != "" {
+		functions := goFunctions(srcdir)
+		functions[len(functions)-1].Body += install
+		template.functions = append(template.functions, functions...)
+	}
+
+	return newScaffold(template, opts)
+}
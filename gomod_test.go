@@ -0,0 +1,155 @@
+package pkgbuild
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadGoModule(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gomod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for name, content := range map[string]string{
+		"go.mod":     "// comment\nmodule github.com/Foo/bar/v2 // v2\n\ngo 1.21\n\nrequire (\n\tgolang.org/x/sys v0.1.0\n)\n",
+		"LICENSE.md": "MIT License\n\nPermission is hereby granted, free of charge, to any person\nobtaining a copy",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m, err := ReadGoModule(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &GoModule{
+		Path:        "github.com/Foo/bar/v2",
+		GoVersion:   "1.21",
+		LicenseFile: "LICENSE.md",
+		License:     "MIT",
+	}
+	if !reflect.DeepEqual(m, expected) {
+		t.Errorf("expected %+v, got %+v", expected, m)
+	}
+	if m.Pkgname() != "bar" {
+		t.Errorf("expected pkgname bar, got %s", m.Pkgname())
+	}
+	if m.URL() != "https://github.com/Foo/bar" {
+		t.Errorf("expected url https://github.com/Foo/bar, got %s", m.URL())
+	}
+
+	if _, err := ReadGoModule(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected error for missing go.mod")
+	}
+}
+
+func TestGuessLicense(t *testing.T) {
+	for text, expected := range map[string]string{
+		"Apache License\n   Version 2.0, January 2004":                                     "Apache-2.0",
+		"GNU GENERAL PUBLIC LICENSE\n   Version 3, 29 June 2007":                           "GPL-3.0-or-later",
+		"Redistribution and use in source and binary forms ... Neither the name of":        "BSD-3-Clause",
+		"Redistribution and use in source and binary forms, with or without\nmodification": "BSD-2-Clause",
+		"All rights reserved.": "",
+	} {
+		if license := guessLicense(text); license != expected {
+			t.Errorf("%q: expected %q, got %q", text, expected, license)
+		}
+	}
+}
+
+func TestNewGoScaffold(t *testing.T) {
+	for _, test := range []struct {
+		module  GoModule
+		kind    GoPackage
+		opts    ScaffoldOptions
+		pkgname string
+		source  string
+		srcdir  string
+	}{
+		{
+			GoModule{Path: "github.com/foo/bar/v2", License: "MIT", LicenseFile: "LICENSE"},
+			GoSource,
+			ScaffoldOptions{Pkgver: "v2.1.0"},
+			"bar",
+			"bar-2.1.0.tar.gz::https://github.com/foo/bar/archive/v2.1.0.tar.gz",
+			`cd "bar-$pkgver"`,
+		},
+		{
+			GoModule{Path: "gitlab.com/foo/bar"},
+			GoSource,
+			ScaffoldOptions{Pkgver: "1.0"},
+			"bar",
+			"https://gitlab.com/foo/bar/-/archive/v1.0/bar-v1.0.tar.gz",
+			`cd "bar-v$pkgver"`,
+		},
+		{
+			GoModule{Path: "example.org/Foo"},
+			GoSource,
+			ScaffoldOptions{Pkgver: "1.0"},
+			"foo",
+			"foo-1.0.zip::https://proxy.golang.org/example.org/!foo/@v/v1.0.zip",
+			`cd "example.org/Foo@v$pkgver"`,
+		},
+		{
+			GoModule{Path: "github.com/foo/bar"},
+			GoGit,
+			ScaffoldOptions{},
+			"bar-git",
+			"git+https://github.com/foo/bar.git",
+			`cd "bar"`,
+		},
+		{
+			GoModule{Path: "github.com/foo/bar"},
+			GoBin,
+			ScaffoldOptions{Pkgver: "1.0", Source: "https://github.com/foo/bar/releases/download/v1.0/bar"},
+			"bar-bin",
+			"https://github.com/foo/bar/releases/download/v1.0/bar",
+			`install -Dm755 bar`,
+		},
+	} {
+		s, err := NewGoScaffold(&test.module, test.kind, test.opts)
+		if err != nil {
+			t.Fatalf("%s: %s", test.module.Path, err)
+		}
+
+		var b bytes.Buffer
+		if err := s.WritePKGBUILD(&b); err != nil {
+			t.Fatal(err)
+		}
+
+		f, err := ParseAST(b.Bytes())
+		if err != nil {
+			t.Fatalf("%s: PKGBUILD not parsable: %s\n%s", test.module.Path, err, b.String())
+		}
+		e := NewExpander(f)
+		if pkgname := e.Values("pkgname"); !reflect.DeepEqual(pkgname, []string{test.pkgname}) {
+			t.Errorf("%s: expected pkgname %s, got %q", test.module.Path, test.pkgname, pkgname)
+		}
+		if source := e.Values("source"); !reflect.DeepEqual(source, []string{test.source}) {
+			t.Errorf("%s: expected source %s, got %q", test.module.Path, test.source, source)
+		}
+		if !strings.Contains(b.String(), test.srcdir) {
+			t.Errorf("%s: expected %s in:\n%s", test.module.Path, test.srcdir, b.String())
+		}
+
+		if test.kind != GoSource && !reflect.DeepEqual(e.Values("provides"), []string{"bar"}) {
+			t.Errorf("%s: expected provides bar, got %q", test.module.Path, e.Values("provides"))
+		}
+		if test.module.LicenseFile != "" && !strings.Contains(b.String(), `install -Dm644 LICENSE -t "$pkgdir/usr/share/licenses/$pkgname"`) {
+			t.Errorf("%s: license not installed:\n%s", test.module.Path, b.String())
+		}
+	}
+
+	if _, err := NewGoScaffold(&GoModule{Path: "github.com/foo/bar"}, GoBin, ScaffoldOptions{Pkgver: "1.0"}); err == nil {
+		t.Error("expected error for GoBin without source")
+	}
+}
@@ -0,0 +1,89 @@
+package pkgbuild
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+)
+
+// InstallHooks are the functions of an install script pacman runs, in the
+// order of a package's lifecycle.
+var InstallHooks = []string{
+	"pre_install",
+	"post_install",
+	"pre_upgrade",
+	"post_upgrade",
+	"pre_remove",
+	"post_remove",
+}
+
+// InstallScript is a parsed install script like foo.install, referenced by
+// the install variable of a PKGBUILD.
+type InstallScript struct {
+	File  *File
+	Hooks map[string]*Function // the defined hooks by name e.g. "post_install"
+}
+
+// ParseInstallScript parses the install script content.
+func ParseInstallScript(content []byte) (*InstallScript, error) {
+	f, err := ParseAST(content)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &InstallScript{File: f, Hooks: make(map[string]*Function)}
+	for _, name := range InstallHooks {
+		if fn := f.Function(name); fn != nil {
+			s.Hooks[name] = fn
+		}
+	}
+	return s, nil
+}
+
+// ParseInstallScriptFile parses the install script file given by path.
+func ParseInstallScriptFile(path string) (*InstallScript, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read file: %s, %s", path, err.Error())
+	}
+
+	return ParseInstallScript(content)
+}
+
+// InstallScript parses the install script of p in the package directory
+// dir. nil is returned if p has no install script.
+func (p *PKGBUILD) InstallScript(dir string) (*InstallScript, error) {
+	if p.Install == "" {
+		return nil, nil
+	}
+	return ParseInstallScriptFile(filepath.Join(dir, p.Install))
+}
+
+// HasHook reports whether s defines the hook name e.g. "post_upgrade".
+func (s *InstallScript) HasHook(name string) bool {
+	return s.Hooks[name] != nil
+}
+
+// DefinedHooks returns the names of the hooks s defines in the order of
+// InstallHooks.
+func (s *InstallScript) DefinedHooks() []string {
+	var hooks []string
+	for _, name := range InstallHooks {
+		if s.HasHook(name) {
+			hooks = append(hooks, name)
+		}
+	}
+	return hooks
+}
+
+// Helpers returns the functions of s which are not hooks, e.g. functions
+// called by the hooks or hooks with a misspelled name.
+func (s *InstallScript) Helpers() []*Function {
+	var helpers []*Function
+	for _, fn := range s.File.Functions() {
+		if !contains(InstallHooks, fn.Name) {
+			helpers = append(helpers, fn)
+		}
+	}
+	return helpers
+}
@@ -0,0 +1,83 @@
+package pkgbuild
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testInstallScript = `# arg 1:  the new package version
+post_install() {
+  echo "run foo-setup"
+  _reload
+}
+
+post_upgrade() {
+  post_install
+}
+
+_reload() {
+  systemctl daemon-reload
+}
+
+pre_remvoe() {
+  :
+}
+`
+
+func TestParseInstallScript(t *testing.T) {
+	s, err := ParseInstallScript([]byte(testInstallScript))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if hooks := s.DefinedHooks(); !reflect.DeepEqual(hooks, []string{"post_install", "post_upgrade"}) {
+		t.Errorf("unexpected hooks %v", hooks)
+	}
+	if !s.HasHook("post_upgrade") || s.HasHook("pre_remove") {
+		t.Error("unexpected HasHook")
+	}
+	if body := s.Hooks["post_upgrade"].Body; strings.TrimSpace(body) != "post_install" {
+		t.Errorf("unexpected body %q", body)
+	}
+
+	var helpers []string
+	for _, fn := range s.Helpers() {
+		helpers = append(helpers, fn.Name)
+	}
+	if !reflect.DeepEqual(helpers, []string{"_reload", "pre_remvoe"}) {
+		t.Errorf("unexpected helpers %v", helpers)
+	}
+}
+
+func TestPKGBUILDInstallScript(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopkgbuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	p := &PKGBUILD{}
+	if s, err := p.InstallScript(dir); s != nil || err != nil {
+		t.Errorf("expected no install script, got %v, %v", s, err)
+	}
+
+	p.Install = "foo.install"
+	if _, err = p.InstallScript(dir); err == nil {
+		t.Error("expected error for missing install script")
+	}
+
+	if err = ioutil.WriteFile(filepath.Join(dir, "foo.install"), []byte(testInstallScript), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := p.InstallScript(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.HasHook("post_install") {
+		t.Error("expected post_install hook")
+	}
+}
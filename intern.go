@@ -0,0 +1,49 @@
+package pkgbuild
+
+import "sync"
+
+// Interner deduplicates strings shared by many parsed PKGBUILDs, like arch
+// and license names or common dependencies. Values interned are copied so
+// they don't keep the parsed input alive. Share one Interner between the
+// parses of a batch with WithInterner. It's safe for concurrent use.
+//
+// Strings are never evicted, so the parser only interns the names of known
+// variables and the values of arch, license, groups and the dependency
+// variables like depends and provides, not unique ones like checksums or
+// sources.
+type Interner struct {
+	mu      sync.RWMutex
+	strings map[string]string
+}
+
+// NewInterner returns an empty Interner.
+func NewInterner() *Interner {
+	return &Interner{strings: make(map[string]string)}
+}
+
+// Intern returns the string equal to s held by in, adding a copy of s if
+// there's none yet.
+func (in *Interner) Intern(s string) string {
+	in.mu.RLock()
+	interned, ok := in.strings[s]
+	in.mu.RUnlock()
+	if ok {
+		return interned
+	}
+
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if interned, ok := in.strings[s]; ok {
+		return interned
+	}
+	interned = string(append([]byte(nil), s...))
+	in.strings[interned] = interned
+	return interned
+}
+
+// Len returns the number of strings held by in.
+func (in *Interner) Len() int {
+	in.mu.RLock()
+	defer in.mu.RUnlock()
+	return len(in.strings)
+}
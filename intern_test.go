@@ -0,0 +1,86 @@
+package pkgbuild
+
+import (
+	"io/ioutil"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestInterner(t *testing.T) {
+	in := NewInterner()
+
+	input := "x86_64 any"
+	a := in.Intern(input[:6])
+	b := in.Intern(string([]byte("x86_64")))
+	if a != "x86_64" || b != a || in.Len() != 1 {
+		t.Errorf("expected one interned string, got %q %q (%d)", a, b, in.Len())
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			in.Intern("any")
+		}()
+	}
+	wg.Wait()
+	if in.Len() != 2 {
+		t.Errorf("expected 2 interned strings, got %d", in.Len())
+	}
+}
+
+func TestParseWithInterner(t *testing.T) {
+	content, err := ioutil.ReadFile("./test_pkgbuilds/SRCINFO_linux")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := ParseSRCINFOContent(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := NewInterner()
+	pkgb, err := ParseSRCINFOContent(content, WithInterner(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(pkgb, expected) {
+		t.Error("interning changed the parsed PKGBUILD")
+	}
+
+	n := in.Len()
+	if n == 0 {
+		t.Fatal("expected interned strings")
+	}
+
+	if _, err = ParseSRCINFOContent(content, WithInterner(in)); err != nil {
+		t.Fatal(err)
+	}
+	if in.Len() != n {
+		t.Errorf("expected parsing again to reuse the %d interned strings, got %d", n, in.Len())
+	}
+
+	// only common values are interned, unique ones like sources are not
+	if in.Intern(pkgb.Arch[0]); in.Len() != n {
+		t.Errorf("expected arch %s to be interned", pkgb.Arch[0])
+	}
+	if in.Intern(pkgb.Source[0]); in.Len() != n+1 {
+		t.Errorf("expected source %s not to be interned", pkgb.Source[0])
+	}
+}
+
+func BenchmarkParseSRCINFOWithInterner(b *testing.B) {
+	content, err := ioutil.ReadFile("./test_pkgbuilds/SRCINFO_linux")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	in := NewInterner()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ParseSRCINFOContent(content, WithInterner(in))
+	}
+}
@@ -0,0 +1,214 @@
+package pkgbuild
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+)
+
+// DefaultKeyservers are the keyservers FetchKey uses if none are given.
+var DefaultKeyservers = []string{
+	"hkps://keyserver.ubuntu.com",
+	"hkps://keys.openpgp.org",
+}
+
+// MissingKeys returns the fingerprints listed in validpgpkeys of p without
+// a public key in keyrings, i.e. the keys needed before the signatures of
+// the sources can be verified.
+func (p *PKGBUILD) MissingKeys(keyrings ...io.Reader) ([]string, error) {
+	found := make(map[string]bool)
+	for _, r := range keyrings {
+		entities, err := readKeyRing(r)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read keyring: %s", err.Error())
+		}
+		for _, entity := range entities {
+			found[fmt.Sprintf("%X", entity.PrimaryKey.Fingerprint)] = true
+		}
+	}
+
+	var missing []string
+	for _, key := range p.Validpgpkeys {
+		if !found[normalizeFingerprint(key)] {
+			missing = append(missing, key)
+		}
+	}
+	return missing, nil
+}
+
+// FetchKey fetches the public key with fingerprint from the keyservers,
+// tried in order, and returns it armored, e.g. to be stored as
+// keys/pgp/<fingerprint>.asc. Keyservers are URLs with the scheme hkps,
+// hkp, https or http. The key is only returned if its fingerprint matches.
+func FetchKey(ctx context.Context, fingerprint string, keyservers ...string) ([]byte, error) {
+	if len(keyservers) == 0 {
+		keyservers = DefaultKeyservers
+	}
+
+	fingerprint = normalizeFingerprint(fingerprint)
+
+	urls := make([]string, 0, len(keyservers))
+	for _, keyserver := range keyservers {
+		u, err := keyserverURL(keyserver)
+		if err != nil {
+			return nil, err
+		}
+		u.Path = "/pks/lookup"
+		u.RawQuery = url.Values{
+			"op":      {"get"},
+			"options": {"mr"},
+			"search":  {"0x" + fingerprint},
+		}.Encode()
+		urls = append(urls, u.String())
+	}
+
+	return fetchKey(ctx, fingerprint, urls)
+}
+
+// FetchKeyWKD fetches the public key of email with fingerprint using the
+// Web Key Directory of the domain of email and returns it armored.
+func FetchKeyWKD(ctx context.Context, email, fingerprint string) ([]byte, error) {
+	urls, err := wkdURLs(email)
+	if err != nil {
+		return nil, err
+	}
+	return fetchKey(ctx, normalizeFingerprint(fingerprint), urls)
+}
+
+// keyserverURL returns the HTTP URL of keyserver.
+func keyserverURL(keyserver string) (*url.URL, error) {
+	u, err := url.Parse(keyserver)
+	if err != nil {
+		return nil, err
+	}
+
+	switch u.Scheme {
+	case "hkps":
+		u.Scheme = "https"
+	case "hkp":
+		u.Scheme = "http"
+		if u.Port() == "" {
+			u.Host += ":11371"
+		}
+	case "https", "http":
+	default:
+		return nil, fmt.Errorf("unsupported keyserver: %s", keyserver)
+	}
+
+	return u, nil
+}
+
+// wkdURLs returns the advanced and direct Web Key Directory URLs of email.
+func wkdURLs(email string) ([]string, error) {
+	i := strings.LastIndex(email, "@")
+	if i <= 0 || i == len(email)-1 {
+		return nil, fmt.Errorf("invalid email address: %s", email)
+	}
+
+	local, domain := email[:i], strings.ToLower(email[i+1:])
+	sum := sha1.Sum([]byte(strings.ToLower(local)))
+	hash := zbase32(sum[:])
+	query := "?l=" + url.QueryEscape(local)
+
+	return []string{
+		"https://openpgpkey." + domain + "/.well-known/openpgpkey/" + domain + "/hu/" + hash + query,
+		"https://" + domain + "/.well-known/openpgpkey/hu/" + hash + query,
+	}, nil
+}
+
+// zbase32 encodes data with the z-base-32 encoding used by Web Key
+// Directory.
+func zbase32(data []byte) string {
+	const alphabet = "ybndrfg8ejkmcpqxot1uwisza345h769"
+
+	var b strings.Builder
+	var buffer uint
+	bits := 0
+	for _, c := range data {
+		buffer = buffer<<8 | uint(c)
+		bits += 8
+		for bits >= 5 {
+			bits -= 5
+			b.WriteByte(alphabet[buffer>>uint(bits)&31])
+		}
+	}
+	if bits > 0 {
+		b.WriteByte(alphabet[buffer<<uint(5-bits)&31])
+	}
+	return b.String()
+}
+
+// fetchKey fetches the key with fingerprint from the first of urls which
+// has it and returns it armored.
+func fetchKey(ctx context.Context, fingerprint string, urls []string) ([]byte, error) {
+	var errs []string
+
+	for _, u := range urls {
+		key, err := fetchKeyURL(ctx, fingerprint, u)
+		if err == nil {
+			return key, nil
+		}
+		errs = append(errs, err.Error())
+	}
+
+	return nil, fmt.Errorf("unable to fetch key %s: %s", fingerprint, strings.Join(errs, "; "))
+}
+
+// fetchKeyURL fetches the key with fingerprint from u and returns it
+// armored. Other keys returned by u are left out.
+func fetchKeyURL(ctx context.Context, fingerprint, u string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", u, resp.Status)
+	}
+
+	content, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", u, err)
+	}
+
+	entities, err := readKeyRing(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", u, err)
+	}
+
+	for _, entity := range entities {
+		if fmt.Sprintf("%X", entity.PrimaryKey.Fingerprint) != fingerprint {
+			continue
+		}
+
+		var b bytes.Buffer
+		w, err := armor.Encode(&b, openpgp.PublicKeyType, nil)
+		if err != nil {
+			return nil, err
+		}
+		if err = entity.Serialize(w); err != nil {
+			return nil, err
+		}
+		if err = w.Close(); err != nil {
+			return nil, err
+		}
+		return b.Bytes(), nil
+	}
+
+	return nil, fmt.Errorf("%s: no key with fingerprint %s", u, fingerprint)
+}
@@ -0,0 +1,97 @@
+package pkgbuild
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+func TestFetchKey(t *testing.T) {
+	config := &packet.Config{RSABits: 1024}
+	key, err := openpgp.NewEntity("test", "", "test@example.org", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := openpgp.NewEntity("other", "", "other@example.org", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fingerprint := fmt.Sprintf("%X", key.PrimaryKey.Fingerprint)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/pks/lookup" || r.URL.Query().Get("search") != "0x"+fingerprint {
+			http.NotFound(w, r)
+			return
+		}
+		other.Serialize(w)
+		key.Serialize(w)
+	}))
+	defer server.Close()
+
+	pkg := &PKGBUILD{Validpgpkeys: []string{fingerprint}}
+
+	missing, err := pkg.MissingKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(missing, []string{fingerprint}) {
+		t.Errorf("expected missing key %s, got %v", fingerprint, missing)
+	}
+
+	armored, err := FetchKey(context.Background(), fingerprint, "hkps://127.0.0.1:1", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entities, err := readKeyRing(bytes.NewReader(armored))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entities) != 1 {
+		t.Errorf("expected only the requested key, got %d keys", len(entities))
+	}
+
+	missing, err = pkg.MissingKeys(bytes.NewReader(armored))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(missing) != 0 {
+		t.Errorf("expected no missing keys, got %v", missing)
+	}
+
+	otherFingerprint := fmt.Sprintf("%X", other.PrimaryKey.Fingerprint)
+	if _, err = FetchKey(context.Background(), otherFingerprint, server.URL); err == nil {
+		t.Error("expected error for a key not on the keyserver")
+	}
+
+	if _, err = FetchKey(context.Background(), fingerprint, "ftp://example.org"); err == nil {
+		t.Error("expected error for an unsupported keyserver")
+	}
+}
+
+func TestWKDURLs(t *testing.T) {
+	// example from the Web Key Directory draft
+	urls, err := wkdURLs("Joe.Doe@Example.ORG")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"https://openpgpkey.example.org/.well-known/openpgpkey/example.org/hu/iy9q119eutrkn8s1mk4r39qejnbu3n5q?l=Joe.Doe",
+		"https://example.org/.well-known/openpgpkey/hu/iy9q119eutrkn8s1mk4r39qejnbu3n5q?l=Joe.Doe",
+	}
+	if !reflect.DeepEqual(urls, expected) {
+		t.Errorf("expected %v, got %v", expected, urls)
+	}
+
+	if _, err = wkdURLs("example.org"); err == nil {
+		t.Error("expected error for an invalid email address")
+	}
+}
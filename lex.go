@@ -7,19 +7,53 @@
 package pkgbuild
 
 import (
+	"bytes"
 	"fmt"
 	"strings"
 	"unicode"
 	"unicode/utf8"
+	"unsafe"
 )
 
 // pos is a position in input being scanned
 type pos int
 
 type item struct {
-	typ itemType
-	pos pos
-	val string
+	typ  itemType
+	pos  pos
+	val  string
+	line int // line number at the start of the item
+}
+
+// Position describes a location in the parsed input.
+type Position struct {
+	Offset int // byte offset, starting at 0
+	Line   int // line number, starting at 1
+	Column int // column number in bytes, starting at 1
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// ParseError is an error at a specific position in the parsed input.
+type ParseError struct {
+	Pos Position
+	Msg string
+	Err error // cause, use errors.Is or errors.As to inspect it
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// errorf returns an error item at the position of i.
+func (i item) errorf(format string, args ...interface{}) item {
+	return item{itemError, i.pos, fmt.Sprintf(format, args...), i.line}
 }
 
 func (i item) String() string {
@@ -36,12 +70,43 @@ func (i item) String() string {
 
 type itemType int
 
+// isVariable reports whether t is the type of a PKGBUILD variable, including
+// variables unknown to the lexer.
+func (t itemType) isVariable() bool {
+	return t == itemVariable || t >= itemPkgname
+}
+
+// isInterned reports whether the values of the variable type t are
+// interned with an Interner, those commonly shared between packages.
+func (t itemType) isInterned() bool {
+	switch t {
+	case itemArch, itemLicense, itemGroups, itemDepends, itemOptdepends,
+		itemMakedepends, itemCheckdepends, itemProvides, itemConflicts, itemReplaces:
+		return true
+	}
+	return false
+}
+
+// isArchSpecific reports whether the variable type t can be arch specific
+// like source_x86_64.
+func (t itemType) isArchSpecific() bool {
+	switch t {
+	case itemDepends, itemOptdepends, itemMakedepends, itemCheckdepends,
+		itemProvides, itemConflicts, itemReplaces, itemSource, itemMd5sums,
+		itemSha1sums, itemSha224sums, itemSha256sums, itemSha384sums,
+		itemSha512sums, itemB2sums:
+		return true
+	}
+	return false
+}
+
 const (
 	itemError itemType = iota
 	itemEOF
 	itemVariable
 	itemValue
 	itemEndSplit
+	itemComment
 	// PKGBUILD variables
 	itemPkgname      // pkgname variable
 	itemPkgver       // pkgver variable
@@ -115,18 +180,31 @@ var variables = map[string]itemType{
 
 const eof = -1
 
+// bom is the UTF-8 byte order mark, skipped if found at the start of input
+const bom = '\uFEFF'
+
 // stateFn represents the state of the scanner as a function that returns the next state
 type stateFn func(*lexer) stateFn
 
 // lexer holds the state of the scanner
 type lexer struct {
-	input   string
-	state   stateFn
-	pos     pos
-	start   pos
-	width   pos
-	lastPos pos
-	items   chan item // channel of scanned items
+	input     []byte // scanned
+	src       string // input as string, the values of items are sliced from it
+	state     stateFn
+	pos       pos
+	start     pos
+	width     pos
+	lastPos   pos
+	line      int    // line number of pos
+	startLine int    // line number of start
+	lastLine  int    // line number of the last item returned by nextItem
+	items     []item // scanned items not yet returned by nextItem
+
+	maxTokenLength  int             // max length of variables and values, 0 for no limit
+	continueOnError bool            // skip to the next line instead of stopping on errors
+	fields          map[string]bool // variables to emit, nil for all
+	interner        *Interner       // interns variables and common values if not nil
+	variable        itemType        // type of the last variable returned by nextItem
 }
 
 // next returns the next rune in the input
@@ -135,9 +213,12 @@ func (l *lexer) next() rune {
 		l.width = 0
 		return eof
 	}
-	r, w := utf8.DecodeRuneInString(l.input[l.pos:])
+	r, w := utf8.DecodeRune(l.input[l.pos:])
 	l.width = pos(w)
 	l.pos += l.width
+	if r == '\n' {
+		l.line++
+	}
 	return r
 }
 
@@ -151,45 +232,139 @@ func (l *lexer) peek() rune {
 // backup steps back one rune. Can only be called once per call of next
 func (l *lexer) backup() {
 	l.pos -= l.width
+	if l.width == 1 && l.input[l.pos] == '\n' {
+		l.line--
+	}
+}
+
+// isLineEnd reports whether r, the rune just scanned, ends the current line.
+// A carriage return only ends the line if it's followed by a newline or the
+// end of input, such that CRLF line endings never end up in values.
+func (l *lexer) isLineEnd(r rune) bool {
+	switch r {
+	case '\n':
+		return true
+	case '\r':
+		rest := l.input[l.pos:]
+		return len(rest) == 0 || rest[0] == '\n'
+	}
+	return false
+}
+
+// skipLineEnd consumes a "\n" or "\r\n" line ending if it's next in the input.
+func (l *lexer) skipLineEnd() bool {
+	switch {
+	case bytes.HasPrefix(l.input[l.pos:], []byte("\n")):
+		l.next()
+	case bytes.HasPrefix(l.input[l.pos:], []byte("\r\n")):
+		l.next()
+		l.next()
+	default:
+		return false
+	}
+	return true
 }
 
-// emit passes an item back to the client
+// emit queues an item to be passed back to the client
 func (l *lexer) emit(t itemType) {
-	l.items <- item{t, l.start, l.input[l.start:l.pos]}
+	l.items = append(l.items, item{t, l.start, l.src[l.start:l.pos], l.startLine})
 	l.start = l.pos
+	l.startLine = l.line
 }
 
 // ignore skips over the pending input before this point
 func (l *lexer) ignore() {
 	l.start = l.pos
+	l.startLine = l.line
 }
 
 // errorf returns an error token and terminates the scan by passing
 // back a nil pointer that will be the next state, terminating l.nextItem.
+// If continueOnError is set the rest of the line is skipped and the scan
+// continues instead.
 func (l *lexer) errorf(format string, args ...interface{}) stateFn {
-	l.items <- item{itemError, l.start, fmt.Sprintf(format, args...)}
-	return nil
+	l.items = append(l.items, item{itemError, l.start, fmt.Sprintf(format, args...), l.startLine})
+	if !l.continueOnError {
+		return nil
+	}
+
+	for r := l.next(); r != eof; r = l.next() {
+		if l.isLineEnd(r) {
+			l.backup()
+			break
+		}
+	}
+	l.ignore()
+	return lexEnv
 }
 
-// nextItem returns the next item from the input.
+// nextItem returns the next item from the input. The state functions are run
+// until an item is available, once the scan has terminated itemEOF is
+// returned.
 func (l *lexer) nextItem() item {
-	item := <-l.items
+	for len(l.items) == 0 {
+		if l.state == nil {
+			return item{itemEOF, l.pos, "", l.line}
+		}
+		l.state = l.state(l)
+	}
+
+	item := l.items[0]
+	l.items = append(l.items[:0], l.items[1:]...)
+
+	if l.maxTokenLength > 0 && item.typ != itemError && len(item.val) > l.maxTokenLength {
+		// terminate the scan
+		item = item.errorf("token exceeds max length of %d bytes", l.maxTokenLength)
+		l.items = nil
+		l.state = nil
+	} else if item.typ.isVariable() {
+		l.variable = item.typ
+		// names of unknown variables are read from the input
+		if l.interner != nil && item.typ != itemVariable {
+			item.val = l.interner.Intern(item.val)
+		}
+	} else if l.interner != nil && item.typ == itemValue && l.variable.isInterned() {
+		item.val = l.interner.Intern(item.val)
+	}
 	l.lastPos = item.pos
+	l.lastLine = item.line
 	return item
 }
 
-func lex(input string) *lexer {
-	l := &lexer{
-		input: input,
-		items: make(chan item),
+// position returns the Position of offset p at line number line.
+func (l *lexer) position(p pos, line int) Position {
+	return Position{
+		Offset: int(p),
+		Line:   line,
+		Column: int(p) - bytes.LastIndexByte(l.input[:p], '\n'),
 	}
-	go l.run()
-	return l
 }
 
-func (l *lexer) run() {
-	for l.state = lexEnv; l.state != nil; {
-		l.state = l.state(l)
+// errorAt returns err as a ParseError at the position of the last item
+// returned by nextItem.
+func (l *lexer) errorAt(err error) error {
+	return &ParseError{
+		Pos: l.position(l.lastPos, l.lastLine),
+		Msg: err.Error(),
+		Err: err,
+	}
+}
+
+// lex returns a lexer scanning input. The values of the items reference
+// input directly if zeroCopy is set, input must then not be modified while
+// they're in use. Otherwise input is copied once.
+func lex(input []byte, zeroCopy bool) *lexer {
+	src := bytesString(input)
+	if !zeroCopy {
+		src = string(input)
+	}
+
+	return &lexer{
+		input:     input,
+		src:       src,
+		state:     lexEnv,
+		line:      1,
+		startLine: 1,
 	}
 }
 
@@ -203,10 +378,8 @@ func lexEnv(l *lexer) stateFn {
 		case isAlphaNumericUnderscore(r):
 			return lexVariable
 		case r == '\n':
-			buffer := l.input[l.start:l.pos]
-			if buffer == "\n" {
-				if l.peek() == '\n' {
-					l.next()
+			if l.pos-l.start == 1 {
+				if l.skipLineEnd() {
 					l.emit(itemEndSplit)
 				}
 				l.ignore()
@@ -215,21 +388,31 @@ func lexEnv(l *lexer) stateFn {
 			l.ignore()
 		case r == ' ':
 			l.ignore()
+		case r == '\r' && l.isLineEnd(r):
+			l.ignore()
+		case r == bom && l.start == 0:
+			l.ignore()
 		case r == '#':
 			return lexComment
 		default:
-			l.errorf("unable to parse character: %c", r)
+			return l.errorf("unable to parse character: %c", r)
 		}
 	}
 }
 
+// lexComment scans a comment line. Only whole line comments are supported as
+// '#' is a valid character in values e.g. in source fragments.
 func lexComment(l *lexer) stateFn {
 	for {
-		switch l.next() {
-		case '\n':
+		switch r := l.next(); {
+		case l.isLineEnd(r):
+			l.backup()
+			l.emit(itemComment)
+			l.skipLineEnd()
 			l.ignore()
 			return lexEnv
-		case eof:
+		case r == eof:
+			l.emit(itemComment)
 			l.emit(itemEOF)
 			return nil
 		}
@@ -243,7 +426,7 @@ func lexVariable(l *lexer) stateFn {
 			// absorb
 		case r == ' ' && l.peek() == '=':
 			l.backup()
-			variable := l.input[l.start:l.pos]
+			variable := l.src[l.start:l.pos]
 
 			// strip arch from source_arch like constructs
 			witharch := strings.SplitN(variable, "_", 2)
@@ -251,34 +434,73 @@ func lexVariable(l *lexer) stateFn {
 				variable = witharch[0]
 			}
 
-			if _, ok := variables[variable]; ok {
-				l.emit(variables[variable])
-				// TODO to cut off ' = '
-				l.next()
-				l.next()
+			// variables unknown to the lexer, e.g. ones added by
+			// newer versions of makepkg, are emitted as generic
+			// variables
+			typ, ok := variables[variable]
+			if !ok {
+				typ = itemVariable
+				variable = l.src[l.start:l.pos]
+			}
+
+			if l.fields != nil && !l.fields[variable] {
+				return lexSkipLine
+			}
+
+			l.emit(typ)
+			// cut off ' = ', the trailing space is missing for
+			// blank values
+			l.next()
+			l.next()
+			if l.peek() == ' ' {
 				l.next()
-				l.ignore()
-				return lexValue
 			}
-			return l.errorf("invalid variable: %s", variable)
+			l.ignore()
+			return lexValue
 		default:
-			pattern := l.input[l.start:l.pos]
+			pattern := l.src[l.start:l.pos]
 			return l.errorf("invalid pattern: %s", pattern)
 		}
 	}
 }
 
+// lexSkipLine skips the rest of the line, e.g. the assignment of a variable
+// which isn't wanted.
+func lexSkipLine(l *lexer) stateFn {
+	for {
+		switch r := l.next(); {
+		case l.isLineEnd(r):
+			l.backup()
+			l.ignore()
+			return lexEnv
+		case r == eof:
+			l.ignore()
+			return lexEnv
+		}
+	}
+}
+
+// lexValue scans the value of a variable up to the end of the line. The value
+// is empty for blank assignments like 'pkgdesc ='.
 func lexValue(l *lexer) stateFn {
 	for {
-		switch l.next() {
-		case '\n':
+		switch r := l.next(); {
+		case l.isLineEnd(r):
 			l.backup()
 			l.emit(itemValue)
 			return lexEnv
+		case r == eof:
+			l.emit(itemValue)
+			return lexEnv
 		}
 	}
 }
 
+// bytesString returns b as a string without copying it.
+func bytesString(b []byte) string {
+	return *(*string)(unsafe.Pointer(&b))
+}
+
 // isAlphaNumericUnderscore reports whether r is an alphabetic, digit, or underscore.
 func isAlphaNumericUnderscore(r rune) bool {
 	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
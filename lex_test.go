@@ -0,0 +1,122 @@
+package pkgbuild
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestLexItems(t *testing.T) {
+	input := "pkgbase = foo\n\tpkgver = 1.0\n\npkgname = foo\n"
+	expected := []item{
+		{itemPkgbase, 0, "pkgbase", 1},
+		{itemValue, 10, "foo", 1},
+		{itemPkgver, 15, "pkgver", 2},
+		{itemValue, 24, "1.0", 2},
+		{itemEndSplit, 27, "\n\n", 2},
+		{itemPkgname, 29, "pkgname", 4},
+		{itemValue, 39, "foo", 4},
+		{itemEOF, 43, "", 5},
+	}
+
+	l := lex([]byte(input), false)
+	for _, e := range expected {
+		i := l.nextItem()
+		if i != e {
+			t.Errorf("expected item %+v, got %+v", e, i)
+		}
+	}
+}
+
+func TestLexNoGoroutineLeak(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	for i := 0; i < 100; i++ {
+		_, err := ParseSRCINFOContent([]byte("pkgbase = foo\n\tfoo = bar\n"))
+		if err == nil {
+			t.Fatal("expected invalid variable error")
+		}
+	}
+
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("lexer leaked %d goroutines", after-before)
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tokens, err := Tokenize("pkgbase = foo\n\tsource_x86_64 = foo.tar.gz\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Token{
+		{TokenVariable, Position{0, 1, 1}, "pkgbase"},
+		{TokenValue, Position{10, 1, 11}, "foo"},
+		{TokenVariable, Position{15, 2, 2}, "source_x86_64"},
+		{TokenValue, Position{31, 2, 18}, "foo.tar.gz"},
+	}
+
+	if len(tokens) != len(expected) {
+		t.Fatalf("expected %d tokens, got %d: %v", len(expected), len(tokens), tokens)
+	}
+
+	for i, token := range tokens {
+		if token != expected[i] {
+			t.Errorf("expected token %v, got %v", expected[i], token)
+		}
+	}
+
+	_, err = Tokenize("pkgbase = foo\n\t=\n")
+	if err == nil {
+		t.Error("expected error for invalid input")
+	}
+}
+
+func TestParseErrorPosition(t *testing.T) {
+	_, err := ParseSRCINFOContent([]byte("pkgbase = foo\n\tpkgver = 1.0\n\tepoch = x\n"))
+	perr, ok := err.(*ParseError)
+	if !ok {
+		t.Fatalf("expected a *ParseError, got %v", err)
+	}
+
+	expected := Position{Offset: 37, Line: 3, Column: 10}
+	if perr.Pos != expected {
+		t.Errorf("expected error at %+v, got %+v", expected, perr.Pos)
+	}
+}
+
+func TestLexCRLFAndBOM(t *testing.T) {
+	input := "\uFEFF# comment\r\npkgbase = foo\r\n\tpkgver = 1.0\r\n\tpkgrel = 1\r\n\tpkgdesc =\r\n\tarch = any\r\n\r\npkgname = foo\r\n"
+
+	pkg, err := ParseSRCINFOContent([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pkg.Pkgbase != "foo" || pkg.Pkgver != "1.0" || pkg.Pkgdesc != "" || pkg.Arch[0] != "any" {
+		t.Errorf("unexpected values: %+v", pkg)
+	}
+
+	if len(pkg.Comments) != 1 || pkg.Comments[0] != "comment" {
+		t.Errorf("unexpected comments: %q", pkg.Comments)
+	}
+
+	tokens, err := Tokenize(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	splits := 0
+	for _, token := range tokens {
+		if strings.Contains(token.Value, "\r") && token.Type != TokenEndSplit {
+			t.Errorf("token %v contains a carriage return", token)
+		}
+		if token.Type == TokenEndSplit {
+			splits++
+		}
+	}
+
+	if splits != 1 {
+		t.Errorf("expected 1 end split, got %d", splits)
+	}
+}
@@ -0,0 +1,143 @@
+package pkgbuild
+
+import "fmt"
+
+// Severity is the severity of a lint issue.
+type Severity int
+
+// Severities
+const (
+	SeverityInfo    Severity = iota // a suggestion
+	SeverityWarning                 // likely a mistake
+	SeverityError                   // makepkg will fail or build the wrong thing
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// MarshalText implements encoding.TextMarshaler, e.g. for JSON output.
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Issue is a problem found by Lint.
+type Issue struct {
+	Severity Severity `json:"severity"`
+	Field    string   `json:"field,omitempty"` // variable the issue is about, if any
+	Message  string   `json:"message"`
+}
+
+func (i Issue) String() string {
+	if i.Field == "" {
+		return i.Severity.String() + ": " + i.Message
+	}
+	return i.Severity.String() + ": " + i.Field + ": " + i.Message
+}
+
+// weakChecksums are the algorithms makepkg no longer recommends.
+var weakChecksums = map[string]bool{
+	"md5":  true,
+	"sha1": true,
+}
+
+// Lint checks p for common mistakes, like checksum arrays not matching the
+// sources or missing recommended variables. Invalid required variables are
+// reported first, the other issues follow the order of the variables.
+func Lint(p *PKGBUILD) []Issue {
+	var issues []Issue
+	add := func(severity Severity, field, format string, args ...interface{}) {
+		issues = append(issues, Issue{severity, field, fmt.Sprintf(format, args...)})
+	}
+
+	for _, err := range p.validate() {
+		add(SeverityError, "", "%s", err)
+	}
+
+	if p.Pkgdesc == "" {
+		add(SeverityWarning, "pkgdesc", "missing package description")
+	}
+
+	if len(p.Arch) > 1 && contains(p.Arch, "any") {
+		add(SeverityError, "arch", "any can't be combined with other architectures")
+	}
+
+	for _, arch := range p.Arch {
+		if _, err := ParseArch(arch); err != nil {
+			add(SeverityError, "arch", "%s", err)
+		}
+	}
+
+	if p.URL == "" {
+		add(SeverityInfo, "url", "missing upstream URL")
+	}
+
+	if len(p.License) == 0 {
+		add(SeverityWarning, "license", "missing license")
+	}
+
+	lintDependencies(p.Depends, "depends", add)
+	lintDependencies(p.Makedepends, "makedepends", add)
+	lintDependencies(p.Checkdepends, "checkdepends", add)
+
+	lintOptions(p.Options, add)
+
+	lintVCS(p, add)
+
+	common := p.archIndependent()
+	lintChecksums(common.Source, common.sums(), "", add)
+	for _, arch := range p.Arch {
+		if a, ok := p.ArchSpecific[arch]; ok {
+			lintChecksums(a.Source, a.sums(), "_"+arch, add)
+		}
+	}
+
+	return issues
+}
+
+// lintDependencies checks that the dependencies of field can be satisfied,
+// e.g. not "foo=1.2" and "foo=1.3" which are merged into an empty range.
+func lintDependencies(deps []*Dependency, field string, add func(Severity, string, string, ...interface{})) {
+	for _, dep := range deps {
+		if dep.Range().IsEmpty() {
+			add(SeverityError, field, "conflicting version constraints: %s", dep)
+		}
+	}
+}
+
+// lintChecksums checks the checksum arrays sums of the sources. suffix is
+// the arch suffix of the variables, if any.
+func lintChecksums(sources []string, sums map[string][]string, suffix string, add func(Severity, string, string, ...interface{})) {
+	declared := false
+	strong := false
+
+	for _, algo := range checksumAlgorithms {
+		if len(sums[algo]) == 0 {
+			continue
+		}
+		declared = true
+		strong = strong || !weakChecksums[algo]
+
+		if len(sums[algo]) != len(sources) {
+			add(SeverityError, algo+"sums"+suffix, "%d checksums for %d sources", len(sums[algo]), len(sources))
+		}
+	}
+
+	if len(sources) == 0 {
+		return
+	}
+
+	if !declared {
+		add(SeverityError, "source"+suffix, "no checksums declared")
+	} else if !strong {
+		add(SeverityInfo, "source"+suffix, "only weak md5 or sha1 checksums declared")
+	}
+}
@@ -0,0 +1,60 @@
+package pkgbuild
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	pkg, err := ParseSRCINFOContent([]byte(`pkgbase = foo
+	pkgver = 1.0
+	pkgrel = 1
+	arch = x86_64
+	depends = bar=1.2
+	depends = bar=1.3
+	source = foo.tar.gz
+	source = foo.patch
+	md5sums = aaaa
+	source_x86_64 = foo.bin
+
+pkgname = foo
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Issue{
+		{SeverityWarning, "pkgdesc", "missing package description"},
+		{SeverityInfo, "url", "missing upstream URL"},
+		{SeverityWarning, "license", "missing license"},
+		{SeverityError, "depends", "conflicting version constraints: bar>=1.3 bar<=1.2"},
+		{SeverityError, "md5sums", "1 checksums for 2 sources"},
+		{SeverityInfo, "source", "only weak md5 or sha1 checksums declared"},
+		{SeverityError, "source_x86_64", "no checksums declared"},
+	}
+
+	issues := Lint(pkg)
+	if !reflect.DeepEqual(issues, expected) {
+		t.Errorf("expected issues %v, got %v", expected, issues)
+	}
+
+	data, err := json.Marshal(issues[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"severity":"warning","field":"pkgdesc","message":"missing package description"}` {
+		t.Errorf("unexpected JSON: %s", data)
+	}
+}
+
+func TestLintClean(t *testing.T) {
+	pkg, err := ParseSRCINFO("./test_pkgbuilds/SRCINFO_sudo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if issues := Lint(pkg); len(issues) != 0 {
+		t.Errorf("expected no issues, got %v", issues)
+	}
+}
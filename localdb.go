@@ -0,0 +1,148 @@
+package pkgbuild
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultDBPath is the default database directory of pacman.
+const DefaultDBPath = "/var/lib/pacman"
+
+// InstalledPackage is a package of the local pacman database.
+type InstalledPackage struct {
+	Name     string
+	Version  string // full version e.g. "1:1.0-1"
+	Base     string
+	Desc     string
+	Arch     string
+	Depends  []string
+	Provides []string
+	Explicit bool // installed explicitly, not as a dependency
+}
+
+// ReadLocalDB reads the packages installed according to the local pacman
+// database in dbpath, e.g. DefaultDBPath, sorted by name.
+func ReadLocalDB(dbpath string) ([]*InstalledPackage, error) {
+	local := filepath.Join(dbpath, "local")
+	entries, err := ioutil.ReadDir(local)
+	if err != nil {
+		return nil, err
+	}
+
+	var installed []*InstalledPackage
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		content, err := ioutil.ReadFile(filepath.Join(local, entry.Name(), "desc"))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		pkg, err := parseDesc(content)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", entry.Name(), err)
+		}
+		installed = append(installed, pkg)
+	}
+
+	sort.Slice(installed, func(i, j int) bool {
+		return installed[i].Name < installed[j].Name
+	})
+	return installed, nil
+}
+
+// parseDesc parses the desc file of a package in the local database, made
+// of sections like "%NAME%" followed by a value per line.
+func parseDesc(content []byte) (*InstalledPackage, error) {
+	sections := make(map[string][]string)
+
+	var section string
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+			section = ""
+		case section == "" && len(line) > 2 && strings.HasPrefix(line, "%") && strings.HasSuffix(line, "%"):
+			section = line[1 : len(line)-1]
+		case section != "":
+			sections[section] = append(sections[section], line)
+		}
+	}
+
+	first := func(name string) string {
+		if values := sections[name]; len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+
+	pkg := &InstalledPackage{
+		Name:     first("NAME"),
+		Version:  first("VERSION"),
+		Base:     first("BASE"),
+		Desc:     first("DESC"),
+		Arch:     first("ARCH"),
+		Depends:  sections["DEPENDS"],
+		Provides: sections["PROVIDES"],
+		Explicit: first("REASON") != "1",
+	}
+
+	if pkg.Name == "" || pkg.Version == "" {
+		return nil, fmt.Errorf("%%NAME%% or %%VERSION%% missing")
+	}
+
+	return pkg, nil
+}
+
+// DependenciesSatisfiedBy checks the depends, makedepends and checkdepends
+// of p against the installed packages like the check before a build. It
+// returns the dependencies no installed package provides and those only
+// provided in a version not satisfying them. Provides are matched like
+// pacman does, see ProvidesStrict. The arch specific dependencies of all
+// archs are included, use ResolveFor first to only check those of one.
+func (p *PKGBUILD) DependenciesSatisfiedBy(installed []*InstalledPackage) (missing, unsatisfied []*Dependency) {
+	// packages by the names they provide, including their own
+	providers := make(map[string][]*InstalledPackage)
+	for _, pkg := range installed {
+		providers[pkg.Name] = append(providers[pkg.Name], pkg)
+		for _, provide := range pkg.Provides {
+			deps, err := parseDependency(provide, nil)
+			if err == nil && len(deps) > 0 && deps[0].Name != pkg.Name {
+				providers[deps[0].Name] = append(providers[deps[0].Name], pkg)
+			}
+		}
+	}
+
+	for _, deps := range [][]*Dependency{p.Depends, p.Makedepends, p.Checkdepends} {
+	Deps:
+		for _, dep := range deps {
+			candidates := providers[dep.Name]
+			if len(candidates) == 0 {
+				missing = append(missing, dep)
+				continue
+			}
+
+			for _, pkg := range candidates {
+				version, err := NewCompleteVersion(pkg.Version)
+				if err != nil {
+					version = nil
+				}
+				if dep.SatisfiedBy(pkg.Name, version, pkg.Provides, ProvidesStrict) {
+					continue Deps
+				}
+			}
+			unsatisfied = append(unsatisfied, dep)
+		}
+	}
+
+	return missing, unsatisfied
+}
@@ -0,0 +1,97 @@
+package pkgbuild
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeLocalDB writes a local pacman database with the desc files by
+// package directory name to dbpath.
+func writeLocalDB(t *testing.T, dbpath string, descs map[string]string) {
+	for dir, desc := range descs {
+		path := filepath.Join(dbpath, "local", dir)
+		if err := os.MkdirAll(path, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(path, "desc"), []byte(desc), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(dbpath, "local", "ALPM_DB_VERSION"), []byte("9\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadLocalDB(t *testing.T) {
+	dbpath, err := ioutil.TempDir("", "gopkgbuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dbpath)
+
+	writeLocalDB(t, dbpath, map[string]string{
+		"pam-1.5.3-3":  "%NAME%\npam\n\n%VERSION%\n1.5.3-3\n\n%DEPENDS%\nglibc\nlibtirpc\n\n%PROVIDES%\nlibpam.so=0-64\n\n%REASON%\n1\n",
+		"glibc-2.38-7": "%NAME%\nglibc\n\n%VERSION%\n2.38-7\n\n%BASE%\nglibc\n\n%DESC%\nGNU C Library\n\n%ARCH%\nx86_64\n",
+	})
+
+	installed, err := ReadLocalDB(dbpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []*InstalledPackage{
+		{Name: "glibc", Version: "2.38-7", Base: "glibc", Desc: "GNU C Library", Arch: "x86_64", Explicit: true},
+		{Name: "pam", Version: "1.5.3-3", Depends: []string{"glibc", "libtirpc"}, Provides: []string{"libpam.so=0-64"}},
+	}
+	if !reflect.DeepEqual(installed, expected) {
+		t.Errorf("expected %+v, got %+v", expected, installed)
+	}
+
+	writeLocalDB(t, dbpath, map[string]string{"broken-1-1": "%NAME%\nbroken\n"})
+	if _, err = ReadLocalDB(dbpath); err == nil {
+		t.Error("expected error for desc without version")
+	}
+
+	if _, err = ReadLocalDB(filepath.Join(dbpath, "missing")); err == nil {
+		t.Error("expected error for missing database")
+	}
+}
+
+func TestDependenciesSatisfiedBy(t *testing.T) {
+	pkg, err := ParseSRCINFOContent([]byte(`pkgbase = foo
+	pkgver = 1.0
+	pkgrel = 1
+	arch = x86_64
+	depends = glibc>=2.38
+	depends = libpam.so=0-64
+	depends = sh
+	makedepends = cmake>=3.20
+	makedepends = ninja
+	checkdepends = python>=3.12
+
+pkgname = foo
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	installed := []*InstalledPackage{
+		{Name: "glibc", Version: "2.38-7"},
+		{Name: "pam", Version: "1.5.3-3", Provides: []string{"libpam.so=0-64"}},
+		{Name: "bash", Version: "5.2.021-1", Provides: []string{"sh", ""}},
+		{Name: "cmake", Version: "3.18.0-1"},
+		{Name: "python", Version: "3.11.5-1"},
+		{Name: "python", Version: "3.12.0-1"},
+	}
+
+	missing, unsatisfied := pkg.DependenciesSatisfiedBy(installed)
+	if names := dependencyStrings(missing); !reflect.DeepEqual(names, []string{"ninja"}) {
+		t.Errorf("expected ninja to be missing, got %v", names)
+	}
+	if names := dependencyStrings(unsatisfied); !reflect.DeepEqual(names, []string{"cmake>=3.20"}) {
+		t.Errorf("expected cmake>=3.20 to be unsatisfied, got %v", names)
+	}
+}
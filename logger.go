@@ -0,0 +1,31 @@
+package pkgbuild
+
+// Logger receives debug diagnostics, e.g. unknown variables found while
+// parsing or the URLs downloaded. log.Logger and most logging libraries
+// can be adapted with LoggerFunc.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+}
+
+// LoggerFunc adapts a printf like function, e.g. log.Printf, to a Logger.
+type LoggerFunc func(format string, args ...interface{})
+
+// Debugf calls f.
+func (f LoggerFunc) Debugf(format string, args ...interface{}) {
+	f(format, args...)
+}
+
+// WithLogger makes the parser and the functions downloading files log
+// their diagnostics to logger. Nothing is logged by default.
+func WithLogger(logger Logger) ParseOption {
+	return func(c *parseConfig) {
+		c.logger = logger
+	}
+}
+
+// debugf logs to the Logger of c if set.
+func (c *parseConfig) debugf(format string, args ...interface{}) {
+	if c.logger != nil {
+		c.logger.Debugf(format, args...)
+	}
+}
@@ -0,0 +1,34 @@
+package pkgbuild
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestWithLogger(t *testing.T) {
+	var logs []string
+	logger := LoggerFunc(func(format string, args ...interface{}) {
+		logs = append(logs, fmt.Sprintf(format, args...))
+	})
+
+	input := "pkgbase = foo\n\tpkgver = 1.0\n\tarch = any\n\tnewfield = a\n\tnewfield = b\n\tdepends = -bar\n\npkgname = foo\n"
+	if _, err := ParseSRCINFOContent([]byte(input), WithLogger(logger), WithContinueOnError()); err == nil {
+		t.Fatal("expected error for invalid dependency")
+	}
+
+	if len(logs) != 2 {
+		t.Fatalf("expected 2 log messages, got %q", logs)
+	}
+	if logs[0] != "unknown variable newfield stored in Extra" {
+		t.Errorf("unexpected message: %s", logs[0])
+	}
+	if !strings.HasPrefix(logs[1], "skipping invalid line: 6:") {
+		t.Errorf("unexpected message: %s", logs[1])
+	}
+
+	// no logger set
+	if _, err := ParseSRCINFOContent([]byte(input), WithContinueOnError()); err == nil {
+		t.Fatal("expected error for invalid dependency")
+	}
+}
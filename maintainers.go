@@ -0,0 +1,81 @@
+package pkgbuild
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Person is a maintainer or contributor named in the header comments of a
+// PKGBUILD e.g. "# Maintainer: Foo Bar <foo at example dot org>".
+type Person struct {
+	Name  string
+	Email string // deobfuscated, e.g. "foo@example.org", empty if not given
+}
+
+func (p Person) String() string {
+	if p.Email == "" {
+		return p.Name
+	}
+	if p.Name == "" {
+		return "<" + p.Email + ">"
+	}
+	return p.Name + " <" + p.Email + ">"
+}
+
+var (
+	headerRegex = regexp.MustCompile(`(?i)^#\s*(maintainer|contributor)s?\s*:\s*(.*)$`)
+	personRegex = regexp.MustCompile(`^([^<]*?)\s*(?:<([^>]*)>)?\s*$`)
+	atRegex     = regexp.MustCompile(`(?i)\s*(?:\s|\[|\(|\{)at(?:\s|\]|\)|\})\s*`)
+	dotRegex    = regexp.MustCompile(`(?i)\s*(?:\s|\[|\(|\{)dot(?:\s|\]|\)|\})\s*`)
+)
+
+// ParsePerson parses a maintainer or contributor like "Foo Bar <foo at
+// example dot org>". Email addresses obfuscated with "at" and "dot", also
+// in brackets like "[at]", are deobfuscated.
+func ParsePerson(s string) Person {
+	m := personRegex.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		// e.g. a stray > in the name
+		return Person{Name: strings.TrimSpace(s)}
+	}
+
+	email := strings.TrimSpace(m[2])
+	email = atRegex.ReplaceAllString(" "+email+" ", "@")
+	email = dotRegex.ReplaceAllString(email, ".")
+
+	return Person{Name: m[1], Email: strings.TrimSpace(email)}
+}
+
+// parseHeader returns the maintainers and contributors named in the comments
+// at the top of the PKGBUILD content, before the first command.
+func parseHeader(content string) (maintainers, contributors []Person) {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "#") {
+			break
+		}
+
+		m := headerRegex.FindStringSubmatch(line)
+		if m == nil || strings.TrimSpace(m[2]) == "" {
+			continue
+		}
+
+		person := ParsePerson(m[2])
+		if strings.EqualFold(m[1], "maintainer") {
+			maintainers = append(maintainers, person)
+		} else {
+			contributors = append(contributors, person)
+		}
+	}
+	return maintainers, contributors
+}
+
+// Header returns the maintainers and contributors named in the comments at
+// the top of f, like "# Maintainer: Foo Bar <foo@example.org>" and
+// "# Contributor: ...", in order.
+func (f *File) Header() (maintainers, contributors []Person) {
+	return parseHeader(f.content)
+}
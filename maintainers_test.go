@@ -0,0 +1,58 @@
+package pkgbuild
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePerson(t *testing.T) {
+	for s, expected := range map[string]Person{
+		"Foo Bar <foo@example.org>":             {"Foo Bar", "foo@example.org"},
+		"Foo Bar <foo at example dot org>":      {"Foo Bar", "foo@example.org"},
+		"Foo Bar <foo [at] example [dot] org>":  {"Foo Bar", "foo@example.org"},
+		"Foo Bar <foo(at)mail(dot)example.org>": {"Foo Bar", "foo@mail.example.org"},
+		"Catherine <cat@example.org>":           {"Catherine", "cat@example.org"},
+		"Foo Bar":                               {"Foo Bar", ""},
+		"<foo@example.org>":                     {"", "foo@example.org"},
+	} {
+		if p := ParsePerson(s); p != expected {
+			t.Errorf("expected %q to parse as %+v, got %+v", s, expected, p)
+		}
+	}
+
+	if s := (Person{"Foo Bar", "foo@example.org"}).String(); s != "Foo Bar <foo@example.org>" {
+		t.Errorf("unexpected string %q", s)
+	}
+}
+
+func TestHeader(t *testing.T) {
+	f, err := ParseAST([]byte(`# Maintainer: Foo Bar <foo at example dot org>
+# Maintainer: Baz <baz@example.org>
+# Contributor: Qux <qux@example.org>
+#Contributors: Quux
+# Some other comment
+
+pkgname=foo
+# Contributor: Not In Header <no@example.org>
+pkgver=1.0
+pkgrel=1
+arch=(any)
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := f.PKGBUILD()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	maintainers := []Person{{"Foo Bar", "foo@example.org"}, {"Baz", "baz@example.org"}}
+	contributors := []Person{{"Qux", "qux@example.org"}, {"Quux", ""}}
+	if !reflect.DeepEqual(p.Maintainers, maintainers) {
+		t.Errorf("expected maintainers %v, got %v", maintainers, p.Maintainers)
+	}
+	if !reflect.DeepEqual(p.Contributors, contributors) {
+		t.Errorf("expected contributors %v, got %v", contributors, p.Contributors)
+	}
+}
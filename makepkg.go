@@ -0,0 +1,273 @@
+package pkgbuild
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ParsePKGBUILD parses the PKGBUILD given by path by running makepkg
+// --printsrcinfo on it. This executes the PKGBUILD, so it must only be used
+// on trusted input unless it is run in a sandbox, see WithSandbox, or
+// evaluated without bash, see WithEmbeddedEvaluation.
+func ParsePKGBUILD(path string, opts ...ParseOption) (*PKGBUILD, error) {
+	return ParsePKGBUILDContext(context.Background(), path, opts...)
+}
+
+// ParsePKGBUILDContext is like ParsePKGBUILD but kills makepkg if ctx is
+// done before it finishes, returning the error of ctx.
+func ParsePKGBUILDContext(ctx context.Context, path string, opts ...ParseOption) (*PKGBUILD, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := newParseConfig(opts)
+	if config.embedded {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		config.debugf("evaluating %s without makepkg", path)
+		f, err := ParseASTFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return f.PKGBUILD(opts...)
+	}
+
+	dir := filepath.Dir(path)
+
+	command := sandboxCommand(config.sandbox, dir, "makepkg", "--printsrcinfo", "-p", filepath.Base(path))
+	config.debugf("running %s in %s", strings.Join(command, " "), dir)
+
+	out, err := runOutput(ctx, dir, command[0], command[1:]...)
+	if err != nil {
+		return nil, err
+	}
+
+	config.zeroCopy = true
+	p, err := parsePKGBUILD(out, config)
+	if err != nil {
+		return nil, err
+	}
+
+	// the header is informational, makepkg already read the file
+	if content, err := ioutil.ReadFile(path); err == nil {
+		p.Maintainers, p.Contributors = parseHeader(string(content))
+	}
+	return p, nil
+}
+
+// sandboxCommand returns command run through the sandbox command template,
+// with {dir} replaced by dir, see WithSandbox.
+func sandboxCommand(sandbox []string, dir string, command ...string) []string {
+	sandboxed := make([]string, 0, len(sandbox)+len(command))
+	for _, arg := range sandbox {
+		sandboxed = append(sandboxed, strings.Replace(arg, "{dir}", dir, -1))
+	}
+	return append(sandboxed, command...)
+}
+
+// ParseSRCINFOContext is like ParseSRCINFO but stops reading the file and
+// returns the error of ctx once ctx is done.
+func ParseSRCINFOContext(ctx context.Context, path string, opts ...ParseOption) (*PKGBUILD, error) {
+	config := newParseConfig(opts)
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read file: %s, %s", path, err.Error())
+	}
+	defer f.Close()
+
+	content, err := config.read(contextReader{ctx, f})
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("unable to read file: %s, %s", path, err.Error())
+	}
+
+	config.zeroCopy = true
+	return parsePKGBUILD(content, config)
+}
+
+// contextReader is a reader failing with the error of ctx once ctx is done.
+type contextReader struct {
+	ctx context.Context
+	r   *os.File
+}
+
+func (c contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
+
+// runOutput runs the command name in dir and returns its stdout. The output
+// is written to temporary files rather than pipes, so a child process left
+// behind when ctx kills the command can't keep runOutput from returning.
+func runOutput(ctx context.Context, dir, name string, args ...string) ([]byte, error) {
+	stdout, err := ioutil.TempFile("", "gopkgbuild-stdout")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(stdout.Name())
+	defer stdout.Close()
+
+	stderr, err := ioutil.TempFile("", "gopkgbuild-stderr")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(stderr.Name())
+	defer stderr.Close()
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		msg, _ := ioutil.ReadFile(stderr.Name())
+		return nil, fmt.Errorf("%s %s failed: %s: %s", name, strings.Join(args, " "), err.Error(), strings.TrimSpace(string(msg)))
+	}
+
+	return ioutil.ReadFile(stdout.Name())
+}
+
+// BuildOptions configures a makepkg run of Build.
+type BuildOptions struct {
+	NoConfirm    bool     // --noconfirm, don't ask for confirmation
+	SkipPGPCheck bool     // --skippgpcheck, don't verify source signatures
+	CleanBuild   bool     // -C, remove $srcdir before building
+	Args         []string // additional makepkg arguments, e.g. "--syncdeps"
+	Env          []string // additional environment, e.g. "PKGDEST=/tmp/pkgs"
+
+	// Stdout and Stderr, if set, are called with each line of output.
+	Stdout func(line string)
+	Stderr func(line string)
+}
+
+// args returns the makepkg arguments of o.
+func (o *BuildOptions) args() []string {
+	var args []string
+	if o.NoConfirm {
+		args = append(args, "--noconfirm")
+	}
+	if o.SkipPGPCheck {
+		args = append(args, "--skippgpcheck")
+	}
+	if o.CleanBuild {
+		args = append(args, "-C")
+	}
+	return append(args, o.Args...)
+}
+
+// Build runs makepkg for the PKGBUILD in dir and returns the paths of the
+// packages it built, as listed by makepkg --packagelist. makepkg is killed
+// if ctx is done before it finishes.
+func Build(ctx context.Context, dir string, opts BuildOptions) ([]string, error) {
+	return build(ctx, dir, "makepkg", opts.args(), opts)
+}
+
+// BuildInChroot is like Build but builds the package in a clean copy of the
+// devtools chroot chrootdir using makechrootpkg. The chroot must have been
+// created with mkarchroot beforehand.
+func BuildInChroot(ctx context.Context, dir, chrootdir string, opts BuildOptions) ([]string, error) {
+	args := append([]string{"-c", "-r", chrootdir, "--"}, opts.args()...)
+	return build(ctx, dir, "makechrootpkg", args, opts)
+}
+
+// build runs the build command name with args in dir and returns the
+// packages built.
+func build(ctx context.Context, dir, name string, args []string, opts BuildOptions) ([]string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	stdout := &lineWriter{fn: opts.Stdout}
+	stderr := &lineWriter{fn: opts.Stderr}
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.Env = append(os.Environ(), opts.Env...)
+
+	err = cmd.Run()
+	stdout.flush()
+	stderr.flush()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("%s failed: %s", name, err.Error())
+	}
+
+	return builtPackages(ctx, dir, opts.Env)
+}
+
+// builtPackages returns the packages listed by makepkg --packagelist for
+// the PKGBUILD in dir which exist.
+func builtPackages(ctx context.Context, dir string, env []string) ([]string, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, "makepkg", "--packagelist")
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.Env = append(os.Environ(), env...)
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("makepkg --packagelist failed: %s: %s", err.Error(), strings.TrimSpace(stderr.String()))
+	}
+
+	var pkgs []string
+	for _, path := range strings.Fields(stdout.String()) {
+		// e.g. debug packages are listed even if they weren't built
+		if _, err := os.Stat(path); err == nil {
+			pkgs = append(pkgs, path)
+		}
+	}
+	return pkgs, nil
+}
+
+// lineWriter calls fn with each line written to it.
+type lineWriter struct {
+	fn  func(line string)
+	buf []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	if w.fn == nil {
+		return len(p), nil
+	}
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.fn(string(w.buf[:i]))
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// flush calls fn with the last line if it didn't end with a newline.
+func (w *lineWriter) flush() {
+	if w.fn != nil && len(w.buf) > 0 {
+		w.fn(string(w.buf))
+		w.buf = nil
+	}
+}
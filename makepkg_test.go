@@ -0,0 +1,223 @@
+package pkgbuild
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeMakepkg puts a makepkg running script first in PATH and returns a
+// function restoring PATH.
+func fakeMakepkg(t *testing.T, dir, script string) func() {
+	return fakeCommand(t, dir, "makepkg", script)
+}
+
+// fakeCommand puts a command name running script first in PATH and returns
+// a function restoring PATH.
+func fakeCommand(t *testing.T, dir, name, script string) func() {
+	err := ioutil.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := os.Getenv("PATH")
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+path)
+	return func() { os.Setenv("PATH", path) }
+}
+
+func TestParsePKGBUILDContext(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopkgbuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer fakeMakepkg(t, dir, `[ "$1 $2 $3" = "--printsrcinfo -p PKGBUILD" ] || exit 1
+cat <<EOF
+pkgbase = foo
+	pkgver = 1.0
+	pkgrel = 1
+	arch = any
+
+pkgname = foo
+EOF
+`)()
+
+	pkg, err := ParsePKGBUILD(filepath.Join(dir, "PKGBUILD"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg.Pkgbase != "foo" || pkg.Version() != "1.0-1" {
+		t.Errorf("unexpected PKGBUILD: %s %s", pkg.Pkgbase, pkg.Version())
+	}
+
+	if _, err = ParsePKGBUILD(filepath.Join(dir, "other")); err == nil {
+		t.Error("expected error when makepkg fails")
+	}
+}
+
+func TestParsePKGBUILDContextTimeout(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopkgbuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the sleep keeps stdout open after the shell is killed
+	defer fakeMakepkg(t, dir, "sleep 10\n")()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = ParsePKGBUILDContext(ctx, filepath.Join(dir, "PKGBUILD"))
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Error("ParsePKGBUILDContext didn't return when the context expired")
+	}
+}
+
+func TestParseSRCINFOContext(t *testing.T) {
+	if _, err := ParseSRCINFOContext(context.Background(), "test_pkgbuilds/SRCINFO_sudo"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := ParseSRCINFOContext(ctx, "test_pkgbuilds/SRCINFO_sudo"); err != context.Canceled {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestParsePKGBUILDSandbox(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopkgbuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer fakeMakepkg(t, dir, "printf 'pkgbase = foo\\n\\tpkgver = 1.0\\n\\tpkgrel = 1\\n\\tarch = any\\n\\npkgname = foo\\n'\n")()
+
+	sandbox := filepath.Join(dir, "sandbox")
+	err = ioutil.WriteFile(sandbox, []byte("#!/bin/sh\n[ \"$1\" = \"--bind=$PWD\" ] || exit 1\nshift\nexec \"$@\"\n"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkg, err := ParsePKGBUILD(filepath.Join(dir, "PKGBUILD"), WithSandbox([]string{sandbox, "--bind={dir}"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg.Pkgbase != "foo" {
+		t.Errorf("expected pkgbase foo, got %s", pkg.Pkgbase)
+	}
+
+	if _, err = ParsePKGBUILD(filepath.Join(dir, "PKGBUILD"), WithSandbox([]string{sandbox, "--bind=/"})); err == nil {
+		t.Error("expected error from the sandbox command")
+	}
+}
+
+func TestParsePKGBUILDEmbedded(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopkgbuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// makepkg must not be run
+	defer fakeMakepkg(t, dir, "exit 1\n")()
+
+	pkg, err := ParsePKGBUILD("./test_pkgbuilds/PKGBUILD_sudo", WithEmbeddedEvaluation())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := ParseSRCINFO("./test_pkgbuilds/SRCINFO_sudo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes := Diff(expected, pkg); len(changes) > 0 {
+		t.Errorf("PKGBUILD evaluated differently from its .SRCINFO:\n%s", changes)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopkgbuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer fakeMakepkg(t, dir, `if [ "$1" = --packagelist ]; then
+	echo "$PWD/foo-1.0-1-any.pkg.tar.zst"
+	echo "$PWD/foo-debug-1.0-1-any.pkg.tar.zst"
+	exit
+fi
+echo "args: $*"
+echo "==> Finished making: foo 1.0-1" >&2
+printf "env: $FOO"
+touch foo-1.0-1-any.pkg.tar.zst
+`)()
+
+	var stdout, stderr []string
+	pkgs, err := Build(context.Background(), dir, BuildOptions{
+		NoConfirm:  true,
+		CleanBuild: true,
+		Args:       []string{"--nodeps"},
+		Env:        []string{"FOO=bar"},
+		Stdout:     func(line string) { stdout = append(stdout, line) },
+		Stderr:     func(line string) { stderr = append(stderr, line) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(stdout, []string{"args: --noconfirm -C --nodeps", "env: bar"}) {
+		t.Errorf("unexpected stdout: %q", stdout)
+	}
+	if !reflect.DeepEqual(stderr, []string{"==> Finished making: foo 1.0-1"}) {
+		t.Errorf("unexpected stderr: %q", stderr)
+	}
+	if !reflect.DeepEqual(pkgs, []string{filepath.Join(dir, "foo-1.0-1-any.pkg.tar.zst")}) {
+		t.Errorf("unexpected packages: %v", pkgs)
+	}
+
+	defer fakeMakepkg(t, dir, "exit 4\n")()
+	if _, err = Build(context.Background(), dir, BuildOptions{}); err == nil {
+		t.Error("expected error when makepkg fails")
+	}
+}
+
+func TestBuildInChroot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopkgbuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer fakeMakepkg(t, dir, "echo \"$PWD/foo-1.0-1-x86_64.pkg.tar.zst\"\n")()
+	defer fakeCommand(t, dir, "makechrootpkg", "echo \"$*\"\ntouch foo-1.0-1-x86_64.pkg.tar.zst\n")()
+
+	var stdout []string
+	pkgs, err := BuildInChroot(context.Background(), dir, "/var/lib/archbuild/extra-x86_64", BuildOptions{
+		SkipPGPCheck: true,
+		Stdout:       func(line string) { stdout = append(stdout, line) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(stdout, []string{"-c -r /var/lib/archbuild/extra-x86_64 -- --skippgpcheck"}) {
+		t.Errorf("unexpected stdout: %q", stdout)
+	}
+	if !reflect.DeepEqual(pkgs, []string{filepath.Join(dir, "foo-1.0-1-x86_64.pkg.tar.zst")}) {
+		t.Errorf("unexpected packages: %v", pkgs)
+	}
+}
@@ -0,0 +1,269 @@
+package pkgbuild
+
+import "fmt"
+
+// ArrayPolicy defines how Merge combines an array of the overlay with the
+// same array of the base.
+type ArrayPolicy int
+
+// Array policies
+const (
+	// ArrayReplace replaces the base values with the overlay values, unless
+	// the overlay array is empty.
+	ArrayReplace ArrayPolicy = iota
+	// ArrayAppend appends the overlay values to the base values, skipping
+	// values already present. Dependencies in the overlay replace base
+	// dependencies of the same name.
+	ArrayAppend
+)
+
+// MergeOption configures how Merge combines two PKGBUILDs.
+type MergeOption func(*mergeConfig)
+
+// mergeConfig holds the settings of a single merge.
+type mergeConfig struct {
+	policy ArrayPolicy
+	fields map[string]ArrayPolicy
+}
+
+// WithArrayPolicy sets the policy used for all arrays without a policy set
+// by WithFieldPolicy. The default is ArrayReplace.
+func WithArrayPolicy(policy ArrayPolicy) MergeOption {
+	return func(c *mergeConfig) {
+		c.policy = policy
+	}
+}
+
+// WithFieldPolicy sets the policy for the array variable field e.g.
+// "depends". The policy also applies to the arch specific variants of the
+// variable like depends_x86_64.
+func WithFieldPolicy(field string, policy ArrayPolicy) MergeOption {
+	return func(c *mergeConfig) {
+		if c.fields == nil {
+			c.fields = make(map[string]ArrayPolicy)
+		}
+		c.fields[field] = policy
+	}
+}
+
+// policyOf returns the policy for the array variable field.
+func (c *mergeConfig) policyOf(field string) ArrayPolicy {
+	if policy, ok := c.fields[field]; ok {
+		return policy
+	}
+	return c.policy
+}
+
+// Merge returns a new PKGBUILD with the non-zero fields of overlay applied
+// on top of base. Scalars like pkgver are replaced if set in the overlay,
+// arrays are combined according to their ArrayPolicy. Neither base nor
+// overlay is modified. An error is returned if the result is not a valid
+// PKGBUILD, e.g. if an arch specific overlay variable references an arch
+// missing from the merged arch array.
+func Merge(base, overlay *PKGBUILD, opts ...MergeOption) (*PKGBUILD, error) {
+	c := &mergeConfig{}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	p := &PKGBUILD{
+		Pkgnames:     c.strings("pkgname", base.Pkgnames, overlay.Pkgnames),
+		Pkgver:       base.Pkgver,
+		Pkgrel:       base.Pkgrel,
+		Pkgdir:       mergeString(base.Pkgdir, overlay.Pkgdir),
+		Epoch:        base.Epoch,
+		Pkgbase:      mergeString(base.Pkgbase, overlay.Pkgbase),
+		Pkgdesc:      mergeString(base.Pkgdesc, overlay.Pkgdesc),
+		Arch:         c.strings("arch", base.Arch, overlay.Arch),
+		URL:          mergeString(base.URL, overlay.URL),
+		License:      c.strings("license", base.License, overlay.License),
+		Groups:       c.strings("groups", base.Groups, overlay.Groups),
+		Backup:       c.strings("backup", base.Backup, overlay.Backup),
+		Options:      c.strings("options", base.Options, overlay.Options),
+		Install:      mergeString(base.Install, overlay.Install),
+		Changelog:    mergeString(base.Changelog, overlay.Changelog),
+		Noextract:    c.strings("noextract", base.Noextract, overlay.Noextract),
+		Validpgpkeys: c.strings("validpgpkeys", base.Validpgpkeys, overlay.Validpgpkeys),
+		Comments:     c.strings("comments", base.Comments, overlay.Comments),
+		Maintainers:  copyPeople(base.Maintainers),
+		Contributors: copyPeople(base.Contributors),
+	}
+
+	if len(overlay.Maintainers) > 0 {
+		p.Maintainers = copyPeople(overlay.Maintainers)
+	}
+	if len(overlay.Contributors) > 0 {
+		p.Contributors = copyPeople(overlay.Contributors)
+	}
+
+	if overlay.Pkgver != "" {
+		p.Pkgver = overlay.Pkgver
+	}
+	if overlay.Pkgrel != "" {
+		p.Pkgrel = overlay.Pkgrel
+	}
+	if overlay.Epoch != 0 {
+		p.Epoch = overlay.Epoch
+	}
+
+	archs := make(map[string]bool)
+	for _, q := range []*PKGBUILD{base, overlay} {
+		for arch := range q.ArchSpecific {
+			archs[arch] = true
+		}
+	}
+	for _, arch := range sortedKeys(archs) {
+		a := c.archSpecific(base.ArchSpecific[arch], overlay.ArchSpecific[arch])
+		if a == nil {
+			continue
+		}
+
+		if !contains(p.Arch, arch) {
+			return nil, fmt.Errorf("unsupported arch for arch specific variables: %s", arch)
+		}
+		if p.ArchSpecific == nil {
+			p.ArchSpecific = make(map[string]*ArchSpecific)
+		}
+		p.ArchSpecific[arch] = a
+	}
+
+	// the variables which can be arch specific are merged without the arch
+	// specific values, which are added back in the order of the arch array
+	common := c.archSpecific(base.archIndependent(), overlay.archIndependent())
+	if common == nil {
+		common = &ArchSpecific{}
+	}
+	p.setArchIndependent(common)
+
+	for _, q := range []*PKGBUILD{base, overlay} {
+		for name := range q.Extra {
+			if _, ok := p.Extra[name]; ok {
+				continue
+			}
+			if p.Extra == nil {
+				p.Extra = make(map[string][]string)
+			}
+			p.Extra[name] = c.strings(name, base.Extra[name], overlay.Extra[name])
+		}
+	}
+
+	if errs := p.validate(); len(errs) > 0 {
+		return nil, errs[0]
+	}
+
+	return p, nil
+}
+
+// archSpecific merges the arch specific values of base and overlay, either
+// of which may be nil. nil is returned if both are empty.
+func (c *mergeConfig) archSpecific(base, overlay *ArchSpecific) *ArchSpecific {
+	if base == nil {
+		base = &ArchSpecific{}
+	}
+	if overlay == nil {
+		overlay = &ArchSpecific{}
+	}
+
+	a := &ArchSpecific{
+		Depends:      c.dependencies("depends", base.Depends, overlay.Depends),
+		Optdepends:   c.strings("optdepends", base.Optdepends, overlay.Optdepends),
+		Makedepends:  c.dependencies("makedepends", base.Makedepends, overlay.Makedepends),
+		Checkdepends: c.dependencies("checkdepends", base.Checkdepends, overlay.Checkdepends),
+		Provides:     c.strings("provides", base.Provides, overlay.Provides),
+		Conflicts:    c.strings("conflicts", base.Conflicts, overlay.Conflicts),
+		Replaces:     c.strings("replaces", base.Replaces, overlay.Replaces),
+		Source:       c.strings("source", base.Source, overlay.Source),
+	}
+
+	empty := len(a.Depends)+len(a.Optdepends)+len(a.Makedepends)+len(a.Checkdepends)+
+		len(a.Provides)+len(a.Conflicts)+len(a.Replaces)+len(a.Source) == 0
+
+	baseSums, overlaySums := base.sums(), overlay.sums()
+	for _, algo := range checksumAlgorithms {
+		sums := c.strings(algo+"sums", baseSums[algo], overlaySums[algo])
+		*a.checksumArray(algo) = sums
+		empty = empty && len(sums) == 0
+	}
+
+	if empty {
+		return nil
+	}
+	return a
+}
+
+// strings merges the values of the array variable field.
+func (c *mergeConfig) strings(field string, base, overlay []string) []string {
+	if len(overlay) == 0 {
+		return copyStrings(base)
+	}
+
+	if c.policyOf(field) == ArrayReplace {
+		return copyStrings(overlay)
+	}
+
+	merged := copyStrings(base)
+	for _, value := range overlay {
+		if !contains(merged, value) {
+			merged = append(merged, value)
+		}
+	}
+	return merged
+}
+
+// dependencies merges the dependencies of the array variable field.
+func (c *mergeConfig) dependencies(field string, base, overlay []*Dependency) []*Dependency {
+	if len(overlay) == 0 {
+		return copyDependencies(base)
+	}
+
+	if c.policyOf(field) == ArrayReplace {
+		return copyDependencies(overlay)
+	}
+
+	merged := copyDependencies(base)
+Overlay:
+	for _, dep := range overlay {
+		for i, d := range merged {
+			if d.Name == dep.Name {
+				merged[i] = dep
+				continue Overlay
+			}
+		}
+		merged = append(merged, dep)
+	}
+	return merged
+}
+
+// mergeString returns overlay if set, otherwise base.
+func mergeString(base, overlay string) string {
+	if overlay != "" {
+		return overlay
+	}
+	return base
+}
+
+// copyStrings returns a copy of values, keeping nil as nil.
+func copyStrings(values []string) []string {
+	if values == nil {
+		return nil
+	}
+	return append([]string{}, values...)
+}
+
+// copyDependencies returns a copy of deps, keeping nil as nil.
+func copyDependencies(deps []*Dependency) []*Dependency {
+	if deps == nil {
+		return nil
+	}
+	return append([]*Dependency{}, deps...)
+}
+
+// contains reports whether values contains value.
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
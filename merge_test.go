@@ -0,0 +1,91 @@
+package pkgbuild
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	base, err := ParseSRCINFOContent([]byte(`pkgbase = foo
+	pkgver = 1.0
+	pkgrel = 1
+	pkgdesc = upstream description
+	arch = x86_64
+	license = MIT
+	depends = glibc
+	depends = bar>=1.0
+	options = !strip
+	source = foo.tar.gz
+	sha256sums = aaaa
+	source_x86_64 = foo.bin
+	sha256sums_x86_64 = bbbb
+
+pkgname = foo
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	overlay := &PKGBUILD{
+		Pkgrel:  "2",
+		Arch:    []string{"x86_64", "aarch64"},
+		Depends: []*Dependency{{Name: "bar"}, {Name: "baz"}},
+		Options: []string{"!lto"},
+		Source:  []string{"local.patch"},
+		ArchSpecific: map[string]*ArchSpecific{
+			"aarch64": {Source: []string{"foo-aarch64.bin"}, Sha256sums: []string{"cccc"}},
+		},
+	}
+
+	merged, err := Merge(base, overlay, WithArrayPolicy(ArrayAppend), WithFieldPolicy("options", ArrayReplace))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if merged.Pkgver != "1.0" || merged.Pkgrel != "2" || merged.Pkgdesc != "upstream description" {
+		t.Errorf("unexpected scalars: %s-%s %q", merged.Pkgver, merged.Pkgrel, merged.Pkgdesc)
+	}
+
+	expected := map[string][]string{
+		"arch":               {"x86_64", "aarch64"},
+		"license":            {"MIT"},
+		"depends":            {"glibc", "bar", "baz"},
+		"options":            {"!lto"},
+		"source":             {"foo.tar.gz", "local.patch"},
+		"sha256sums":         {"aaaa"},
+		"source_x86_64":      {"foo.bin"},
+		"source_aarch64":     {"foo-aarch64.bin"},
+		"sha256sums_aarch64": {"cccc"},
+	}
+
+	arrays := merged.diffArrays()
+	for name, values := range expected {
+		if !reflect.DeepEqual(arrays[name], values) {
+			t.Errorf("expected %s %v, got %v", name, values, arrays[name])
+		}
+	}
+
+	if len(base.Options) != 1 || base.Options[0] != "!strip" || len(base.Source) != 2 {
+		t.Errorf("merge modified the base PKGBUILD")
+	}
+
+	replaced, err := Merge(base, overlay)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(dependencyStrings(replaced.Depends), []string{"bar", "baz"}) {
+		t.Errorf("expected depends to be replaced, got %v", dependencyStrings(replaced.Depends))
+	}
+}
+
+func TestMergeUnsupportedArch(t *testing.T) {
+	base := &PKGBUILD{Pkgnames: []string{"foo"}, Pkgver: "1.0", Pkgrel: "1", Arch: []string{"x86_64"}}
+	overlay := &PKGBUILD{
+		ArchSpecific: map[string]*ArchSpecific{"i686": {Source: []string{"foo.bin"}}},
+	}
+
+	if _, err := Merge(base, overlay); err == nil {
+		t.Error("expected error for arch specific variables of unsupported arch")
+	}
+}
@@ -0,0 +1,210 @@
+package pkgbuild
+
+import "fmt"
+
+// Copy returns a deep copy of p, which can be modified without affecting p.
+func (p *PKGBUILD) Copy() *PKGBUILD {
+	c := *p
+
+	c.Pkgnames = copyStrings(p.Pkgnames)
+	c.Arch = copyStrings(p.Arch)
+	c.License = copyStrings(p.License)
+	c.Groups = copyStrings(p.Groups)
+	c.Depends = deepCopyDependencies(p.Depends)
+	c.Optdepends = copyStrings(p.Optdepends)
+	c.Makedepends = deepCopyDependencies(p.Makedepends)
+	c.Checkdepends = deepCopyDependencies(p.Checkdepends)
+	c.Provides = copyStrings(p.Provides)
+	c.Conflicts = copyStrings(p.Conflicts)
+	c.Replaces = copyStrings(p.Replaces)
+	c.Backup = copyStrings(p.Backup)
+	c.Options = copyStrings(p.Options)
+	c.Source = copyStrings(p.Source)
+	c.Noextract = copyStrings(p.Noextract)
+	c.Validpgpkeys = copyStrings(p.Validpgpkeys)
+	c.Comments = copyStrings(p.Comments)
+	c.Maintainers = copyPeople(p.Maintainers)
+	c.Contributors = copyPeople(p.Contributors)
+	for algo, sums := range p.sums() {
+		*c.checksumArray(algo) = copyStrings(sums)
+	}
+
+	if p.ArchSpecific != nil {
+		c.ArchSpecific = make(map[string]*ArchSpecific, len(p.ArchSpecific))
+		for arch, a := range p.ArchSpecific {
+			c.ArchSpecific[arch] = a.copy()
+		}
+	}
+
+	if p.base != nil {
+		c.base = p.base.copy()
+	}
+
+	if p.Extra != nil {
+		c.Extra = make(map[string][]string, len(p.Extra))
+		for name, values := range p.Extra {
+			c.Extra[name] = copyStrings(values)
+		}
+	}
+
+	return &c
+}
+
+// copyPeople returns a copy of people, keeping nil as nil.
+func copyPeople(people []Person) []Person {
+	if people == nil {
+		return nil
+	}
+	return append([]Person{}, people...)
+}
+
+// copy returns a deep copy of a.
+func (a *ArchSpecific) copy() *ArchSpecific {
+	if a == nil {
+		return nil
+	}
+
+	c := &ArchSpecific{
+		Depends:      deepCopyDependencies(a.Depends),
+		Optdepends:   copyStrings(a.Optdepends),
+		Makedepends:  deepCopyDependencies(a.Makedepends),
+		Checkdepends: deepCopyDependencies(a.Checkdepends),
+		Provides:     copyStrings(a.Provides),
+		Conflicts:    copyStrings(a.Conflicts),
+		Replaces:     copyStrings(a.Replaces),
+		Source:       copyStrings(a.Source),
+	}
+	for algo, sums := range a.sums() {
+		*c.checksumArray(algo) = copyStrings(sums)
+	}
+	return c
+}
+
+// deepCopyDependencies returns a copy of deps including copies of the
+// dependencies and their versions, keeping nil as nil.
+func deepCopyDependencies(deps []*Dependency) []*Dependency {
+	if deps == nil {
+		return nil
+	}
+
+	copied := make([]*Dependency, len(deps))
+	for i, dep := range deps {
+		r := dep.Range()
+		if r.Min != nil {
+			r.Min = copyVersion(r.Min)
+		}
+		if r.Max != nil {
+			r.Max = copyVersion(r.Max)
+		}
+		if r.Exclude != nil {
+			exclude := make([]*CompleteVersion, len(r.Exclude))
+			for j, v := range r.Exclude {
+				exclude[j] = copyVersion(v)
+			}
+			r.Exclude = exclude
+		}
+		copied[i] = newDependency(dep.Name, r)
+	}
+	return copied
+}
+
+// SetPkgver sets pkgver.
+func (p *PKGBUILD) SetPkgver(pkgver Version) error {
+	if !validPkgver(string(pkgver)) {
+		return fmt.Errorf("%w: %s", ErrInvalidPkgver, pkgver)
+	}
+	p.Pkgver = pkgver
+	return nil
+}
+
+// SetPkgrel sets pkgrel.
+func (p *PKGBUILD) SetPkgrel(pkgrel Version) error {
+	if !validPkgver(string(pkgrel)) {
+		return fmt.Errorf("%w: %s", ErrInvalidPkgrel, pkgrel)
+	}
+	p.Pkgrel = pkgrel
+	return nil
+}
+
+// AddDepend adds the dependency dep e.g. "foo>=1.0" to depends. Like when
+// parsing, a dependency with the same name as an existing one is merged with
+// it using Restrict.
+func (p *PKGBUILD) AddDepend(dep string) error {
+	if dep == "" {
+		return fmt.Errorf("empty dependency")
+	}
+
+	common := p.archIndependent()
+	deps, err := parseDependency(dep, common.Depends)
+	if err != nil {
+		return err
+	}
+	common.Depends = deps
+	p.setArchIndependent(common)
+	return nil
+}
+
+// AddProvide adds provide e.g. "libfoo.so=1-64" to provides unless it's
+// already provided.
+func (p *PKGBUILD) AddProvide(provide string) error {
+	if provide == "" {
+		return fmt.Errorf("empty provide")
+	}
+
+	if _, err := parseDependency(provide, nil); err != nil {
+		return fmt.Errorf("invalid provide: %s, %s", provide, err)
+	}
+
+	common := p.archIndependent()
+	if !contains(common.Provides, provide) {
+		common.Provides = append(common.Provides, provide)
+		p.setArchIndependent(common)
+	}
+	return nil
+}
+
+// RemoveSource removes the source entry source along with its checksums,
+// so the remaining sources stay paired with the right checksums. Arch
+// specific sources are removed as well.
+func (p *PKGBUILD) RemoveSource(source string) error {
+	removed := false
+	remove := func(a *ArchSpecific) {
+		if i := indexOf(a.Source, source); i >= 0 {
+			a.Source = removeIndex(a.Source, i)
+			for _, algo := range checksumAlgorithms {
+				sums := a.checksumArray(algo)
+				if i < len(*sums) {
+					*sums = removeIndex(*sums, i)
+				}
+			}
+			removed = true
+		}
+	}
+
+	common := p.archIndependent()
+	remove(common)
+	for _, a := range p.ArchSpecific {
+		remove(a)
+	}
+
+	if !removed {
+		return fmt.Errorf("no such source: %s", source)
+	}
+	p.setArchIndependent(common)
+	return nil
+}
+
+// indexOf returns the index of the first occurrence of value in values or -1.
+func indexOf(values []string, value string) int {
+	for i, v := range values {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
+
+// removeIndex returns values without the value at index i.
+func removeIndex(values []string, i int) []string {
+	return append(values[:i:i], values[i+1:]...)
+}
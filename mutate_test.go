@@ -0,0 +1,136 @@
+package pkgbuild
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSetters(t *testing.T) {
+	pkg, err := ParseSRCINFOContent([]byte(`pkgbase = foo
+	pkgver = 1.0
+	pkgrel = 1
+	arch = x86_64
+	depends = bar>=1.0
+	source = foo.tar.gz
+	source = foo.patch
+	source = foo.service
+	md5sums = aaaa
+	md5sums = bbbb
+	md5sums = cccc
+	sha256sums = dddd
+	sha256sums = eeee
+	sha256sums = ffff
+
+pkgname = foo
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pkg.SetPkgver("1.1"); err != nil {
+		t.Error(err)
+	}
+	if err := pkg.SetPkgrel("2"); err != nil {
+		t.Error(err)
+	}
+	if pkg.Version() != "1.1-2" {
+		t.Errorf("expected version 1.1-2, got %s", pkg.Version())
+	}
+
+	if err := pkg.AddDepend("bar<2.0"); err != nil {
+		t.Error(err)
+	}
+	if err := pkg.AddDepend("baz"); err != nil {
+		t.Error(err)
+	}
+	if deps := dependencyStrings(pkg.Depends); !reflect.DeepEqual(deps, []string{"bar>=1.0 bar<2.0", "baz"}) {
+		t.Errorf("unexpected depends: %v", deps)
+	}
+
+	if err := pkg.AddProvide("libfoo.so=1-64"); err != nil {
+		t.Error(err)
+	}
+	if err := pkg.AddProvide("libfoo.so=1-64"); err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(pkg.Provides, []string{"libfoo.so=1-64"}) {
+		t.Errorf("unexpected provides: %v", pkg.Provides)
+	}
+
+	if err := pkg.RemoveSource("foo.patch"); err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(pkg.Source, []string{"foo.tar.gz", "foo.service"}) ||
+		!reflect.DeepEqual(pkg.Md5sums, []string{"aaaa", "cccc"}) ||
+		!reflect.DeepEqual(pkg.Sha256sums, []string{"dddd", "ffff"}) {
+		t.Errorf("unexpected sources after remove: %v %v %v", pkg.Source, pkg.Md5sums, pkg.Sha256sums)
+	}
+}
+
+func TestSettersInvalid(t *testing.T) {
+	pkg := &PKGBUILD{Pkgver: "1.0", Pkgrel: "1"}
+
+	for name, err := range map[string]error{
+		"SetPkgver":    pkg.SetPkgver("1.0-1"),
+		"SetPkgrel":    pkg.SetPkgrel(""),
+		"AddDepend":    pkg.AddDepend("-foo"),
+		"AddProvide":   pkg.AddProvide("foo>=bar:"),
+		"RemoveSource": pkg.RemoveSource("foo.tar.gz"),
+	} {
+		if err == nil {
+			t.Errorf("expected %s to fail", name)
+		}
+	}
+
+	if pkg.Pkgver != "1.0" || pkg.Pkgrel != "1" || len(pkg.Depends) != 0 || len(pkg.Provides) != 0 {
+		t.Errorf("invalid input modified the PKGBUILD")
+	}
+}
+
+func TestCopy(t *testing.T) {
+	pkg, err := ParseSRCINFOContent([]byte(`pkgbase = foo
+	pkgver = 1.0
+	pkgrel = 1
+	arch = x86_64
+	depends = bar>=1.0
+	depends = baz=2.0
+	depends_x86_64 = qux<3
+	source = foo.tar.gz
+	sha256sums = aaaa
+	source_x86_64 = foo.bin
+	sha256sums_x86_64 = bbbb
+	_extra = value
+
+pkgname = foo
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := pkg.Copy()
+	if !reflect.DeepEqual(c, pkg) {
+		t.Fatalf("expected copy to be equal, got %s", Diff(pkg, c))
+	}
+
+	c.Arch[0] = "i686"
+	c.Depends[0].MinVer.Version = "9"
+	c.Depends[1].Name = "other"
+	c.ArchSpecific["x86_64"].Source[0] = "other.bin"
+	c.ArchSpecific["x86_64"].Depends[0].Name = "other"
+	c.Sha256sums[0] = "cccc"
+	c.Extra["_extra"][0] = "other"
+
+	if len(Diff(pkg, c)) == 0 {
+		t.Error("expected the copy to differ")
+	}
+
+	if pkg.Arch[0] != "x86_64" || pkg.Depends[0].MinVer.Version != "1.0" || pkg.Depends[1].Name != "baz" ||
+		pkg.ArchSpecific["x86_64"].Source[0] != "foo.bin" || pkg.ArchSpecific["x86_64"].Depends[0].Name != "qux" ||
+		pkg.Sha256sums[0] != "aaaa" || pkg.Extra["_extra"][0] != "value" {
+		t.Error("expected the original to be unmodified")
+	}
+
+	if c.Depends[1].MinVer != c.Depends[1].MaxVer {
+		t.Error("expected exact versions to share the version")
+	}
+}
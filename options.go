@@ -0,0 +1,231 @@
+package pkgbuild
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// ParseOption configures how .SRCINFO content is parsed.
+type ParseOption func(*parseConfig)
+
+// parseConfig holds the settings of a single parse.
+type parseConfig struct {
+	limits          Limits
+	hardened        bool
+	continueOnError bool
+	sandbox         []string // command template ParsePKGBUILD runs makepkg in
+	embedded        bool     // evaluate PKGBUILDs without makepkg
+	dialect         Dialect
+	mingwEnv        string          // MSYS2 environment e.g. "ucrt64"
+	fields          map[string]bool // variables to parse, nil for all
+	interner        *Interner
+	zeroCopy        bool // reference the input instead of copying it
+	progress        ProgressFunc
+	logger          Logger
+}
+
+// newParseConfig returns the parse config resulting from applying opts.
+func newParseConfig(opts []ParseOption) *parseConfig {
+	config := &parseConfig{}
+	for _, opt := range opts {
+		opt(config)
+	}
+	return config
+}
+
+// Limits restricts the resources used when parsing untrusted input. A zero
+// value means no limit.
+type Limits struct {
+	MaxInputSize   int // max size of the input in bytes
+	MaxArrayLength int // max number of values of a single variable
+	MaxTokenLength int // max length of a variable name or value in bytes
+}
+
+// DefaultLimits are limits suitable for parsing untrusted .SRCINFO content.
+var DefaultLimits = Limits{
+	MaxInputSize:   1 << 20,
+	MaxArrayLength: 4096,
+	MaxTokenLength: 64 << 10,
+}
+
+// WithLimits enables the hardened parsing mode for untrusted input. Input
+// exceeding limits is rejected with an error. As a last resort an
+// unexpected panic during parsing is returned as a PanicError, holding the
+// stack trace, instead of crashing the program.
+func WithLimits(limits Limits) ParseOption {
+	return func(c *parseConfig) {
+		c.limits = limits
+		c.hardened = true
+	}
+}
+
+// WithContinueOnError makes the parser record problems like an invalid
+// dependency or epoch and keep going instead of failing at the first one.
+// The partially populated PKGBUILD is then returned along with a ParseErrors
+// error listing all the issues found.
+func WithContinueOnError() ParseOption {
+	return func(c *parseConfig) {
+		c.continueOnError = true
+	}
+}
+
+// DefaultSandbox is a bubblewrap command template running makepkg, or
+// pkgver() for File.EvalPkgver, without network access and with only the
+// system directories and the package, or source, directory, read-only,
+// available.
+var DefaultSandbox = []string{
+	"bwrap",
+	"--unshare-all",
+	"--die-with-parent",
+	"--new-session",
+	"--ro-bind", "/usr", "/usr",
+	"--symlink", "usr/bin", "/bin",
+	"--symlink", "usr/bin", "/sbin",
+	"--symlink", "usr/lib", "/lib",
+	"--symlink", "usr/lib", "/lib64",
+	"--ro-bind", "/etc", "/etc",
+	"--proc", "/proc",
+	"--dev", "/dev",
+	"--tmpfs", "/tmp",
+	"--ro-bind", "{dir}", "{dir}",
+	"--chdir", "{dir}",
+	"--",
+}
+
+// WithSandbox makes ParsePKGBUILD run makepkg through the command template,
+// e.g. DefaultSandbox or []string{"unshare", "--net", "--"}. The makepkg
+// command is appended to the template and {dir} in any of its arguments is
+// replaced by the absolute path of the directory of the PKGBUILD. It also
+// sets the template File.EvalPkgver runs pkgver() through.
+func WithSandbox(command []string) ParseOption {
+	return func(c *parseConfig) {
+		c.sandbox = command
+	}
+}
+
+// WithEmbeddedEvaluation makes ParsePKGBUILD evaluate the variables of the
+// PKGBUILD with the built-in shell parser and Expander instead of running
+// makepkg, see File.PKGBUILD. Nothing is executed, so this is safe for
+// untrusted input, but command substitutions and references to unknown
+// variables are kept as is.
+func WithEmbeddedEvaluation() ParseOption {
+	return func(c *parseConfig) {
+		c.embedded = true
+	}
+}
+
+// WithFields makes the parser only read the variables fields, e.g.
+// "pkgver" or "depends", the values of all others are skipped by the lexer
+// and left empty. Arch specific variants like depends_x86_64 are included
+// with their variable, pkgbase and pkgname are always read as they start
+// the sections. Only the requested required variables are validated. This
+// speeds up bulk parsing when only a few variables are needed.
+func WithFields(fields ...string) ParseOption {
+	return func(c *parseConfig) {
+		c.fields = map[string]bool{"pkgbase": true, "pkgname": true}
+		for _, field := range fields {
+			c.fields[field] = true
+		}
+	}
+}
+
+// WithInterner makes the parser intern variable names and common values
+// with in, see Interner, so PKGBUILDs parsed with the same Interner share
+// their common strings.
+func WithInterner(in *Interner) ParseOption {
+	return func(c *parseConfig) {
+		c.interner = in
+	}
+}
+
+// WithZeroCopy makes ParseSRCINFOContent and WalkSRCINFO reference the
+// content passed to them from the parsed values instead of copying it,
+// saving an allocation of the size of the content. The content must not be
+// modified while the values are in use. Values interned with WithInterner
+// are copied regardless.
+func WithZeroCopy() ParseOption {
+	return func(c *parseConfig) {
+		c.zeroCopy = true
+	}
+}
+
+// ProgressFunc is called by batch operations before each item with the
+// number of items done, the total and the item, e.g. a file name. It's
+// called once more with done equal to total and an empty item when the
+// batch is complete.
+type ProgressFunc func(done, total int, item string)
+
+// report calls fn unless it's nil.
+func (fn ProgressFunc) report(done, total int, item string) {
+	if fn != nil {
+		fn(done, total, item)
+	}
+}
+
+// WithProgress makes batch operations like ParseSRCINFODirFS report their
+// progress to fn.
+func WithProgress(fn ProgressFunc) ParseOption {
+	return func(c *parseConfig) {
+		c.progress = fn
+	}
+}
+
+// ParseErrors lists the issues found when parsing with WithContinueOnError.
+type ParseErrors []error
+
+func (e ParseErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Is reports whether any of the errors matches target, see errors.Is.
+func (e ParseErrors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first of the errors matching target, see errors.As.
+func (e ParseErrors) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// read reads all of r, failing if it exceeds the max input size.
+func (c *parseConfig) read(r io.Reader) ([]byte, error) {
+	if c.limits.MaxInputSize <= 0 {
+		return ioutil.ReadAll(r)
+	}
+
+	content, err := ioutil.ReadAll(io.LimitReader(r, int64(c.limits.MaxInputSize)+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.checkInputSize(len(content)); err != nil {
+		return nil, err
+	}
+
+	return content, nil
+}
+
+// checkInputSize returns an error if size exceeds the max input size.
+func (c *parseConfig) checkInputSize(size int) error {
+	if c.limits.MaxInputSize > 0 && size > c.limits.MaxInputSize {
+		return fmt.Errorf("%w of %d bytes", ErrInputTooLarge, c.limits.MaxInputSize)
+	}
+	return nil
+}
@@ -0,0 +1,267 @@
+package pkgbuild
+
+import (
+	"errors"
+	"io/ioutil"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// Test that malformed input never panics or hangs the parser
+func TestParseMalformedInput(t *testing.T) {
+	for _, name := range []string{"teamviewer", "linux", "shaman-git"} {
+		content, err := ioutil.ReadFile("./test_pkgbuilds/SRCINFO_" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i := range content {
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						t.Errorf("parsing %s truncated at %d panicked: %v", name, i, r)
+					}
+				}()
+
+				ParseSRCINFOContent(content[:i])
+				ParseSRCINFOContent(content[i:])
+			}()
+		}
+	}
+
+	inputs := []string{
+		"",
+		"\n\n\n",
+		"# comment_with_underscore\n",
+		"pkgname = foo\n",
+		"pkgbase = foo\n\tdepends = ø>1\n",
+		"pkgbase = foo\n\tpkgver",
+		"pkgbase = foo\n\tsource_x86_64 = foo\n",
+		"pkgbase_ = foo\n",
+		"pkgbase_x86_64 = foo\n\tpkgver = 1\n",
+	}
+
+	for _, input := range inputs {
+		// without the recover of the hardened mode
+		for _, opts := range [][]ParseOption{nil, {WithContinueOnError()}} {
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						t.Errorf("parsing %q panicked: %v", input, r)
+					}
+				}()
+				ParseSRCINFOContent([]byte(input), opts...)
+			}()
+		}
+
+		_, err := ParseSRCINFOContent([]byte(input), WithLimits(DefaultLimits))
+		if err == nil {
+			t.Errorf("expected error parsing %q", input)
+		}
+	}
+}
+
+// Test that randomly mutated input never panics the parser, with a fixed
+// seed to be reproducible
+func TestParseMutatedInput(t *testing.T) {
+	seeds := readSRCINFOFixtures(t)
+	alphabet := []byte(" \t\n=#_-<>:.$\"'\\pkgbasenamever0123456789x86_64")
+	r := rand.New(rand.NewSource(1))
+
+	iterations := 20000
+	if testing.Short() {
+		iterations = 1000
+	}
+
+	for i := 0; i < iterations; i++ {
+		input := append([]byte{}, seeds[r.Intn(len(seeds))]...)
+		for n := 1 + r.Intn(8); n > 0 && len(input) > 0; n-- {
+			pos := r.Intn(len(input))
+			switch r.Intn(3) {
+			case 0:
+				input[pos] = alphabet[r.Intn(len(alphabet))]
+			case 1:
+				input = append(input[:pos:pos], input[pos+r.Intn(len(input)-pos):]...)
+			case 2:
+				input = append(input[:pos:pos], append([]byte{alphabet[r.Intn(len(alphabet))]}, input[pos:]...)...)
+			}
+		}
+
+		for _, opts := range [][]ParseOption{nil, {WithContinueOnError()}, {WithFields("depends", "source")}} {
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						t.Fatalf("parsing %q panicked: %v", input, r)
+					}
+				}()
+				ParseSRCINFOContent(input, opts...)
+			}()
+		}
+	}
+}
+
+func TestParsePanicError(t *testing.T) {
+	// panics of the parser are bugs, forced here by the logger
+	logger := LoggerFunc(func(format string, args ...interface{}) {
+		panic("logger")
+	})
+
+	var err error
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				t.Errorf("expected the hardened mode to recover, got: %v", r)
+			}
+		}()
+		_, err = ParseSRCINFOContent([]byte("pkgbase = foo\n\tnewfield = a\n"), WithLogger(logger), WithLimits(DefaultLimits))
+	}()
+
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) || panicErr.Value != "logger" || len(panicErr.Stack) == 0 {
+		t.Errorf("expected a PanicError with a stack trace, got: %v", err)
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	valid := "pkgbase = foo\n\tpkgver = 1.0\n\tpkgrel = 1\n\tarch = any\n\tsource = a\n\tsource = b\n\npkgname = foo\n"
+
+	_, err := ParseSRCINFOContent([]byte(valid), WithLimits(DefaultLimits))
+	if err != nil {
+		t.Errorf("should parse with default limits: %s", err)
+	}
+
+	limits := map[string]Limits{
+		"input size":   {MaxInputSize: 10},
+		"array length": {MaxArrayLength: 1},
+		"token length": {MaxTokenLength: 4},
+	}
+
+	for name, l := range limits {
+		_, err = ParseSRCINFOContent([]byte(valid), WithLimits(l))
+		if err == nil {
+			t.Errorf("should fail with %s limit", name)
+		}
+	}
+
+	_, err = ParseSRCINFO("./test_pkgbuilds/SRCINFO_linux", WithLimits(Limits{MaxInputSize: 100}))
+	if err == nil || !strings.Contains(err.Error(), "max size") {
+		t.Errorf("should fail with input size limit, got: %v", err)
+	}
+}
+
+func TestParseContinueOnError(t *testing.T) {
+	input := `pkgbase = foo
+	pkgver = 1.0
+	pkgrel = 1
+	epoch = x
+	arch = x86_64
+	depends = -invalid
+	depends = bar>=1.0
+	source_armv7h = foo.tar.gz
+	foo = bar
+	source = foo.tar.gz
+
+pkgname = foo
+`
+
+	_, err := ParseSRCINFOContent([]byte(input))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	pkg, err := ParseSRCINFOContent([]byte(input), WithContinueOnError())
+	issues, ok := err.(ParseErrors)
+	if !ok {
+		t.Fatalf("expected ParseErrors, got %v", err)
+	}
+
+	if len(issues) != 3 {
+		t.Errorf("expected 3 issues, got %d: %s", len(issues), issues)
+	}
+
+	if pkg == nil {
+		t.Fatal("expected a partially parsed PKGBUILD")
+	}
+
+	if len(pkg.Depends) != 1 || pkg.Depends[0].Name != "bar" {
+		t.Errorf("unexpected depends: %v", pkg.Depends)
+	}
+
+	if len(pkg.Source) != 1 || len(pkg.Pkgnames) != 1 {
+		t.Errorf("parsing should continue after errors: %v %v", pkg.Source, pkg.Pkgnames)
+	}
+
+	_, err = ParseSRCINFOContent([]byte("pkgbase = foo\n"), WithContinueOnError())
+	if issues, ok := err.(ParseErrors); !ok || len(issues) != 3 {
+		t.Errorf("expected 3 validation issues, got %v", err)
+	}
+}
+
+func TestParseWithFields(t *testing.T) {
+	content, err := ioutil.ReadFile("./test_pkgbuilds/SRCINFO_linux")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	full, err := ParseSRCINFOContent(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkgb, err := ParseSRCINFOContent(content, WithFields("pkgver", "makedepends"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pkgb.Pkgbase != full.Pkgbase || pkgb.Pkgver != full.Pkgver {
+		t.Errorf("expected %s %s, got %s %s", full.Pkgbase, full.Pkgver, pkgb.Pkgbase, pkgb.Pkgver)
+	}
+
+	if len(pkgb.Makedepends) != len(full.Makedepends) {
+		t.Errorf("expected %d makedepends, got %d", len(full.Makedepends), len(pkgb.Makedepends))
+	}
+
+	if len(pkgb.Pkgnames) != len(full.Pkgnames) {
+		t.Errorf("expected pkgnames %v, got %v", full.Pkgnames, pkgb.Pkgnames)
+	}
+
+	if pkgb.Pkgrel != "" || len(pkgb.Arch) != 0 || len(pkgb.Source) != 0 || len(pkgb.License) != 0 {
+		t.Error("expected fields not requested to be empty")
+	}
+
+	// masked required fields are not validated
+	_, err = ParseSRCINFOContent([]byte("pkgbase = foo\n\tpkgver = 1.0\n\npkgname = foo\n"), WithFields("pkgver"))
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	_, err = ParseSRCINFOContent([]byte("pkgbase = foo\n\tpkgrel = 1\n\npkgname = foo\n"), WithFields("pkgver"))
+	if err == nil {
+		t.Error("expected error for missing pkgver")
+	}
+}
+
+func BenchmarkParseSRCINFO(b *testing.B) {
+	content, err := ioutil.ReadFile("./test_pkgbuilds/SRCINFO_linux")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ParseSRCINFOContent(content)
+	}
+}
+
+func BenchmarkParseSRCINFOWithFields(b *testing.B) {
+	content, err := ioutil.ReadFile("./test_pkgbuilds/SRCINFO_linux")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ParseSRCINFOContent(content, WithFields("pkgver", "depends"))
+	}
+}
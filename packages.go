@@ -0,0 +1,55 @@
+package pkgbuild
+
+// HasDebugPackage reports whether makepkg builds a pkgbase-debug package
+// for p. confOptions is the OPTIONS array of makepkg.conf, which applies
+// unless options of p override it. Debug packages need both the debug and
+// strip options. makepkg skips the debug package if there turn out to be
+// no debug symbols, which can't be predicted.
+func (p *PKGBUILD) HasDebugPackage(confOptions []string) bool {
+	return p.OptionEnabled(OptionDebug, confOptions) && p.OptionEnabled(OptionStrip, confOptions)
+}
+
+// PackageNames returns the names of the packages makepkg builds from p, the
+// pkgnames followed by the debug package if enabled, see HasDebugPackage.
+func (p *PKGBUILD) PackageNames(confOptions []string) []string {
+	names := append([]string{}, p.Pkgnames...)
+	if p.HasDebugPackage(confOptions) {
+		names = append(names, p.pkgbase()+"-debug")
+	}
+	return names
+}
+
+// PackageFileNames returns the file names of the packages makepkg builds
+// from p for the architecture carch like makepkg --packagelist, without
+// the PKGDEST directory. pkgext is the PKGEXT of makepkg.conf e.g.
+// ".pkg.tar.zst".
+func (p *PKGBUILD) PackageFileNames(carch, pkgext string, confOptions []string) []string {
+	arch := carch
+	if contains(p.Arch, "any") {
+		arch = "any"
+	}
+
+	names := p.PackageNames(confOptions)
+	for i, name := range names {
+		names[i] = name + "-" + p.Version() + "-" + arch + pkgext
+	}
+	return names
+}
+
+// pkgbase returns the pkgbase of p, which defaults to the first pkgname.
+func (p *PKGBUILD) pkgbase() string {
+	if p.Pkgbase == "" && len(p.Pkgnames) > 0 {
+		return p.Pkgnames[0]
+	}
+	return p.Pkgbase
+}
+
+// checkOption reports whether the option name is enabled, like check_option
+// in makepkg. The last of name or !name wins, options take precedence over
+// confOptions.
+func checkOption(name string, options, confOptions []string) bool {
+	if state := optionState(name, options); state != OptionUnset {
+		return state == OptionEnabled
+	}
+	return optionState(name, confOptions) == OptionEnabled
+}
@@ -0,0 +1,53 @@
+package pkgbuild
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPackageFileNames(t *testing.T) {
+	conf := []string{"strip", "docs", "!libtool", "!staticlibs", "emptydirs", "zipman", "purge", "debug", "lto"}
+
+	pkg := &PKGBUILD{
+		Pkgbase:  "foo",
+		Pkgnames: []string{"foo", "libfoo"},
+		Pkgver:   "1.0",
+		Pkgrel:   "2",
+		Epoch:    1,
+		Arch:     []string{"x86_64", "aarch64"},
+	}
+
+	expected := []string{
+		"foo-1:1.0-2-x86_64.pkg.tar.zst",
+		"libfoo-1:1.0-2-x86_64.pkg.tar.zst",
+		"foo-debug-1:1.0-2-x86_64.pkg.tar.zst",
+	}
+	if names := pkg.PackageFileNames("x86_64", ".pkg.tar.zst", conf); !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+
+	for _, test := range []struct {
+		options []string
+		conf    []string
+		debug   bool
+	}{
+		{nil, conf, true},
+		{nil, nil, false},
+		{[]string{"!debug"}, conf, false},
+		{[]string{"!strip"}, conf, false},
+		{[]string{"debug"}, []string{"strip", "!debug"}, true},
+		{[]string{"debug", "!debug"}, conf, false},
+		{[]string{"debug"}, []string{"!strip"}, false},
+	} {
+		pkg.Options = test.options
+		if debug := pkg.HasDebugPackage(test.conf); debug != test.debug {
+			t.Errorf("expected debug package %t for options %v and %v", test.debug, test.options, test.conf)
+		}
+	}
+
+	pkg = &PKGBUILD{Pkgnames: []string{"bar"}, Pkgver: "2.0", Pkgrel: "1", Arch: []string{"any"}}
+	expected = []string{"bar-2.0-1-any.pkg.tar.xz", "bar-debug-2.0-1-any.pkg.tar.xz"}
+	if names := pkg.PackageFileNames("x86_64", ".pkg.tar.xz", conf); !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+}
@@ -2,8 +2,10 @@ package pkgbuild
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
-	"io/ioutil"
+	"os"
+	"runtime/debug"
 	"strconv"
 	"strings"
 )
@@ -15,108 +17,41 @@ type Dependency struct {
 	sgt    bool             // defines if min version is strictly greater than
 	MaxVer *CompleteVersion // max version
 	slt    bool             // defines if max version is strictly less than
+
+	// Exclude lists versions not satisfying the dependency like the 1.2 of
+	// "foo!=1.2". It's not supported by pacman, see ParseConstraint.
+	Exclude []*CompleteVersion
 }
 
 // Restrict merges two dependencies together into a new dependency where the
-// conditions of both a and b are met
+// conditions of both a and b are met. Pins like "=1.2" are ranges with equal
+// bounds, so "=1.2" restricted by ">=1.0 <2" is "=1.2" while conflicting
+// conditions like "=1.2" and "=1.3" result in an empty range, see Combine.
 func (a *Dependency) Restrict(b *Dependency) *Dependency {
-	newDep := &Dependency{
-		Name: a.Name,
-	}
-
-	if a.MaxVer != nil || b.MaxVer != nil {
-		newDep.MaxVer = &CompleteVersion{}
-
-		if a.MaxVer == nil {
-			*newDep.MaxVer = *b.MaxVer
-			newDep.slt = b.slt
-		} else if b.MaxVer == nil {
-			*newDep.MaxVer = *a.MaxVer
-			newDep.slt = a.slt
-		} else {
-			cmpMax := a.MaxVer.cmp(b.MaxVer)
-			if cmpMax >= 1 {
-				*newDep.MaxVer = *b.MaxVer
-				newDep.slt = b.slt
-			} else if cmpMax <= -1 {
-				*newDep.MaxVer = *a.MaxVer
-				newDep.slt = a.slt
-			} else if cmpMax == 0 {
-				if len(a.MaxVer.Pkgrel) > len(b.MaxVer.Pkgrel) {
-					*newDep.MaxVer = *a.MaxVer
-				} else {
-					*newDep.MaxVer = *b.MaxVer
-				}
-				if a.slt != b.slt {
-					newDep.slt = true
-				} else {
-					newDep.slt = a.slt
-				}
-			}
-		}
-	}
-
-	if a.MinVer != nil || b.MinVer != nil {
-		newDep.MinVer = &CompleteVersion{}
-
-		if a.MinVer == nil {
-			*newDep.MinVer = *b.MinVer
-			newDep.sgt = b.slt
-		} else if b.MinVer == nil {
-			*newDep.MinVer = *a.MinVer
-			newDep.sgt = a.sgt
-		} else {
-			cmpMin := a.MinVer.cmp(b.MinVer)
-			if cmpMin >= 1 {
-				*newDep.MinVer = *a.MinVer
-				newDep.sgt = a.sgt
-			} else if cmpMin <= -1 {
-				*newDep.MinVer = *b.MinVer
-				newDep.sgt = b.sgt
-			} else if cmpMin == 0 {
-				if len(a.MinVer.Pkgrel) > len(b.MinVer.Pkgrel) {
-					*newDep.MinVer = *a.MinVer
-				} else {
-					*newDep.MinVer = *b.MinVer
-				}
-				if a.sgt != b.sgt {
-					newDep.sgt = true
-				} else {
-					newDep.sgt = a.sgt
-				}
-			}
-		}
-	}
-
-	return newDep
+	return newDependency(a.Name, a.Range().Intersect(b.Range()))
 }
 
-func (dep *Dependency) String() string {
-	str := ""
-	greaterThan := ">"
-	lessThan := "<"
-
-	if !dep.sgt {
-		greaterThan = ">="
+// Combine is like Restrict but returns a *ConflictError if a and b are on
+// different names or if no version meets the conditions of both.
+func (a *Dependency) Combine(b *Dependency) (*Dependency, error) {
+	if a.Name != b.Name {
+		return nil, &ConflictError{A: a, B: b}
 	}
 
-	if !dep.slt {
-		lessThan = "<="
-	}
-
-	if dep.MinVer != nil {
-		str += dep.Name + greaterThan + dep.MinVer.String()
-
-		if dep.MaxVer != nil {
-			str += " "
-		}
+	dep := a.Restrict(b)
+	if dep.Range().IsEmpty() {
+		return nil, &ConflictError{A: a, B: b}
 	}
+	return dep, nil
+}
 
-	if dep.MaxVer != nil {
-		str += dep.Name + lessThan + dep.MaxVer.String()
+func (dep *Dependency) String() string {
+	constraints := dep.Constraints()
+	parts := make([]string, 0, len(constraints))
+	for _, c := range constraints {
+		parts = append(parts, dep.Name+c.String())
 	}
-
-	return str
+	return strings.Join(parts, " ")
 }
 
 // PKGBUILD is a struct describing a parsed PKGBUILD file.
@@ -161,40 +96,137 @@ type PKGBUILD struct {
 	Sha512sums   []string
 	B2sums       []string
 	Validpgpkeys []string
+	ArchSpecific map[string]*ArchSpecific
+	Comments     []string            // leading comments e.g. "Generated by mksrcinfo v8"
+	Extra        map[string][]string // values of unknown variables by name
+	Maintainers  []Person            // from the PKGBUILD header, not part of .SRCINFO
+	Contributors []Person
+
+	// base holds the values of the variables which can be arch specific
+	// without those of ArchSpecific, in parse order. It's nil for a
+	// PKGBUILD which wasn't parsed.
+	base *ArchSpecific
 }
 
-// Newer is true if p has a higher version number than p2
-func (p *PKGBUILD) Newer(p2 *PKGBUILD) bool {
-	if p.Epoch < p2.Epoch {
-		return false
-	}
+// ArchSpecific holds the values of architecture specific variables like
+// source_x86_64 or depends_i686 for a single architecture. The values are
+// included in the variables of the PKGBUILD too, e.g. Depends contains the
+// values of depends and of all depends_<arch>.
+type ArchSpecific struct {
+	Depends      []*Dependency
+	Optdepends   []string
+	Makedepends  []*Dependency
+	Checkdepends []*Dependency
+	Provides     []string
+	Conflicts    []string
+	Replaces     []string
+	Source       []string
+	Md5sums      []string
+	Sha1sums     []string
+	Sha224sums   []string
+	Sha256sums   []string
+	Sha384sums   []string
+	Sha512sums   []string
+	B2sums       []string
+}
 
-	if p.Pkgver.bigger(p2.Pkgver) {
-		return true
-	}
+// add adds value to the variable of type typ.
+func (a *ArchSpecific) add(typ itemType, value string) error {
+	var err error
 
-	if p2.Pkgver.bigger(p.Pkgver) {
-		return false
+	switch typ {
+	case itemDepends:
+		a.Depends, err = parseDependency(value, a.Depends)
+	case itemOptdepends:
+		a.Optdepends = appendValue(a.Optdepends, value)
+	case itemMakedepends:
+		a.Makedepends, err = parseDependency(value, a.Makedepends)
+	case itemCheckdepends:
+		a.Checkdepends, err = parseDependency(value, a.Checkdepends)
+	case itemProvides:
+		a.Provides = appendValue(a.Provides, value)
+	case itemConflicts:
+		a.Conflicts = appendValue(a.Conflicts, value)
+	case itemReplaces:
+		a.Replaces = appendValue(a.Replaces, value)
+	case itemSource:
+		a.Source = appendValue(a.Source, value)
+	case itemMd5sums:
+		a.Md5sums = appendValue(a.Md5sums, value)
+	case itemSha1sums:
+		a.Sha1sums = appendValue(a.Sha1sums, value)
+	case itemSha224sums:
+		a.Sha224sums = appendValue(a.Sha224sums, value)
+	case itemSha256sums:
+		a.Sha256sums = appendValue(a.Sha256sums, value)
+	case itemSha384sums:
+		a.Sha384sums = appendValue(a.Sha384sums, value)
+	case itemSha512sums:
+		a.Sha512sums = appendValue(a.Sha512sums, value)
+	case itemB2sums:
+		a.B2sums = appendValue(a.B2sums, value)
+	default:
+		return fmt.Errorf("variable can not be arch specific")
+	}
+
+	return err
+}
+
+// addArchSpecific adds value to the variable of type typ for arch.
+func (p *PKGBUILD) addArchSpecific(typ itemType, variable, arch, value string) error {
+	for _, a := range p.Arch {
+		if a == arch {
+			if err := p.archSpecific(arch).add(typ, value); err != nil {
+				return fmt.Errorf("%w: %s_%s", err, variable, arch)
+			}
+			return nil
+		}
 	}
 
-	return p.Pkgrel > p2.Pkgrel
+	return fmt.Errorf("unsupported arch for variable: %s_%s", variable, arch)
 }
 
-// Older is true if p has a smaller version number than p2
-func (p *PKGBUILD) Older(p2 *PKGBUILD) bool {
-	if p.Epoch < p2.Epoch {
-		return true
+// archSpecific returns the arch specific values for arch, creating them if
+// they don't exist yet.
+func (p *PKGBUILD) archSpecific(arch string) *ArchSpecific {
+	if p.ArchSpecific == nil {
+		p.ArchSpecific = make(map[string]*ArchSpecific)
 	}
 
-	if p2.Pkgver.bigger(p.Pkgver) {
-		return true
+	a, ok := p.ArchSpecific[arch]
+	if !ok {
+		a = &ArchSpecific{}
+		p.ArchSpecific[arch] = a
 	}
 
-	if p.Pkgver.bigger(p2.Pkgver) {
-		return false
-	}
+	return a
+}
+
+// Newer is true if p has a higher version number than p2
+func (p *PKGBUILD) Newer(p2 *PKGBUILD) bool {
+	return p.Compare(p2) == 1
+}
 
-	return p.Pkgrel < p2.Pkgrel
+// Older is true if p has a smaller version number than p2
+func (p *PKGBUILD) Older(p2 *PKGBUILD) bool {
+	return p.Compare(p2) == -1
+}
+
+// Compare compares the full versions of p and p2, including epoch and
+// pkgrel, returning -1 if p is older than p2, 0 if they are equal and 1 if
+// p is newer.
+func (p *PKGBUILD) Compare(p2 *PKGBUILD) int {
+	// the epochs are compared as int, CompleteVersion only holds 8 bits
+	switch {
+	case p.Epoch > p2.Epoch:
+		return 1
+	case p.Epoch < p2.Epoch:
+		return -1
+	}
+
+	a := CompleteVersion{Version: p.Pkgver, Pkgrel: p.Pkgrel}
+	b := CompleteVersion{Version: p2.Pkgver, Pkgrel: p2.Pkgrel}
+	return a.Compare(&b)
 }
 
 // Version returns the full version of the PKGBUILD (including epoch and rel)
@@ -228,27 +260,15 @@ func (p *PKGBUILD) BuildDepends() []*Dependency {
 }
 
 // IsDevel returns true if package contains devel packages (-{bzr,git,svn,hg})
-// TODO: more robust check.
+// or is built from VCS sources.
 func (p *PKGBUILD) IsDevel() bool {
 	for _, name := range p.Pkgnames {
-		if strings.HasSuffix(name, "-git") {
-			return true
-		}
-
-		if strings.HasSuffix(name, "-svn") {
-			return true
-		}
-
-		if strings.HasSuffix(name, "-hg") {
-			return true
-		}
-
-		if strings.HasSuffix(name, "-bzr") {
+		if _, ok := VCSBaseName(name); ok {
 			return true
 		}
 	}
 
-	return false
+	return len(p.VCSSources()) > 0
 }
 
 // MustParseSRCINFO must parse the .SRCINFO given by path or it will panic
@@ -263,166 +283,260 @@ func MustParseSRCINFO(path string) *PKGBUILD {
 // ParseSRCINFO parses .SRCINFO file given by path.
 // This is a safe alternative to ParsePKGBUILD given that a .SRCINFO file is
 // available
-func ParseSRCINFO(path string) (*PKGBUILD, error) {
-	f, err := ioutil.ReadFile(path)
+func ParseSRCINFO(path string, opts ...ParseOption) (*PKGBUILD, error) {
+	config := newParseConfig(opts)
+
+	f, err := os.Open(path)
 	if err != nil {
 		return nil, fmt.Errorf("unable to read file: %s, %s", path, err.Error())
 	}
+	defer f.Close()
 
-	return parsePKGBUILD(string(f))
+	content, err := config.read(f)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read file: %s, %s", path, err.Error())
+	}
+
+	// content isn't used by anything else
+	config.zeroCopy = true
+	return parsePKGBUILD(content, config)
 }
 
 // ParseSRCINFOContent parses a .SRCINFO formatted byte slice.
 // This is a safe alternative to ParsePKGBUILD given that the .SRCINFO content
 // is available
-func ParseSRCINFOContent(content []byte) (*PKGBUILD, error) {
-	return parsePKGBUILD(string(content))
+func ParseSRCINFOContent(content []byte, opts ...ParseOption) (*PKGBUILD, error) {
+	return parsePKGBUILD(content, newParseConfig(opts))
 }
 
 // parse a PKGBUILD and check that the required fields has a non-empty value
-func parsePKGBUILD(input string) (*PKGBUILD, error) {
-	pkgb, err := parse(input)
-	if err != nil {
+func parsePKGBUILD(input []byte, config *parseConfig) (pkgb *PKGBUILD, err error) {
+	// a panic is a bug, don't take down the caller for untrusted input
+	if config.hardened {
+		defer func() {
+			if r := recover(); r != nil {
+				pkgb, err = nil, &PanicError{Value: r, Stack: debug.Stack()}
+			}
+		}()
+	}
+
+	if err := config.checkInputSize(len(input)); err != nil {
 		return nil, err
 	}
 
-	if !validPkgver(string(pkgb.Pkgver)) {
-		return nil, fmt.Errorf("invalid pkgver: %s", pkgb.Pkgver)
+	pkgb, err = parse(input, config)
+	if pkgb == nil {
+		return nil, err
+	}
+
+	// in continue on error mode parse returns the issues found so far
+	issues, _ := err.(ParseErrors)
+
+	for _, err := range pkgb.validateFields(config.fields) {
+		if !config.continueOnError {
+			return nil, err
+		}
+		issues = append(issues, err)
+	}
+
+	if len(issues) > 0 {
+		return pkgb, issues
+	}
+
+	return pkgb, nil
+}
+
+// validate checks that the required fields of p have a valid value.
+func (p *PKGBUILD) validate() []error {
+	return p.validateFields(nil)
+}
+
+// validateFields is validate for only the required fields in fields, all
+// if fields is nil.
+func (p *PKGBUILD) validateFields(fields map[string]bool) []error {
+	var errs []error
+	want := func(field string) bool {
+		return fields == nil || fields[field]
+	}
+
+	if want("pkgver") && !validPkgver(string(p.Pkgver)) {
+		errs = append(errs, fmt.Errorf("%w: %s", ErrInvalidPkgver, p.Pkgver))
+	}
+
+	if want("arch") && len(p.Arch) == 0 {
+		errs = append(errs, ErrMissingArch)
 	}
 
-	if len(pkgb.Arch) == 0 {
-		return nil, fmt.Errorf("Arch missing")
+	if !want("pkgname") {
+		return errs
 	}
 
-	if len(pkgb.Pkgnames) == 0 {
-		return nil, fmt.Errorf("missing pkgname")
+	if len(p.Pkgnames) == 0 {
+		errs = append(errs, ErrMissingPkgname)
 	}
 
-	for _, name := range pkgb.Pkgnames {
+	for _, name := range p.Pkgnames {
 		if !validPkgname(name) {
-			return nil, fmt.Errorf("invalid pkgname: %s", name)
+			errs = append(errs, fmt.Errorf("%w: %s", ErrInvalidPkgname, name))
 		}
 	}
 
-	return pkgb, nil
+	return errs
 }
 
 // parses a SRCINFO formatted PKGBUILD
-func parse(input string) (*PKGBUILD, error) {
+func parse(input []byte, config *parseConfig) (*PKGBUILD, error) {
 	var pkgbuild *PKGBUILD
 	var next item
+	var comments []string
+	var issues ParseErrors
+	counts := make(map[string]int)
+
+	lexer := lex(input, config.zeroCopy)
+	lexer.maxTokenLength = config.limits.MaxTokenLength
+	lexer.continueOnError = config.continueOnError
+	lexer.fields = config.fields
+	lexer.interner = config.interner
+
+	// report returns err as a ParseError or, when continuing on errors,
+	// records it as an issue and returns nil
+	report := func(err error) error {
+		perr := lexer.errorAt(err)
+		if !config.continueOnError {
+			return perr
+		}
+		config.debugf("skipping invalid line: %s", perr)
+		issues = append(issues, perr)
+		return nil
+	}
 
-	lexer := lex(input)
 Loop:
 	for {
+		var err error
 		token := lexer.nextItem()
 
-		// strip arch from source_arch like constructs
-		witharch := strings.SplitN(token.val, "_", 2)
-		if len(witharch) == 2 {
-			found := false
-			for _, arch := range pkgbuild.Arch {
-				if arch == witharch[1] {
-					token.val = witharch[0]
-					found = true
-					break
-				}
+		if token.typ.isVariable() {
+			counts[token.val]++
+			if max := config.limits.MaxArrayLength; max > 0 && counts[token.val] > max {
+				return nil, lexer.errorAt(fmt.Errorf("%w: %s", ErrTooManyValues, token.val))
 			}
 
-			if !found {
-				return nil, fmt.Errorf("unsupported arch for variable: %s", token.val)
+			// strip arch from source_arch like constructs
+			witharch := strings.SplitN(token.val, "_", 2)
+			first := pkgbuild == nil && (token.typ != itemPkgbase || len(witharch) == 2)
+			if first || (token.typ != itemVariable && len(witharch) == 2) {
+				next = lexer.nextItem()
+				if first {
+					err = fmt.Errorf("pkgbase must be the first variable, got: %s", token.val)
+				} else {
+					err = pkgbuild.addArchSpecific(token.typ, witharch[0], witharch[1], next.val)
+				}
+
+				// the values are part of the variable too, like when
+				// building for the arch
+				if err == nil && !first {
+					merged := pkgbuild.archVariables()
+					if err = merged.add(token.typ, next.val); err == nil {
+						pkgbuild.setArchVariables(merged)
+					}
+				}
+
+				if err != nil {
+					if err = report(err); err != nil {
+						return nil, err
+					}
+				}
+				continue
 			}
 		}
 
 		switch token.typ {
+		case itemVariable:
+			next = lexer.nextItem()
+			if counts[token.val] == 1 {
+				config.debugf("unknown variable %s stored in Extra", token.val)
+			}
+			if pkgbuild.Extra == nil {
+				pkgbuild.Extra = make(map[string][]string)
+			}
+			pkgbuild.Extra[token.val] = append(pkgbuild.Extra[token.val], next.val)
 		case itemPkgbase:
 			next = lexer.nextItem()
-			pkgbuild = &PKGBUILD{Epoch: 0, Pkgbase: next.val}
+			pkgbuild = &PKGBUILD{Epoch: 0, Pkgbase: next.val, Comments: comments, base: &ArchSpecific{}}
 		case itemPkgname:
 			next = lexer.nextItem()
-			pkgbuild.Pkgnames = append(pkgbuild.Pkgnames, next.val)
+			pkgbuild.Pkgnames = appendValue(pkgbuild.Pkgnames, next.val)
 		case itemPkgver:
 			next = lexer.nextItem()
-			version, err := parseVersion(next.val)
-			if err != nil {
-				return nil, err
+			var version Version
+			version, err = parseVersion(next.val)
+			if err == nil {
+				pkgbuild.Pkgver = version
+			} else {
+				err = fmt.Errorf("%w: %s", ErrInvalidPkgver, next.val)
 			}
-			pkgbuild.Pkgver = version
 		case itemPkgrel:
 			next = lexer.nextItem()
-			rel, err := parseVersion(next.val)
-			if err != nil {
-				return nil, err
+			var rel Version
+			rel, err = parseVersion(next.val)
+			if err == nil {
+				pkgbuild.Pkgrel = rel
+			} else {
+				err = fmt.Errorf("%w: %s", ErrInvalidPkgrel, next.val)
 			}
-			pkgbuild.Pkgrel = rel
 		case itemPkgdir:
 			next = lexer.nextItem()
 			pkgbuild.Pkgdir = next.val
 		case itemEpoch:
 			next = lexer.nextItem()
-			epoch, err := strconv.ParseInt(next.val, 10, 0)
-			if err != nil {
-				return nil, err
+			epoch, perr := strconv.ParseInt(next.val, 10, 0)
+			if perr != nil || epoch < 0 {
+				err = fmt.Errorf("%w: %q", ErrInvalidEpoch, next.val)
+			} else {
+				pkgbuild.Epoch = int(epoch)
 			}
-
-			if epoch < 0 {
-				return nil, fmt.Errorf("invalid epoch: %d", epoch)
-			}
-			pkgbuild.Epoch = int(epoch)
 		case itemPkgdesc:
 			next = lexer.nextItem()
 			pkgbuild.Pkgdesc = next.val
 		case itemArch:
 			next = lexer.nextItem()
-			pkgbuild.Arch = append(pkgbuild.Arch, next.val)
+			pkgbuild.Arch = appendValue(pkgbuild.Arch, next.val)
 		case itemURL:
 			next = lexer.nextItem()
 			pkgbuild.URL = next.val
 		case itemLicense:
 			next = lexer.nextItem()
-			pkgbuild.License = append(pkgbuild.License, next.val)
+			pkgbuild.License = appendValue(pkgbuild.License, next.val)
 		case itemGroups:
 			next = lexer.nextItem()
-			pkgbuild.Groups = append(pkgbuild.Groups, next.val)
+			pkgbuild.Groups = appendValue(pkgbuild.Groups, next.val)
 		case itemDepends:
 			next = lexer.nextItem()
-			deps, err := parseDependency(next.val, pkgbuild.Depends)
-			if err != nil {
-				return nil, err
-			}
-			pkgbuild.Depends = deps
+			pkgbuild.Depends, err = parseDependencyKeep(next.val, pkgbuild.Depends)
 		case itemOptdepends:
 			next = lexer.nextItem()
-			pkgbuild.Optdepends = append(pkgbuild.Optdepends, next.val)
+			pkgbuild.Optdepends = appendValue(pkgbuild.Optdepends, next.val)
 		case itemMakedepends:
 			next = lexer.nextItem()
-			deps, err := parseDependency(next.val, pkgbuild.Makedepends)
-			if err != nil {
-				return nil, err
-			}
-			pkgbuild.Makedepends = deps
+			pkgbuild.Makedepends, err = parseDependencyKeep(next.val, pkgbuild.Makedepends)
 		case itemCheckdepends:
 			next = lexer.nextItem()
-			deps, err := parseDependency(next.val, pkgbuild.Checkdepends)
-			if err != nil {
-				return nil, err
-			}
-			pkgbuild.Checkdepends = deps
+			pkgbuild.Checkdepends, err = parseDependencyKeep(next.val, pkgbuild.Checkdepends)
 		case itemProvides:
 			next = lexer.nextItem()
-			pkgbuild.Provides = append(pkgbuild.Provides, next.val)
+			pkgbuild.Provides = appendValue(pkgbuild.Provides, next.val)
 		case itemConflicts:
 			next = lexer.nextItem()
-			pkgbuild.Conflicts = append(pkgbuild.Conflicts, next.val)
+			pkgbuild.Conflicts = appendValue(pkgbuild.Conflicts, next.val)
 		case itemReplaces:
 			next = lexer.nextItem()
-			pkgbuild.Replaces = append(pkgbuild.Replaces, next.val)
+			pkgbuild.Replaces = appendValue(pkgbuild.Replaces, next.val)
 		case itemBackup:
 			next = lexer.nextItem()
-			pkgbuild.Backup = append(pkgbuild.Backup, next.val)
+			pkgbuild.Backup = appendValue(pkgbuild.Backup, next.val)
 		case itemOptions:
 			next = lexer.nextItem()
-			pkgbuild.Options = append(pkgbuild.Options, next.val)
+			pkgbuild.Options = appendValue(pkgbuild.Options, next.val)
 		case itemInstall:
 			next = lexer.nextItem()
 			pkgbuild.Install = next.val
@@ -431,46 +545,93 @@ Loop:
 			pkgbuild.Changelog = next.val
 		case itemSource:
 			next = lexer.nextItem()
-			pkgbuild.Source = append(pkgbuild.Source, next.val)
+			pkgbuild.Source = appendValue(pkgbuild.Source, next.val)
 		case itemNoextract:
 			next = lexer.nextItem()
-			pkgbuild.Noextract = append(pkgbuild.Noextract, next.val)
+			pkgbuild.Noextract = appendValue(pkgbuild.Noextract, next.val)
 		case itemMd5sums:
 			next = lexer.nextItem()
-			pkgbuild.Md5sums = append(pkgbuild.Md5sums, next.val)
+			pkgbuild.Md5sums = appendValue(pkgbuild.Md5sums, next.val)
 		case itemSha1sums:
 			next = lexer.nextItem()
-			pkgbuild.Sha1sums = append(pkgbuild.Sha1sums, next.val)
+			pkgbuild.Sha1sums = appendValue(pkgbuild.Sha1sums, next.val)
 		case itemSha224sums:
 			next = lexer.nextItem()
-			pkgbuild.Sha224sums = append(pkgbuild.Sha224sums, next.val)
+			pkgbuild.Sha224sums = appendValue(pkgbuild.Sha224sums, next.val)
 		case itemSha256sums:
 			next = lexer.nextItem()
-			pkgbuild.Sha256sums = append(pkgbuild.Sha256sums, next.val)
+			pkgbuild.Sha256sums = appendValue(pkgbuild.Sha256sums, next.val)
 		case itemSha384sums:
 			next = lexer.nextItem()
-			pkgbuild.Sha384sums = append(pkgbuild.Sha384sums, next.val)
+			pkgbuild.Sha384sums = appendValue(pkgbuild.Sha384sums, next.val)
 		case itemSha512sums:
 			next = lexer.nextItem()
-			pkgbuild.Sha512sums = append(pkgbuild.Sha512sums, next.val)
+			pkgbuild.Sha512sums = appendValue(pkgbuild.Sha512sums, next.val)
 		case itemB2sums:
 			next = lexer.nextItem()
-			pkgbuild.B2sums = append(pkgbuild.B2sums, next.val)
+			pkgbuild.B2sums = appendValue(pkgbuild.B2sums, next.val)
 		case itemValidpgpkeys:
 			next = lexer.nextItem()
-			pkgbuild.Validpgpkeys = append(pkgbuild.Validpgpkeys, next.val)
+			pkgbuild.Validpgpkeys = appendValue(pkgbuild.Validpgpkeys, next.val)
 		case itemEndSplit:
+		case itemComment:
+			if pkgbuild == nil {
+				comment := strings.TrimSpace(strings.TrimPrefix(token.val, "#"))
+				comments = append(comments, comment)
+			}
 		case itemError:
-			return nil, fmt.Errorf(token.val)
+			err = errors.New(token.val)
 		case itemEOF:
 			break Loop
 		default:
-			return nil, fmt.Errorf("invalid variable: %s", token.val)
+			err = fmt.Errorf("invalid variable: %s", token.val)
+		}
+
+		// keep the values apart from the arch specific ones too
+		if err == nil && token.typ.isArchSpecific() {
+			err = pkgbuild.base.add(token.typ, next.val)
+		}
+
+		if err != nil {
+			if err = report(err); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if pkgbuild == nil {
+		if err := report(ErrMissingPkgbase); err != nil {
+			return nil, err
 		}
+		return nil, issues
+	}
+
+	if len(issues) > 0 {
+		return pkgbuild, issues
 	}
+
 	return pkgbuild, nil
 }
 
+// parseDependencyKeep is like parseDependency but returns deps unchanged if
+// dep can't be parsed.
+func parseDependencyKeep(dep string, deps []*Dependency) ([]*Dependency, error) {
+	newDeps, err := parseDependency(dep, deps)
+	if err != nil {
+		return deps, err
+	}
+	return newDeps, nil
+}
+
+// appendValue appends value to values unless it's blank, so empty arrays
+// like 'arch =' don't result in an empty entry.
+func appendValue(values []string, value string) []string {
+	if value == "" {
+		return values
+	}
+	return append(values, value)
+}
+
 // parse and validate a version string
 func parseVersion(s string) (Version, error) {
 	s = strings.TrimSpace(s)
@@ -478,7 +639,7 @@ func parseVersion(s string) (Version, error) {
 		return Version(s), nil
 	}
 
-	return "", fmt.Errorf("invalid version string: %s", s)
+	return "", fmt.Errorf("%w string: %s", ErrInvalidVersion, s)
 }
 
 // check if name is a valid pkgname format
@@ -546,15 +707,15 @@ func parseDependency(dep string, deps []*Dependency) ([]*Dependency, error) {
 	}
 
 	if dep[0] == '-' {
-		return nil, fmt.Errorf("invalid dependency name")
+		return nil, &InvalidDependencyError{Dep: dep, Err: errors.New("invalid dependency name")}
 	}
 
-	i := 0
-	for _, c := range dep {
+	i := len(dep)
+	for n, c := range dep {
 		if !isValidPkgnameChar(c) {
+			i = n
 			break
 		}
-		i++
 	}
 
 	// check if the dependency has been set before
@@ -585,7 +746,7 @@ func parseDependency(dep string, deps []*Dependency) ([]*Dependency, error) {
 
 		version, err := NewCompleteVersion(dep[i:])
 		if err != nil {
-			return nil, err
+			return nil, &InvalidDependencyError{Dep: dep, Err: err}
 		}
 
 		switch eq.String() {
@@ -1,6 +1,12 @@
 package pkgbuild
 
-import "testing"
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
 
 // Test version parsing
 func TestVersionParsing(t *testing.T) {
@@ -141,6 +147,58 @@ func TestOlder(t *testing.T) {
 	}
 }
 
+// Test that a higher epoch always wins
+func TestCompare(t *testing.T) {
+	a := &PKGBUILD{Epoch: 1, Pkgver: "1.0", Pkgrel: "1"}
+	b := &PKGBUILD{Epoch: 0, Pkgver: "2.0", Pkgrel: "3"}
+	c := &PKGBUILD{Epoch: 1, Pkgver: "1.0", Pkgrel: "1"}
+
+	if a.Compare(b) != 1 || b.Compare(a) != -1 || a.Compare(c) != 0 {
+		t.Errorf("unexpected comparison of %s, %s and %s", a.Version(), b.Version(), c.Version())
+	}
+
+	if !a.Newer(b) || a.Older(b) || !b.Older(a) || b.Newer(a) {
+		t.Errorf("a (%s) should be newer than b (%s)", a.Version(), b.Version())
+	}
+
+	if a.Newer(c) || a.Older(c) {
+		t.Errorf("a (%s) should be equal to c (%s)", a.Version(), c.Version())
+	}
+
+	// epochs beyond 255 don't wrap around
+	d := &PKGBUILD{Epoch: 300, Pkgver: "1.0", Pkgrel: "1"}
+	e := &PKGBUILD{Epoch: 256, Pkgver: "2.0", Pkgrel: "1"}
+	if d.Compare(a) != 1 || d.Compare(e) != 1 || e.Compare(a) != 1 || !a.Older(e) {
+		t.Errorf("unexpected comparison of %s, %s and %s", d.Version(), e.Version(), a.Version())
+	}
+}
+
+// Test pkgrel values like 2.1 used for rebuilds
+func TestNonIntegerPkgrel(t *testing.T) {
+	pkg, err := ParseSRCINFOContent([]byte("pkgbase = foo\n\tpkgver = 1.0\n\tpkgrel = 2.1\n\tarch = any\n\npkgname = foo\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pkg.Pkgrel != "2.1" || pkg.Version() != "1.0-2.1" {
+		t.Errorf("expected pkgrel 2.1, got %s", pkg.Version())
+	}
+
+	for _, rel := range []Version{"1", "2", "2.0", "10"} {
+		other := &PKGBUILD{Pkgver: "1.0", Pkgrel: rel}
+		newer := rel != "10"
+		if pkg.Newer(other) != newer || other.Older(pkg) != newer {
+			t.Errorf("%s should be newer than %s: %t", pkg.Version(), other.Version(), newer)
+		}
+	}
+
+	a := &PKGBUILD{Pkgver: "1.0", Pkgrel: "9"}
+	b := &PKGBUILD{Pkgver: "1.0", Pkgrel: "10"}
+	if !b.Newer(a) || !a.Older(b) || a.Newer(b) || b.Older(a) {
+		t.Errorf("%s should be newer than %s", b.Version(), a.Version())
+	}
+}
+
 // Test Version method
 func TestVersionMethod(t *testing.T) {
 	a := &PKGBUILD{
@@ -300,3 +358,270 @@ func TestRestrict(t *testing.T) {
 		}
 	}
 }
+
+func TestCombine(t *testing.T) {
+	for _, test := range []struct {
+		a, b     string
+		expected string // empty for a conflict
+	}{
+		{"a=1.2", "a>=1.0 a<2", "a=1.2"},
+		{"a>=1.0 a<2", "a=1.2", "a=1.2"},
+		{"a=1.0", "a=1.0-2", "a=1.0-2"},
+		{"a>=1", "a<=1", "a=1"},
+		{"a=1:1.0", "a>=1.0", "a=1:1.0"},
+		{"a>1", "a>=1", "a>1"},
+		{"a=1.2", "a=1.3", ""},
+		{"a=1.2", "a<1.2", ""},
+		{"a>2", "a<1", ""},
+		{"a", "b", ""},
+	} {
+		a, b := parseTestDependency(t, test.a), parseTestDependency(t, test.b)
+
+		dep, err := a.Combine(b)
+		if test.expected == "" {
+			if _, ok := err.(*ConflictError); !ok {
+				t.Errorf("expected conflict combining %s and %s, got %v", test.a, test.b, dep)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error combining %s and %s: %s", test.a, test.b, err)
+			continue
+		}
+
+		if strings.Join(dependencyStrings([]*Dependency{dep}), " ") != test.expected {
+			t.Errorf("expected %s and %s to combine to %s, got %s", test.a, test.b, test.expected, dep)
+		}
+	}
+}
+
+// parseTestDependency parses dep e.g. "foo>1 foo<2".
+func parseTestDependency(t *testing.T, dep string) *Dependency {
+	deps, err := ParseDeps(strings.Fields(dep))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return deps[0]
+}
+
+// Test parsing of blank values and input without a trailing newline
+func TestParseBlankValues(t *testing.T) {
+	pkg, err := ParseSRCINFOContent([]byte(`pkgbase = foo
+	pkgdesc =
+	pkgver = 1.0
+	pkgrel = 1
+	license =
+	arch = any
+	depends =
+
+pkgname = foo`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pkg.Pkgdesc != "" {
+		t.Errorf("pkgdesc should be empty, got %q", pkg.Pkgdesc)
+	}
+
+	if string(pkg.Pkgver) != "1.0" {
+		t.Errorf("pkgver should be 1.0, got %q", pkg.Pkgver)
+	}
+
+	if len(pkg.License) != 0 || len(pkg.Depends) != 0 {
+		t.Errorf("license and depends should be empty, got %v %v", pkg.License, pkg.Depends)
+	}
+
+	if len(pkg.Pkgnames) != 1 || pkg.Pkgnames[0] != "foo" {
+		t.Errorf("pkgnames should be [foo], got %v", pkg.Pkgnames)
+	}
+}
+
+// Test that comments are skipped and leading comments exposed
+func TestParseComments(t *testing.T) {
+	pkg, err := ParseSRCINFO("./test_pkgbuilds/SRCINFO_teamviewer")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pkg.Comments) != 2 || pkg.Comments[0] != "Generated by mksrcinfo v8" {
+		t.Errorf("unexpected comments: %q", pkg.Comments)
+	}
+
+	pkg, err = ParseSRCINFOContent([]byte(`pkgbase = foo
+	pkgver = 1.0
+	pkgrel = 1
+	# comment
+	arch = any
+	source = git+https://example.org/foo.git#tag=v1.0
+
+# comment
+pkgname = foo
+# comment`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pkg.Comments) != 0 {
+		t.Errorf("unexpected comments: %q", pkg.Comments)
+	}
+
+	if len(pkg.Source) != 1 || pkg.Source[0] != "git+https://example.org/foo.git#tag=v1.0" {
+		t.Errorf("unexpected source: %v", pkg.Source)
+	}
+}
+
+// Test that unknown variables are kept instead of failing the parse
+func TestParseUnknownVariables(t *testing.T) {
+	pkg, err := ParseSRCINFOContent([]byte(`pkgbase = foo
+	pkgver = 1.0
+	pkgrel = 1
+	arch = x86_64
+	sha224sums = SKIP
+	validpgpkeys = ABCDEF
+	newfield = a
+	newfield = b
+	new_field_x86_64 = c
+
+pkgname = foo
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pkg.Sha224sums) != 1 || len(pkg.Validpgpkeys) != 1 {
+		t.Errorf("sha224sums and validpgpkeys should be parsed: %v %v", pkg.Sha224sums, pkg.Validpgpkeys)
+	}
+
+	if values := pkg.Extra["newfield"]; len(values) != 2 || values[1] != "b" {
+		t.Errorf("unexpected values for newfield: %v", values)
+	}
+
+	if values := pkg.Extra["new_field_x86_64"]; len(values) != 1 || values[0] != "c" {
+		t.Errorf("unexpected values for new_field_x86_64: %v", values)
+	}
+}
+
+func TestParseQuotedValues(t *testing.T) {
+	// quotes are part of the values in a .SRCINFO file
+	pkg, err := ParseSRCINFOContent([]byte(`pkgbase = foo
+	pkgver = 1.0
+	pkgrel = 1
+	arch = any
+	optdepends = bar: the "bar" backend
+	source = it's.tar.gz
+	source = 'quoted'.patch
+
+pkgname = foo
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pkg.Optdepends) != 1 || pkg.Optdepends[0] != `bar: the "bar" backend` {
+		t.Errorf("unexpected optdepends: %q", pkg.Optdepends)
+	}
+	if len(pkg.Source) != 2 || pkg.Source[0] != "it's.tar.gz" || pkg.Source[1] != "'quoted'.patch" {
+		t.Errorf("unexpected source: %q", pkg.Source)
+	}
+}
+
+func TestParseArchSpecific(t *testing.T) {
+	pkg, err := ParseSRCINFOContent([]byte(`pkgbase = foo
+	pkgver = 1.0
+	pkgrel = 1
+	arch = x86_64
+	arch = i686
+	depends = glibc
+	depends_x86_64 = lib32-glibc
+	depends_i686 = bar>=1.0
+	source = foo.tar.gz
+	source_x86_64 = foo-x86_64.bin
+	sha256sums = SKIP
+	sha256sums_x86_64 = SKIP
+
+pkgname = foo
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the arch specific values are part of the variables
+	if len(pkg.Depends) != 3 || pkg.Depends[1].Name != "lib32-glibc" || pkg.Depends[2].Name != "bar" {
+		t.Errorf("unexpected depends: %v", pkg.Depends)
+	}
+	if len(pkg.Source) != 2 || pkg.Source[1] != "foo-x86_64.bin" || len(pkg.Sha256sums) != 2 {
+		t.Errorf("unexpected sources: %v %v", pkg.Source, pkg.Sha256sums)
+	}
+
+	// and available per arch
+	a := pkg.ArchSpecific["x86_64"]
+	if a == nil || len(a.Depends) != 1 || len(a.Source) != 1 || len(a.Sha256sums) != 1 {
+		t.Errorf("unexpected x86_64 values: %+v", a)
+	}
+	if a := pkg.ArchSpecific["i686"]; a == nil || len(a.Depends) != 1 || a.Depends[0].Name != "bar" {
+		t.Errorf("unexpected i686 values: %+v", a)
+	}
+}
+
+// readSRCINFOFixtures returns the content of the .SRCINFO test files.
+func readSRCINFOFixtures(tb testing.TB) [][]byte {
+	paths, err := filepath.Glob("./test_pkgbuilds/SRCINFO_*")
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	contents := make([][]byte, 0, len(paths))
+	for _, path := range paths {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		contents = append(contents, content)
+	}
+	return contents
+}
+
+func TestParseZeroCopy(t *testing.T) {
+	for _, content := range readSRCINFOFixtures(t) {
+		expected, err := ParseSRCINFOContent(content)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		pkgb, err := ParseSRCINFOContent(content, WithZeroCopy())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(pkgb, expected) {
+			t.Errorf("%s: zero-copy parse differs", expected.Pkgbase)
+		}
+	}
+}
+
+func benchmarkParseFixtures(b *testing.B, opts ...ParseOption) {
+	contents := readSRCINFOFixtures(b)
+	var size int64
+	for _, content := range contents {
+		size += int64(len(content))
+	}
+
+	b.SetBytes(size)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, content := range contents {
+			if _, err := ParseSRCINFOContent(content, opts...); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkParseFixtures(b *testing.B) {
+	benchmarkParseFixtures(b)
+}
+
+func BenchmarkParseFixturesZeroCopy(b *testing.B) {
+	benchmarkParseFixtures(b, WithZeroCopy())
+}
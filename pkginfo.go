@@ -0,0 +1,315 @@
+package pkgbuild
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// PKGINFO is the .PKGINFO metadata makepkg stores in a built package.
+type PKGINFO struct {
+	Pkgname      string
+	Pkgbase      string
+	Pkgver       string // full version e.g. "1:1.0-1"
+	Pkgdesc      string
+	URL          string
+	BuildDate    int64 // unix time
+	Packager     string
+	Size         int64 // installed size in bytes
+	Arch         string
+	License      []string
+	Replaces     []string
+	Groups       []string
+	Conflicts    []string
+	Provides     []string
+	Backup       []string
+	Depends      []string
+	Optdepends   []string
+	Makedepends  []string
+	Checkdepends []string
+	XData        []string // e.g. "pkgtype=pkg"
+}
+
+// parseKeyValues parses "key = value" lines like those of .PKGINFO and
+// .BUILDINFO files, calling fn for each. Comments and empty lines are
+// skipped.
+func parseKeyValues(content []byte, fn func(key, value string) error) error {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		i := strings.Index(line, "=")
+		if i < 0 {
+			return fmt.Errorf("line %d: expected key = value, got %q", n, line)
+		}
+
+		if err := fn(strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])); err != nil {
+			return fmt.Errorf("line %d: %s", n, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// ParsePKGINFO parses the content of a .PKGINFO file.
+func ParsePKGINFO(content []byte) (*PKGINFO, error) {
+	info := &PKGINFO{}
+
+	arrays := map[string]*[]string{
+		"license":     &info.License,
+		"replaces":    &info.Replaces,
+		"group":       &info.Groups,
+		"conflict":    &info.Conflicts,
+		"provides":    &info.Provides,
+		"backup":      &info.Backup,
+		"depend":      &info.Depends,
+		"optdepend":   &info.Optdepends,
+		"makedepend":  &info.Makedepends,
+		"checkdepend": &info.Checkdepends,
+		"xdata":       &info.XData,
+	}
+	scalars := map[string]*string{
+		"pkgname":  &info.Pkgname,
+		"pkgbase":  &info.Pkgbase,
+		"pkgver":   &info.Pkgver,
+		"pkgdesc":  &info.Pkgdesc,
+		"url":      &info.URL,
+		"packager": &info.Packager,
+		"arch":     &info.Arch,
+	}
+
+	err := parseKeyValues(content, func(key, value string) error {
+		var err error
+		switch {
+		case arrays[key] != nil:
+			if value != "" {
+				*arrays[key] = append(*arrays[key], value)
+			}
+		case scalars[key] != nil:
+			*scalars[key] = value
+		case key == "builddate":
+			info.BuildDate, err = strconv.ParseInt(value, 10, 64)
+		case key == "size":
+			info.Size, err = strconv.ParseInt(value, 10, 64)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Pkgname == "" || info.Pkgver == "" {
+		return nil, fmt.Errorf("pkgname or pkgver missing")
+	}
+
+	return info, nil
+}
+
+// ReadPKGINFO reads the .PKGINFO of the built package file at path e.g.
+// foo-1.0-1-x86_64.pkg.tar.zst. Packages compressed with gzip or bzip2 are
+// read directly, zstd and xz compressed ones through the zstd and xz
+// commands.
+func ReadPKGINFO(path string) (*PKGINFO, error) {
+	content, err := readPackageFile(path, ".PKGINFO")
+	if err != nil {
+		return nil, err
+	}
+	return ParsePKGINFO(content)
+}
+
+// readPackageFile returns the content of the file name in the package
+// archive at path.
+func readPackageFile(path, name string) ([]byte, error) {
+	var content []byte
+	found := false
+	err := walkArchive(path, func(file string, r io.Reader) (bool, error) {
+		if file != name {
+			return true, nil
+		}
+
+		found = true
+		var err error
+		content, err = ioutil.ReadAll(r)
+		return false, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("%s: %s not found", path, name)
+	}
+	return content, nil
+}
+
+// walkArchive calls fn with the name and content of each file in the tar
+// archive at path, e.g. a package or a sync database, until fn returns
+// false. Archives compressed with gzip or bzip2 are read directly, zstd and
+// xz compressed ones through the zstd and xz commands. Zip archives are read
+// too.
+func walkArchive(path string, fn func(name string, r io.Reader) (bool, error)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	magic, _ := r.Peek(6)
+
+	var archive io.Reader = r
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return walkArchiveCommand(path, fn, "zstd", "-dcq")
+	case bytes.HasPrefix(magic, []byte{0xfd, '7', 'z', 'X', 'Z', 0}):
+		return walkArchiveCommand(path, fn, "xz", "-dc")
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		archive = gz
+	case bytes.HasPrefix(magic, []byte("BZh")):
+		archive = bzip2.NewReader(r)
+	case bytes.HasPrefix(magic, []byte("PK\x03\x04")), bytes.HasPrefix(magic, []byte("PK\x05\x06")):
+		return walkZip(f, path, fn)
+	}
+
+	return walkTar(archive, path, fn)
+}
+
+// walkArchiveCommand is walkArchive for archives decompressed by the
+// command name with args, which is killed once fn returns false.
+func walkArchiveCommand(path string, fn func(name string, r io.Reader) (bool, error), command ...string) error {
+	cmd := exec.Command(command[0], append(command[1:], path)...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	defer func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	}()
+
+	return walkTar(stdout, path, fn)
+}
+
+// walkTar is walkArchive for the tar archive r read from path.
+func walkTar(r io.Reader, path string, fn func(name string, r io.Reader) (bool, error)) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %s", path, err)
+		}
+
+		next, err := fn(strings.TrimPrefix(header.Name, "./"), tr)
+		if err != nil {
+			return fmt.Errorf("%s: %s", path, err)
+		}
+		if !next {
+			return nil
+		}
+	}
+}
+
+// walkZip is walkArchive for the zip archive f read from path.
+func walkZip(f *os.File, path string, fn func(name string, r io.Reader) (bool, error)) error {
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	zr, err := zip.NewReader(f, info.Size())
+	if err != nil {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+
+	for _, file := range zr.File {
+		rc, err := file.Open()
+		if err != nil {
+			return fmt.Errorf("%s: %s", path, err)
+		}
+
+		next, err := fn(strings.TrimPrefix(file.Name, "./"), rc)
+		rc.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %s", path, err)
+		}
+		if !next {
+			return nil
+		}
+	}
+	return nil
+}
+
+// CheckPKGINFO compares the .PKGINFO of a package built from p with p and
+// returns the differences, e.g. a package built from an older version of
+// the PKGBUILD. Mismatching names, versions and architectures are errors.
+// Differing depends, provides and licenses are warnings, since package
+// functions of split packages may override them. Shared library provides
+// and depends added by makepkg, like "libfoo.so=1-64", are ignored.
+func (p *PKGBUILD) CheckPKGINFO(info *PKGINFO) []Issue {
+	var issues []Issue
+	add := func(severity Severity, field, format string, args ...interface{}) {
+		issues = append(issues, Issue{severity, field, fmt.Sprintf(format, args...)})
+	}
+
+	if !contains(p.Pkgnames, info.Pkgname) && info.Pkgname != p.pkgbase()+"-debug" {
+		add(SeverityError, "pkgname", "package %s is not built by the PKGBUILD", info.Pkgname)
+	}
+	if info.Pkgbase != "" && info.Pkgbase != p.pkgbase() {
+		add(SeverityError, "pkgbase", "package has pkgbase %s, PKGBUILD %s", info.Pkgbase, p.pkgbase())
+	}
+	if VerCmp(info.Pkgver, p.Version()) != 0 {
+		add(SeverityError, "pkgver", "package has version %s, PKGBUILD %s", info.Pkgver, p.Version())
+	}
+
+	resolved, err := p.ResolveFor(Arch(info.Arch))
+	if err != nil {
+		add(SeverityError, "arch", "package has arch %s, PKGBUILD %s", info.Arch, strings.Join(p.Arch, " "))
+		return issues
+	}
+	if contains(p.Arch, Any.String()) && info.Arch != Any.String() {
+		add(SeverityError, "arch", "package has arch %s, PKGBUILD any", info.Arch)
+	}
+
+	// debug packages only have their own metadata
+	if info.Pkgname == p.pkgbase()+"-debug" {
+		return issues
+	}
+
+	compare := func(field string, declared, built []string) {
+		for _, value := range difference(declared, built) {
+			add(SeverityWarning, field, "%s is missing from the package", value)
+		}
+		for _, value := range difference(built, declared) {
+			if !strings.Contains(value, ".so") {
+				add(SeverityWarning, field, "%s is not declared in the PKGBUILD", value)
+			}
+		}
+	}
+	compare("depends", splitConstraints(dependencyStrings(resolved.Depends)), info.Depends)
+	compare("provides", resolved.Provides, info.Provides)
+	compare("license", resolved.License, info.License)
+
+	return issues
+}
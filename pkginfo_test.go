@@ -0,0 +1,170 @@
+package pkgbuild
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testPKGINFO = `# Generated by makepkg 6.0.2
+# using fakeroot version 1.31
+pkgname = sudo
+pkgbase = sudo
+xdata = pkgtype=pkg
+pkgver = 1.8.11.p2-1
+pkgdesc = Give certain users the ability to run some commands as root
+url = http://www.sudo.ws/sudo/
+builddate = 1427201013
+packager = Foo Bar <foo@example.org>
+size = 3186688
+arch = x86_64
+license = custom
+backup = etc/sudoers
+depend = glibc
+depend = pam
+depend = libldap
+depend = libpam.so=0-64
+provides = libsudo_util.so=0-64
+`
+
+func TestParsePKGINFO(t *testing.T) {
+	info, err := ParsePKGINFO([]byte(testPKGINFO))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &PKGINFO{
+		Pkgname:   "sudo",
+		Pkgbase:   "sudo",
+		Pkgver:    "1.8.11.p2-1",
+		Pkgdesc:   "Give certain users the ability to run some commands as root",
+		URL:       "http://www.sudo.ws/sudo/",
+		BuildDate: 1427201013,
+		Packager:  "Foo Bar <foo@example.org>",
+		Size:      3186688,
+		Arch:      "x86_64",
+		License:   []string{"custom"},
+		Backup:    []string{"etc/sudoers"},
+		Depends:   []string{"glibc", "pam", "libldap", "libpam.so=0-64"},
+		Provides:  []string{"libsudo_util.so=0-64"},
+		XData:     []string{"pkgtype=pkg"},
+	}
+	if !reflect.DeepEqual(info, expected) {
+		t.Errorf("expected %+v, got %+v", expected, info)
+	}
+
+	for _, content := range []string{"pkgname = foo\n", "pkgname = foo\npkgver = 1-1\nsize = big\n", "pkgname\n"} {
+		if _, err := ParsePKGINFO([]byte(content)); err == nil {
+			t.Errorf("expected error for %q", content)
+		}
+	}
+}
+
+func TestCheckPKGINFO(t *testing.T) {
+	pkg, err := ParseSRCINFO("./test_pkgbuilds/SRCINFO_sudo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := ParsePKGINFO([]byte(testPKGINFO))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if issues := pkg.CheckPKGINFO(info); len(issues) != 0 {
+		t.Errorf("expected no issues, got %v", issues)
+	}
+
+	info.Pkgver = "1.8.12-1"
+	info.Arch = "aarch64"
+	info.Depends = []string{"glibc", "pam", "openssl"}
+	expected := []Issue{
+		{SeverityError, "pkgver", "package has version 1.8.12-1, PKGBUILD 1.8.11.p2-1"},
+		{SeverityError, "arch", "package has arch aarch64, PKGBUILD i686 x86_64"},
+	}
+	if issues := pkg.CheckPKGINFO(info); !reflect.DeepEqual(issues, expected) {
+		t.Errorf("expected issues %v, got %v", expected, issues)
+	}
+
+	info.Pkgver = "1.8.11.p2-1"
+	info.Arch = "x86_64"
+	expected = []Issue{
+		{SeverityWarning, "depends", "libldap is missing from the package"},
+		{SeverityWarning, "depends", "openssl is not declared in the PKGBUILD"},
+	}
+	if issues := pkg.CheckPKGINFO(info); !reflect.DeepEqual(issues, expected) {
+		t.Errorf("expected issues %v, got %v", expected, issues)
+	}
+}
+
+// writeTestPackage writes a package archive with the files to path.
+func writeTestPackage(t *testing.T, path string, files map[string]string, compress bool) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data := buf.Bytes()
+	if compress {
+		var gz bytes.Buffer
+		w := gzip.NewWriter(&gz)
+		w.Write(data)
+		w.Close()
+		data = gz.Bytes()
+	}
+
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadPKGINFO(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopkgbuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tarball := filepath.Join(dir, "sudo-1.8.11.p2-1-x86_64.pkg.tar")
+	writeTestPackage(t, tarball, map[string]string{".PKGINFO": testPKGINFO}, false)
+	gzipped := filepath.Join(dir, "sudo-1.8.11.p2-1-x86_64.pkg.tar.gz")
+	writeTestPackage(t, gzipped, map[string]string{".PKGINFO": testPKGINFO}, true)
+	paths := []string{tarball, gzipped}
+
+	if _, err := exec.LookPath("zstd"); err == nil {
+		zstd := tarball + ".zst"
+		if err := exec.Command("zstd", "-q", tarball, "-o", zstd).Run(); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, zstd)
+	}
+
+	for _, path := range paths {
+		info, err := ReadPKGINFO(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Pkgname != "sudo" || info.Pkgver != "1.8.11.p2-1" {
+			t.Errorf("%s: unexpected .PKGINFO %+v", path, info)
+		}
+	}
+
+	if _, err = ReadPKGINFO(filepath.Join(dir, "missing.pkg.tar")); err == nil {
+		t.Error("expected error for missing package")
+	}
+}
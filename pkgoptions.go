@@ -0,0 +1,125 @@
+package pkgbuild
+
+import "fmt"
+
+// MakepkgOption is an option of the options array like "strip", which is
+// disabled by a leading "!" e.g. "!strip".
+type MakepkgOption string
+
+// Options known to makepkg
+const (
+	OptionStrip      MakepkgOption = "strip"
+	OptionDocs       MakepkgOption = "docs"
+	OptionLibtool    MakepkgOption = "libtool"
+	OptionStaticlibs MakepkgOption = "staticlibs"
+	OptionEmptydirs  MakepkgOption = "emptydirs"
+	OptionZipman     MakepkgOption = "zipman"
+	OptionPurge      MakepkgOption = "purge"
+	OptionDebug      MakepkgOption = "debug"
+	OptionLTO        MakepkgOption = "lto"
+	OptionAutodeps   MakepkgOption = "autodeps"
+	OptionCcache     MakepkgOption = "ccache"
+	OptionDistcc     MakepkgOption = "distcc"
+	OptionBuildflags MakepkgOption = "buildflags"
+	OptionMakeflags  MakepkgOption = "makeflags"
+)
+
+// knownOptions are the options makepkg accepts in the options array.
+var knownOptions = map[MakepkgOption]bool{
+	OptionStrip:      true,
+	OptionDocs:       true,
+	OptionLibtool:    true,
+	OptionStaticlibs: true,
+	OptionEmptydirs:  true,
+	OptionZipman:     true,
+	OptionPurge:      true,
+	OptionDebug:      true,
+	OptionLTO:        true,
+	OptionAutodeps:   true,
+	OptionCcache:     true,
+	OptionDistcc:     true,
+	OptionBuildflags: true,
+	OptionMakeflags:  true,
+}
+
+// OptionState is the state of an option in an options array.
+type OptionState int
+
+// Option states
+const (
+	OptionUnset    OptionState = iota // neither name nor !name is set
+	OptionEnabled                     // e.g. "strip"
+	OptionDisabled                    // e.g. "!strip"
+)
+
+func (s OptionState) String() string {
+	switch s {
+	case OptionUnset:
+		return "unset"
+	case OptionEnabled:
+		return "enabled"
+	case OptionDisabled:
+		return "disabled"
+	}
+	return fmt.Sprintf("OptionState(%d)", int(s))
+}
+
+// OptionState returns the state of the option name in the options array of
+// p. Like makepkg the last of name or !name wins.
+func (p *PKGBUILD) OptionState(name MakepkgOption) OptionState {
+	return optionState(string(name), p.Options)
+}
+
+// OptionEnabled reports whether makepkg enables the option name when
+// building p. confOptions is the OPTIONS array of makepkg.conf, which
+// applies if the option is unset in p.
+func (p *PKGBUILD) OptionEnabled(name MakepkgOption, confOptions []string) bool {
+	return checkOption(string(name), p.Options, confOptions)
+}
+
+// SetOption sets the option name to state, replacing any existing entry
+// for it in the options array. OptionUnset removes the option.
+func (p *PKGBUILD) SetOption(name MakepkgOption, state OptionState) {
+	options := p.Options[:0:0]
+	for _, option := range p.Options {
+		if option != string(name) && option != "!"+string(name) {
+			options = append(options, option)
+		}
+	}
+
+	switch state {
+	case OptionEnabled:
+		options = append(options, string(name))
+	case OptionDisabled:
+		options = append(options, "!"+string(name))
+	}
+	p.Options = options
+}
+
+// optionState returns the state of the option name in options.
+func optionState(name string, options []string) OptionState {
+	for i := len(options) - 1; i >= 0; i-- {
+		switch options[i] {
+		case name:
+			return OptionEnabled
+		case "!" + name:
+			return OptionDisabled
+		}
+	}
+	return OptionUnset
+}
+
+// lintOptions checks that the options array only contains options known to
+// makepkg.
+func lintOptions(options []string, add func(Severity, string, string, ...interface{})) {
+	for _, option := range options {
+		name := MakepkgOption(option)
+		if len(option) > 0 && option[0] == '!' {
+			name = name[1:]
+		}
+
+		if !knownOptions[name] {
+			add(SeverityError, "options", "unknown option: %s", option)
+		}
+	}
+}
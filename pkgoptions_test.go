@@ -0,0 +1,50 @@
+package pkgbuild
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOptionState(t *testing.T) {
+	p := &PKGBUILD{Options: []string{"!strip", "debug", "strip", "!lto"}}
+
+	for name, expected := range map[MakepkgOption]OptionState{
+		OptionStrip: OptionEnabled,
+		OptionDebug: OptionEnabled,
+		OptionLTO:   OptionDisabled,
+		OptionDocs:  OptionUnset,
+	} {
+		if state := p.OptionState(name); state != expected {
+			t.Errorf("expected %s to be %s, got %s", name, expected, state)
+		}
+	}
+
+	if !p.OptionEnabled(OptionDocs, []string{"docs", "!lto"}) || p.OptionEnabled(OptionLTO, []string{"lto"}) {
+		t.Error("expected makepkg.conf options to apply only to unset options")
+	}
+
+	p.SetOption(OptionStrip, OptionDisabled)
+	p.SetOption(OptionLTO, OptionUnset)
+	p.SetOption(OptionZipman, OptionEnabled)
+	if expected := []string{"debug", "!strip", "zipman"}; !reflect.DeepEqual(p.Options, expected) {
+		t.Errorf("expected options %v, got %v", expected, p.Options)
+	}
+}
+
+func TestLintOptions(t *testing.T) {
+	p := &PKGBUILD{Pkgnames: []string{"foo"}, Pkgver: "1", Pkgrel: "1", Arch: []string{"any"},
+		Options: []string{"!strip", "!stirp", "debug"}}
+
+	found := false
+	for _, issue := range Lint(p) {
+		if issue.Field == "options" {
+			if issue.Message != "unknown option: !stirp" {
+				t.Errorf("unexpected issue %s", issue)
+			}
+			found = true
+		}
+	}
+	if !found {
+		t.Error("expected an issue for the unknown option")
+	}
+}
@@ -0,0 +1,140 @@
+package pkgbuild
+
+// ProvidePolicy controls how provides without a version are matched
+// against dependencies with one.
+type ProvidePolicy int
+
+// Provide policies
+const (
+	// ProvidesStrict matches like pacman: a provide without a version, like
+	// "sh", only satisfies dependencies without a version.
+	ProvidesStrict ProvidePolicy = iota
+	// ProvidesLoose lets a provide without a version satisfy any dependency
+	// on the name, e.g. "sh" satisfies "sh>=5".
+	ProvidesLoose
+)
+
+// SatisfiedBy reports whether dep is satisfied by the package name at
+// version with provides, either directly or by one of its provides like
+// "libfoo.so=1-64". version may be nil if unknown, then only dependencies
+// without a version are satisfied by the name. Invalid provides are
+// ignored. policy decides whether provides without a version satisfy dep if
+// it has one.
+func (dep *Dependency) SatisfiedBy(name string, version *CompleteVersion, provides []string, policy ProvidePolicy) bool {
+	unversioned := !dep.versioned()
+
+	if name == dep.Name && (unversioned || version != nil && version.Satisfies(dep)) {
+		return true
+	}
+
+	for _, provide := range provides {
+		deps, err := parseDependency(provide, nil)
+		if err != nil || len(deps) == 0 || deps[0].Name != dep.Name {
+			continue
+		}
+
+		provided := deps[0].MinVer
+		switch {
+		case unversioned:
+			return true
+		case provided == nil:
+			if policy == ProvidesLoose {
+				return true
+			}
+		case provided.Satisfies(dep):
+			return true
+		}
+	}
+
+	return false
+}
+
+// Satisfies reports whether any of the packages of p satisfies dep, see
+// Dependency.SatisfiedBy. Provides of arch are included if arch is not
+// empty.
+func (p *PKGBUILD) Satisfies(dep *Dependency, arch string, policy ProvidePolicy) bool {
+	return SelectProvider(dep, p.Candidates(arch, "", 0), policy, nil) != nil
+}
+
+// Candidate is a package which may satisfy a dependency, e.g. one of the
+// packages of a PKGBUILD or of a repository.
+type Candidate struct {
+	Name     string
+	Version  *CompleteVersion // nil if unknown
+	Provides []string
+	Repo     string // e.g. "core" or "aur"
+	Priority int    // of Repo, lower is preferred like the order of pacman.conf
+}
+
+// ProviderLess reports whether candidate a is preferred over b as provider
+// of dep. It's used by SelectProvider to rank the candidates satisfying dep.
+type ProviderLess func(dep *Dependency, a, b *Candidate) bool
+
+// DefaultProviderLess prefers a candidate named like dep over one only
+// providing it, then the candidate with the lower repo priority, then the
+// newer version. Candidates equal by these rules are ordered by repo and
+// name to keep the choice deterministic.
+func DefaultProviderLess(dep *Dependency, a, b *Candidate) bool {
+	if exactA, exactB := a.Name == dep.Name, b.Name == dep.Name; exactA != exactB {
+		return exactA
+	}
+
+	if a.Priority != b.Priority {
+		return a.Priority < b.Priority
+	}
+
+	if a.Version != nil && b.Version != nil {
+		if cmp := a.Version.Compare(b.Version); cmp != 0 {
+			return cmp > 0
+		}
+	} else if a.Version != b.Version {
+		return a.Version != nil
+	}
+
+	if a.Repo != b.Repo {
+		return a.Repo < b.Repo
+	}
+	return a.Name < b.Name
+}
+
+// SelectProvider returns the candidate preferred by less among those
+// satisfying dep, nil if none does. less defaults to DefaultProviderLess.
+// Provides are matched with policy, see Dependency.SatisfiedBy.
+func SelectProvider(dep *Dependency, candidates []*Candidate, policy ProvidePolicy, less ProviderLess) *Candidate {
+	if less == nil {
+		less = DefaultProviderLess
+	}
+
+	var selected *Candidate
+	for _, c := range candidates {
+		if !dep.SatisfiedBy(c.Name, c.Version, c.Provides, policy) {
+			continue
+		}
+		if selected == nil || less(dep, c, selected) {
+			selected = c
+		}
+	}
+	return selected
+}
+
+// Candidates returns a candidate for each package of p, with the provides
+// of arch included if arch is not empty.
+func (p *PKGBUILD) Candidates(arch, repo string, priority int) []*Candidate {
+	provides := p.archIndependent().Provides
+	if a, ok := p.ArchSpecific[arch]; ok {
+		provides = append(provides[:len(provides):len(provides)], a.Provides...)
+	}
+
+	version := p.CompleteVersion()
+	candidates := make([]*Candidate, 0, len(p.Pkgnames))
+	for _, name := range p.Pkgnames {
+		candidates = append(candidates, &Candidate{
+			Name:     name,
+			Version:  &version,
+			Provides: provides,
+			Repo:     repo,
+			Priority: priority,
+		})
+	}
+	return candidates
+}
@@ -0,0 +1,136 @@
+package pkgbuild
+
+import "testing"
+
+func TestSatisfiedBy(t *testing.T) {
+	version, err := NewCompleteVersion("1:2.0-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	provides := []string{"sh", "libfoo.so=1-64", "bar=3.0", "-invalid", ""}
+
+	for _, test := range []struct {
+		dep           string
+		strict, loose bool
+	}{
+		{"foo", true, true},
+		{"foo>=1:1.0", true, true},
+		{"foo<2.0", false, false},
+		{"sh", true, true},
+		{"sh>=5", false, true},
+		{"libfoo.so", true, true},
+		{"libfoo.so=1-64", true, true},
+		{"libfoo.so>=2", false, false},
+		{"bar>2.5", true, true},
+		{"bar<3.0", false, false},
+		{"baz", false, false},
+	} {
+		deps, err := parseDependency(test.dep, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dep := deps[0]
+
+		if dep.SatisfiedBy("foo", version, provides, ProvidesStrict) != test.strict {
+			t.Errorf("strict: %s satisfied should be %t", test.dep, test.strict)
+		}
+		if dep.SatisfiedBy("foo", version, provides, ProvidesLoose) != test.loose {
+			t.Errorf("loose: %s satisfied should be %t", test.dep, test.loose)
+		}
+	}
+
+	deps, _ := parseDependency("foo>=1", nil)
+	if deps[0].SatisfiedBy("foo", nil, nil, ProvidesLoose) {
+		t.Error("versioned dependency should not be satisfied by an unknown version")
+	}
+}
+
+func TestPKGBUILDSatisfies(t *testing.T) {
+	pkg := &PKGBUILD{
+		Pkgnames: []string{"foo", "libfoo"},
+		Pkgver:   "1.0",
+		Pkgrel:   "1",
+		Provides: []string{"sh"},
+		ArchSpecific: map[string]*ArchSpecific{
+			"x86_64": {Provides: []string{"libfoo.so=1-64"}},
+		},
+	}
+
+	for _, test := range []struct {
+		dep      string
+		arch     string
+		expected bool
+	}{
+		{"libfoo>=1.0", "", true},
+		{"libfoo>1.0", "", false},
+		{"sh", "", true},
+		{"libfoo.so", "", false},
+		{"libfoo.so", "x86_64", true},
+		{"libfoo.so", "i686", false},
+	} {
+		deps, err := parseDependency(test.dep, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pkg.Satisfies(deps[0], test.arch, ProvidesStrict) != test.expected {
+			t.Errorf("%s for %q satisfied should be %t", test.dep, test.arch, test.expected)
+		}
+	}
+}
+
+func TestSelectProvider(t *testing.T) {
+	version := func(v string) *CompleteVersion {
+		cv, err := NewCompleteVersion(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cv
+	}
+
+	candidates := []*Candidate{
+		{Name: "jre-openjdk", Version: version("21.0.1-1"), Provides: []string{"java-runtime=21"}, Repo: "extra", Priority: 2},
+		{Name: "jre17-openjdk", Version: version("17.0.9-1"), Provides: []string{"java-runtime=17"}, Repo: "extra", Priority: 2},
+		{Name: "jre-zulu", Version: version("21.0.1-1"), Provides: []string{"java-runtime=21"}, Repo: "aur", Priority: 3},
+		{Name: "java-runtime", Version: version("1-1"), Repo: "aur", Priority: 3},
+		{Name: "jre-custom", Version: version("22-1"), Provides: []string{"java-runtime=22"}, Repo: "local", Priority: 1},
+	}
+
+	for _, test := range []struct {
+		dep      string
+		expected string
+	}{
+		// exact name match wins over repo priority
+		{"java-runtime", "java-runtime"},
+		// repo priority wins over the newer version
+		{"java-runtime>=17", "jre-custom"},
+		{"java-runtime=17", "jre17-openjdk"},
+		{"java-runtime>22", ""},
+	} {
+		deps, err := parseDependency(test.dep, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		name := ""
+		if c := SelectProvider(deps[0], candidates, ProvidesStrict, nil); c != nil {
+			name = c.Name
+		}
+		if name != test.expected {
+			t.Errorf("expected %s to select %q, got %q", test.dep, test.expected, name)
+		}
+	}
+
+	// newest version within the same repo
+	deps, _ := parseDependency("java-runtime>=17", nil)
+	if c := SelectProvider(deps[0], candidates[:3], ProvidesStrict, nil); c == nil || c.Name != "jre-openjdk" {
+		t.Errorf("expected jre-openjdk, got %v", c)
+	}
+
+	// the hook decides
+	oldest := func(dep *Dependency, a, b *Candidate) bool {
+		return a.Version.Compare(b.Version) < 0
+	}
+	if c := SelectProvider(deps[0], candidates, ProvidesStrict, oldest); c == nil || c.Name != "jre17-openjdk" {
+		t.Errorf("expected the hook to select jre17-openjdk, got %v", c)
+	}
+}
@@ -0,0 +1,231 @@
+package pkgbuild
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// DefaultPyPIURL is the URL of the JSON API of the Python Package Index.
+const DefaultPyPIURL = "https://pypi.org/pypi"
+
+// PyPI is a client of the JSON API of the Python Package Index.
+type PyPI struct {
+	URL    string       // DefaultPyPIURL if empty
+	Client *http.Client // http.DefaultClient if nil
+}
+
+// PyPIProject is the metadata of a release of a project on PyPI.
+type PyPIProject struct {
+	Info struct {
+		Name              string            `json:"name"`
+		Version           string            `json:"version"`
+		Summary           string            `json:"summary"`
+		HomePage          string            `json:"home_page"`
+		ProjectURLs       map[string]string `json:"project_urls"`
+		License           string            `json:"license"`
+		LicenseExpression string            `json:"license_expression"`
+		Classifiers       []string          `json:"classifiers"`
+		RequiresDist      []string          `json:"requires_dist"`
+	} `json:"info"`
+	URLs []PyPIFile `json:"urls"`
+}
+
+// PyPIFile is a file of a release on PyPI.
+type PyPIFile struct {
+	Filename    string            `json:"filename"`
+	PackageType string            `json:"packagetype"` // e.g. "sdist" or "bdist_wheel"
+	URL         string            `json:"url"`
+	Digests     map[string]string `json:"digests"`
+}
+
+// Project returns the metadata of version of the project name, of the
+// latest release if version is empty.
+func (c *PyPI) Project(ctx context.Context, name, version string) (*PyPIProject, error) {
+	base := c.URL
+	if base == "" {
+		base = DefaultPyPIURL
+	}
+
+	u := strings.TrimSuffix(base, "/") + "/" + url.PathEscape(name)
+	if version != "" {
+		u += "/" + url.PathEscape(version)
+	}
+
+	var project PyPIProject
+	if err := getJSON(ctx, c.Client, u+"/json", &project); err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// sdist returns the source distribution of the release, nil if missing.
+func (p *PyPIProject) sdist() *PyPIFile {
+	for i, file := range p.URLs {
+		if file.PackageType == "sdist" {
+			return &p.URLs[i]
+		}
+	}
+	return nil
+}
+
+// pythonNameSeparators matches the separators normalized by PEP 503.
+var pythonNameSeparators = regexp.MustCompile(`[-_.]+`)
+
+// PythonPkgname returns the name of the package of the Python project name,
+// its PEP 503 normalized name prefixed with python-.
+func PythonPkgname(name string) string {
+	name = strings.ToLower(pythonNameSeparators.ReplaceAllString(name, "-"))
+	if strings.HasPrefix(name, "python-") {
+		return name
+	}
+	return "python-" + name
+}
+
+// requirement matches the name and marker of a requires_dist entry, e.g.
+// `foo[bar] (>=1.0) ; extra == "baz"`.
+var requirement = regexp.MustCompile(`^\s*([A-Za-z0-9][A-Za-z0-9._-]*)[^;]*(?:;\s*(.*))?$`)
+
+// extraMarker matches a marker only selecting an extra.
+var extraMarker = regexp.MustCompile(`^extra\s*==\s*["']([^"']+)["']$`)
+
+// Dependencies maps the requirements of the project to package names. The
+// version specifiers are dropped, as the repositories only carry a single
+// version anyway. Requirements of extras are returned as optdepends,
+// requirements with other markers, e.g. backports for older Pythons, are
+// skipped.
+func (p *PyPIProject) Dependencies() (depends, optdepends []string) {
+	for _, req := range p.Info.RequiresDist {
+		match := requirement.FindStringSubmatch(req)
+		if match == nil {
+			continue
+		}
+
+		name := PythonPkgname(match[1])
+		marker := strings.TrimSpace(match[2])
+		switch {
+		case marker == "":
+			if !contains(depends, name) {
+				depends = append(depends, name)
+			}
+		case extraMarker.MatchString(marker):
+			extra := extraMarker.FindStringSubmatch(marker)[1]
+			optdepends = appendValue(optdepends, name+": "+extra)
+		}
+	}
+	return depends, optdepends
+}
+
+// licenseClassifiers maps the trove classifiers of common licenses to SPDX
+// identifiers.
+var licenseClassifiers = map[string]string{
+	"License :: OSI Approved :: Apache Software License":                                 "Apache-2.0",
+	"License :: OSI Approved :: BSD License":                                             "BSD-3-Clause",
+	"License :: OSI Approved :: GNU General Public License v2 (GPLv2)":                   "GPL-2.0-only",
+	"License :: OSI Approved :: GNU General Public License v3 (GPLv3)":                   "GPL-3.0-only",
+	"License :: OSI Approved :: GNU General Public License v3 or later (GPLv3+)":         "GPL-3.0-or-later",
+	"License :: OSI Approved :: GNU Lesser General Public License v3 (LGPLv3)":           "LGPL-3.0-only",
+	"License :: OSI Approved :: ISC License (ISCL)":                                      "ISC",
+	"License :: OSI Approved :: MIT License":                                             "MIT",
+	"License :: OSI Approved :: Mozilla Public License 2.0 (MPL 2.0)":                    "MPL-2.0",
+	"License :: OSI Approved :: Python Software Foundation License":                      "PSF-2.0",
+	"License :: OSI Approved :: GNU Lesser General Public License v2 or later (LGPLv2+)": "LGPL-2.0-or-later",
+}
+
+// Licenses returns the licenses of the project, taken from the license
+// expression, a short license field or the license classifiers.
+func (p *PyPIProject) Licenses() []string {
+	if p.Info.LicenseExpression != "" {
+		return []string{p.Info.LicenseExpression}
+	}
+
+	// the license field often holds the full license text
+	if license := strings.TrimSpace(p.Info.License); license != "" && len(license) <= 32 && !strings.Contains(license, "\n") {
+		return []string{license}
+	}
+
+	var licenses []string
+	for _, classifier := range p.Info.Classifiers {
+		if license, ok := licenseClassifiers[classifier]; ok {
+			licenses = appendValue(licenses, license)
+		}
+	}
+	return licenses
+}
+
+// URL returns the homepage of the project, its PyPI page if none is given.
+func (p *PyPIProject) URL() string {
+	if p.Info.HomePage != "" {
+		return p.Info.HomePage
+	}
+	for _, key := range []string{"Homepage", "homepage", "Source", "Repository"} {
+		if u := p.Info.ProjectURLs[key]; u != "" {
+			return u
+		}
+	}
+	return "https://pypi.org/project/" + p.Info.Name
+}
+
+// NewPyPIScaffold returns the scaffold of the package of the PyPI project,
+// following the Python package guidelines, like pip2pkgbuild. The source
+// is the source distribution with its sha256 checksum, depends and
+// optdepends are mapped from requires_dist. Empty options default to the
+// values of the project.
+func NewPyPIScaffold(project *PyPIProject, opts ScaffoldOptions) (*Scaffold, error) {
+	sdist := project.sdist()
+	if sdist == nil || project.Info.Name == "" {
+		return nil, fmt.Errorf("source distribution of %s %s missing", project.Info.Name, project.Info.Version)
+	}
+
+	if opts.Pkgname == "" {
+		opts.Pkgname = PythonPkgname(project.Info.Name)
+	}
+	if opts.Pkgver == "" {
+		opts.Pkgver = Version(project.Info.Version)
+	}
+	if opts.Pkgdesc == "" {
+		opts.Pkgdesc = project.Info.Summary
+	}
+	if opts.URL == "" {
+		opts.URL = project.URL()
+	}
+	if len(opts.License) == 0 {
+		opts.License = project.Licenses()
+	}
+
+	// the canonical source URL doesn't contain the hash of the file
+	sum := sdist.Digests["sha256"]
+	if opts.Source == "" {
+		opts.Source = fmt.Sprintf("https://files.pythonhosted.org/packages/source/%c/%s/%s", project.Info.Name[0], project.Info.Name, sdist.Filename)
+	} else {
+		sum = ""
+	}
+
+	srcdir := sdist.Filename
+	for _, ext := range []string{".tar.gz", ".tar.bz2", ".zip"} {
+		srcdir = strings.TrimSuffix(srcdir, ext)
+	}
+	srcdir = `"` + strings.Replace(srcdir, project.Info.Version, "$pkgver", 1) + `"`
+
+	depends, optdepends := project.Dependencies()
+	opts.Depends = append(depends, opts.Depends...)
+
+	s, err := newScaffold(scaffoldTemplate{
+		arch:        []string{"any"},
+		depends:     []string{"python"},
+		makedepends: []string{"python-build", "python-installer", "python-wheel"},
+		functions:   pythonFunctions(srcdir),
+	}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	s.PKGBUILD.Optdepends = optdepends
+	if sum != "" {
+		s.PKGBUILD.Sha256sums = []string{sum}
+	}
+	return s, nil
+}
@@ -0,0 +1,132 @@
+package pkgbuild
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+const testPyPIProject = `{
+  "info": {
+    "name": "pip2pkgbuild",
+    "version": "0.2.3",
+    "summary": "Generate PKGBUILD file for a Python module from PyPi",
+    "home_page": "https://github.com/wenLiangcan/pip2pkgbuild",
+    "license": "MIT",
+    "classifiers": ["License :: OSI Approved :: MIT License"],
+    "requires_dist": [
+      "pip",
+      "Jinja2 (>=2.0)",
+      "typing_extensions; python_version < \"3.8\"",
+      "PySocks (!=1.5.7,>=1.5.6) ; extra == 'socks'",
+      "pip>=9 ; sys_platform == \"linux\""
+    ]
+  },
+  "urls": [
+    {
+      "filename": "pip2pkgbuild-0.2.3-py3-none-any.whl",
+      "packagetype": "bdist_wheel",
+      "url": "https://files.pythonhosted.org/packages/aa/pip2pkgbuild-0.2.3-py3-none-any.whl",
+      "digests": {"sha256": "1111"}
+    },
+    {
+      "filename": "pip2pkgbuild-0.2.3.tar.gz",
+      "packagetype": "sdist",
+      "url": "https://files.pythonhosted.org/packages/bb/pip2pkgbuild-0.2.3.tar.gz",
+      "digests": {"sha256": "2222"}
+    }
+  ]
+}`
+
+func TestPyPIProject(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.URL.Path == "/pypi/missing/json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(testPyPIProject))
+	}))
+	defer server.Close()
+
+	c := &PyPI{URL: server.URL + "/pypi"}
+	project, err := c.Project(context.Background(), "pip2pkgbuild", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if project.Info.Version != "0.2.3" {
+		t.Errorf("expected version 0.2.3, got %s", project.Info.Version)
+	}
+
+	if _, err := c.Project(context.Background(), "pip2pkgbuild", "0.2.3"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Project(context.Background(), "missing", ""); err == nil {
+		t.Error("expected error for missing project")
+	}
+
+	expected := []string{"/pypi/pip2pkgbuild/json", "/pypi/pip2pkgbuild/0.2.3/json", "/pypi/missing/json"}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("expected requests %q, got %q", expected, paths)
+	}
+
+	depends, optdepends := project.Dependencies()
+	if expected := []string{"python-pip", "python-jinja2"}; !reflect.DeepEqual(depends, expected) {
+		t.Errorf("expected depends %q, got %q", expected, depends)
+	}
+	if expected := []string{"python-pysocks: socks"}; !reflect.DeepEqual(optdepends, expected) {
+		t.Errorf("expected optdepends %q, got %q", expected, optdepends)
+	}
+	if licenses := project.Licenses(); !reflect.DeepEqual(licenses, []string{"MIT"}) {
+		t.Errorf("expected license MIT, got %q", licenses)
+	}
+
+	s, err := NewPyPIScaffold(project, ScaffoldOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var srcinfo bytes.Buffer
+	if err := s.WriteSRCINFO(&srcinfo); err != nil {
+		t.Fatal(err)
+	}
+	pkgb, err := ParseSRCINFOContent(srcinfo.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkgb.Pkgnames[0] != "python-pip2pkgbuild" || pkgb.Pkgver != "0.2.3" {
+		t.Errorf("expected python-pip2pkgbuild 0.2.3, got %s %s", pkgb.Pkgnames[0], pkgb.Pkgver)
+	}
+	if expected := []string{"https://files.pythonhosted.org/packages/source/p/pip2pkgbuild/pip2pkgbuild-0.2.3.tar.gz"}; !reflect.DeepEqual(pkgb.Source, expected) {
+		t.Errorf("expected source %q, got %q", expected, pkgb.Source)
+	}
+	if !reflect.DeepEqual(pkgb.Sha256sums, []string{"2222"}) {
+		t.Errorf("expected sha256sums 2222, got %q", pkgb.Sha256sums)
+	}
+
+	var pkgbuild bytes.Buffer
+	if err := s.WritePKGBUILD(&pkgbuild); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(pkgbuild.Bytes(), []byte(`cd "pip2pkgbuild-$pkgver"`)) {
+		t.Errorf("expected build in pip2pkgbuild-$pkgver:\n%s", pkgbuild.String())
+	}
+}
+
+func TestPythonPkgname(t *testing.T) {
+	for name, expected := range map[string]string{
+		"requests":          "python-requests",
+		"Jinja2":            "python-jinja2",
+		"typing_extensions": "python-typing-extensions",
+		"python-dateutil":   "python-dateutil",
+		"zope.interface":    "python-zope-interface",
+	} {
+		if pkgname := PythonPkgname(name); pkgname != expected {
+			t.Errorf("%s: expected %s, got %s", name, expected, pkgname)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package pkgbuild
+
+import (
+	"errors"
+	"strings"
+)
+
+// VersionRange is a range of versions like the one of a dependency such as
+// "foo>=1.0 foo<2.0". A nil bound means the range is unbounded on that side.
+type VersionRange struct {
+	Min          *CompleteVersion
+	MinExclusive bool // Min itself is not part of the range, i.e. >
+	Max          *CompleteVersion
+	MaxExclusive bool // Max itself is not part of the range, i.e. <
+
+	// Exclude lists versions within the bounds which are not part of the
+	// range, like 1.2 of "foo!=1.2".
+	Exclude []*CompleteVersion
+}
+
+// Range returns the version range of dep.
+func (dep *Dependency) Range() VersionRange {
+	return VersionRange{
+		Min:          dep.MinVer,
+		MinExclusive: dep.sgt,
+		Max:          dep.MaxVer,
+		MaxExclusive: dep.slt,
+		Exclude:      dep.Exclude,
+	}
+}
+
+// versioned reports whether dep restricts the version, by bounds or by
+// exclusions.
+func (dep *Dependency) versioned() bool {
+	return dep.MinVer != nil || dep.MaxVer != nil || len(dep.Exclude) > 0
+}
+
+// newDependency returns the dependency on name in the range r.
+func newDependency(name string, r VersionRange) *Dependency {
+	dep := &Dependency{
+		Name:    name,
+		MinVer:  r.Min,
+		sgt:     r.MinExclusive,
+		MaxVer:  r.Max,
+		slt:     r.MaxExclusive,
+		Exclude: r.Exclude,
+	}
+
+	// exact versions like foo=1.0 share the version
+	if dep.MinVer != nil && dep.MaxVer != nil && !dep.sgt && !dep.slt && *dep.MinVer == *dep.MaxVer {
+		dep.MaxVer = dep.MinVer
+	}
+
+	return dep
+}
+
+// Contains reports whether version is in r.
+func (r VersionRange) Contains(version *CompleteVersion) bool {
+	if r.Max != nil {
+		cmp := version.cmp(r.Max)
+		if cmp == 1 || cmp == 0 && r.MaxExclusive {
+			return false
+		}
+	}
+
+	if r.Min != nil {
+		cmp := version.cmp(r.Min)
+		if cmp == -1 || cmp == 0 && r.MinExclusive {
+			return false
+		}
+	}
+
+	return !r.excludes(version)
+}
+
+// excludes reports whether version is one of the exclusions of r.
+func (r VersionRange) excludes(version *CompleteVersion) bool {
+	for _, v := range r.Exclude {
+		if version.cmp(v) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// exclusionsIn returns copies of the exclusions of r which are in the
+// range n and not in skip, for combining exclusions of ranges.
+func (r VersionRange) exclusionsIn(n VersionRange, skip func(*CompleteVersion) bool) []*CompleteVersion {
+	var exclude []*CompleteVersion
+	for _, v := range r.Exclude {
+		if n.Contains(v) && !skip(v) {
+			exclude = append(exclude, copyVersion(v))
+		}
+	}
+	return exclude
+}
+
+// IsEmpty reports whether no version is in r, e.g. for ">2.0 <1.0" or
+// "=1.0 !=1.0".
+func (r VersionRange) IsEmpty() bool {
+	if r.Min == nil || r.Max == nil {
+		return false
+	}
+
+	cmp := r.Min.cmp(r.Max)
+	return cmp == 1 || cmp == 0 && (r.MinExclusive || r.MaxExclusive || r.excludes(r.Min))
+}
+
+// Intersect returns the range of the versions in both r and r2, which is
+// what Dependency.Restrict does. Of equal bounds like 1.0 and 1.0-2 the more
+// specific one is kept. The result may be empty, see IsEmpty.
+func (r VersionRange) Intersect(r2 VersionRange) VersionRange {
+	var n VersionRange
+	n.Min, n.MinExclusive = tighterBound(r.Min, r.MinExclusive, r2.Min, r2.MinExclusive, 1)
+	n.Max, n.MaxExclusive = tighterBound(r.Max, r.MaxExclusive, r2.Max, r2.MaxExclusive, -1)
+
+	// exclusions of either range within the new bounds
+	exclude := r.exclusionsIn(n, func(*CompleteVersion) bool { return false })
+	n.Exclude = append(exclude, r2.exclusionsIn(n, VersionRange{Exclude: exclude}.excludes)...)
+	return n
+}
+
+// Union returns the ranges of the versions in r or r2, ordered from oldest
+// to newest. That is a single range if r and r2 overlap or touch, and both
+// otherwise. Empty ranges are left out.
+func (r VersionRange) Union(r2 VersionRange) []VersionRange {
+	switch {
+	case r.IsEmpty() && r2.IsEmpty():
+		return nil
+	case r.IsEmpty():
+		return []VersionRange{r2}
+	case r2.IsEmpty():
+		return []VersionRange{r}
+	case r.endsBefore(r2):
+		return []VersionRange{r, r2}
+	case r2.endsBefore(r):
+		return []VersionRange{r2, r}
+	}
+
+	var n VersionRange
+	n.Min, n.MinExclusive = looserBound(r.Min, r.MinExclusive, r2.Min, r2.MinExclusive, 1)
+	n.Max, n.MaxExclusive = looserBound(r.Max, r.MaxExclusive, r2.Max, r2.MaxExclusive, -1)
+
+	// exclusions of one range remain if the other one doesn't contain them
+	exclude := r.exclusionsIn(n, r2.Contains)
+	n.Exclude = append(exclude, r2.exclusionsIn(n, func(v *CompleteVersion) bool {
+		return r.Contains(v) || VersionRange{Exclude: exclude}.excludes(v)
+	})...)
+	return []VersionRange{n}
+}
+
+// endsBefore reports whether all versions of r are older than the ones of
+// r2, with a gap or a bound excluded by both in between.
+func (r VersionRange) endsBefore(r2 VersionRange) bool {
+	if r.Max == nil || r2.Min == nil {
+		return false
+	}
+
+	cmp := r.Max.cmp(r2.Min)
+	return cmp == -1 || cmp == 0 && r.MaxExclusive && r2.MinExclusive
+}
+
+func (r VersionRange) String() string {
+	if r.Min != nil && r.Max != nil && !r.MinExclusive && !r.MaxExclusive && r.Min.cmp(r.Max) == 0 && len(r.Exclude) == 0 {
+		return "=" + r.Min.String()
+	}
+
+	str := ""
+	if r.Min != nil {
+		str = ">=" + r.Min.String()
+		if r.MinExclusive {
+			str = ">" + r.Min.String()
+		}
+	}
+
+	if r.Max != nil {
+		if str != "" {
+			str += " "
+		}
+		if r.MaxExclusive {
+			str += "<" + r.Max.String()
+		} else {
+			str += "<=" + r.Max.String()
+		}
+	}
+
+	for _, v := range r.Exclude {
+		if str != "" {
+			str += " "
+		}
+		str += "!=" + v.String()
+	}
+
+	return str
+}
+
+// tighterBound returns a copy of the tighter of the bounds a and b. dir is
+// 1 for lower bounds, where the newer version is tighter, and -1 for upper
+// bounds.
+func tighterBound(a *CompleteVersion, aExclusive bool, b *CompleteVersion, bExclusive bool, dir int8) (*CompleteVersion, bool) {
+	switch {
+	case a == nil && b == nil:
+		return nil, false
+	case a == nil:
+		return copyVersion(b), bExclusive
+	case b == nil:
+		return copyVersion(a), aExclusive
+	}
+
+	switch a.cmp(b) * dir {
+	case 1:
+		return copyVersion(a), aExclusive
+	case -1:
+		return copyVersion(b), bExclusive
+	}
+
+	if len(a.Pkgrel) > len(b.Pkgrel) {
+		return copyVersion(a), aExclusive || bExclusive
+	}
+	return copyVersion(b), aExclusive || bExclusive
+}
+
+// looserBound returns a copy of the looser of the bounds a and b, nil if
+// either is unbounded. dir is as for tighterBound.
+func looserBound(a *CompleteVersion, aExclusive bool, b *CompleteVersion, bExclusive bool, dir int8) (*CompleteVersion, bool) {
+	if a == nil || b == nil {
+		return nil, false
+	}
+
+	switch a.cmp(b) * dir {
+	case 1:
+		return copyVersion(b), bExclusive
+	case -1:
+		return copyVersion(a), aExclusive
+	}
+
+	return copyVersion(a), aExclusive && bExclusive
+}
+
+// copyVersion returns a copy of v.
+func copyVersion(v *CompleteVersion) *CompleteVersion {
+	c := *v
+	return &c
+}
+
+// ParseConstraint parses the constraint s on a single dependency for tools
+// built on this package, e.g. a list of known broken versions. It's made of
+// space separated dependencies as in a PKGBUILD, "foo>=1.0 foo<2", which may
+// also exclude versions with "!=" like "foo>=1.0 foo!=1.2". Exclusions are
+// not supported by pacman, so they must not be written to a PKGBUILD. An
+// error is returned if the dependencies are on different names or conflict.
+func ParseConstraint(s string) (*Dependency, error) {
+	var dep *Dependency
+	for _, field := range strings.Fields(s) {
+		var next *Dependency
+		if i := strings.Index(field, "!="); i >= 0 {
+			version, err := NewCompleteVersion(field[i+2:])
+			if err != nil {
+				return nil, &InvalidDependencyError{Dep: field, Err: err}
+			}
+
+			deps, err := parseDependency(field[:i], nil)
+			if err != nil {
+				return nil, err
+			}
+			if len(deps) == 0 {
+				return nil, &InvalidDependencyError{Dep: field, Err: errors.New("invalid dependency name")}
+			}
+			next = deps[0]
+			if next.MinVer != nil || next.MaxVer != nil {
+				return nil, &InvalidDependencyError{Dep: field, Err: ErrInvalidVersion}
+			}
+			next.Exclude = []*CompleteVersion{version}
+		} else {
+			deps, err := parseDependency(field, nil)
+			if err != nil {
+				return nil, err
+			}
+			next = deps[0]
+		}
+
+		if dep == nil {
+			dep = next
+			continue
+		}
+
+		var err error
+		if dep, err = dep.Combine(next); err != nil {
+			return nil, err
+		}
+	}
+
+	if dep == nil {
+		return nil, &InvalidDependencyError{Dep: s, Err: errors.New("empty constraint")}
+	}
+	return dep, nil
+}
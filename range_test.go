@@ -0,0 +1,176 @@
+package pkgbuild
+
+import (
+	"strings"
+	"testing"
+)
+
+// parseRange returns the range of the dependency dep e.g. "foo>=1.0" or
+// "foo>1 foo<2".
+func parseRange(t *testing.T, dep string) VersionRange {
+	var deps []*Dependency
+	var err error
+	for _, d := range strings.Fields(dep) {
+		if deps, err = parseDependency(d, deps); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return deps[0].Range()
+}
+
+func TestVersionRangeContains(t *testing.T) {
+	r := parseRange(t, "foo>1.0 foo<=2.0")
+
+	for v, expected := range map[string]bool{
+		"0.9":   false,
+		"1.0":   false,
+		"1.0-2": false,
+		"1.1":   true,
+		"2.0":   true,
+		"2.0-5": true,
+		"2.0.1": false,
+		"1:0.1": false,
+	} {
+		version, err := NewCompleteVersion(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Contains(version) != expected {
+			t.Errorf("%s contains %s should be %t", r, v, expected)
+		}
+	}
+
+	if !(VersionRange{}).Contains(&CompleteVersion{Version: "1"}) {
+		t.Error("unbounded range should contain every version")
+	}
+}
+
+func TestVersionRangeIntersect(t *testing.T) {
+	for _, test := range []struct {
+		a, b     string
+		expected string
+		empty    bool
+	}{
+		{"a>=1", "a<2", ">=1 <2", false},
+		{"a>=1 a<=2", "a>1 a<2", ">1 <2", false},
+		{"a>=1", "a>=1-2", ">=1-2", false},
+		{"a>=2", "a<1", ">=2 <1", true},
+		{"a>=1", "a<1", ">=1 <1", true},
+		{"a<=1", "a>=1", "=1", false},
+		{"a=1", "a>=0.5", "=1", false},
+	} {
+		r := parseRange(t, test.a).Intersect(parseRange(t, test.b))
+		if r.String() != test.expected || r.IsEmpty() != test.empty {
+			t.Errorf("expected %s and %s to intersect as %s (empty %t), got %s (empty %t)",
+				test.a, test.b, test.expected, test.empty, r, r.IsEmpty())
+		}
+	}
+}
+
+func TestVersionRangeUnion(t *testing.T) {
+	for _, test := range []struct {
+		a, b     string
+		expected []string
+	}{
+		{"a>=1 a<3", "a>=2 a<4", []string{">=1 <4"}},
+		{"a>=3", "a<=1", []string{"<=1", ">=3"}},
+		{"a<1", "a>1", []string{"<1", ">1"}},
+		{"a<1", "a>=1", []string{""}},
+		{"a>=1 a<=2", "a>1.5", []string{">=1"}},
+		{"a>2 a<1", "a=3", []string{"=3"}},
+	} {
+		ranges := parseRange(t, test.a).Union(parseRange(t, test.b))
+		var union []string
+		for _, r := range ranges {
+			union = append(union, r.String())
+		}
+		if len(union) != len(test.expected) {
+			t.Errorf("expected union of %s and %s to be %q, got %q", test.a, test.b, test.expected, union)
+			continue
+		}
+		for i := range union {
+			if union[i] != test.expected[i] {
+				t.Errorf("expected union of %s and %s to be %q, got %q", test.a, test.b, test.expected, union)
+				break
+			}
+		}
+	}
+}
+
+func TestParseConstraint(t *testing.T) {
+	dep, err := ParseConstraint("foo>=1.0 foo!=1.2 foo<2 foo!=3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dep.String() != "foo>=1.0 foo<2 foo!=1.2" {
+		t.Errorf("unexpected constraint: %s", dep)
+	}
+
+	for v, expected := range map[string]bool{
+		"1.1":   true,
+		"1.2":   false,
+		"1.2-3": false,
+		"1.2.1": true,
+		"2":     false,
+	} {
+		version, err := NewCompleteVersion(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if version.Satisfies(dep) != expected {
+			t.Errorf("%s satisfies %s should be %t", v, dep, expected)
+		}
+		if e := version.Explain(dep); !expected && v != "2" && (e.Reason != ReasonExcluded || e.String() != "requires !=1.2, found "+v) {
+			t.Errorf("expected %s to be excluded, got %s", v, e)
+		}
+	}
+
+	dep, err = ParseConstraint("foo!=1.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dep.SatisfiedBy("foo", &CompleteVersion{Version: "1.2"}, nil, ProvidesStrict) {
+		t.Errorf("1.2 shouldn't satisfy %s", dep)
+	}
+
+	for _, s := range []string{"", "!=1.2", "foo>=1 bar<2", "foo=1.2 foo!=1.2", "foo>=1!=2", "foo!=a:b"} {
+		if dep, err := ParseConstraint(s); err == nil {
+			t.Errorf("expected error parsing %q, got %s", s, dep)
+		}
+	}
+}
+
+func TestVersionRangeExclusions(t *testing.T) {
+	exclude := func(dep string) VersionRange {
+		d, err := ParseConstraint(dep)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d.Range()
+	}
+
+	for _, test := range []struct {
+		a, b      string
+		intersect string
+		union     string
+	}{
+		{"a>=1 a!=1.5", "a<2 a!=3", ">=1 <2 !=1.5", ""},
+		{"a>=1 a!=1.5", "a>=0.5 a!=3", ">=1 !=1.5 !=3", ">=0.5"},
+		{"a>=1 a!=1.5", "a>=2", ">=2", ">=1 !=1.5"},
+		{"a!=1", "a!=1", "!=1", "!=1"},
+		{"a!=1", "a!=2", "!=1 !=2", ""},
+		{"a>=1 a<2 a!=1.5", "a>=1.2 a<3", ">=1.2 <2 !=1.5", ">=1 <3"},
+	} {
+		a, b := exclude(test.a), exclude(test.b)
+		if r := a.Intersect(b); r.String() != test.intersect {
+			t.Errorf("expected %s and %s to intersect as %q, got %q", test.a, test.b, test.intersect, r)
+		}
+		if r := a.Union(b); len(r) != 1 || r[0].String() != test.union {
+			t.Errorf("expected union of %s and %s to be %q, got %v", test.a, test.b, test.union, r)
+		}
+	}
+
+	if r := exclude("a>=1 a<=1").Intersect(exclude("a!=1")); !r.IsEmpty() {
+		t.Errorf("expected %s to be empty", r)
+	}
+}
@@ -0,0 +1,175 @@
+package pkgbuild
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Default URLs of the APIs used by ReleaseProber.
+const (
+	DefaultGitHubURL = "https://api.github.com"
+	DefaultGitLabURL = "https://gitlab.com/api/v4"
+)
+
+// ReleaseProber lists the tags of the GitHub and GitLab repositories
+// PKGBUILD sources point at, to find newer releases. Only the first 100
+// tags are listed, the ones returned first by the APIs. Authentication,
+// e.g. to raise the GitHub rate limit, can be added by a Client with a
+// custom Transport.
+type ReleaseProber struct {
+	GitHubURL string       // DefaultGitHubURL if empty
+	GitLabURL string       // DefaultGitLabURL if empty
+	Client    *http.Client // http.DefaultClient if nil
+}
+
+// ReleaseCandidate is a tag of the upstream repository mapped to a pkgver.
+type ReleaseCandidate struct {
+	Tag        string
+	Pkgver     Version
+	Prerelease bool // e.g. tagged 2.0-rc1
+}
+
+// sourceRepository returns the host and path of the GitHub or GitLab repository
+// the source entry points at, e.g. "github.com" and "foo/bar", ok is false
+// for other sources.
+func sourceRepository(source string) (host, path string, ok bool) {
+	u := Source(source).URL()
+	if i := strings.Index(u, "+"); i >= 0 && i < strings.Index(u, "://") {
+		u = u[i+1:]
+	}
+
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return "", "", false
+	}
+
+	parts := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	switch parsed.Host {
+	case "github.com":
+		if len(parts) < 2 {
+			return "", "", false
+		}
+		return parsed.Host, parts[0] + "/" + strings.TrimSuffix(parts[1], ".git"), true
+	case "gitlab.com":
+		// projects can be nested in groups, the path ends before "-"
+		for i, part := range parts {
+			if part == "-" {
+				parts = parts[:i]
+				break
+			}
+		}
+		if len(parts) < 2 {
+			return "", "", false
+		}
+		path := strings.TrimSuffix(strings.Join(parts, "/"), ".git")
+		return parsed.Host, path, true
+	}
+	return "", "", false
+}
+
+// Tags returns the names of the tags of the GitHub or GitLab repository
+// source points at.
+func (r *ReleaseProber) Tags(ctx context.Context, source string) ([]string, error) {
+	host, path, ok := sourceRepository(source)
+	if !ok {
+		return nil, fmt.Errorf("not a GitHub or GitLab source: %s", source)
+	}
+
+	var u string
+	switch host {
+	case "github.com":
+		base := r.GitHubURL
+		if base == "" {
+			base = DefaultGitHubURL
+		}
+		u = strings.TrimSuffix(base, "/") + "/repos/" + path + "/tags?per_page=100"
+	case "gitlab.com":
+		base := r.GitLabURL
+		if base == "" {
+			base = DefaultGitLabURL
+		}
+		u = strings.TrimSuffix(base, "/") + "/projects/" + url.PathEscape(path) + "/repository/tags?per_page=100"
+	}
+
+	var tags []struct {
+		Name string `json:"name"`
+	}
+	if err := getJSON(ctx, r.Client, u, &tags); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	return names, nil
+}
+
+// prereleaseMarkers are the parts of versions marking prereleases.
+var prereleaseMarkers = []string{"alpha", "beta", "rc", "pre", "dev", "snapshot"}
+
+// TagPkgver maps the tag to a pkgver: a prefix like "v", "release-" or
+// "foo-" is stripped and dashes are replaced by underscores. ok is false if
+// the tag doesn't contain a version.
+func TagPkgver(tag string) (pkgver Version, ok bool) {
+	for i := 0; i < len(tag); i++ {
+		start := i == 0 || i == 1 && (tag[0] == 'v' || tag[0] == 'V') || tag[i-1] == '-' || tag[i-1] == '_'
+		if !start || !isDigit(rune(tag[i])) {
+			continue
+		}
+
+		version := strings.Replace(tag[i:], "-", "_", -1)
+		if validPkgver(version) {
+			return Version(version), true
+		}
+	}
+	return "", false
+}
+
+// Probe returns the tags of the upstream repository of the first GitHub or
+// GitLab source of p newer than its pkgver, newest first. Tags not
+// containing a version are skipped.
+func (r *ReleaseProber) Probe(ctx context.Context, p *PKGBUILD) ([]ReleaseCandidate, error) {
+	var source string
+	for _, s := range p.allSources() {
+		if _, _, ok := sourceRepository(s); ok {
+			source = s
+			break
+		}
+	}
+	if source == "" {
+		return nil, fmt.Errorf("no GitHub or GitLab source in %s", p.Pkgbase)
+	}
+
+	tags, err := r.Tags(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []ReleaseCandidate
+	for _, tag := range tags {
+		pkgver, ok := TagPkgver(tag)
+		if !ok || rpmvercmp(pkgver, p.Pkgver) <= 0 {
+			continue
+		}
+
+		candidate := ReleaseCandidate{Tag: tag, Pkgver: pkgver}
+		lower := strings.ToLower(string(pkgver))
+		for _, marker := range prereleaseMarkers {
+			if strings.Contains(lower, marker) {
+				candidate.Prerelease = true
+				break
+			}
+		}
+		candidates = append(candidates, candidate)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return rpmvercmp(candidates[i].Pkgver, candidates[j].Pkgver) > 0
+	})
+	return candidates, nil
+}
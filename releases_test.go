@@ -0,0 +1,101 @@
+package pkgbuild
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestSourceRepository(t *testing.T) {
+	for _, test := range []struct {
+		source string
+		host   string
+		path   string
+		ok     bool
+	}{
+		{"foo-1.0.tar.gz::https://github.com/foo/bar/archive/v1.0.tar.gz", "github.com", "foo/bar", true},
+		{"https://github.com/foo/bar/releases/download/v1.0/bar", "github.com", "foo/bar", true},
+		{"git+https://github.com/foo/bar.git#tag=v1.0", "github.com", "foo/bar", true},
+		{"https://gitlab.com/group/sub/bar/-/archive/v1.0/bar-v1.0.tar.gz", "gitlab.com", "group/sub/bar", true},
+		{"git+https://gitlab.com/foo/bar.git", "gitlab.com", "foo/bar", true},
+		{"https://example.org/foo-1.0.tar.gz", "", "", false},
+		{"foo.patch", "", "", false},
+	} {
+		host, path, ok := sourceRepository(test.source)
+		if host != test.host || path != test.path || ok != test.ok {
+			t.Errorf("%s: expected %s %s %t, got %s %s %t", test.source, test.host, test.path, test.ok, host, path, ok)
+		}
+	}
+}
+
+func TestTagPkgver(t *testing.T) {
+	for tag, expected := range map[string]Version{
+		"v1.2.3":       "1.2.3",
+		"1.2.3":        "1.2.3",
+		"release-1.2":  "1.2",
+		"foo2-1.0":     "1.0",
+		"foo_1_2":      "1_2",
+		"v2.0.0-rc1":   "2.0.0_rc1",
+		"nightly":      "",
+		"latest/1.0":   "",
+		"v1.0:special": "",
+	} {
+		pkgver, ok := TagPkgver(tag)
+		if pkgver != expected || ok != (expected != "") {
+			t.Errorf("%s: expected %q, got %q %t", tag, expected, pkgver, ok)
+		}
+	}
+}
+
+func TestReleaseProber(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.EscapedPath() {
+		case "/github/repos/foo/bar/tags":
+			w.Write([]byte(`[{"name": "v2.0.0-rc1"}, {"name": "v1.10.0"}, {"name": "v1.2.0"}, {"name": "nightly"}, {"name": "v1.9.1"}, {"name": "v1.0.0"}]`))
+		case "/gitlab/projects/group%2Fbar/repository/tags":
+			w.Write([]byte(`[{"name": "bar-0.3"}, {"name": "bar-0.2"}]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	r := &ReleaseProber{GitHubURL: server.URL + "/github", GitLabURL: server.URL + "/gitlab"}
+
+	p := &PKGBUILD{
+		Pkgbase: "bar",
+		Pkgver:  "1.2.0",
+		Source: []string{
+			"fix.patch",
+			"bar-1.2.0.tar.gz::https://github.com/foo/bar/archive/v1.2.0.tar.gz",
+		},
+	}
+	candidates, err := r.Probe(context.Background(), p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []ReleaseCandidate{
+		{Tag: "v2.0.0-rc1", Pkgver: "2.0.0_rc1", Prerelease: true},
+		{Tag: "v1.10.0", Pkgver: "1.10.0"},
+		{Tag: "v1.9.1", Pkgver: "1.9.1"},
+	}
+	if !reflect.DeepEqual(candidates, expected) {
+		t.Errorf("expected %+v, got %+v", expected, candidates)
+	}
+
+	p = &PKGBUILD{Pkgbase: "bar", Pkgver: "0.3", Source: []string{"https://gitlab.com/group/bar/-/archive/bar-0.3/bar-bar-0.3.tar.gz"}}
+	candidates, err = r.Probe(context.Background(), p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candidates) != 0 {
+		t.Errorf("expected no candidates, got %+v", candidates)
+	}
+
+	p = &PKGBUILD{Pkgbase: "baz", Pkgver: "1.0", Source: []string{"https://example.org/baz-1.0.tar.gz"}}
+	if _, err := r.Probe(context.Background(), p); err == nil {
+		t.Error("expected error for source not on GitHub or GitLab")
+	}
+}
@@ -0,0 +1,157 @@
+package pkgbuild
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// DefaultRepologyURL is the URL of repology.org.
+const DefaultRepologyURL = "https://repology.org"
+
+// Repology is a client of the API of Repology, which tracks the versions
+// of projects packaged by many distributions.
+type Repology struct {
+	URL    string       // DefaultRepologyURL if empty
+	Client *http.Client // http.DefaultClient if nil
+	Repo   string       // repository the PKGBUILDs belong to, "arch" if empty
+}
+
+// RepologyPackage is a package of a project in a repository tracked by
+// Repology.
+type RepologyPackage struct {
+	Repo        string `json:"repo"` // e.g. "arch" or "debian_unstable"
+	Srcname     string `json:"srcname"`
+	Binname     string `json:"binname"`
+	Version     string `json:"version"` // normalized by Repology
+	OrigVersion string `json:"origversion"`
+	Status      string `json:"status"` // e.g. "newest", "outdated" or "devel"
+}
+
+// url returns the URL of Repology without trailing slash.
+func (c *Repology) url() string {
+	if c.URL == "" {
+		return DefaultRepologyURL
+	}
+	return strings.TrimSuffix(c.URL, "/")
+}
+
+// repo returns the repository the PKGBUILDs belong to.
+func (c *Repology) repo() string {
+	if c.Repo == "" {
+		return "arch"
+	}
+	return c.Repo
+}
+
+// Project returns the packages of the Repology project name, e.g.
+// "python:requests".
+func (c *Repology) Project(ctx context.Context, name string) ([]RepologyPackage, error) {
+	var packages []RepologyPackage
+	if err := getJSON(ctx, c.Client, c.url()+"/api/v1/project/"+url.PathEscape(name), &packages); err != nil {
+		return nil, err
+	}
+	return packages, nil
+}
+
+// Packages returns the packages of the project the source package pkgbase
+// of Repo belongs to.
+func (c *Repology) Packages(ctx context.Context, pkgbase string) ([]RepologyPackage, error) {
+	query := url.Values{
+		"repo":        {c.repo()},
+		"name_type":   {"srcname"},
+		"target_page": {"api_v1_project"},
+		"name":        {pkgbase},
+	}
+
+	// redirects to the API page of the project
+	var packages []RepologyPackage
+	if err := getJSON(ctx, c.Client, c.url()+"/tools/project-by?"+query.Encode(), &packages); err != nil {
+		return nil, err
+	}
+	if len(packages) == 0 {
+		return nil, fmt.Errorf("no project of %s found", pkgbase)
+	}
+	return packages, nil
+}
+
+// Rank compares the pkgver of p with the other repositories packaging the
+// same project.
+func (c *Repology) Rank(ctx context.Context, p *PKGBUILD) (*VersionRank, error) {
+	packages, err := c.Packages(ctx, p.Pkgbase)
+	if err != nil {
+		return nil, err
+	}
+	return RankVersion(p, c.repo(), packages), nil
+}
+
+// VersionRank is how the version of a package ranks against the versions
+// of the same project in other repositories.
+type VersionRank struct {
+	Pkgbase string
+	Version Version  // pkgver of the package
+	Newest  Version  // newest version of all repositories, at least Version
+	Newer   []string // repositories with a newer version
+	Same    []string // repositories with the same version
+	Older   []string // repositories with an older version
+}
+
+// rankedStatus lists the statuses of the packages ranked. Development
+// versions and versions Repology doesn't trust are ignored.
+var rankedStatus = map[string]bool{
+	"newest":   true,
+	"outdated": true,
+	"unique":   true,
+	"legacy":   true,
+}
+
+// RankVersion ranks the pkgver of p against the packages of the project in
+// repositories other than repo. The newest stable version packaged by each
+// repository is compared.
+func RankVersion(p *PKGBUILD, repo string, packages []RepologyPackage) *VersionRank {
+	newest := make(map[string]Version)
+	for _, pkg := range packages {
+		if pkg.Repo == repo || !rankedStatus[pkg.Status] {
+			continue
+		}
+		if v, ok := newest[pkg.Repo]; !ok || rpmvercmp(Version(pkg.Version), v) > 0 {
+			newest[pkg.Repo] = Version(pkg.Version)
+		}
+	}
+
+	rank := &VersionRank{Pkgbase: p.Pkgbase, Version: p.Pkgver, Newest: p.Pkgver}
+	for repo, v := range newest {
+		switch cmp := rpmvercmp(v, p.Pkgver); {
+		case cmp > 0:
+			rank.Newer = append(rank.Newer, repo)
+			if rpmvercmp(v, rank.Newest) > 0 {
+				rank.Newest = v
+			}
+		case cmp < 0:
+			rank.Older = append(rank.Older, repo)
+		default:
+			rank.Same = append(rank.Same, repo)
+		}
+	}
+	sort.Strings(rank.Newer)
+	sort.Strings(rank.Same)
+	sort.Strings(rank.Older)
+
+	return rank
+}
+
+// Outdated returns true if any other repository has a newer version.
+func (r *VersionRank) Outdated() bool {
+	return len(r.Newer) > 0
+}
+
+// String returns the rank like "foo 2.0.3: 2 newer (2.1.0), 5 same, 1 older".
+func (r *VersionRank) String() string {
+	if r.Outdated() {
+		return fmt.Sprintf("%s %s: %d newer (%s), %d same, %d older", r.Pkgbase, r.Version, len(r.Newer), r.Newest, len(r.Same), len(r.Older))
+	}
+	return fmt.Sprintf("%s %s: %d newer, %d same, %d older", r.Pkgbase, r.Version, len(r.Newer), len(r.Same), len(r.Older))
+}
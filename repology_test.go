@@ -0,0 +1,62 @@
+package pkgbuild
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestRepology(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/tools/project-by":
+			query := r.URL.Query()
+			if query.Get("repo") != "arch" || query.Get("name_type") != "srcname" || query.Get("name") != "python-foo" {
+				http.NotFound(w, r)
+				return
+			}
+			http.Redirect(w, r, "/api/v1/project/python:foo", http.StatusFound)
+		case "/api/v1/project/python:foo":
+			w.Write([]byte(`[
+  {"repo": "arch", "srcname": "python-foo", "version": "2.0.3", "status": "outdated"},
+  {"repo": "debian_unstable", "srcname": "foo", "version": "2.1.0", "status": "newest"},
+  {"repo": "fedora_rawhide", "srcname": "python-foo", "version": "2.0.3", "status": "outdated"},
+  {"repo": "fedora_rawhide", "srcname": "python-foo", "version": "2.2.0b1", "status": "devel"},
+  {"repo": "gentoo", "srcname": "dev-python/foo", "version": "2.0.3", "status": "outdated"},
+  {"repo": "gentoo", "srcname": "dev-python/foo", "version": "2.1.0", "status": "newest"},
+  {"repo": "ubuntu_20_04", "srcname": "foo", "version": "1.0", "status": "legacy"},
+  {"repo": "nix_unstable", "srcname": "foo", "version": "9999", "status": "ignored"}
+]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	c := &Repology{URL: server.URL}
+	rank, err := c.Rank(context.Background(), &PKGBUILD{Pkgbase: "python-foo", Pkgver: "2.0.3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &VersionRank{
+		Pkgbase: "python-foo",
+		Version: "2.0.3",
+		Newest:  "2.1.0",
+		Newer:   []string{"debian_unstable", "gentoo"},
+		Same:    []string{"fedora_rawhide"},
+		Older:   []string{"ubuntu_20_04"},
+	}
+	if !reflect.DeepEqual(rank, expected) {
+		t.Errorf("expected %+v, got %+v", expected, rank)
+	}
+	if rank.String() != "python-foo 2.0.3: 2 newer (2.1.0), 1 same, 1 older" {
+		t.Errorf("unexpected rank: %s", rank)
+	}
+
+	if _, err := c.Packages(context.Background(), "bar"); err == nil {
+		t.Error("expected error for unknown package")
+	}
+}
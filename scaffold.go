@@ -0,0 +1,289 @@
+package pkgbuild
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Ecosystem is a build system a Scaffold can be generated for.
+type Ecosystem string
+
+// Ecosystems with a scaffold template
+const (
+	EcosystemAutotools Ecosystem = "autotools"
+	EcosystemCMake     Ecosystem = "cmake"
+	EcosystemMeson     Ecosystem = "meson"
+	EcosystemPython    Ecosystem = "python"
+	EcosystemGo        Ecosystem = "go"
+	EcosystemRust      Ecosystem = "rust"
+)
+
+// scaffoldTemplate is the variables and functions of an Ecosystem.
+type scaffoldTemplate struct {
+	arch        []string
+	depends     []string
+	makedepends []string
+	provides    []string
+	conflicts   []string
+	functions   []*Function
+}
+
+// scaffoldTemplates holds the templates following the packaging guidelines
+// of each Ecosystem. The sources are expected to be extracted to
+// $pkgname-$pkgver.
+var scaffoldTemplates = map[Ecosystem]scaffoldTemplate{
+	EcosystemAutotools: {
+		arch: []string{"x86_64"},
+		functions: []*Function{
+			{Name: "build", Body: `
+cd "$pkgname-$pkgver"
+./configure --prefix=/usr
+make
+`},
+			{Name: "check", Body: `
+cd "$pkgname-$pkgver"
+make check
+`},
+			{Name: "package", Body: `
+cd "$pkgname-$pkgver"
+make DESTDIR="$pkgdir" install
+`},
+		},
+	},
+	EcosystemCMake: {
+		arch:        []string{"x86_64"},
+		makedepends: []string{"cmake"},
+		functions: []*Function{
+			{Name: "build", Body: `
+cmake -B build -S "$pkgname-$pkgver" \
+-DCMAKE_BUILD_TYPE='None' \
+-DCMAKE_INSTALL_PREFIX='/usr' \
+-Wno-dev
+cmake --build build
+`},
+			{Name: "check", Body: `
+ctest --test-dir build --output-on-failure
+`},
+			{Name: "package", Body: `
+DESTDIR="$pkgdir" cmake --install build
+`},
+		},
+	},
+	EcosystemMeson: {
+		arch:        []string{"x86_64"},
+		makedepends: []string{"meson"},
+		functions: []*Function{
+			{Name: "build", Body: `
+arch-meson "$pkgname-$pkgver" build
+meson compile -C build
+`},
+			{Name: "check", Body: `
+meson test -C build --print-errorlogs
+`},
+			{Name: "package", Body: `
+meson install -C build --destdir "$pkgdir"
+`},
+		},
+	},
+	EcosystemPython: {
+		arch:        []string{"any"},
+		depends:     []string{"python"},
+		makedepends: []string{"python-build", "python-installer", "python-wheel"},
+		functions:   pythonFunctions(`"$pkgname-$pkgver"`),
+	},
+	EcosystemGo: {
+		arch:        []string{"x86_64"},
+		depends:     []string{"glibc"},
+		makedepends: []string{"go"},
+		functions:   goFunctions(`"$pkgname-$pkgver"`),
+	},
+	EcosystemRust: {
+		arch:        []string{"x86_64"},
+		depends:     []string{"gcc-libs", "glibc"},
+		makedepends: []string{"cargo"},
+		functions:   rustFunctions(`"$pkgname-$pkgver"`, `"$pkgname"`),
+	},
+}
+
+// pythonFunctions returns the functions building the Python project in
+// srcdir, a quoted shell word, following the Python package guidelines.
+func pythonFunctions(srcdir string) []*Function {
+	return []*Function{
+		{Name: "build", Body: `
+cd ` + srcdir + `
+python -m build --wheel --no-isolation
+`},
+		{Name: "package", Body: `
+cd ` + srcdir + `
+python -m installer --destdir="$pkgdir" dist/*.whl
+`},
+	}
+}
+
+// goFunctions returns the functions building the Go module in srcdir, a
+// quoted shell word, following the Go package guidelines.
+func goFunctions(srcdir string) []*Function {
+	return []*Function{
+		{Name: "prepare", Body: `
+cd ` + srcdir + `
+mkdir -p build
+`},
+		{Name: "build", Body: `
+cd ` + srcdir + `
+export CGO_CPPFLAGS="${CPPFLAGS}"
+export CGO_CFLAGS="${CFLAGS}"
+export CGO_CXXFLAGS="${CXXFLAGS}"
+export CGO_LDFLAGS="${LDFLAGS}"
+export GOFLAGS="-buildmode=pie -trimpath -ldflags=-linkmode=external -mod=readonly -modcacherw"
+go build -o build ./...
+`},
+		{Name: "check", Body: `
+cd ` + srcdir + `
+go test ./...
+`},
+		{Name: "package", Body: `
+cd ` + srcdir + `
+install -Dm755 build/* -t "$pkgdir/usr/bin"
+`},
+	}
+}
+
+// rustFunctions returns the functions building the crate in srcdir and
+// installing its binary bin, both quoted shell words, following the Rust
+// package guidelines.
+func rustFunctions(srcdir, bin string) []*Function {
+	return []*Function{
+		{Name: "prepare", Body: `
+cd ` + srcdir + `
+export RUSTUP_TOOLCHAIN=stable
+cargo fetch --locked --target "$(rustc -vV | sed -n 's/host: //p')"
+`},
+		{Name: "build", Body: `
+cd ` + srcdir + `
+export RUSTUP_TOOLCHAIN=stable
+export CARGO_TARGET_DIR=target
+cargo build --frozen --release --all-features
+`},
+		{Name: "check", Body: `
+cd ` + srcdir + `
+export RUSTUP_TOOLCHAIN=stable
+cargo test --frozen --all-features
+`},
+		{Name: "package", Body: `
+cd ` + srcdir + `
+install -Dm0755 -t "$pkgdir/usr/bin/" target/release/` + bin + `
+`},
+	}
+}
+
+// ScaffoldOptions are the inputs of NewScaffold.
+type ScaffoldOptions struct {
+	Pkgname     string
+	Pkgver      Version
+	Pkgdesc     string
+	URL         string
+	Source      string // source URL, occurrences of Pkgver are replaced by $pkgver
+	License     []string
+	Maintainer  *Person
+	Depends     []string // in addition to the ones of the ecosystem
+	Makedepends []string
+}
+
+// Scaffold is a ready to edit PKGBUILD generated for an Ecosystem.
+type Scaffold struct {
+	PKGBUILD  *PKGBUILD
+	Functions []*Function
+}
+
+// NewScaffold returns the scaffold of the package described by opts built
+// with ecosystem. Checksums are set to SKIP, to be updated e.g. with
+// updpkgsums.
+func NewScaffold(ecosystem Ecosystem, opts ScaffoldOptions) (*Scaffold, error) {
+	template, ok := scaffoldTemplates[ecosystem]
+	if !ok {
+		return nil, fmt.Errorf("unknown ecosystem: %s", ecosystem)
+	}
+	return newScaffold(template, opts)
+}
+
+// newScaffold returns the scaffold of the package described by opts using
+// template.
+func newScaffold(template scaffoldTemplate, opts ScaffoldOptions) (*Scaffold, error) {
+	b := NewBuilder(opts.Pkgname).
+		Pkgver(opts.Pkgver).
+		Pkgdesc(opts.Pkgdesc).
+		URL(opts.URL).
+		Arch(template.arch...).
+		License(opts.License...).
+		Depends(append(append([]string(nil), template.depends...), opts.Depends...)...).
+		Makedepends(append(append([]string(nil), template.makedepends...), opts.Makedepends...)...).
+		Provides(template.provides...).
+		Conflicts(template.conflicts...)
+	if opts.Source != "" {
+		b.Source(opts.Source).Checksums("sha256", "SKIP")
+	}
+
+	p, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+	if opts.Maintainer != nil {
+		p.Maintainers = []Person{*opts.Maintainer}
+	}
+
+	functions := make([]*Function, 0, len(template.functions))
+	for _, f := range template.functions {
+		functions = append(functions, &Function{Name: f.Name, Body: f.Body})
+	}
+
+	return &Scaffold{PKGBUILD: p, Functions: functions}, nil
+}
+
+// WritePKGBUILD writes the PKGBUILD of s to w. The version in the sources
+// other than VCS sources is replaced by $pkgver.
+func (s *Scaffold) WritePKGBUILD(w io.Writer) error {
+	pkgver := string(s.PKGBUILD.Pkgver)
+	return writePKGBUILD(w, s.PKGBUILD, func(name, value string) string {
+		if name == "source" || strings.HasPrefix(name, "source_") {
+			if _, vcs := parseVCSSource(value); !vcs && pkgver != "" && strings.Contains(value, pkgver) {
+				parts := strings.Split(value, pkgver)
+				for i, part := range parts {
+					parts[i] = strings.TrimSuffix(strings.TrimPrefix(quoteWordAs(part, '"'), `"`), `"`)
+				}
+				return `"` + strings.Join(parts, "${pkgver}") + `"`
+			}
+		}
+		return quoteWord(value)
+	}, s.Functions)
+}
+
+// WriteSRCINFO writes the .SRCINFO of s to w.
+func (s *Scaffold) WriteSRCINFO(w io.Writer) error {
+	return s.PKGBUILD.WriteSRCINFO(w)
+}
+
+// WriteDir writes the PKGBUILD and .SRCINFO of s to dir, creating it if
+// needed.
+func (s *Scaffold) WriteDir(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	var pkgbuild, srcinfo bytes.Buffer
+	if err := s.WritePKGBUILD(&pkgbuild); err != nil {
+		return err
+	}
+	if err := s.WriteSRCINFO(&srcinfo); err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "PKGBUILD"), pkgbuild.Bytes(), 0644); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, ".SRCINFO"), srcinfo.Bytes(), 0644)
+}
@@ -0,0 +1,104 @@
+package pkgbuild
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestNewScaffold(t *testing.T) {
+	opts := ScaffoldOptions{
+		Pkgname:    "foo",
+		Pkgver:     "1.2.3",
+		Pkgdesc:    "A foo",
+		URL:        "https://example.org/foo",
+		Source:     "https://example.org/foo/foo-1.2.3.tar.gz",
+		License:    []string{"MIT"},
+		Maintainer: &Person{Name: "Jane Doe", Email: "jane@example.org"},
+		Depends:    []string{"zlib"},
+	}
+
+	for ecosystem, template := range scaffoldTemplates {
+		s, err := NewScaffold(ecosystem, opts)
+		if err != nil {
+			t.Fatalf("%s: %s", ecosystem, err)
+		}
+
+		var pkgbuild bytes.Buffer
+		if err := s.WritePKGBUILD(&pkgbuild); err != nil {
+			t.Fatalf("%s: %s", ecosystem, err)
+		}
+
+		f, err := ParseAST(pkgbuild.Bytes())
+		if err != nil {
+			t.Fatalf("%s: PKGBUILD not parsable: %s\n%s", ecosystem, err, pkgbuild.String())
+		}
+		for _, function := range template.functions {
+			if !f.HasFunction(function.Name) {
+				t.Errorf("%s: %s() missing", ecosystem, function.Name)
+			}
+		}
+
+		e := NewExpander(f)
+		if source := e.Values("source"); !reflect.DeepEqual(source, []string{opts.Source}) {
+			t.Errorf("%s: expected source %q, got %q", ecosystem, opts.Source, source)
+		}
+		if depends := e.Values("depends"); len(depends) == 0 || depends[len(depends)-1] != "zlib" {
+			t.Errorf("%s: expected depends ending with zlib, got %q", ecosystem, depends)
+		}
+
+		var srcinfo bytes.Buffer
+		if err := s.WriteSRCINFO(&srcinfo); err != nil {
+			t.Fatalf("%s: %s", ecosystem, err)
+		}
+		pkgb, err := ParseSRCINFOContent(srcinfo.Bytes())
+		if err != nil {
+			t.Fatalf("%s: .SRCINFO not parsable: %s", ecosystem, err)
+		}
+		if !Equal(pkgb, s.PKGBUILD) {
+			t.Errorf("%s: .SRCINFO does not match the scaffold", ecosystem)
+		}
+	}
+
+	if _, err := NewScaffold("scons", opts); err == nil {
+		t.Error("expected error for unknown ecosystem")
+	}
+
+	opts.Pkgname = "-foo"
+	if _, err := NewScaffold(EcosystemMeson, opts); err == nil {
+		t.Error("expected error for invalid pkgname")
+	}
+}
+
+func TestScaffoldWriteDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scaffold")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := NewScaffold(EcosystemPython, ScaffoldOptions{Pkgname: "python-foo", Pkgver: "0.1", License: []string{"MIT"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir = filepath.Join(dir, "python-foo")
+	if err := s.WriteDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	pkgb, err := ParseSRCINFO(filepath.Join(dir, ".SRCINFO"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(pkgb.Arch, []string{"any"}) {
+		t.Errorf("expected arch any, got %q", pkgb.Arch)
+	}
+
+	if _, err := ParseASTFile(filepath.Join(dir, "PKGBUILD")); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,252 @@
+package pkgbuild
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PkgbuildSet is a set of PKGBUILDs like the packages of the AUR or a
+// repository, keyed by pkgbase. Package names are unique within a set.
+type PkgbuildSet struct {
+	pkgbuilds map[string]*PKGBUILD  // by pkgbase
+	packages  map[string]*PKGBUILD  // by pkgname
+	providers map[string][]Provider // by provided name, including pkgnames
+	replacers map[string][]replacer // by replaced name
+}
+
+// replacer is a package of a PkgbuildSet replacing a name.
+type replacer struct {
+	pkgname  string
+	pkgbuild *PKGBUILD
+	replace  *Dependency // the replaces entry, possibly versioned
+}
+
+// Provider is a package of a PkgbuildSet providing a name, either by its
+// pkgname or by one of its provides.
+type Provider struct {
+	Pkgname  string
+	PKGBUILD *PKGBUILD
+	Version  *CompleteVersion // the provided version, nil if unversioned
+}
+
+// NewPkgbuildSet returns a set of the PKGBUILDs pkgs, see Add.
+func NewPkgbuildSet(pkgs ...*PKGBUILD) *PkgbuildSet {
+	s := &PkgbuildSet{
+		pkgbuilds: make(map[string]*PKGBUILD, len(pkgs)),
+		packages:  make(map[string]*PKGBUILD, len(pkgs)),
+		providers: make(map[string][]Provider, len(pkgs)),
+		replacers: make(map[string][]replacer),
+	}
+	for _, p := range pkgs {
+		s.Add(p)
+	}
+	return s
+}
+
+// Add adds p to s, replacing the PKGBUILD with the same pkgbase and the
+// ones building packages of the same name. The provides of all
+// architectures are indexed.
+func (s *PkgbuildSet) Add(p *PKGBUILD) {
+	s.Remove(p.pkgbase())
+	for _, name := range p.Pkgnames {
+		if old, ok := s.packages[name]; ok {
+			s.Remove(old.pkgbase())
+		}
+	}
+
+	s.pkgbuilds[p.pkgbase()] = p
+	for _, name := range p.Pkgnames {
+		s.packages[name] = p
+	}
+
+	version := p.CompleteVersion()
+	for _, name := range p.Pkgnames {
+		s.providers[name] = append(s.providers[name], Provider{name, p, &version})
+	}
+	for _, provide := range p.allProvides() {
+		deps, err := parseDependency(provide, nil)
+		if err != nil || len(deps) == 0 {
+			continue
+		}
+		for _, name := range p.Pkgnames {
+			s.providers[deps[0].Name] = append(s.providers[deps[0].Name], Provider{name, p, deps[0].MinVer})
+		}
+	}
+	for _, replace := range p.Replaces {
+		deps, err := parseDependency(replace, nil)
+		if err != nil || len(deps) == 0 {
+			continue
+		}
+		for _, name := range p.Pkgnames {
+			s.replacers[deps[0].Name] = append(s.replacers[deps[0].Name], replacer{name, p, deps[0]})
+		}
+	}
+}
+
+// allProvides returns the provides of p including the arch specific ones.
+func (p *PKGBUILD) allProvides() []string {
+	return p.allArchs().Provides
+}
+
+// Remove removes the PKGBUILD with pkgbase from s and reports whether it
+// was part of s.
+func (s *PkgbuildSet) Remove(pkgbase string) bool {
+	p, ok := s.pkgbuilds[pkgbase]
+	if !ok {
+		return false
+	}
+
+	delete(s.pkgbuilds, pkgbase)
+	for _, name := range p.Pkgnames {
+		delete(s.packages, name)
+	}
+
+	provided := append([]string{}, p.Pkgnames...)
+	for _, provide := range p.allProvides() {
+		if deps, err := parseDependency(provide, nil); err == nil && len(deps) > 0 {
+			provided = append(provided, deps[0].Name)
+		}
+	}
+	for _, name := range provided {
+		providers := s.providers[name][:0]
+		for _, provider := range s.providers[name] {
+			if provider.PKGBUILD != p {
+				providers = append(providers, provider)
+			}
+		}
+		if len(providers) == 0 {
+			delete(s.providers, name)
+		} else {
+			s.providers[name] = providers
+		}
+	}
+
+	for _, replace := range p.Replaces {
+		deps, err := parseDependency(replace, nil)
+		if err != nil || len(deps) == 0 {
+			continue
+		}
+		name := deps[0].Name
+		replacers := s.replacers[name][:0]
+		for _, r := range s.replacers[name] {
+			if r.pkgbuild != p {
+				replacers = append(replacers, r)
+			}
+		}
+		if len(replacers) == 0 {
+			delete(s.replacers, name)
+		} else {
+			s.replacers[name] = replacers
+		}
+	}
+	return true
+}
+
+// WhatProvides returns the packages of s named name or providing it, in the
+// order they were added.
+func (s *PkgbuildSet) WhatProvides(name string) []Provider {
+	return append([]Provider(nil), s.providers[name]...)
+}
+
+// WhatSatisfies returns the packages of s satisfying dep, by name or by
+// their provides matched with policy, see Dependency.SatisfiedBy.
+func (s *PkgbuildSet) WhatSatisfies(dep *Dependency, policy ProvidePolicy) []Provider {
+	var providers []Provider
+	for _, provider := range s.providers[dep.Name] {
+		if provider.satisfies(dep, policy) {
+			providers = append(providers, provider)
+		}
+	}
+	return providers
+}
+
+// satisfies reports whether the name provided by provider satisfies dep,
+// which has the same name.
+func (provider Provider) satisfies(dep *Dependency, policy ProvidePolicy) bool {
+	switch {
+	case !dep.versioned():
+		return true
+	case provider.Version == nil:
+		return policy == ProvidesLoose
+	}
+	return provider.Version.Satisfies(dep)
+}
+
+// Get returns the PKGBUILD with pkgbase, nil if there is none.
+func (s *PkgbuildSet) Get(pkgbase string) *PKGBUILD {
+	return s.pkgbuilds[pkgbase]
+}
+
+// Package returns the PKGBUILD building the package name, nil if there is
+// none.
+func (s *PkgbuildSet) Package(name string) *PKGBUILD {
+	return s.packages[name]
+}
+
+// Len returns the number of PKGBUILDs in s.
+func (s *PkgbuildSet) Len() int {
+	return len(s.pkgbuilds)
+}
+
+// PKGBUILDs returns the PKGBUILDs of s ordered by pkgbase.
+func (s *PkgbuildSet) PKGBUILDs() []*PKGBUILD {
+	bases := make([]string, 0, len(s.pkgbuilds))
+	for base := range s.pkgbuilds {
+		bases = append(bases, base)
+	}
+	sort.Strings(bases)
+
+	pkgs := make([]*PKGBUILD, 0, len(bases))
+	for _, base := range bases {
+		pkgs = append(pkgs, s.pkgbuilds[base])
+	}
+	return pkgs
+}
+
+// replacement returns the package of s replacing the package name, if any.
+// A versioned replaces like "foo<2.0" only applies if s has no package foo
+// or its version satisfies it. Of several replacements the first by name
+// is returned.
+func (s *PkgbuildSet) replacement(name string) string {
+	var version *CompleteVersion
+	if old := s.packages[name]; old != nil {
+		v := old.CompleteVersion()
+		version = &v
+	}
+
+	replacement := ""
+	for _, r := range s.replacers[name] {
+		if r.pkgname == name || (replacement != "" && r.pkgname >= replacement) {
+			continue
+		}
+		if version != nil && !version.Satisfies(r.replace) {
+			continue
+		}
+		replacement = r.pkgname
+	}
+	return replacement
+}
+
+// ResolveReplaces follows the replaces of the packages in s starting from
+// the package name, e.g. to find what to install for a package that was
+// renamed, possibly several times. It returns the chain of names starting
+// with name, the last one is the package to install. An error is returned
+// if the replaces form a cycle.
+func (s *PkgbuildSet) ResolveReplaces(name string) ([]string, error) {
+	chain := []string{name}
+	seen := map[string]bool{name: true}
+
+	for {
+		next := s.replacement(chain[len(chain)-1])
+		if next == "" {
+			return chain, nil
+		}
+
+		chain = append(chain, next)
+		if seen[next] {
+			return nil, fmt.Errorf("replaces cycle: %s", strings.Join(chain, " -> "))
+		}
+		seen[next] = true
+	}
+}
@@ -0,0 +1,168 @@
+package pkgbuild
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// testPKGBUILD returns a PKGBUILD with pkgbase building pkgnames at version
+// 1.0-1 with replaces.
+func testPKGBUILD(pkgbase string, pkgnames []string, replaces ...string) *PKGBUILD {
+	return &PKGBUILD{
+		Pkgbase:  pkgbase,
+		Pkgnames: pkgnames,
+		Pkgver:   "1.0",
+		Pkgrel:   "1",
+		Arch:     []string{"any"},
+		Replaces: replaces,
+	}
+}
+
+func TestPkgbuildSet(t *testing.T) {
+	s := NewPkgbuildSet(
+		testPKGBUILD("foo", []string{"foo", "foo-docs"}),
+		testPKGBUILD("bar", []string{"bar"}),
+	)
+
+	if s.Len() != 2 || s.Package("foo-docs") != s.Get("foo") || s.Package("baz") != nil {
+		t.Error("unexpected set content")
+	}
+
+	// replaces the PKGBUILD building foo-docs
+	s.Add(testPKGBUILD("foo-docs", []string{"foo-docs"}))
+	var bases []string
+	for _, p := range s.PKGBUILDs() {
+		bases = append(bases, p.Pkgbase)
+	}
+	if !reflect.DeepEqual(bases, []string{"bar", "foo-docs"}) || s.Package("foo") != nil {
+		t.Errorf("unexpected PKGBUILDs %v", bases)
+	}
+
+	if !s.Remove("bar") || s.Remove("bar") || s.Package("bar") != nil {
+		t.Error("unexpected Remove")
+	}
+}
+
+func TestResolveReplaces(t *testing.T) {
+	s := NewPkgbuildSet(
+		testPKGBUILD("foo-ng", []string{"foo-ng"}, "foo"),
+		testPKGBUILD("foo2", []string{"foo2"}, "foo-ng<2"),
+		testPKGBUILD("bar", []string{"bar"}),
+		testPKGBUILD("baz", []string{"baz"}, "bar>=2"),
+		testPKGBUILD("a", []string{"a"}, "b"),
+		testPKGBUILD("b", []string{"b"}, "a"),
+	)
+
+	for name, expected := range map[string][]string{
+		"foo":    {"foo", "foo-ng", "foo2"},
+		"foo-ng": {"foo-ng", "foo2"},
+		"foo2":   {"foo2"},
+		"bar":    {"bar"},
+		"qux":    {"qux"},
+	} {
+		chain, err := s.ResolveReplaces(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(chain, expected) {
+			t.Errorf("expected %s to resolve as %v, got %v", name, expected, chain)
+		}
+	}
+
+	if _, err := s.ResolveReplaces("a"); err == nil {
+		t.Error("expected error for replaces cycle")
+	}
+
+	// the replaces index follows changes of the set
+	s.Remove("foo2")
+	s.Add(testPKGBUILD("qux-ng", []string{"qux-ng"}, "qux"))
+	for name, expected := range map[string][]string{
+		"foo": {"foo", "foo-ng"},
+		"qux": {"qux", "qux-ng"},
+	} {
+		if chain, err := s.ResolveReplaces(name); err != nil || !reflect.DeepEqual(chain, expected) {
+			t.Errorf("expected %s to resolve as %v, got %v, %v", name, expected, chain, err)
+		}
+	}
+}
+
+func TestPkgbuildSetEmptyValues(t *testing.T) {
+	foo := testPKGBUILD("foo", []string{"foo"}, "")
+	foo.Provides = []string{""}
+	s := NewPkgbuildSet(foo, testPKGBUILD("bar", []string{"bar"}))
+
+	chain, err := s.ResolveReplaces("bar")
+	if err != nil || !reflect.DeepEqual(chain, []string{"bar"}) {
+		t.Errorf("unexpected replaces chain %v: %v", chain, err)
+	}
+	if !s.Remove("foo") {
+		t.Error("expected foo to be removed")
+	}
+}
+
+func TestWhatProvides(t *testing.T) {
+	jdk := testPKGBUILD("jdk-openjdk", []string{"jre-openjdk", "jdk-openjdk"})
+	jdk.Provides = []string{"java-runtime=21"}
+	zulu := testPKGBUILD("jre-zulu", []string{"jre-zulu"})
+	zulu.Arch = []string{"x86_64"}
+	zulu.ArchSpecific = map[string]*ArchSpecific{"x86_64": {Provides: []string{"java-runtime"}}}
+
+	s := NewPkgbuildSet(jdk, zulu)
+
+	names := func(providers []Provider) []string {
+		var names []string
+		for _, provider := range providers {
+			names = append(names, provider.Pkgname)
+		}
+		return names
+	}
+
+	if n := names(s.WhatProvides("java-runtime")); !reflect.DeepEqual(n, []string{"jre-openjdk", "jdk-openjdk", "jre-zulu"}) {
+		t.Errorf("unexpected providers %v", n)
+	}
+	if n := names(s.WhatProvides("jre-zulu")); !reflect.DeepEqual(n, []string{"jre-zulu"}) {
+		t.Errorf("unexpected providers %v", n)
+	}
+
+	deps, _ := parseDependency("java-runtime>=17", nil)
+	if n := names(s.WhatSatisfies(deps[0], ProvidesStrict)); !reflect.DeepEqual(n, []string{"jre-openjdk", "jdk-openjdk"}) {
+		t.Errorf("unexpected strict providers %v", n)
+	}
+	if n := names(s.WhatSatisfies(deps[0], ProvidesLoose)); len(n) != 3 {
+		t.Errorf("unexpected loose providers %v", n)
+	}
+
+	// the index follows changes of the set
+	s.Remove("jdk-openjdk")
+	if n := names(s.WhatProvides("java-runtime")); !reflect.DeepEqual(n, []string{"jre-zulu"}) {
+		t.Errorf("unexpected providers after remove %v", n)
+	}
+	if n := s.WhatProvides("jdk-openjdk"); len(n) != 0 {
+		t.Errorf("unexpected providers after remove %v", n)
+	}
+
+	zulu2 := testPKGBUILD("jre-zulu", []string{"jre-zulu"})
+	s.Add(zulu2)
+	if n := s.WhatProvides("java-runtime"); len(n) != 0 {
+		t.Errorf("unexpected providers after replacing %v", n)
+	}
+	if n := s.WhatProvides("jre-zulu"); len(n) != 1 || n[0].PKGBUILD != zulu2 {
+		t.Errorf("unexpected providers after replacing %v", n)
+	}
+}
+
+func BenchmarkWhatProvides(b *testing.B) {
+	pkgs := make([]*PKGBUILD, 90000)
+	for i := range pkgs {
+		name := "pkg" + strconv.Itoa(i)
+		pkgs[i] = testPKGBUILD(name, []string{name})
+		pkgs[i].Provides = []string{"virtual" + strconv.Itoa(i%1000) + "=1.0"}
+	}
+	s := NewPkgbuildSet(pkgs...)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.WhatProvides("virtual" + strconv.Itoa(i%1000))
+	}
+}
@@ -0,0 +1,644 @@
+package pkgbuild
+
+import (
+	"fmt"
+	"strings"
+)
+
+// This file implements a scanner for the subset of bash used in PKGBUILD
+// files. It splits the input into top level statements (assignments,
+// functions, comments and other commands) and keeps track of the exact byte
+// ranges of each, such that the input can be edited without losing anything
+// not touched.
+
+// span is a byte range [start, end) of the input.
+type span struct {
+	start int
+	end   int
+}
+
+type shellTokenType int
+
+const (
+	shellEOF shellTokenType = iota
+	shellWord
+	shellOperator
+	shellNewline
+	shellComment
+)
+
+type shellToken struct {
+	typ shellTokenType
+	val string
+	span
+}
+
+// shellScanner splits bash input into words, operators, newlines and
+// comments.
+type shellScanner struct {
+	input    string
+	pos      int
+	heredocs []heredoc // heredocs whose body starts after the next newline
+	err      error
+}
+
+// heredoc is a pending here-document.
+type heredoc struct {
+	delim     string
+	stripTabs bool
+}
+
+// errorf records an error at offset and stops the scan.
+func (s *shellScanner) errorf(offset int, format string, args ...interface{}) {
+	if s.err == nil {
+		s.err = &ParseError{
+			Pos: offsetPosition(s.input, offset),
+			Msg: fmt.Sprintf(format, args...),
+		}
+	}
+	s.pos = len(s.input)
+}
+
+// offsetPosition returns the Position of offset in input.
+func offsetPosition(input string, offset int) Position {
+	before := input[:offset]
+	return Position{
+		Offset: offset,
+		Line:   strings.Count(before, "\n") + 1,
+		Column: offset - strings.LastIndexByte(before, '\n'),
+	}
+}
+
+// next returns the next token of the input.
+func (s *shellScanner) next() shellToken {
+	// skip blanks and line continuations
+	for s.pos < len(s.input) {
+		if c := s.input[s.pos]; c == ' ' || c == '\t' || c == '\r' {
+			s.pos++
+		} else if strings.HasPrefix(s.input[s.pos:], "\\\n") {
+			s.pos += 2
+		} else {
+			break
+		}
+	}
+
+	start := s.pos
+	if s.pos >= len(s.input) {
+		return shellToken{typ: shellEOF, span: span{start, start}}
+	}
+
+	switch c := s.input[s.pos]; {
+	case c == '\n':
+		s.pos++
+		s.skipHeredocs()
+		return s.token(shellNewline, start)
+	case c == '#':
+		for s.pos < len(s.input) && s.input[s.pos] != '\n' {
+			s.pos++
+		}
+		return s.token(shellComment, start)
+	case strings.IndexByte(";&|()<>", c) >= 0:
+		s.scanOperator()
+		return s.token(shellOperator, start)
+	}
+
+	s.scanWord()
+	return s.token(shellWord, start)
+}
+
+// token returns a token of type typ from start to the current position.
+func (s *shellScanner) token(typ shellTokenType, start int) shellToken {
+	return shellToken{typ, s.input[start:s.pos], span{start, s.pos}}
+}
+
+// shellOperators lists the multi character operators, longest first.
+var shellOperators = []string{"<<<", "<<-", ";;&", "&&", "||", ";;", ";&", "<<", ">>", "<&", ">&", "<>", "&>", ">|"}
+
+func (s *shellScanner) scanOperator() {
+	rest := s.input[s.pos:]
+	for _, op := range shellOperators {
+		if strings.HasPrefix(rest, op) {
+			s.pos += len(op)
+			if op == "<<" || op == "<<-" {
+				s.scanHeredocDelim(op == "<<-")
+			}
+			return
+		}
+	}
+	s.pos++
+}
+
+// scanHeredocDelim registers the heredoc started by the operator just
+// scanned.
+func (s *shellScanner) scanHeredocDelim(stripTabs bool) {
+	for s.pos < len(s.input) && (s.input[s.pos] == ' ' || s.input[s.pos] == '\t') {
+		s.pos++
+	}
+
+	start := s.pos
+	s.scanWord()
+	if s.pos == start {
+		s.errorf(start, "missing heredoc delimiter")
+		return
+	}
+
+	delim := unquoteWord(s.input[start:s.pos])
+	s.heredocs = append(s.heredocs, heredoc{delim, stripTabs})
+}
+
+// skipHeredocs skips the bodies of pending heredocs.
+func (s *shellScanner) skipHeredocs() {
+	for _, h := range s.heredocs {
+		for s.pos < len(s.input) {
+			end := strings.IndexByte(s.input[s.pos:], '\n')
+			if end < 0 {
+				end = len(s.input) - s.pos
+			}
+
+			line := s.input[s.pos : s.pos+end]
+			s.pos += end
+			if s.pos < len(s.input) {
+				s.pos++
+			}
+
+			if h.stripTabs {
+				line = strings.TrimLeft(line, "\t")
+			}
+			if strings.TrimSuffix(line, "\r") == h.delim {
+				break
+			}
+		}
+	}
+	s.heredocs = nil
+}
+
+// scanWord scans a word up to the next unquoted metacharacter.
+func (s *shellScanner) scanWord() {
+	for s.pos < len(s.input) {
+		c := s.input[s.pos]
+		switch {
+		case strings.IndexByte(" \t\r\n;&|()<>", c) >= 0:
+			return
+		case c == '\\':
+			s.pos += 2
+		case c == '\'':
+			s.scanSingleQuoted()
+		case c == '"':
+			s.scanDoubleQuoted()
+		case c == '`':
+			s.scanBackquoted()
+		case c == '$':
+			s.scanDollar()
+		default:
+			s.pos++
+		}
+	}
+
+	if s.pos > len(s.input) {
+		s.pos = len(s.input)
+	}
+}
+
+func (s *shellScanner) scanSingleQuoted() {
+	start := s.pos
+	end := strings.IndexByte(s.input[s.pos+1:], '\'')
+	if end < 0 {
+		s.errorf(start, "unterminated single quote")
+		return
+	}
+	s.pos += end + 2
+}
+
+func (s *shellScanner) scanDoubleQuoted() {
+	start := s.pos
+	s.pos++
+	for s.pos < len(s.input) {
+		switch s.input[s.pos] {
+		case '"':
+			s.pos++
+			return
+		case '\\':
+			s.pos += 2
+		case '`':
+			s.scanBackquoted()
+		case '$':
+			s.scanDollar()
+		default:
+			s.pos++
+		}
+	}
+	s.errorf(start, "unterminated double quote")
+}
+
+func (s *shellScanner) scanBackquoted() {
+	start := s.pos
+	s.pos++
+	for s.pos < len(s.input) {
+		switch s.input[s.pos] {
+		case '`':
+			s.pos++
+			return
+		case '\\':
+			s.pos += 2
+		default:
+			s.pos++
+		}
+	}
+	s.errorf(start, "unterminated backquote")
+}
+
+// scanDollar scans a parameter expansion, command substitution or
+// arithmetic expansion starting at '$'.
+func (s *shellScanner) scanDollar() {
+	start := s.pos
+	s.pos++
+	if s.pos >= len(s.input) {
+		return
+	}
+
+	switch s.input[s.pos] {
+	case '{':
+		s.pos++
+		for s.pos < len(s.input) {
+			switch s.input[s.pos] {
+			case '}':
+				s.pos++
+				return
+			case '\\':
+				s.pos += 2
+			case '\'':
+				s.scanSingleQuoted()
+			case '"':
+				s.scanDoubleQuoted()
+			case '$':
+				s.scanDollar()
+			default:
+				s.pos++
+			}
+		}
+		s.errorf(start, "unterminated parameter expansion")
+	case '(':
+		s.pos++
+		s.scanSubshell(start)
+	case '\'':
+		// ANSI-C quoting
+		s.scanSingleQuoted()
+	}
+}
+
+// scanSubshell scans tokens up to the closing parenthesis of a command
+// substitution or subshell.
+func (s *shellScanner) scanSubshell(start int) {
+	depth := 1
+	for s.err == nil {
+		token := s.next()
+		switch {
+		case token.typ == shellEOF:
+			s.errorf(start, "unterminated command substitution")
+			return
+		case token.typ == shellOperator && token.val == "(":
+			depth++
+		case token.typ == shellOperator && token.val == ")":
+			depth--
+			if depth == 0 {
+				return
+			}
+		}
+	}
+}
+
+// unquoteWord removes quotes and backslash escapes from a shell word. Any
+// expansions like $pkgver are kept as is.
+func unquoteWord(word string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(word); i++ {
+		switch c := word[i]; c {
+		case '\\':
+			if i+1 < len(word) {
+				i++
+				if word[i] != '\n' {
+					b.WriteByte(word[i])
+				}
+			}
+		case '\'':
+			end := strings.IndexByte(word[i+1:], '\'')
+			if end < 0 {
+				end = len(word) - i - 1
+			}
+			b.WriteString(word[i+1 : i+1+end])
+			i += end + 1
+		case '"':
+			for i++; i < len(word) && word[i] != '"'; i++ {
+				if word[i] == '\\' && i+1 < len(word) && strings.IndexByte("$`\"\\\n", word[i+1]) >= 0 {
+					i++
+					if word[i] == '\n' {
+						continue
+					}
+				}
+				b.WriteByte(word[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String()
+}
+
+// quoteWord quotes value such that bash reads it back unchanged. Values made
+// up of safe characters only are left unquoted.
+func quoteWord(value string) string {
+	if value != "" && strings.Trim(value, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789._+-:/@%,=") == "" {
+		return value
+	}
+	return "'" + strings.Replace(value, "'", `'\''`, -1) + "'"
+}
+
+// quoteWordAs quotes value using quote (' or ") if possible.
+func quoteWordAs(value string, quote byte) string {
+	switch quote {
+	case '\'':
+		return "'" + strings.Replace(value, "'", `'\''`, -1) + "'"
+	case '"':
+		r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`")
+		return `"` + r.Replace(value) + `"`
+	}
+	return quoteWord(value)
+}
+
+type stmtKind int
+
+const (
+	stmtCommand stmtKind = iota
+	stmtAssignment
+	stmtFunction
+	stmtComment
+)
+
+// statement is a top level statement of a PKGBUILD.
+type statement struct {
+	kind     stmtKind
+	name     string // variable or function name
+	append   bool   // assignment using +=
+	array    bool   // array assignment
+	value    span   // scalar value, array contents or function body
+	elements []span // words of an array assignment
+	span
+}
+
+// shellParser splits the tokens of a shellScanner into top level
+// statements.
+type shellParser struct {
+	scanner *shellScanner
+	peeked  *shellToken
+}
+
+func (p *shellParser) next() shellToken {
+	if p.peeked != nil {
+		t := *p.peeked
+		p.peeked = nil
+		return t
+	}
+	return p.scanner.next()
+}
+
+func (p *shellParser) peek() shellToken {
+	if p.peeked == nil {
+		t := p.scanner.next()
+		p.peeked = &t
+	}
+	return *p.peeked
+}
+
+// parseShell splits the bash input into top level statements.
+func parseShell(input string) ([]statement, error) {
+	p := &shellParser{scanner: &shellScanner{input: input}}
+	var stmts []statement
+
+	for {
+		token := p.next()
+		switch token.typ {
+		case shellEOF:
+			return stmts, p.scanner.err
+		case shellNewline:
+			continue
+		case shellComment:
+			stmts = append(stmts, statement{kind: stmtComment, value: token.span, span: token.span})
+		case shellOperator:
+			if token.val == ";" {
+				continue
+			}
+			stmts = append(stmts, p.parseCommand(token))
+		case shellWord:
+			if stmt, ok := p.parseAssignment(token); ok {
+				stmts = append(stmts, stmt)
+			} else if stmt, ok := p.parseFunction(token); ok {
+				stmts = append(stmts, stmt)
+			} else {
+				stmts = append(stmts, p.parseCommand(token))
+			}
+		}
+
+		if p.scanner.err != nil {
+			return nil, p.scanner.err
+		}
+	}
+}
+
+// parseAssignment parses a name=value or name=(...) assignment starting with
+// token.
+func (p *shellParser) parseAssignment(token shellToken) (statement, bool) {
+	eq := strings.IndexByte(token.val, '=')
+	if eq <= 0 {
+		return statement{}, false
+	}
+
+	name := token.val[:eq]
+	stmt := statement{kind: stmtAssignment, name: name, span: token.span}
+	if strings.HasSuffix(name, "+") {
+		stmt.name = name[:len(name)-1]
+		stmt.append = true
+	}
+
+	if !isShellName(stmt.name) {
+		return statement{}, false
+	}
+
+	stmt.value = span{token.start + eq + 1, token.end}
+
+	next := p.peek()
+	if eq+1 == len(token.val) && next.typ == shellOperator && next.val == "(" && next.start == token.end {
+		p.next()
+		stmt.array = true
+		stmt.value.start = next.end
+		for {
+			t := p.next()
+			switch {
+			case t.typ == shellWord:
+				stmt.elements = append(stmt.elements, t.span)
+			case t.typ == shellOperator && t.val == ")":
+				stmt.value.end = t.start
+				stmt.end = t.end
+				return stmt, true
+			case t.typ == shellEOF:
+				p.scanner.errorf(next.start, "unterminated array")
+				return stmt, true
+			case t.typ == shellOperator:
+				p.scanner.errorf(t.start, "unexpected %q in array", t.val)
+				return stmt, true
+			}
+		}
+	}
+
+	return stmt, true
+}
+
+// parseFunction parses a function definition: name() { ... } or
+// function name { ... }.
+func (p *shellParser) parseFunction(token shellToken) (statement, bool) {
+	stmt := statement{kind: stmtFunction, span: token.span}
+
+	if token.val == "function" {
+		name := p.peek()
+		if name.typ != shellWord {
+			return statement{}, false
+		}
+		p.next()
+		stmt.name = name.val
+	} else {
+		// function names of split packages may contain any pkgname
+		// character e.g. package_lib32-foo
+		if !validPkgname(token.val) {
+			return statement{}, false
+		}
+		stmt.name = token.val
+	}
+
+	if t := p.peek(); t.typ == shellOperator && t.val == "(" {
+		p.next()
+		if t := p.next(); t.typ != shellOperator || t.val != ")" {
+			p.scanner.errorf(t.start, "expected ) in function definition of %s", stmt.name)
+			return stmt, true
+		}
+	} else if token.val != "function" {
+		return statement{}, false
+	}
+
+	// skip newlines before the body
+	t := p.next()
+	for t.typ == shellNewline {
+		t = p.next()
+	}
+
+	if t.typ != shellWord || t.val != "{" {
+		p.scanner.errorf(t.start, "expected { in function definition of %s", stmt.name)
+		return stmt, true
+	}
+
+	stmt.value.start = t.end
+	end := p.skipCompound(t)
+	stmt.value.end = end.start
+	stmt.end = end.end
+	return stmt, true
+}
+
+// compoundEnds maps the keywords starting a compound command to the keyword
+// ending it.
+var compoundEnds = map[string]string{
+	"{":      "}",
+	"if":     "fi",
+	"case":   "esac",
+	"for":    "done",
+	"while":  "done",
+	"until":  "done",
+	"select": "done",
+}
+
+// skipCompound skips tokens up to the end of the compound command started by
+// the keyword token start and returns the token ending it.
+func (p *shellParser) skipCompound(start shellToken) shellToken {
+	ends := []string{compoundEnds[start.val]}
+	commandStart := true
+
+	for {
+		t := p.next()
+		switch t.typ {
+		case shellEOF:
+			p.scanner.errorf(start.start, "missing %s for %s", ends[0], start.val)
+			return t
+		case shellNewline:
+			commandStart = true
+			continue
+		case shellOperator:
+			commandStart = t.val != ")" || len(ends) > 0 && ends[len(ends)-1] == "esac"
+			continue
+		case shellWord:
+			if !commandStart {
+				continue
+			}
+
+			if end, ok := compoundEnds[t.val]; ok {
+				ends = append(ends, end)
+			} else if t.val == ends[len(ends)-1] {
+				ends = ends[:len(ends)-1]
+				if len(ends) == 0 {
+					return t
+				}
+			}
+
+			switch t.val {
+			case "then", "do", "else", "{", "!", "time":
+				// keywords followed by a command
+				commandStart = true
+			default:
+				commandStart = false
+			}
+		}
+	}
+}
+
+// parseCommand parses any other command up to the end of the line.
+func (p *shellParser) parseCommand(token shellToken) statement {
+	stmt := statement{kind: stmtCommand, span: token.span}
+
+	if _, ok := compoundEnds[token.val]; ok && token.typ == shellWord {
+		stmt.end = p.skipCompound(token).end
+	} else if token.typ == shellOperator && token.val == "(" {
+		p.scanner.scanSubshell(token.start)
+		stmt.end = p.scanner.pos
+	}
+
+	for {
+		t := p.peek()
+		if t.typ == shellEOF || t.typ == shellNewline || t.typ == shellComment {
+			break
+		}
+		p.next()
+		stmt.end = t.end
+
+		if t.typ == shellOperator && t.val == "(" {
+			// subshell or arithmetic command
+			p.scanner.scanSubshell(t.start)
+			stmt.end = p.scanner.pos
+		}
+	}
+
+	stmt.value = stmt.span
+	return stmt
+}
+
+// isShellName reports whether name is a valid bash variable name.
+func isShellName(name string) bool {
+	if name == "" || isDigit(rune(name[0])) {
+		return false
+	}
+
+	for _, c := range name {
+		if c != '_' && !('a' <= c && c <= 'z') && !('A' <= c && c <= 'Z') && !('0' <= c && c <= '9') {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,220 @@
+package pkgbuild
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	pgperrors "golang.org/x/crypto/openpgp/errors"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+// signatureExtensions lists the file extensions makepkg treats as detached
+// signatures.
+var signatureExtensions = []string{".sig", ".asc", ".sign"}
+
+// SignatureStatus is the outcome of verifying a source signature.
+type SignatureStatus int
+
+// Signature statuses
+const (
+	SignaturePassed     SignatureStatus = iota // made by a key in validpgpkeys
+	SignatureFailed                            // the signature is invalid
+	SignatureUnknownKey                        // the key is not in the keyring
+	SignatureUntrusted                         // the key is not in validpgpkeys
+	SignatureNotFound                          // the signature or signed file does not exist
+)
+
+func (s SignatureStatus) String() string {
+	switch s {
+	case SignaturePassed:
+		return "Passed"
+	case SignatureFailed:
+		return "FAILED"
+	case SignatureUnknownKey:
+		return "UNKNOWN PUBLIC KEY"
+	case SignatureUntrusted:
+		return "NOT IN VALIDPGPKEYS"
+	case SignatureNotFound:
+		return "NOT FOUND"
+	}
+	return fmt.Sprintf("SignatureStatus(%d)", int(s))
+}
+
+// SignatureResult is the result of verifying a single source signature.
+type SignatureResult struct {
+	Source   string // the signature source e.g. "foo.tar.gz.sig"
+	Arch     string
+	FileName string // the signed file e.g. "foo.tar.gz"
+	Key      string // fingerprint of the signing key, or its key ID if unknown
+	Status   SignatureStatus
+}
+
+// VerifySignatures verifies the detached signatures (.sig, .asc or .sign)
+// among the sources of p against the public keys read from keyrings, like
+// makepkg --verifysource. The signatures and signed files are looked up in
+// sourcesDir, local ones in the directory given with WithStartDir if any.
+// The keyrings may be armored or binary, a signature only passes if it was
+// made by a key listed in validpgpkeys. No gpg keyring is needed.
+func (p *PKGBUILD) VerifySignatures(sourcesDir string, keyrings []io.Reader, opts ...SourceOption) ([]SignatureResult, error) {
+	config := newSourceConfig(opts)
+
+	var keyring openpgp.EntityList
+	for _, r := range keyrings {
+		entities, err := readKeyRing(r)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read keyring: %s", err.Error())
+		}
+		keyring = append(keyring, entities...)
+	}
+
+	trusted := make(map[string]bool, len(p.Validpgpkeys))
+	for _, key := range p.Validpgpkeys {
+		trusted[normalizeFingerprint(key)] = true
+	}
+
+	var results []SignatureResult
+	verify := func(sources []string, arch string) error {
+		for _, source := range sources {
+			name := Source(source).FileName()
+			ext := filepath.Ext(name)
+			if !contains(signatureExtensions, ext) {
+				continue
+			}
+
+			result := SignatureResult{
+				Source:   source,
+				Arch:     arch,
+				FileName: strings.TrimSuffix(name, ext),
+			}
+			sigPath := config.sourcePath(sourcesDir, Source(source))
+			path := filepath.Join(filepath.Dir(sigPath), result.FileName)
+			for _, s := range p.allSources() {
+				if Source(s).FileName() == result.FileName {
+					path = config.sourcePath(sourcesDir, Source(s))
+					break
+				}
+			}
+
+			err := verifySignature(&result, keyring, sigPath, path)
+			if err != nil {
+				return err
+			}
+			if result.Status == SignaturePassed && !trusted[result.Key] {
+				result.Status = SignatureUntrusted
+			}
+			results = append(results, result)
+		}
+		return nil
+	}
+
+	if err := verify(p.archIndependent().Source, ""); err != nil {
+		return nil, err
+	}
+	for _, arch := range p.Arch {
+		if a, ok := p.ArchSpecific[arch]; ok {
+			if err := verify(a.Source, arch); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// verifySignature checks the detached signature at sigPath of the file at
+// path and records the outcome in result.
+func verifySignature(result *SignatureResult, keyring openpgp.EntityList, sigPath, path string) error {
+	signature, err := readSignature(sigPath)
+	if os.IsNotExist(err) {
+		result.Status = SignatureNotFound
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	signed, err := os.Open(path)
+	if os.IsNotExist(err) {
+		result.Status = SignatureNotFound
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer signed.Close()
+
+	signer, err := openpgp.CheckDetachedSignature(keyring, signed, bytes.NewReader(signature))
+	switch {
+	case err == nil:
+		result.Key = fmt.Sprintf("%X", signer.PrimaryKey.Fingerprint)
+		result.Status = SignaturePassed
+	case err == pgperrors.ErrUnknownIssuer:
+		result.Key = signatureIssuer(signature)
+		result.Status = SignatureUnknownKey
+	default:
+		result.Status = SignatureFailed
+	}
+
+	return nil
+}
+
+// readSignature reads the signature at path, removing the armor if any.
+func readSignature(path string) ([]byte, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.HasPrefix(bytes.TrimSpace(content), []byte("-----BEGIN")) {
+		return content, nil
+	}
+
+	block, err := armor.Decode(bytes.NewReader(content))
+	if err != nil {
+		// let the signature check fail
+		return content, nil
+	}
+	return ioutil.ReadAll(block.Body)
+}
+
+// signatureIssuer returns the key ID of the issuer of signature, if known.
+func signatureIssuer(signature []byte) string {
+	p, err := packet.Read(bytes.NewReader(signature))
+	if err != nil {
+		return ""
+	}
+
+	switch sig := p.(type) {
+	case *packet.Signature:
+		if sig.IssuerKeyId != nil {
+			return fmt.Sprintf("%016X", *sig.IssuerKeyId)
+		}
+	case *packet.SignatureV3:
+		return fmt.Sprintf("%016X", sig.IssuerKeyId)
+	}
+	return ""
+}
+
+// readKeyRing reads an armored or binary keyring from r.
+func readKeyRing(r io.Reader) (openpgp.EntityList, error) {
+	br := bufio.NewReader(r)
+	prefix, _ := br.Peek(len("-----BEGIN"))
+	if string(prefix) == "-----BEGIN" {
+		return openpgp.ReadArmoredKeyRing(br)
+	}
+	return openpgp.ReadKeyRing(br)
+}
+
+// normalizeFingerprint returns the fingerprint key in upper case without
+// spaces, the way VerifySignatures reports it.
+func normalizeFingerprint(key string) string {
+	return strings.ToUpper(strings.Replace(key, " ", "", -1))
+}
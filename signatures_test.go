@@ -0,0 +1,121 @@
+package pkgbuild
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+func TestVerifySignatures(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopkgbuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := &packet.Config{RSABits: 1024}
+	trusted, err := openpgp.NewEntity("trusted", "", "trusted@example.org", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := openpgp.NewEntity("other", "", "other@example.org", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unknown, err := openpgp.NewEntity("unknown", "", "unknown@example.org", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	write := func(name string, content []byte) {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sign := func(name string, signer *openpgp.Entity, armored bool) {
+		content, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var sig bytes.Buffer
+		ext := ".sig"
+		if armored {
+			ext = ".asc"
+			err = openpgp.ArmoredDetachSign(&sig, signer, bytes.NewReader(content), config)
+		} else {
+			err = openpgp.DetachSign(&sig, signer, bytes.NewReader(content), config)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		write(name+ext, sig.Bytes())
+	}
+
+	for _, name := range []string{"passed", "armored", "failed", "untrusted", "unknown"} {
+		write(name+".tar.gz", []byte(name))
+	}
+	sign("passed.tar.gz", trusted, false)
+	sign("armored.tar.gz", trusted, true)
+	sign("failed.tar.gz", trusted, false)
+	write("failed.tar.gz", []byte("modified"))
+	sign("untrusted.tar.gz", other, false)
+	sign("unknown.tar.gz", unknown, false)
+
+	var keyring, otherKeyring bytes.Buffer
+	if err := trusted.Serialize(&keyring); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Serialize(&otherKeyring); err != nil {
+		t.Fatal(err)
+	}
+
+	fingerprint := fmt.Sprintf("%X", trusted.PrimaryKey.Fingerprint)
+	pkg := &PKGBUILD{
+		Source: []string{
+			"https://example.org/passed.tar.gz",
+			"https://example.org/passed.tar.gz.sig",
+			"armored.tar.gz.asc",
+			"failed.tar.gz.sig",
+			"untrusted.tar.gz.sig",
+			"unknown.tar.gz.sig",
+			"missing.tar.gz.sig",
+		},
+		Validpgpkeys: []string{fingerprint},
+	}
+
+	results, err := pkg.VerifySignatures(dir, []io.Reader{&keyring, &otherKeyring})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []struct {
+		fileName string
+		key      string
+		status   SignatureStatus
+	}{
+		{"passed.tar.gz", fingerprint, SignaturePassed},
+		{"armored.tar.gz", fingerprint, SignaturePassed},
+		{"failed.tar.gz", "", SignatureFailed},
+		{"untrusted.tar.gz", fmt.Sprintf("%X", other.PrimaryKey.Fingerprint), SignatureUntrusted},
+		{"unknown.tar.gz", fmt.Sprintf("%016X", unknown.PrimaryKey.KeyId), SignatureUnknownKey},
+		{"missing.tar.gz", "", SignatureNotFound},
+	}
+
+	if len(results) != len(expected) {
+		t.Fatalf("expected %d results, got %d: %+v", len(expected), len(results), results)
+	}
+	for i, result := range results {
+		e := expected[i]
+		if result.FileName != e.fileName || result.Key != e.key || result.Status != e.status {
+			t.Errorf("expected %s %s %s, got %s %s %s", e.fileName, e.key, e.status, result.FileName, result.Key, result.Status)
+		}
+	}
+}
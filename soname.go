@@ -0,0 +1,154 @@
+package pkgbuild
+
+import (
+	"sort"
+	"strings"
+)
+
+// Soname is a shared library provided or depended on by a built package,
+// either in the "libfoo.so=1-64" form of makepkg or in the "lib:libfoo.so.1"
+// form of library prefixes.
+type Soname struct {
+	Name    string // e.g. "libfoo.so"
+	Version string // major version e.g. "1"
+	Arch    string // e.g. "64", empty for library prefixes
+	Prefix  string // e.g. "lib", empty for the makepkg form
+}
+
+// ParseSoname parses the provides or depends entry s, ok is false if it's
+// not a soname.
+func ParseSoname(s string) (soname Soname, ok bool) {
+	if i := strings.Index(s, ":"); i > 0 && !strings.Contains(s, "=") {
+		name := s[i+1:]
+		j := strings.Index(name, ".so.")
+		if j < 0 {
+			return Soname{}, false
+		}
+		return Soname{Name: name[:j+3], Version: name[j+4:], Prefix: s[:i]}, true
+	}
+
+	i := strings.Index(s, ".so=")
+	if i < 0 {
+		return Soname{}, false
+	}
+	soname = Soname{Name: s[:i+3], Version: s[i+4:]}
+	if j := strings.LastIndex(soname.Version, "-"); j >= 0 {
+		soname.Version, soname.Arch = soname.Version[:j], soname.Version[j+1:]
+	}
+	return soname, true
+}
+
+// String returns the soname in the form it was parsed from.
+func (s Soname) String() string {
+	if s.Prefix != "" {
+		return s.Prefix + ":" + s.Name + "." + s.Version
+	}
+	if s.Arch != "" {
+		return s.Name + "=" + s.Version + "-" + s.Arch
+	}
+	return s.Name + "=" + s.Version
+}
+
+// sameLibrary returns true if s and s2 are versions of the same library.
+func (s Soname) sameLibrary(s2 Soname) bool {
+	return s.Name == s2.Name && s.Arch == s2.Arch && s.Prefix == s2.Prefix
+}
+
+// SonameBump is a soname no longer provided by a package.
+type SonameBump struct {
+	Pkgname    string
+	Old        Soname
+	New        *Soname  // nil if the library was dropped
+	Dependents []string // packages depending on Old, to be rebuilt
+}
+
+// DetectSonameBumps compares the sonames provided by the packages of old
+// and new, e.g. the packages of a repository before and after an update,
+// and returns the sonames no longer provided by any package, ordered by
+// package and soname. The dependents of a bump are the packages depending
+// on the old soname, taking the new version of packages in both sets.
+func DetectSonameBumps(old, new []*PKGINFO) []SonameBump {
+	provided := make(map[string]bool)
+	for _, info := range new {
+		for _, provide := range info.Provides {
+			if soname, ok := ParseSoname(provide); ok {
+				provided[soname.String()] = true
+			}
+		}
+	}
+
+	current := make(map[string]*PKGINFO)
+	for _, info := range old {
+		current[info.Pkgname] = info
+	}
+	newByName := make(map[string]*PKGINFO)
+	for _, info := range new {
+		current[info.Pkgname] = info
+		newByName[info.Pkgname] = info
+	}
+
+	var bumps []SonameBump
+	for _, info := range old {
+		updated, ok := newByName[info.Pkgname]
+		if !ok {
+			continue
+		}
+
+		for _, provide := range info.Provides {
+			soname, ok := ParseSoname(provide)
+			if !ok || provided[soname.String()] {
+				continue
+			}
+
+			bump := SonameBump{Pkgname: info.Pkgname, Old: soname}
+			for _, provide := range updated.Provides {
+				if s, ok := ParseSoname(provide); ok && s.sameLibrary(soname) {
+					bump.New = &s
+					break
+				}
+			}
+			bump.Dependents = dependents(current, soname)
+			bumps = append(bumps, bump)
+		}
+	}
+
+	sort.Slice(bumps, func(i, j int) bool {
+		if bumps[i].Pkgname != bumps[j].Pkgname {
+			return bumps[i].Pkgname < bumps[j].Pkgname
+		}
+		return bumps[i].Old.String() < bumps[j].Old.String()
+	})
+	return bumps
+}
+
+// dependents returns the sorted names of the packages depending on soname.
+func dependents(packages map[string]*PKGINFO, soname Soname) []string {
+	var names []string
+	for name, info := range packages {
+		for _, depend := range info.Depends {
+			if s, ok := ParseSoname(depend); ok && s == soname {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RebuildList returns the sorted names of the packages to rebuild for the
+// bumps.
+func RebuildList(bumps []SonameBump) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, bump := range bumps {
+		for _, name := range bump.Dependents {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
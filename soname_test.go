@@ -0,0 +1,70 @@
+package pkgbuild
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSoname(t *testing.T) {
+	for s, expected := range map[string]*Soname{
+		"libfoo.so=1-64":   {Name: "libfoo.so", Version: "1", Arch: "64"},
+		"libbar.so=2.1-32": {Name: "libbar.so", Version: "2.1", Arch: "32"},
+		"libbaz.so=3":      {Name: "libbaz.so", Version: "3"},
+		"lib:libfoo.so.1":  {Name: "libfoo.so", Version: "1", Prefix: "lib"},
+		"foo=1.0":          nil,
+		"lib:libfoo.a":     nil,
+		"libfoo.so":        nil,
+	} {
+		soname, ok := ParseSoname(s)
+		if expected == nil {
+			if ok {
+				t.Errorf("%s: expected no soname, got %+v", s, soname)
+			}
+			continue
+		}
+		if !ok || soname != *expected {
+			t.Errorf("%s: expected %+v, got %+v", s, *expected, soname)
+		}
+		if soname.String() != s {
+			t.Errorf("%s: expected String %s, got %s", s, s, soname.String())
+		}
+	}
+}
+
+func TestDetectSonameBumps(t *testing.T) {
+	old := []*PKGINFO{
+		{Pkgname: "libfoo", Provides: []string{"libfoo.so=1-64", "libfoo-extra.so=1-64", "libfoo-gone.so=0-64"}},
+		{Pkgname: "bar", Depends: []string{"libfoo", "libfoo.so=1-64"}},
+		{Pkgname: "baz", Depends: []string{"libfoo.so=1-64", "libfoo-gone.so=0-64"}},
+		{Pkgname: "qux", Depends: []string{"libfoo-extra.so=1-64", "libfoo.so"}},
+		{Pkgname: "libmoved", Provides: []string{"libmoved.so=1-64"}},
+	}
+	new := []*PKGINFO{
+		{Pkgname: "libfoo", Provides: []string{"libfoo.so=2-64", "libfoo-extra.so=1-64", "libmoved.so=1-64"}},
+		// already rebuilt
+		{Pkgname: "baz", Depends: []string{"libfoo.so=2-64"}},
+		{Pkgname: "libmoved"},
+	}
+
+	bumps := DetectSonameBumps(old, new)
+	expected := []SonameBump{
+		{
+			Pkgname:    "libfoo",
+			Old:        Soname{Name: "libfoo-gone.so", Version: "0", Arch: "64"},
+			Dependents: nil,
+		},
+		{
+			Pkgname:    "libfoo",
+			Old:        Soname{Name: "libfoo.so", Version: "1", Arch: "64"},
+			New:        &Soname{Name: "libfoo.so", Version: "2", Arch: "64"},
+			Dependents: []string{"bar"},
+		},
+	}
+	if !reflect.DeepEqual(bumps, expected) {
+		t.Errorf("expected %+v, got %+v", expected, bumps)
+	}
+
+	if rebuild := RebuildList(bumps); !reflect.DeepEqual(rebuild, []string{"bar"}) {
+		t.Errorf("expected rebuild list [bar], got %q", rebuild)
+	}
+}
@@ -0,0 +1,213 @@
+package pkgbuild
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// VCS protocols supported by makepkg
+var vcsProtocols = map[string]bool{
+	"bzr":    true,
+	"fossil": true,
+	"git":    true,
+	"hg":     true,
+	"svn":    true,
+}
+
+// Source is a single source entry of a PKGBUILD e.g.
+// "foo-1.0.tar.gz::https://example.org/v1.0.tar.gz".
+type Source string
+
+// Protocol returns the protocol of the source entry the same way as makepkg:
+// "local" for local files, "https", "git" etc. for remote sources.
+func (s Source) Protocol() string {
+	source := string(s)
+
+	if strings.Contains(source, "://") {
+		// strip leading filename
+		proto := source
+		if i := strings.Index(proto, "::"); i >= 0 {
+			proto = proto[i+2:]
+		}
+		proto = proto[:strings.Index(proto, "://")]
+		// strip proto+uri://
+		if i := strings.Index(proto, "+"); i >= 0 {
+			proto = proto[:i]
+		}
+		return proto
+	}
+
+	if strings.Contains(source, "lp:") {
+		proto := source
+		if i := strings.Index(proto, "::"); i >= 0 {
+			proto = proto[i+2:]
+		}
+		if i := strings.Index(proto, "+lp:"); i >= 0 {
+			proto = proto[:i]
+		}
+		return proto
+	}
+
+	return "local"
+}
+
+// FileName returns the local filename makepkg uses for the source entry: the
+// name given before "::" if any, the last component of the URL, or the repo
+// directory name for VCS sources.
+func (s Source) FileName() string {
+	source := string(s)
+
+	// if a filename is specified, use it
+	filename := source
+	if i := strings.Index(filename, "::"); i >= 0 {
+		filename = filename[:i]
+	}
+
+	proto := s.Protocol()
+	if !vcsProtocols[proto] {
+		// if it is just an URL, we only keep the last component
+		return filename[strings.LastIndex(filename, "/")+1:]
+	}
+
+	if i := strings.Index(filename, "#"); i >= 0 {
+		filename = filename[:i]
+	}
+	if i := strings.Index(filename, "?"); i >= 0 {
+		filename = filename[:i]
+	}
+	filename = strings.TrimSuffix(filename, "/")
+	filename = filename[strings.LastIndex(filename, "/")+1:]
+
+	switch proto {
+	case "bzr":
+		if i := strings.Index(filename, "lp:"); i >= 0 {
+			filename = filename[i+3:]
+		}
+	case "fossil":
+		filename += ".fossil"
+	case "git":
+		if i := strings.Index(filename, ".git"); i >= 0 {
+			filename = filename[:i]
+		}
+	}
+
+	return filename
+}
+
+// URL returns the URL of a remote source entry without the leading filename,
+// e.g. "git+https://example.org/foo.git". It's empty for local files.
+func (s Source) URL() string {
+	if s.Protocol() == "local" {
+		return ""
+	}
+
+	source := string(s)
+	if i := strings.Index(source, "::"); i >= 0 {
+		source = source[i+2:]
+	}
+	return source
+}
+
+// SourceOption configures the operations on the source files of a PKGBUILD
+// like DownloadSources, Verify and GenerateChecksums.
+type SourceOption func(*sourceConfig)
+
+// sourceConfig holds the settings of a single source operation.
+type sourceConfig struct {
+	startDir string // directory of the local sources
+	progress ProgressFunc
+}
+
+// newSourceConfig returns the source config resulting from applying opts.
+func newSourceConfig(opts []SourceOption) *sourceConfig {
+	config := &sourceConfig{}
+	for _, opt := range opts {
+		opt(config)
+	}
+	return config
+}
+
+// WithStartDir makes the source operations look up local sources, the
+// entries without a URL like patches, in dir, the directory of the
+// PKGBUILD, instead of the sources directory passed to them. Like with
+// makepkg's SRCDEST, the sources directory then only needs to hold the
+// downloaded sources.
+func WithStartDir(dir string) SourceOption {
+	return func(c *sourceConfig) {
+		c.startDir = dir
+	}
+}
+
+// WithDownloadProgress makes DownloadSources report the sources downloaded
+// to fn.
+func WithDownloadProgress(fn ProgressFunc) SourceOption {
+	return func(c *sourceConfig) {
+		c.progress = fn
+	}
+}
+
+// sourcePath returns the path of the file of source in sourcesDir, or in
+// the directory given with WithStartDir for local sources.
+func (c *sourceConfig) sourcePath(sourcesDir string, source Source) string {
+	if c.startDir != "" && source.Protocol() == "local" {
+		sourcesDir = c.startDir
+	}
+	return filepath.Join(sourcesDir, source.FileName())
+}
+
+// allSources returns the sources of p including the arch specific ones.
+func (p *PKGBUILD) allSources() []string {
+	return p.allArchs().Source
+}
+
+// ResolveNoextract resolves the noextract entries against the local filenames
+// of the sources. It returns the sources matched by noextract and the
+// noextract entries that don't match any source.
+func (p *PKGBUILD) ResolveNoextract() ([]string, []string) {
+	var sources, unmatched []string
+
+	for _, entry := range p.Noextract {
+		found := false
+		for _, source := range p.allSources() {
+			if Source(source).FileName() == entry {
+				sources = append(sources, source)
+				found = true
+			}
+		}
+
+		if !found {
+			unmatched = append(unmatched, entry)
+		}
+	}
+
+	return sources, unmatched
+}
+
+// IsExtracted reports whether makepkg will try to extract source. VCS sources
+// and sources listed in noextract are never extracted.
+func (p *PKGBUILD) IsExtracted(source string) bool {
+	if vcsProtocols[Source(source).Protocol()] {
+		return false
+	}
+
+	filename := Source(source).FileName()
+	for _, entry := range p.Noextract {
+		if entry == filename {
+			return false
+		}
+	}
+
+	return true
+}
+
+// SourcesFor returns the sources makepkg uses when building for arch, i.e.
+// source followed by source_<arch>.
+func (p *PKGBUILD) SourcesFor(arch string) []string {
+	sources := append([]string{}, p.archIndependent().Source...)
+
+	if a, ok := p.ArchSpecific[arch]; ok {
+		sources = append(sources, a.Source...)
+	}
+
+	return sources
+}
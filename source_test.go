@@ -0,0 +1,117 @@
+package pkgbuild
+
+import "testing"
+
+func TestSourceFileName(t *testing.T) {
+	sources := map[string]string{
+		"foo.patch":                                           "foo.patch",
+		"https://example.org/foo-1.0.tar.gz":                  "foo-1.0.tar.gz",
+		"https://example.org/foo-1.0.tar.gz.sig":              "foo-1.0.tar.gz.sig",
+		"bar-1.0.tar.gz::https://example.org/v1.0.tar.gz":     "bar-1.0.tar.gz",
+		"git+https://github.com/mikkeloscar/gopkgbuild.git":   "gopkgbuild",
+		"git+https://github.com/foo/bar.git#tag=v1.0":         "bar",
+		"git+https://github.com/foo/bar.git#branch=fix/slash": "bar",
+		"git://git.example.org/foo/":                          "foo",
+		"baz::git+https://github.com/foo/bar.git":             "baz",
+		"hg+https://hg.example.org/foo#revision=10":           "foo",
+		"svn+https://svn.example.org/trunk/foo?p=1":           "foo",
+		"fossil+https://fossil.example.org/foo":               "foo.fossil",
+		"bzr+lp:foo":                                          "foo",
+	}
+
+	for source, filename := range sources {
+		if f := Source(source).FileName(); f != filename {
+			t.Errorf("filename of %s should be %s, got %s", source, filename, f)
+		}
+	}
+}
+
+func TestSourceProtocol(t *testing.T) {
+	sources := map[string]string{
+		"foo.patch":                               "local",
+		"https://example.org/foo-1.0.tar.gz":      "https",
+		"foo.tar.gz::ftp://example.org/foo.tgz":   "ftp",
+		"git+https://github.com/foo/bar.git":      "git",
+		"bar::git+https://github.com/foo/bar.git": "git",
+		"bzr+lp:foo":                              "bzr",
+	}
+
+	for source, proto := range sources {
+		if p := Source(source).Protocol(); p != proto {
+			t.Errorf("protocol of %s should be %s, got %s", source, proto, p)
+		}
+	}
+}
+
+func TestSourceURL(t *testing.T) {
+	sources := map[string]string{
+		"foo.patch":                               "",
+		"https://example.org/foo-1.0.tar.gz":      "https://example.org/foo-1.0.tar.gz",
+		"foo.tar.gz::ftp://example.org/foo.tgz":   "ftp://example.org/foo.tgz",
+		"bar::git+https://github.com/foo/bar.git": "git+https://github.com/foo/bar.git",
+	}
+
+	for source, url := range sources {
+		if u := Source(source).URL(); u != url {
+			t.Errorf("URL of %s should be %s, got %s", source, url, u)
+		}
+	}
+}
+
+func TestResolveNoextract(t *testing.T) {
+	pkg := &PKGBUILD{
+		Arch: []string{"x86_64"},
+		Source: []string{
+			"foo-1.0.tar.gz::https://example.org/v1.0.tar.gz",
+			"https://example.org/bar.zip",
+			"git+https://github.com/foo/baz.git",
+		},
+		Noextract: []string{"foo-1.0.tar.gz", "qux.tar.gz"},
+		ArchSpecific: map[string]*ArchSpecific{
+			"x86_64": {Source: []string{"https://example.org/qux.tar.gz"}},
+		},
+	}
+
+	sources, unmatched := pkg.ResolveNoextract()
+	if len(sources) != 2 || sources[0] != pkg.Source[0] || sources[1] != "https://example.org/qux.tar.gz" {
+		t.Errorf("unexpected noextract sources: %v", sources)
+	}
+
+	if len(unmatched) != 0 {
+		t.Errorf("unexpected unmatched noextract entries: %v", unmatched)
+	}
+
+	pkg.Noextract = append(pkg.Noextract, "missing.tar.gz")
+	_, unmatched = pkg.ResolveNoextract()
+	if len(unmatched) != 1 || unmatched[0] != "missing.tar.gz" {
+		t.Errorf("unexpected unmatched noextract entries: %v", unmatched)
+	}
+
+	if pkg.IsExtracted(pkg.Source[0]) {
+		t.Errorf("%s should not be extracted", pkg.Source[0])
+	}
+
+	if !pkg.IsExtracted(pkg.Source[1]) {
+		t.Errorf("%s should be extracted", pkg.Source[1])
+	}
+
+	if pkg.IsExtracted(pkg.Source[2]) {
+		t.Errorf("%s should not be extracted", pkg.Source[2])
+	}
+}
+
+func TestSourcesFor(t *testing.T) {
+	pkg, err := ParseSRCINFO("./test_pkgbuilds/SRCINFO_teamviewer")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sources := pkg.SourcesFor("x86_64")
+	if len(sources) != 2 || sources[0] != "https://download.teamviewer.com/download/version_12x/teamviewer_12.0.90041_amd64.deb" {
+		t.Errorf("unexpected sources for x86_64: %v", sources)
+	}
+
+	if sources = pkg.SourcesFor("armv7h"); len(sources) != 0 {
+		t.Errorf("unexpected sources for armv7h: %v", sources)
+	}
+}
@@ -0,0 +1,342 @@
+package pkgbuild
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// srcinfoFields lists the variables of the pkgbase section of a .SRCINFO
+// file in the order makepkg --printsrcinfo writes them.
+var srcinfoFields = []string{
+	"pkgdesc",
+	"pkgver",
+	"pkgrel",
+	"epoch",
+	"url",
+	"install",
+	"changelog",
+	"arch",
+	"groups",
+	"license",
+	"checkdepends",
+	"makedepends",
+	"depends",
+	"optdepends",
+	"provides",
+	"conflicts",
+	"replaces",
+	"noextract",
+	"options",
+	"backup",
+	"source",
+	"validpgpkeys",
+	"md5sums",
+	"sha1sums",
+	"sha224sums",
+	"sha256sums",
+	"sha384sums",
+	"sha512sums",
+	"b2sums",
+}
+
+// srcinfoArchFields lists the arch specific variables of a .SRCINFO file in
+// the order makepkg --printsrcinfo writes them.
+var srcinfoArchFields = []string{
+	"source",
+	"provides",
+	"conflicts",
+	"depends",
+	"replaces",
+	"optdepends",
+	"makedepends",
+	"checkdepends",
+	"md5sums",
+	"sha1sums",
+	"sha224sums",
+	"sha256sums",
+	"sha384sums",
+	"sha512sums",
+	"b2sums",
+}
+
+// Vars returns the values of the variables of p by name, the way they are
+// written to a .SRCINFO file, e.g. "depends_x86_64" or "pkgver". Unset
+// variables are left out.
+func (p *PKGBUILD) Vars() map[string][]string {
+	vars := make(map[string][]string)
+
+	for name, values := range p.diffArrays() {
+		if len(values) == 0 {
+			continue
+		}
+		if isDependencyVariable(name) {
+			values = splitConstraints(values)
+		}
+		vars[name] = values
+	}
+
+	for name, value := range p.diffScalars() {
+		if value != "" && !(name == "epoch" && value == "0") {
+			vars[name] = []string{value}
+		}
+	}
+
+	return vars
+}
+
+// isDependencyVariable reports whether name is depends, makedepends or
+// checkdepends, or an arch specific variant of them.
+func isDependencyVariable(name string) bool {
+	switch strings.SplitN(name, "_", 2)[0] {
+	case "depends", "makedepends", "checkdepends":
+		return true
+	}
+	return false
+}
+
+// splitConstraints splits dependencies with several constraints, like
+// "bar>=1.0 bar<2.0", into a value per constraint, the way they are
+// written in a .SRCINFO file.
+func splitConstraints(deps []string) []string {
+	values := make([]string, 0, len(deps))
+	for _, dep := range deps {
+		values = append(values, strings.Fields(dep)...)
+	}
+	return values
+}
+
+// WriteSRCINFO writes p to w in the .SRCINFO format of makepkg
+// --printsrcinfo. Values of unknown variables are written after the known
+// ones.
+func (p *PKGBUILD) WriteSRCINFO(w io.Writer) error {
+	return writeSRCINFO(w, p.Comments, p.Vars(), nil)
+}
+
+// writeSRCINFO writes the variables vars to w in the .SRCINFO format, with
+// comments at the top. The overrides of a package, by pkgname, are written
+// in its section, an empty override as a blank value.
+func writeSRCINFO(w io.Writer, comments []string, vars map[string][]string, overrides map[string]map[string][]string) error {
+	var b strings.Builder
+
+	for _, comment := range comments {
+		b.WriteString("# " + comment + "\n")
+	}
+
+	pkgnames := vars["pkgname"]
+	pkgbase := vars["pkgbase"]
+	if len(pkgbase) == 0 {
+		pkgbase = pkgnames
+	}
+	if len(pkgbase) > 0 {
+		b.WriteString("pkgbase = " + pkgbase[0] + "\n")
+	}
+
+	written := map[string]bool{"pkgbase": true, "pkgname": true}
+	write := func(name string) {
+		for _, value := range vars[name] {
+			b.WriteString("\t" + name + " = " + value + "\n")
+		}
+		written[name] = true
+	}
+
+	for _, name := range srcinfoFields {
+		write(name)
+	}
+
+	for _, arch := range vars["arch"] {
+		// there's no support for e.g. depends_any
+		if arch == "any" {
+			continue
+		}
+		for _, name := range srcinfoArchFields {
+			write(name + "_" + arch)
+		}
+	}
+
+	var extra []string
+	for name := range vars {
+		if !written[name] {
+			extra = append(extra, name)
+		}
+	}
+	sort.Strings(extra)
+	for _, name := range extra {
+		write(name)
+	}
+
+	// sections are closed by a blank line
+	b.WriteString("\n")
+	for _, pkgname := range pkgnames {
+		b.WriteString("pkgname = " + pkgname + "\n")
+
+		override := overrides[pkgname]
+		writeOverride := func(name string) {
+			values, ok := override[name]
+			if !ok {
+				return
+			}
+			if len(values) == 0 {
+				b.WriteString("\t" + name + " = \n")
+			}
+			for _, value := range values {
+				b.WriteString("\t" + name + " = " + value + "\n")
+			}
+		}
+
+		for _, name := range srcinfoPackageFields {
+			writeOverride(name)
+		}
+
+		archs := vars["arch"]
+		if values, ok := override["arch"]; ok {
+			archs = values
+		}
+		for _, arch := range archs {
+			if arch == "any" {
+				continue
+			}
+			for _, name := range srcinfoArchFields {
+				if contains(srcinfoPackageFields, name) {
+					writeOverride(name + "_" + arch)
+				}
+			}
+		}
+
+		b.WriteString("\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// srcinfoPackageFields lists the variables a package_*() function can
+// override in the order makepkg --printsrcinfo writes them.
+var srcinfoPackageFields = []string{
+	"pkgdesc",
+	"url",
+	"install",
+	"changelog",
+	"arch",
+	"groups",
+	"license",
+	"checkdepends",
+	"depends",
+	"optdepends",
+	"provides",
+	"conflicts",
+	"replaces",
+	"options",
+	"backup",
+}
+
+// isPackageVariable reports whether name can be overridden in a
+// package_*() function, including the arch specific variants like
+// depends_x86_64.
+func isPackageVariable(name string) bool {
+	if contains(srcinfoPackageFields, name) {
+		return true
+	}
+	i := strings.IndexByte(name, '_')
+	return i > 0 && archFields[name[:i]] && contains(srcinfoPackageFields, name[:i])
+}
+
+// srcinfoVars evaluates the variables of f written to a .SRCINFO file and
+// the overrides assigned in the package_<pkgname>() functions, by pkgname.
+func (f *File) srcinfoVars(config *parseConfig) (map[string][]string, map[string]map[string][]string, error) {
+	env, err := config.mingwEnvVars()
+	if err != nil {
+		return nil, nil, err
+	}
+	e := newExpander(f, env)
+
+	vars := map[string][]string{
+		"pkgbase": e.Values("pkgbase"),
+		"pkgname": e.Values("pkgname"),
+	}
+	for _, name := range srcinfoFields {
+		vars[name] = e.Values(name)
+	}
+	for _, arch := range e.Values("arch") {
+		for _, name := range srcinfoArchFields {
+			vars[name+"_"+arch] = e.Values(name + "_" + arch)
+		}
+	}
+	if config.dialect == DialectMSYS2 {
+		for _, a := range f.Assignments() {
+			if isMSYS2Variable(a.Name) {
+				vars[a.Name] = e.Values(a.Name)
+			}
+		}
+	}
+
+	overrides := make(map[string]map[string][]string)
+	for _, pkgname := range vars["pkgname"] {
+		fn := f.Function("package_" + pkgname)
+		if fn == nil {
+			continue
+		}
+
+		body, err := ParseAST([]byte(fn.Body))
+		if err != nil {
+			return nil, nil, fmt.Errorf("package_%s: %w", pkgname, err)
+		}
+
+		// evaluated on top of the global variables, like in makepkg
+		pe := newExpander(body, e.Vars)
+		for _, a := range body.Assignments() {
+			if !isPackageVariable(a.Name) {
+				continue
+			}
+			if overrides[pkgname] == nil {
+				overrides[pkgname] = make(map[string][]string)
+			}
+			overrides[pkgname][a.Name] = append([]string{}, pe.Values(a.Name)...)
+		}
+	}
+
+	return vars, overrides, nil
+}
+
+// WriteSRCINFO evaluates f like File.PKGBUILD and writes the result to w in
+// the .SRCINFO format of makepkg --printsrcinfo, including the overrides of
+// the package_*() functions in the sections of their packages.
+func (f *File) WriteSRCINFO(w io.Writer, opts ...ParseOption) error {
+	vars, overrides, err := f.srcinfoVars(newParseConfig(opts))
+	if err != nil {
+		return err
+	}
+	return writeSRCINFO(w, nil, vars, overrides)
+}
+
+// PKGBUILD evaluates the variables assigned at the top level of f with an
+// Expander, without running bash, and parses the result like a .SRCINFO
+// file. Variables which aren't part of a .SRCINFO file, like _commit, are
+// left out. Overrides assigned in package_<pkgname>() functions are
+// evaluated too and merged into the result like the package sections of a
+// .SRCINFO file. With DialectMSYS2 the mingw_arch and msys2_* variables are
+// evaluated too and kept in Extra.
+func (f *File) PKGBUILD(opts ...ParseOption) (*PKGBUILD, error) {
+	config := newParseConfig(opts)
+
+	vars, overrides, err := f.srcinfoVars(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	if err := writeSRCINFO(&b, nil, vars, overrides); err != nil {
+		return nil, err
+	}
+
+	config.zeroCopy = true
+	p, err := parsePKGBUILD(b.Bytes(), config)
+	if err != nil {
+		return nil, err
+	}
+
+	p.Maintainers, p.Contributors = f.Header()
+	return p, nil
+}
@@ -0,0 +1,149 @@
+package pkgbuild
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestWriteSRCINFO(t *testing.T) {
+	for _, name := range []string{"sudo", "openssh", "teamviewer"} {
+		content, err := ioutil.ReadFile("./test_pkgbuilds/SRCINFO_" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		pkg, err := ParseSRCINFOContent(content)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var b bytes.Buffer
+		if err := pkg.WriteSRCINFO(&b); err != nil {
+			t.Fatal(err)
+		}
+
+		if b.String() != string(content) {
+			t.Errorf(".SRCINFO for %s was not written as generated by makepkg:\n%s", name, b.String())
+		}
+	}
+}
+
+func TestFilePKGBUILD(t *testing.T) {
+	// PKGBUILDs matching their .SRCINFO
+	for _, name := range []string{"sudo", "openssh", "pulseaudio-ctl", "shaman-git", "teamviewer", "pip2pkgbuild"} {
+		file, err := ParseASTFile("./test_pkgbuilds/PKGBUILD_" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		pkg, err := file.PKGBUILD()
+		if err != nil {
+			t.Errorf("PKGBUILD for %s did not evaluate: %s", name, err)
+			continue
+		}
+
+		expected, err := ParseSRCINFO("./test_pkgbuilds/SRCINFO_" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if changes := Diff(expected, pkg); len(changes) > 0 {
+			t.Errorf("PKGBUILD for %s evaluated differently from its .SRCINFO:\n%s", name, changes)
+		}
+	}
+}
+
+func TestFileWriteSRCINFO(t *testing.T) {
+	file, err := ParseAST([]byte(`pkgbase=foo
+pkgname=(foo foo-docs)
+pkgver=1.0
+pkgrel=1
+pkgdesc="foo tool"
+arch=(x86_64)
+license=(MIT)
+depends=(glibc)
+
+package_foo() {
+  depends+=(bar)
+  depends_x86_64=(lib32-glibc)
+  make install
+}
+
+package_foo-docs() {
+  pkgdesc="$pkgdesc (documentation)"
+  arch=(any)
+  depends=()
+  _unrelated=1
+}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if err := file.WriteSRCINFO(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `pkgbase = foo
+	pkgdesc = foo tool
+	pkgver = 1.0
+	pkgrel = 1
+	arch = x86_64
+	license = MIT
+	depends = glibc
+
+pkgname = foo
+	depends = glibc
+	depends = bar
+	depends_x86_64 = lib32-glibc
+
+pkgname = foo-docs
+	pkgdesc = foo tool (documentation)
+	arch = any
+	depends = 
+
+`
+	if b.String() != expected {
+		t.Errorf("unexpected .SRCINFO:\n%s", b.String())
+	}
+
+	pkg, err := file.PKGBUILD()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pkg.Depends) != 3 || pkg.Depends[1].Name != "bar" || pkg.ArchSpecific["x86_64"] == nil {
+		t.Errorf("expected the overrides to be evaluated, got %v", pkg.Depends)
+	}
+}
+
+func TestVars(t *testing.T) {
+	pkg, err := ParseSRCINFO("./test_pkgbuilds/SRCINFO_teamviewer")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vars := pkg.Vars()
+	if len(vars["pkgver"]) != 1 || vars["pkgver"][0] != string(pkg.Pkgver) {
+		t.Errorf("expected pkgver %s, got %v", pkg.Pkgver, vars["pkgver"])
+	}
+
+	if len(vars["depends_x86_64"]) != len(pkg.ArchSpecific["x86_64"].Depends) {
+		t.Errorf("unexpected depends_x86_64: %v", vars["depends_x86_64"])
+	}
+
+	if _, ok := vars["epoch"]; ok {
+		t.Errorf("expected unset epoch to be left out")
+	}
+
+	// a value per constraint, like in the .SRCINFO
+	pkg, err = ParseSRCINFOContent([]byte("pkgbase = foo\n\tpkgver = 1.0\n\tpkgrel = 1\n\tarch = any\n\tdepends = bar>=1.0\n\tdepends = bar<2.0\n\npkgname = foo\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if depends := pkg.Vars()["depends"]; !reflect.DeepEqual(depends, []string{"bar>=1.0", "bar<2.0"}) {
+		t.Errorf("unexpected depends: %v", depends)
+	}
+}
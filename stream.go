@@ -0,0 +1,145 @@
+package pkgbuild
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// SRCINFOStream reads the .SRCINFO documents of a stream of concatenated
+// documents, like metadata dumps, one at a time:
+//
+//	stream := NewSRCINFOStream(r)
+//	for stream.Next() {
+//		pkgb := stream.PKGBUILD()
+//		...
+//	}
+//	if err := stream.Err(); err != nil {
+//		...
+//	}
+//
+// A document starts at each pkgbase line, comments right before it belong
+// to it.
+type SRCINFOStream struct {
+	r      *bufio.Reader
+	config *parseConfig
+	line   int
+
+	next      []byte // pkgbase line starting the next document
+	nextStart int    // line of next
+	pending   []byte // comments and empty lines following the last value
+
+	pkgb *PKGBUILD
+	err  error
+}
+
+// NewSRCINFOStream returns a SRCINFOStream reading from r, parsing the
+// documents with opts. The limits of WithLimits apply to each document.
+func NewSRCINFOStream(r io.Reader, opts ...ParseOption) *SRCINFOStream {
+	return &SRCINFOStream{r: bufio.NewReader(r), config: newParseConfig(opts)}
+}
+
+// isPkgbaseLine returns true if line is a pkgbase variable.
+func isPkgbaseLine(line []byte) bool {
+	line = bytes.TrimSpace(line)
+	if !bytes.HasPrefix(line, []byte("pkgbase")) {
+		return false
+	}
+	return bytes.HasPrefix(bytes.TrimSpace(line[len("pkgbase"):]), []byte("="))
+}
+
+// Next parses the next document, which is then returned by PKGBUILD. It
+// returns false at the end of the stream or on the first error, which is
+// returned by Err.
+func (s *SRCINFOStream) Next() bool {
+	if s.err != nil {
+		return false
+	}
+
+	var doc []byte
+	start := s.nextStart
+	if s.next != nil {
+		doc = append(doc, s.pending...)
+		doc = append(doc, s.next...)
+		s.pending, s.next = nil, nil
+	}
+
+	for {
+		line, err := s.r.ReadBytes('\n')
+		if len(line) > 0 {
+			s.line++
+			trimmed := bytes.TrimSpace(line)
+
+			switch {
+			case len(trimmed) == 0 || trimmed[0] == '#':
+				s.pending = append(s.pending, line...)
+			case isPkgbaseLine(line) && doc != nil:
+				s.next, s.nextStart = line, s.line
+				return s.parse(doc, start)
+			default:
+				if doc == nil {
+					start = s.line
+				}
+				doc = append(doc, s.pending...)
+				doc = append(doc, line...)
+				s.pending = nil
+
+				// don't buffer more than the document would be parsed
+				if err := s.config.checkInputSize(len(doc)); err != nil {
+					s.err = fmt.Errorf("document at line %d: %w", start, err)
+					return false
+				}
+			}
+		}
+
+		if err == io.EOF {
+			if doc == nil {
+				return false
+			}
+			doc = append(doc, s.pending...)
+			s.pending = nil
+			return s.parse(doc, start)
+		}
+		if err != nil {
+			s.err = err
+			return false
+		}
+	}
+}
+
+// parse parses the document doc starting at line start.
+func (s *SRCINFOStream) parse(doc []byte, start int) bool {
+	// doc isn't used by anything else
+	config := *s.config
+	config.zeroCopy = true
+
+	s.pkgb, s.err = parsePKGBUILD(doc, &config)
+	if s.err != nil {
+		s.pkgb = nil
+		s.err = fmt.Errorf("document at line %d: %w", start, s.err)
+		return false
+	}
+	return true
+}
+
+// PKGBUILD returns the document parsed by the last call to Next.
+func (s *SRCINFOStream) PKGBUILD() *PKGBUILD {
+	return s.pkgb
+}
+
+// Err returns the first error reading or parsing the stream.
+func (s *SRCINFOStream) Err() error {
+	return s.err
+}
+
+// ParseSRCINFOStream parses all .SRCINFO documents of the stream of
+// concatenated documents r, see SRCINFOStream.
+func ParseSRCINFOStream(r io.Reader, opts ...ParseOption) ([]*PKGBUILD, error) {
+	var pkgbs []*PKGBUILD
+	stream := NewSRCINFOStream(r, opts...)
+	for stream.Next() {
+		pkgbs = append(pkgbs, stream.PKGBUILD())
+	}
+	return pkgbs, stream.Err()
+}
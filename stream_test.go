@@ -0,0 +1,136 @@
+package pkgbuild
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestParseSRCINFOStream(t *testing.T) {
+	names := []string{"sudo", "linux", "openssh", "glibc"}
+
+	var stream bytes.Buffer
+	for i, name := range names {
+		content, err := ioutil.ReadFile("./test_pkgbuilds/SRCINFO_" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i%2 == 1 {
+			stream.WriteString("\n\n")
+		}
+		stream.Write(content)
+	}
+
+	pkgbs, err := ParseSRCINFOStream(&stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pkgbs) != len(names) {
+		t.Fatalf("expected %d documents, got %d", len(names), len(pkgbs))
+	}
+	for i, name := range names {
+		expected, err := ParseSRCINFO("./test_pkgbuilds/SRCINFO_" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if changes := Diff(expected, pkgbs[i]); len(changes) > 0 {
+			t.Errorf("document %d differs from SRCINFO_%s:\n%s", i, name, changes)
+		}
+	}
+}
+
+func TestSRCINFOStreamComments(t *testing.T) {
+	content := `# Generated for foo
+pkgbase = foo
+	pkgver = 1.0
+	pkgrel = 1
+	arch = any
+
+pkgname = foo
+# Generated for bar
+pkgbase = bar
+	pkgver = 2.0
+	pkgrel = 1
+	arch = any
+
+pkgname = bar
+`
+
+	stream := NewSRCINFOStream(strings.NewReader(content))
+	var pkgbases []string
+	for stream.Next() {
+		p := stream.PKGBUILD()
+		pkgbases = append(pkgbases, p.Pkgbase)
+		if len(p.Pkgnames) != 1 || p.Pkgnames[0] != p.Pkgbase {
+			t.Errorf("expected pkgname %s, got %v", p.Pkgbase, p.Pkgnames)
+		}
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(pkgbases, " ") != "foo bar" {
+		t.Errorf("expected documents foo bar, got %v", pkgbases)
+	}
+
+	if stream.Next() {
+		t.Error("expected no documents after the end of the stream")
+	}
+}
+
+func TestSRCINFOStreamError(t *testing.T) {
+	content := `pkgbase = foo
+	pkgver = 1.0
+	pkgrel = 1
+	arch = any
+
+pkgname = foo
+
+pkgbase = bar
+	pkgver = 2.0
+	pkgrel = 1
+	arch = any
+	unknown
+
+pkgname = bar
+
+pkgbase = baz
+	pkgver = 3.0
+	pkgrel = 1
+	arch = any
+
+pkgname = baz
+`
+
+	pkgbs, err := ParseSRCINFOStream(strings.NewReader(content))
+	if err == nil {
+		t.Fatal("expected error for the invalid document")
+	}
+	if !strings.HasPrefix(err.Error(), "document at line 8: ") {
+		t.Errorf("expected error to point at the document, got: %s", err)
+	}
+	if len(pkgbs) != 1 || pkgbs[0].Pkgbase != "foo" {
+		t.Errorf("expected the documents before the error, got %d", len(pkgbs))
+	}
+}
+
+func TestSRCINFOStreamLimits(t *testing.T) {
+	doc := "pkgbase = foo\n\tpkgver = 1.0\n\tpkgrel = 1\n\tarch = any\n\npkgname = foo\n"
+	content := strings.Repeat(doc, 10)
+
+	// the limits apply to each document, not the stream
+	pkgbs, err := ParseSRCINFOStream(strings.NewReader(content), WithLimits(Limits{MaxInputSize: len(doc)}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pkgbs) != 10 {
+		t.Errorf("expected 10 documents, got %d", len(pkgbs))
+	}
+
+	_, err = ParseSRCINFOStream(strings.NewReader(content), WithLimits(Limits{MaxInputSize: len(doc) - 1}))
+	if !errors.Is(err, ErrInputTooLarge) {
+		t.Errorf("expected %q, got %v", ErrInputTooLarge, err)
+	}
+}
@@ -0,0 +1,168 @@
+package pkgbuild
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+)
+
+// ReadSyncDB reads the packages of the sync database at dbPath, e.g.
+// custom.db of a repository created with repo-add, sorted by name. Explicit
+// has no meaning for packages of a sync database.
+func ReadSyncDB(dbPath string) ([]*InstalledPackage, error) {
+	var packages []*InstalledPackage
+	err := walkArchive(dbPath, func(name string, r io.Reader) (bool, error) {
+		if path.Base(name) != "desc" {
+			return true, nil
+		}
+
+		content, err := ioutil.ReadAll(r)
+		if err != nil {
+			return false, err
+		}
+		pkg, err := parseDesc(content)
+		if err != nil {
+			return false, fmt.Errorf("%s: %s", path.Dir(name), err)
+		}
+		packages = append(packages, pkg)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(packages, func(i, j int) bool {
+		return packages[i].Name < packages[j].Name
+	})
+	return packages, nil
+}
+
+// DriftStatus is how the version of a package in a database differs from
+// its PKGBUILD.
+type DriftStatus int
+
+// Drift statuses
+const (
+	// DriftOutdated is a package older than its PKGBUILD, to be built.
+	DriftOutdated DriftStatus = iota
+	// DriftNewer is a package newer than its PKGBUILD, e.g. if the
+	// PKGBUILD was reverted.
+	DriftNewer
+	// DriftMissing is a package of a PKGBUILD not in the database.
+	DriftMissing
+	// DriftOrphaned is a package in the database without PKGBUILD.
+	DriftOrphaned
+)
+
+func (s DriftStatus) String() string {
+	switch s {
+	case DriftOutdated:
+		return "outdated"
+	case DriftNewer:
+		return "newer"
+	case DriftMissing:
+		return "missing"
+	case DriftOrphaned:
+		return "orphaned"
+	}
+	return "unknown"
+}
+
+// Drift is a package whose version in a database differs from its PKGBUILD.
+type Drift struct {
+	Pkgname         string
+	Pkgbase         string
+	DBVersion       string // empty if missing
+	PKGBUILDVersion string // empty if orphaned
+	Status          DriftStatus
+}
+
+func (d Drift) String() string {
+	switch d.Status {
+	case DriftMissing:
+		return fmt.Sprintf("%s: %s missing in the database", d.Pkgname, d.PKGBUILDVersion)
+	case DriftOrphaned:
+		return fmt.Sprintf("%s: %s has no PKGBUILD", d.Pkgname, d.DBVersion)
+	}
+	return fmt.Sprintf("%s: database %s, PKGBUILD %s (%s)", d.Pkgname, d.DBVersion, d.PKGBUILDVersion, d.Status)
+}
+
+// CompareSyncDB compares the versions of the packages in the database with
+// the PKGBUILDs they were built from, and returns the packages differing
+// sorted by name. Debug packages are compared with the PKGBUILD of their
+// pkgbase, but aren't missing if not in the database.
+func CompareSyncDB(packages []*InstalledPackage, pkgbs []*PKGBUILD) []Drift {
+	byName := make(map[string]*PKGBUILD)
+	for _, p := range pkgbs {
+		for _, name := range p.Pkgnames {
+			byName[name] = p
+		}
+		byName[p.pkgbase()+"-debug"] = p
+	}
+
+	inDB := make(map[string]bool)
+	var drifts []Drift
+	for _, pkg := range packages {
+		inDB[pkg.Name] = true
+
+		p, ok := byName[pkg.Name]
+		if !ok {
+			drifts = append(drifts, Drift{Pkgname: pkg.Name, Pkgbase: pkg.Base, DBVersion: pkg.Version, Status: DriftOrphaned})
+			continue
+		}
+
+		drift := Drift{Pkgname: pkg.Name, Pkgbase: p.pkgbase(), DBVersion: pkg.Version, PKGBUILDVersion: p.Version()}
+		switch cmp := VerCmp(pkg.Version, p.Version()); {
+		case cmp < 0:
+			drift.Status = DriftOutdated
+		case cmp > 0:
+			drift.Status = DriftNewer
+		default:
+			continue
+		}
+		drifts = append(drifts, drift)
+	}
+
+	for _, p := range pkgbs {
+		for _, name := range p.Pkgnames {
+			if !inDB[name] {
+				drifts = append(drifts, Drift{Pkgname: name, Pkgbase: p.pkgbase(), PKGBUILDVersion: p.Version(), Status: DriftMissing})
+			}
+		}
+	}
+
+	sort.Slice(drifts, func(i, j int) bool {
+		return drifts[i].Pkgname < drifts[j].Pkgname
+	})
+	return drifts
+}
+
+// SyncDBDrift compares the sync database at dbPath with the package
+// directories below dir, the directories containing a .SRCINFO or PKGBUILD
+// as found by a Watcher, parsed with opts. PKGBUILDs without .SRCINFO are
+// evaluated without running them, as by WithEmbeddedEvaluation. To have
+// makepkg parse them, use CompareSyncDB with a Watcher with Makepkg set.
+func SyncDBDrift(ctx context.Context, dbPath, dir string, opts ...ParseOption) ([]Drift, error) {
+	packages, err := ReadSyncDB(dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := NewWatcher(dir, opts...).Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pkgbs := make([]*PKGBUILD, 0, len(events))
+	for _, event := range events {
+		if event.Err != nil {
+			return nil, fmt.Errorf("%s: %w", event.File, event.Err)
+		}
+		pkgbs = append(pkgbs, event.New)
+	}
+
+	return CompareSyncDB(packages, pkgbs), nil
+}
@@ -0,0 +1,115 @@
+package pkgbuild
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func testDesc(name, base, version string) string {
+	return "%FILENAME%\n" + name + "-" + version + "-x86_64.pkg.tar.zst\n\n%NAME%\n" + name + "\n\n%BASE%\n" + base + "\n\n%VERSION%\n" + version + "\n\n%ARCH%\nx86_64\n"
+}
+
+func TestSyncDBDrift(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopkgbuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db := filepath.Join(dir, "custom.db.tar.gz")
+	writeTestPackage(t, db, map[string]string{
+		"foo-1.0-1/desc":       testDesc("foo", "foo", "1.0-1"),
+		"foo-debug-1.0-1/desc": testDesc("foo-debug", "foo", "1.0-1"),
+		"bar-2.0-1/desc":       testDesc("bar", "bar", "2.0-1"),
+		"bar-2.0-1/files":      "%FILES%\nusr/\n",
+		"baz-1:1.0-1/desc":     testDesc("baz", "baz", "1:1.0-1"),
+		"old-1.0-1/desc":       testDesc("old", "old", "1.0-1"),
+	}, true)
+
+	packages, err := ReadSyncDB(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packages) != 5 || packages[0].Name != "bar" {
+		t.Fatalf("unexpected packages: %+v", packages)
+	}
+
+	pkgbuilds := filepath.Join(dir, "pkgbuilds")
+	for name, srcinfo := range map[string]string{
+		"foo": "pkgbase = foo\n\tpkgver = 1.0\n\tpkgrel = 1\n\tarch = x86_64\n\npkgname = foo\n",
+		"bar": "pkgbase = bar\n\tpkgver = 2.1\n\tpkgrel = 1\n\tarch = x86_64\n\npkgname = bar\n\npkgname = bar-docs\n",
+		"baz": "pkgbase = baz\n\tpkgver = 1.1\n\tpkgrel = 1\n\tarch = x86_64\n\npkgname = baz\n",
+	} {
+		if err := os.MkdirAll(filepath.Join(pkgbuilds, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(pkgbuilds, name, ".SRCINFO"), []byte(srcinfo), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	drifts, err := SyncDBDrift(context.Background(), db, pkgbuilds)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Drift{
+		{Pkgname: "bar", Pkgbase: "bar", DBVersion: "2.0-1", PKGBUILDVersion: "2.1-1", Status: DriftOutdated},
+		{Pkgname: "bar-docs", Pkgbase: "bar", PKGBUILDVersion: "2.1-1", Status: DriftMissing},
+		{Pkgname: "baz", Pkgbase: "baz", DBVersion: "1:1.0-1", PKGBUILDVersion: "1.1-1", Status: DriftNewer},
+		{Pkgname: "old", Pkgbase: "old", DBVersion: "1.0-1", Status: DriftOrphaned},
+	}
+	if !reflect.DeepEqual(drifts, expected) {
+		t.Errorf("expected %+v, got %+v", expected, drifts)
+	}
+
+	for i, s := range []string{
+		"bar: database 2.0-1, PKGBUILD 2.1-1 (outdated)",
+		"bar-docs: 2.1-1 missing in the database",
+		"baz: database 1:1.0-1, PKGBUILD 1.1-1 (newer)",
+		"old: 1.0-1 has no PKGBUILD",
+	} {
+		if i < len(drifts) && drifts[i].String() != s {
+			t.Errorf("expected %q, got %q", s, drifts[i])
+		}
+	}
+}
+
+func TestSyncDBDriftPKGBUILD(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopkgbuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db := filepath.Join(dir, "custom.db.tar.gz")
+	writeTestPackage(t, db, map[string]string{
+		"foo-1.0-1/desc": testDesc("foo", "foo", "1.0-1"),
+	}, true)
+
+	marker := filepath.Join(dir, "executed")
+	pkgbuild := "pkgname=foo\npkgver=1.1\npkgrel=1\narch=(x86_64)\ntouch " + marker + "\n"
+	if err := os.MkdirAll(filepath.Join(dir, "pkgbuilds", "foo"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "pkgbuilds", "foo", "PKGBUILD"), []byte(pkgbuild), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	drifts, err := SyncDBDrift(context.Background(), db, filepath.Join(dir, "pkgbuilds"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(drifts) != 1 || drifts[0].Status != DriftOutdated || drifts[0].PKGBUILDVersion != "1.1-1" {
+		t.Errorf("expected foo to be outdated, got %+v", drifts)
+	}
+
+	// evaluated without running the PKGBUILD
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Error("expected the PKGBUILD not to be executed")
+	}
+}
@@ -0,0 +1,148 @@
+package pkgbuild
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+)
+
+// expacFields maps the expac style placeholders of a Template to the
+// values they render.
+var expacFields = map[byte]func(p *PKGBUILD) []string{
+	'n': func(p *PKGBUILD) []string { return p.Pkgnames },
+	'e': func(p *PKGBUILD) []string { return []string{p.pkgbase()} },
+	'v': func(p *PKGBUILD) []string { return []string{p.Version()} },
+	'd': func(p *PKGBUILD) []string { return []string{p.Pkgdesc} },
+	'u': func(p *PKGBUILD) []string { return []string{p.URL} },
+	'a': func(p *PKGBUILD) []string { return p.Arch },
+	'L': func(p *PKGBUILD) []string { return p.License },
+	'G': func(p *PKGBUILD) []string { return p.Groups },
+	'D': func(p *PKGBUILD) []string { return dependencyStrings(p.Depends) },
+	'E': func(p *PKGBUILD) []string { return dependencyNames(p.Depends) },
+	'M': func(p *PKGBUILD) []string { return dependencyStrings(p.Makedepends) },
+	'K': func(p *PKGBUILD) []string { return dependencyStrings(p.Checkdepends) },
+	'O': func(p *PKGBUILD) []string { return p.Optdepends },
+	'P': func(p *PKGBUILD) []string { return p.Provides },
+	'C': func(p *PKGBUILD) []string { return p.Conflicts },
+	'R': func(p *PKGBUILD) []string { return p.Replaces },
+	'B': func(p *PKGBUILD) []string { return p.Backup },
+	'S': func(p *PKGBUILD) []string { return p.Source },
+}
+
+// templateFuncs are the functions available in Go templates.
+var templateFuncs = template.FuncMap{
+	"join": strings.Join,
+	"deps": dependencyStrings,
+}
+
+// Template renders the metadata of PKGBUILDs, e.g. for custom columns in
+// reports. The format is either a Go template with the *PKGBUILD as data,
+// like "{{.Pkgbase}}-{{.Version}}", or an expac style format string like
+// "%n %v":
+//
+//	%n pkgname     %e pkgbase       %v full version  %d pkgdesc
+//	%u url         %a arch          %L license       %G groups
+//	%D depends     %E depends without versions       %M makedepends
+//	%K checkdepends                 %O optdepends    %P provides
+//	%C conflicts   %R replaces      %B backup        %S source
+//	%% a literal %
+//
+// Arrays are joined by ListSeparator. The escapes \n, \t and \\ are
+// supported in expac style formats. Go templates can use the functions join
+// (strings.Join) and deps, which returns dependencies as strings.
+type Template struct {
+	ListSeparator string // defaults to two spaces like expac
+
+	tmpl  *template.Template
+	parts []templatePart
+}
+
+// templatePart is a literal text or, if field is set, a placeholder of an
+// expac style format.
+type templatePart struct {
+	text  string
+	field byte
+}
+
+// NewTemplate parses format, which is treated as a Go template if it
+// contains "{{".
+func NewTemplate(format string) (*Template, error) {
+	t := &Template{ListSeparator: "  "}
+
+	if strings.Contains(format, "{{") {
+		tmpl, err := template.New("pkgbuild").Funcs(templateFuncs).Parse(format)
+		if err != nil {
+			return nil, err
+		}
+		t.tmpl = tmpl
+		return t, nil
+	}
+
+	var text strings.Builder
+	for i := 0; i < len(format); i++ {
+		c := format[i]
+		if c != '%' && c != '\\' || i+1 == len(format) {
+			text.WriteByte(c)
+			continue
+		}
+
+		i++
+		next := format[i]
+		switch {
+		case c == '\\' && next == 'n':
+			text.WriteByte('\n')
+		case c == '\\' && next == 't':
+			text.WriteByte('\t')
+		case c == '\\':
+			text.WriteByte(next)
+		case next == '%':
+			text.WriteByte('%')
+		case expacFields[next] != nil:
+			t.parts = append(t.parts, templatePart{text: text.String()}, templatePart{field: next})
+			text.Reset()
+		default:
+			return nil, fmt.Errorf("unknown placeholder: %%%c", next)
+		}
+	}
+	t.parts = append(t.parts, templatePart{text: text.String()})
+
+	return t, nil
+}
+
+// Execute writes the metadata of p rendered by t to w.
+func (t *Template) Execute(w io.Writer, p *PKGBUILD) error {
+	if t.tmpl != nil {
+		return t.tmpl.Execute(w, p)
+	}
+
+	for _, part := range t.parts {
+		text := part.text
+		if part.field != 0 {
+			text = strings.Join(expacFields[part.field](p), t.ListSeparator)
+		}
+		if _, err := io.WriteString(w, text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Render returns the metadata of p rendered by t.
+func (t *Template) Render(p *PKGBUILD) (string, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, p); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// dependencyNames returns the names of the dependencies.
+func dependencyNames(deps []*Dependency) []string {
+	names := make([]string, 0, len(deps))
+	for _, dep := range deps {
+		names = append(names, dep.Name)
+	}
+	return names
+}
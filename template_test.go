@@ -0,0 +1,55 @@
+package pkgbuild
+
+import "testing"
+
+func TestTemplate(t *testing.T) {
+	pkg, err := ParseSRCINFO("./test_pkgbuilds/SRCINFO_sudo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for format, expected := range map[string]string{
+		"%n %v":                            "sudo 1.8.11.p2-1",
+		`%e\t%D|%E 100%%`:                  "sudo\tglibc  pam  libldap|glibc  pam  libldap 100%",
+		"{{.Pkgbase}}-{{.Version}}":        "sudo-1.8.11.p2-1",
+		`{{join (deps .Depends) ","}}`:     "glibc,pam,libldap",
+		"{{range .Arch}}[{{.}}]{{end}} %n": "[i686][x86_64] %n",
+		"":                                 "",
+	} {
+		tmpl, err := NewTemplate(format)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		s, err := tmpl.Render(pkg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s != expected {
+			t.Errorf("expected %q to render %q, got %q", format, expected, s)
+		}
+	}
+
+	tmpl, err := NewTemplate("%a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl.ListSeparator = ","
+	if s, _ := tmpl.Render(pkg); s != "i686,x86_64" {
+		t.Errorf("expected i686,x86_64, got %q", s)
+	}
+
+	for _, format := range []string{"%x", "{{.Foo"} {
+		if _, err := NewTemplate(format); err == nil {
+			t.Errorf("expected error for %q", format)
+		}
+	}
+
+	tmpl, err = NewTemplate("{{.Unknown}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = tmpl.Render(pkg); err == nil {
+		t.Error("expected error for unknown field")
+	}
+}
@@ -0,0 +1,109 @@
+package pkgbuild
+
+import "fmt"
+
+// TokenType identifies the type of a Token.
+type TokenType int
+
+// Token types
+const (
+	TokenError    TokenType = iota // error, Value holds the error message
+	TokenEOF                       // end of input
+	TokenVariable                  // variable name e.g. "depends_x86_64"
+	TokenValue                     // value of the preceding variable
+	TokenEndSplit                  // blank line ending a pkgbase or pkgname section
+	TokenComment                   // comment line including the leading '#'
+)
+
+func (t TokenType) String() string {
+	switch t {
+	case TokenError:
+		return "Error"
+	case TokenEOF:
+		return "EOF"
+	case TokenVariable:
+		return "Variable"
+	case TokenValue:
+		return "Value"
+	case TokenEndSplit:
+		return "EndSplit"
+	case TokenComment:
+		return "Comment"
+	}
+	return fmt.Sprintf("TokenType(%d)", int(t))
+}
+
+// Token is a lexical token of .SRCINFO formatted input.
+type Token struct {
+	Type  TokenType
+	Pos   Position
+	Value string
+}
+
+func (t Token) String() string {
+	return fmt.Sprintf("%s %q", t.Type, t.Value)
+}
+
+// Scanner yields the tokens of .SRCINFO formatted input one at a time.
+type Scanner struct {
+	lexer *lexer
+	done  bool
+}
+
+// NewScanner returns a Scanner reading from input.
+func NewScanner(input string) *Scanner {
+	return &Scanner{lexer: lex([]byte(input), true)}
+}
+
+// Next returns the next token of the input. Once a TokenEOF or TokenError
+// has been returned all subsequent calls return TokenEOF.
+func (s *Scanner) Next() Token {
+	if s.done {
+		l := s.lexer
+		return Token{Type: TokenEOF, Pos: l.position(pos(len(l.input)), l.line)}
+	}
+
+	i := s.lexer.nextItem()
+	token := Token{
+		Pos:   s.lexer.position(i.pos, i.line),
+		Value: i.val,
+	}
+
+	switch i.typ {
+	case itemError:
+		token.Type = TokenError
+	case itemEOF:
+		token.Type = TokenEOF
+	case itemValue:
+		token.Type = TokenValue
+	case itemEndSplit:
+		token.Type = TokenEndSplit
+	case itemComment:
+		token.Type = TokenComment
+	default:
+		token.Type = TokenVariable
+	}
+
+	if token.Type == TokenEOF || token.Type == TokenError {
+		s.done = true
+	}
+
+	return token
+}
+
+// Tokenize returns all tokens of input up to, but not including, TokenEOF.
+func Tokenize(input string) ([]Token, error) {
+	var tokens []Token
+
+	s := NewScanner(input)
+	for {
+		token := s.Next()
+		switch token.Type {
+		case TokenEOF:
+			return tokens, nil
+		case TokenError:
+			return nil, &ParseError{Pos: token.Pos, Msg: token.Value}
+		}
+		tokens = append(tokens, token)
+	}
+}
@@ -0,0 +1,324 @@
+package pkgbuild
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// vcsSource is a parsed VCS source entry e.g.
+// "foo::git+https://example.org/foo.git#branch=dev".
+type vcsSource struct {
+	protocol string
+	url      string
+	dir      string // local checkout directory name
+	fragment string // e.g. "branch", "tag" or "commit"
+	ref      string
+}
+
+// parseVCSSource parses the VCS source entry the same way as makepkg.
+func parseVCSSource(source string) (vcsSource, bool) {
+	proto := Source(source).Protocol()
+	if !vcsProtocols[proto] {
+		return vcsSource{}, false
+	}
+
+	s := vcsSource{
+		protocol: proto,
+		dir:      Source(source).FileName(),
+	}
+
+	url := source
+	if i := strings.Index(url, "::"); i >= 0 {
+		url = url[i+2:]
+	}
+	url = strings.TrimPrefix(url, proto+"+")
+
+	if i := strings.Index(url, "#"); i >= 0 {
+		fragment := url[i+1:]
+		url = url[:i]
+
+		fragment = strings.SplitN(fragment, "?", 2)[0]
+		if i := strings.Index(fragment, "="); i >= 0 {
+			s.fragment = fragment[:i]
+			s.ref = fragment[i+1:]
+		}
+	}
+	s.url = strings.SplitN(url, "?", 2)[0]
+
+	return s, true
+}
+
+// FetchVCSSources clones the VCS sources of f into srcdir, or updates them if
+// they have been cloned before, and checks out the revision given by the
+// source fragment. Only git and hg sources are supported. Arch specific
+// sources are fetched for the architecture of the running system.
+func (f *File) FetchVCSSources(ctx context.Context, srcdir string) error {
+	e := NewExpander(f)
+	e.KeepUnresolved = true
+
+	sources := append(e.Values("source"), e.Values("source_"+carch())...)
+
+	for _, source := range sources {
+		s, ok := parseVCSSource(source)
+		if !ok {
+			continue
+		}
+
+		if err := s.fetch(ctx, srcdir); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fetch clones or updates the source in srcdir.
+func (s vcsSource) fetch(ctx context.Context, srcdir string) error {
+	dir := filepath.Join(srcdir, s.dir)
+	_, err := os.Stat(dir)
+	exists := err == nil
+
+	switch s.protocol {
+	case "git":
+		if exists {
+			err = run(ctx, srcdir, "git", "-C", dir, "fetch", "--tags", "--prune", "origin")
+		} else {
+			err = run(ctx, srcdir, "git", "clone", "--no-checkout", s.url, dir)
+		}
+		if err != nil {
+			return err
+		}
+
+		ref := "origin/HEAD"
+		switch s.fragment {
+		case "commit", "revision":
+			ref = s.ref
+		case "tag":
+			ref = "tags/" + s.ref
+		case "branch":
+			ref = "origin/" + s.ref
+		}
+		return run(ctx, srcdir, "git", "-C", dir, "checkout", "--quiet", "--force", "--detach", ref)
+	case "hg":
+		if exists {
+			err = run(ctx, srcdir, "hg", "pull", "--repository", dir)
+		} else {
+			err = run(ctx, srcdir, "hg", "clone", "--noupdate", s.url, dir)
+		}
+		if err != nil {
+			return err
+		}
+
+		ref := "default"
+		if s.ref != "" {
+			ref = s.ref
+		}
+		return run(ctx, srcdir, "hg", "update", "--clean", "--repository", dir, ref)
+	}
+
+	return fmt.Errorf("unsupported VCS protocol: %s", s.protocol)
+}
+
+// run runs the command name in dir.
+func run(ctx context.Context, dir, name string, args ...string) error {
+	var stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s %s failed: %s: %s", name, strings.Join(args, " "), err.Error(), strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
+
+// pkgverScript sources the PKGBUILD read from stdin and prints the output
+// of pkgver() run from $srcdir.
+const pkgverScript = `set -e
+cd "$srcdir"
+source /dev/stdin >/dev/null
+cd "$srcdir"
+pkgver
+`
+
+// EvalPkgver computes the version of a VCS package by running its pkgver()
+// function with bash, with srcdir as the source directory. The sources are
+// expected to be fetched already, see FetchVCSSources. If f has no pkgver()
+// function the pkgver variable is returned.
+//
+// The PKGBUILD is executed with a minimal environment through the sandbox
+// command template of WithSandbox, DefaultSandbox if none is given, with
+// {dir} replaced by srcdir. Only srcdir is set, not startdir, as the
+// sandbox doesn't make the directory of the PKGBUILD available. WithSandbox
+// with an empty template runs it as the current user without a sandbox,
+// which must only be used on trusted PKGBUILDs. ctx can be used to limit
+// the run time.
+func (f *File) EvalPkgver(ctx context.Context, srcdir string, opts ...ParseOption) (Version, error) {
+	if !f.HasFunction("pkgver") {
+		pkgver := NewExpander(f).Values("pkgver")
+		if len(pkgver) != 1 || !validPkgver(pkgver[0]) {
+			return "", fmt.Errorf("%w: %v", ErrInvalidPkgver, pkgver)
+		}
+		return Version(pkgver[0]), nil
+	}
+
+	srcdir, err := filepath.Abs(srcdir)
+	if err != nil {
+		return "", err
+	}
+
+	config := newParseConfig(opts)
+	sandbox := config.sandbox
+	if sandbox == nil {
+		sandbox = DefaultSandbox
+	}
+	command := sandboxCommand(sandbox, srcdir, "bash", "--noprofile", "--norc", "-c", pkgverScript, "pkgver")
+	config.debugf("running pkgver() with %s in %s", strings.Join(command, " "), srcdir)
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Dir = srcdir
+	cmd.Stdin = strings.NewReader(f.content)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.Env = []string{
+		"PATH=" + os.Getenv("PATH"),
+		"HOME=" + os.Getenv("HOME"),
+		"LANG=C",
+		"srcdir=" + srcdir,
+		"CARCH=" + carch(),
+	}
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("pkgver() failed: %s: %s", err.Error(), strings.TrimSpace(stderr.String()))
+	}
+
+	pkgver := strings.TrimSpace(stdout.String())
+	if !validPkgver(pkgver) {
+		return "", fmt.Errorf("pkgver() returned an %w: %q", ErrInvalidPkgver, pkgver)
+	}
+
+	return Version(pkgver), nil
+}
+
+// carch returns the makepkg CARCH of the running system.
+func carch() string {
+	return HostArch().String()
+}
+
+// VCSVersion holds the parts of a pkgver generated by the pkgver() function
+// of a VCS package.
+type VCSVersion struct {
+	Base     Version // release the revision is based on e.g. "1.2.3", if any
+	Revision int     // number of commits, -1 if unknown
+	Commit   string  // abbreviated commit hash, if any
+	Date     string  // date like "20230415", if any
+}
+
+var (
+	// e.g. r123.abc1234 or 1.2.3.r45.gdeadbee
+	vcsRevisionRegexp = regexp.MustCompile(`^(?:(.+)\.)?r([0-9]+)(?:\.g?([0-9a-f]{7,40}))?$`)
+	// e.g. 20230415 or 1.0.20230415.abc1234
+	vcsDateRegexp = regexp.MustCompile(`^(?:(.+)\.)?((?:19|20)[0-9]{2}(?:0[1-9]|1[0-2])(?:0[1-9]|[12][0-9]|3[01]))(?:\.g?([0-9a-f]{7,40}))?$`)
+)
+
+// ParseVCSVersion recognizes the pkgver styles commonly generated for VCS
+// packages, revision counts with an optional commit like r123.abc1234 or
+// 1.2.3.r45.gdeadbee, and dates like 20230415, and returns their parts.
+func ParseVCSVersion(v Version) (*VCSVersion, bool) {
+	if m := vcsRevisionRegexp.FindStringSubmatch(string(v)); m != nil {
+		revision, err := strconv.Atoi(m[2])
+		if err != nil {
+			return nil, false
+		}
+		return &VCSVersion{Base: Version(m[1]), Revision: revision, Commit: m[3]}, true
+	}
+
+	if m := vcsDateRegexp.FindStringSubmatch(string(v)); m != nil {
+		return &VCSVersion{Base: Version(m[1]), Revision: -1, Date: m[2], Commit: m[3]}, true
+	}
+
+	return nil, false
+}
+
+// SameSource reports whether v and v2 were most likely generated from the
+// same sources, i.e. whether a rebuild would change anything. Commits are
+// compared if both have one, otherwise the revision, date and base.
+func (v *VCSVersion) SameSource(v2 *VCSVersion) bool {
+	if v.Commit != "" && v2.Commit != "" {
+		n := min(len(v.Commit), len(v2.Commit))
+		return v.Commit[:n] == v2.Commit[:n]
+	}
+	return v.Base == v2.Base && v.Revision == v2.Revision && v.Date == v2.Date
+}
+
+// vcsSuffixes are the pkgname suffixes conventionally used for packages
+// built from VCS sources.
+var vcsSuffixes = []string{"-git", "-svn", "-hg", "-bzr", "-fossil"}
+
+// VCSBaseName returns the name of the package the VCS package name follows,
+// e.g. "foo" for "foo-git", and whether name has a VCS suffix at all. VCS
+// packages conventionally provide and conflict with the base name.
+func VCSBaseName(name string) (string, bool) {
+	for _, suffix := range vcsSuffixes {
+		if strings.HasSuffix(name, suffix) && len(name) > len(suffix) {
+			return strings.TrimSuffix(name, suffix), true
+		}
+	}
+	return name, false
+}
+
+// VCSSources returns the sources of p, including the architecture specific
+// ones, fetched with a VCS like git+https://example.org/foo.git.
+func (p *PKGBUILD) VCSSources() []string {
+	var sources []string
+	for _, s := range p.allSources() {
+		if vcsProtocols[Source(s).Protocol()] {
+			sources = append(sources, s)
+		}
+	}
+	return sources
+}
+
+// lintVCS checks that packages with a VCS suffix provide and conflict with
+// their base name.
+func lintVCS(p *PKGBUILD, add func(Severity, string, string, ...interface{})) {
+	all := p.allArchs()
+	provides, conflicts := all.Provides, all.Conflicts
+
+	for _, name := range p.Pkgnames {
+		base, ok := VCSBaseName(name)
+		if !ok {
+			continue
+		}
+
+		if !dependsOn(provides, base) {
+			add(SeverityWarning, "provides", "%s doesn't provide %s", name, base)
+		}
+		if !dependsOn(conflicts, base) {
+			add(SeverityWarning, "conflicts", "%s doesn't conflict with %s", name, base)
+		}
+	}
+}
+
+// dependsOn reports whether any of the dependency strings deps, like
+// "foo=1.0", is on name.
+func dependsOn(deps []string, name string) bool {
+	for _, dep := range deps {
+		parsed, err := parseDependency(dep, nil)
+		if err == nil && len(parsed) > 0 && parsed[0].Name == name {
+			return true
+		}
+	}
+	return false
+}
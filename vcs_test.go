@@ -0,0 +1,239 @@
+package pkgbuild
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseVCSSource(t *testing.T) {
+	sources := map[string]vcsSource{
+		"git+https://example.org/foo.git":                    {"git", "https://example.org/foo.git", "foo", "", ""},
+		"bar::git+https://example.org/foo.git#branch=dev":    {"git", "https://example.org/foo.git", "bar", "branch", "dev"},
+		"git://example.org/foo.git#tag=v1.0?signed":          {"git", "git://example.org/foo.git", "foo", "tag", "v1.0"},
+		"hg+https://example.org/foo#revision=abc":            {"hg", "https://example.org/foo", "foo", "revision", "abc"},
+		"svn+https://example.org/svn/foo/trunk#revision=123": {"svn", "https://example.org/svn/foo/trunk", "trunk", "revision", "123"},
+	}
+
+	for source, expected := range sources {
+		s, ok := parseVCSSource(source)
+		if !ok || s != expected {
+			t.Errorf("%s should parse as %+v, got %+v", source, expected, s)
+		}
+	}
+
+	if _, ok := parseVCSSource("https://example.org/foo.tar.gz"); ok {
+		t.Error("non VCS source should not parse")
+	}
+}
+
+func TestParseVCSVersion(t *testing.T) {
+	versions := map[Version]VCSVersion{
+		"r123.abc1234":          {"", 123, "abc1234", ""},
+		"1.2.3.r45.gdeadbee":    {"1.2.3", 45, "deadbee", ""},
+		"r1234":                 {"", 1234, "", ""},
+		"0.1.r12":               {"0.1", 12, "", ""},
+		"20230415":              {"", -1, "", "20230415"},
+		"1.0.20230415.abc12345": {"1.0", -1, "abc12345", "20230415"},
+	}
+
+	for v, expected := range versions {
+		vcs, ok := ParseVCSVersion(v)
+		if !ok || *vcs != expected {
+			t.Errorf("%s should parse as %+v, got %+v", v, expected, vcs)
+		}
+	}
+
+	for _, v := range []Version{"1.2.3", "1.0rc1", "r12.xyz", "20231399", "1.0.abc1234"} {
+		if vcs, ok := ParseVCSVersion(v); ok {
+			t.Errorf("%s should not parse as a VCS version, got %+v", v, vcs)
+		}
+	}
+
+	same := func(a, b Version) bool {
+		va, _ := ParseVCSVersion(a)
+		vb, _ := ParseVCSVersion(b)
+		return va.SameSource(vb)
+	}
+	if !same("r123.abc1234", "1.0.r123.abc1234567") || same("r123.abc1234", "r124.def5678") || !same("r12", "r12") || same("r12", "1.0.r12") {
+		t.Error("unexpected SameSource result")
+	}
+}
+
+func TestEvalPkgver(t *testing.T) {
+	for _, name := range []string{"git", "bash"} {
+		if _, err := exec.LookPath(name); err != nil {
+			t.Skipf("%s not found", name)
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "gopkgbuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	repo := filepath.Join(dir, "upstream")
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.org", "-C", repo}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %s: %s", args, err, out)
+		}
+	}
+
+	if err = os.Mkdir(repo, 0755); err != nil {
+		t.Fatal(err)
+	}
+	git("init", "-q")
+	git("commit", "-q", "--allow-empty", "-m", "first")
+	git("commit", "-q", "--allow-empty", "-m", "second")
+	git("tag", "v0.1")
+	git("commit", "-q", "--allow-empty", "-m", "third")
+
+	file, err := ParseAST([]byte(`pkgname=foo-git
+pkgver=0
+pkgrel=1
+arch=('any')
+source=("foo::git+file://` + repo + `#tag=v0.1")
+
+pkgver() {
+  cd foo
+  printf "r%s" "$(git rev-list --count HEAD)"
+}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srcdir := filepath.Join(dir, "src")
+	if err = os.Mkdir(srcdir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if err = file.FetchVCSSources(ctx, srcdir); err != nil {
+		t.Fatal(err)
+	}
+
+	// sandboxed with bubblewrap by default
+	pkgver, err := file.EvalPkgver(ctx, srcdir)
+	if _, lerr := exec.LookPath("bwrap"); lerr != nil {
+		if err == nil {
+			t.Error("expected pkgver() to be run with bwrap")
+		}
+	} else if err != nil || pkgver != "r2" {
+		t.Errorf("pkgver should be r2, got %s, %v", pkgver, err)
+	}
+
+	unsandboxed := WithSandbox([]string{})
+	pkgver, err = file.EvalPkgver(ctx, srcdir, unsandboxed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkgver != "r2" {
+		t.Errorf("pkgver should be r2, got %s", pkgver)
+	}
+
+	pkgver, err = file.EvalPkgver(ctx, srcdir, WithSandbox([]string{"env", "srcdir={dir}"}))
+	if err != nil || pkgver != "r2" {
+		t.Errorf("pkgver should be r2 with a sandbox template, got %s, %v", pkgver, err)
+	}
+
+	// startdir isn't available in the sandbox, so it isn't set either
+	startdir, err := ParseAST([]byte("pkgname=foo\npkgver=0\npkgrel=1\narch=(any)\npkgver() {\n  printf %s \"${startdir:-unset}\"\n}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkgver, err = startdir.EvalPkgver(ctx, srcdir, unsandboxed); err != nil || pkgver != "unset" {
+		t.Errorf("expected startdir to be unset, got %s, %v", pkgver, err)
+	}
+
+	// updating an existing clone
+	if err = file.FetchVCSSources(ctx, srcdir); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err = ParseAST([]byte("pkgname=foo\npkgver=1.0\npkgver() {\n  echo '1 0'\n}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = file.EvalPkgver(ctx, srcdir, unsandboxed); err == nil {
+		t.Error("expected error for invalid pkgver")
+	}
+
+	file, err = ParseAST([]byte("pkgname=foo\n_ver=1.0\npkgver=$_ver\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkgver, err = file.EvalPkgver(ctx, srcdir); err != nil || pkgver != "1.0" {
+		t.Errorf("pkgver should be 1.0, got %s, %v", pkgver, err)
+	}
+}
+
+func TestVCSBaseName(t *testing.T) {
+	for name, expected := range map[string]string{
+		"foo-git":    "foo",
+		"foo-bar-hg": "foo-bar",
+		"foo-svn":    "foo",
+		"foo":        "",
+		"-git":       "",
+		"gitfoo":     "",
+	} {
+		base, ok := VCSBaseName(name)
+		if ok != (expected != "") || ok && base != expected {
+			t.Errorf("expected base name %q of %s, got %q (%t)", expected, name, base, ok)
+		}
+	}
+}
+
+func TestVCSConventions(t *testing.T) {
+	pkg, err := ParseSRCINFOContent([]byte(`pkgbase = foo-git
+	pkgdesc = foo
+	pkgver = r1.abc
+	pkgrel = 1
+	url = https://example.org
+	arch = x86_64
+	license = MIT
+	provides = foo=1.0
+	source = git+https://example.org/foo.git
+	source = foo.patch
+	sha256sums = SKIP
+	sha256sums = abcd
+
+pkgname = foo-git
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !pkg.IsDevel() {
+		t.Error("expected a devel package")
+	}
+	if sources := pkg.VCSSources(); len(sources) != 1 || sources[0] != "git+https://example.org/foo.git" {
+		t.Errorf("unexpected VCS sources %v", sources)
+	}
+
+	expected := []Issue{{SeverityWarning, "conflicts", "foo-git doesn't conflict with foo"}}
+	if issues := Lint(pkg); !reflect.DeepEqual(issues, expected) {
+		t.Errorf("expected issues %v, got %v", expected, issues)
+	}
+
+	pkg.Pkgnames = []string{"foo"}
+	if !pkg.IsDevel() {
+		t.Error("expected a devel package by its sources")
+	}
+	if issues := Lint(pkg); len(issues) != 0 {
+		t.Errorf("expected no issues, got %v", issues)
+	}
+
+	if dependsOn([]string{""}, "foo") {
+		t.Error("an empty value should not depend on foo")
+	}
+}
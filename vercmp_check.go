@@ -0,0 +1,76 @@
+//go:build vercmp
+// +build vercmp
+
+package pkgbuild
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// VercmpPath is the path of the pacman vercmp binary used by CheckVerCmp.
+var VercmpPath = "/usr/bin/vercmp"
+
+// VerCmpMismatch is a pair of versions VerCmp compares differently from
+// pacman's vercmp.
+type VerCmpMismatch struct {
+	A, B string
+	Got  int // result of VerCmp
+	Want int // result of vercmp
+}
+
+func (m VerCmpMismatch) String() string {
+	return fmt.Sprintf("VerCmp(%q, %q) = %d, vercmp returned %d", m.A, m.B, m.Got, m.Want)
+}
+
+// CheckVerCmp compares the results of VerCmp for each pair of versions to
+// the ones of the system vercmp and returns the pairs they differ for. It
+// is only built with the vercmp build tag and is meant to validate
+// compatibility on a corpus of versions, e.g. all versions of a repository.
+func CheckVerCmp(ctx context.Context, pairs [][2]string) ([]VerCmpMismatch, error) {
+	var mismatches []VerCmpMismatch
+
+	for _, pair := range pairs {
+		want, err := vercmp(ctx, pair[0], pair[1])
+		if err != nil {
+			return nil, err
+		}
+
+		if got := VerCmp(pair[0], pair[1]); got != want {
+			mismatches = append(mismatches, VerCmpMismatch{pair[0], pair[1], got, want})
+		}
+	}
+
+	return mismatches, nil
+}
+
+// vercmp runs the system vercmp on a and b.
+func vercmp(ctx context.Context, a, b string) (int, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, VercmpPath, a, b)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return 0, fmt.Errorf("vercmp failed: %s: %s", err.Error(), strings.TrimSpace(stderr.String()))
+	}
+
+	ret, err := strconv.Atoi(strings.TrimSpace(stdout.String()))
+	if err != nil {
+		return 0, fmt.Errorf("unexpected vercmp output: %q", stdout.String())
+	}
+
+	// vercmp normalizes to -1, 0 and 1 but be safe
+	switch {
+	case ret < 0:
+		return -1, nil
+	case ret > 0:
+		return 1, nil
+	}
+	return 0, nil
+}
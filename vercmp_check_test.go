@@ -0,0 +1,37 @@
+//go:build vercmp
+// +build vercmp
+
+package pkgbuild
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestCheckVerCmp(t *testing.T) {
+	if _, err := os.Stat(VercmpPath); err != nil {
+		t.Skipf("%s not found", VercmpPath)
+	}
+
+	versions := []string{
+		"", "0", "1", "1.0", "1.0.0", "1.0a", "1.0alpha", "1.0.a", "1.0-1", "1.0-2",
+		"1:1.0", "1:1.0-1", "2:0.1", "1.0+r1", "1.0_1", "1.0rc1", "1.0.rc1",
+		"1.0.1", "1.01", "a", "a.b", "1..0", "1.0~rc1", "r123.abcdef", "2.0.r5.g1234",
+	}
+
+	var pairs [][2]string
+	for _, a := range versions {
+		for _, b := range versions {
+			pairs = append(pairs, [2]string{a, b})
+		}
+	}
+
+	mismatches, err := CheckVerCmp(context.Background(), pairs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range mismatches {
+		t.Error(m)
+	}
+}
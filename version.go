@@ -2,6 +2,7 @@ package pkgbuild
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"unicode"
@@ -10,6 +11,21 @@ import (
 // Version string
 type Version string
 
+// MarshalText implements encoding.TextMarshaler.
+func (v Version) MarshalText() ([]byte, error) {
+	return []byte(v), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, rejecting invalid
+// versions. An empty text results in the empty version.
+func (v *Version) UnmarshalText(text []byte) error {
+	if len(text) > 0 && !validPkgver(string(text)) {
+		return fmt.Errorf("%w: %s", ErrInvalidVersion, text)
+	}
+	*v = Version(text)
+	return nil
+}
+
 type CompleteVersion struct {
 	Version Version
 	Epoch   uint8
@@ -32,6 +48,41 @@ func (c *CompleteVersion) String() string {
 	return str
 }
 
+// Normalize returns a copy of c without redundant parts. The epoch 0 is
+// never written and a pkgrel comparing equal to "0", like in "1.0-0", is
+// removed.
+func (c *CompleteVersion) Normalize() *CompleteVersion {
+	n := *c
+	if n.Pkgrel != "" && rpmvercmp(n.Pkgrel, "0") == 0 {
+		n.Pkgrel = ""
+	}
+	return &n
+}
+
+// Canonical returns the string of the normalized version, which is the
+// same for spellings like "1.0", "0:1.0" and "1.0-0". This makes it
+// suitable as a map key or for deduplication.
+func (c *CompleteVersion) Canonical() string {
+	return c.Normalize().String()
+}
+
+// MarshalText implements encoding.TextMarshaler, so versions are encoded as
+// strings like "1:2.3-4" e.g. in JSON.
+func (c CompleteVersion) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, parsing text like
+// NewCompleteVersion.
+func (c *CompleteVersion) UnmarshalText(text []byte) error {
+	version, err := NewCompleteVersion(string(text))
+	if err != nil {
+		return err
+	}
+	*c = *version
+	return nil
+}
+
 // NewCompleteVersion creates a CompleteVersion including basic version, epoch
 // and rel from string
 func NewCompleteVersion(s string) (*CompleteVersion, error) {
@@ -42,7 +93,7 @@ func NewCompleteVersion(s string) (*CompleteVersion, error) {
 	// handle possible epoch
 	versions := strings.Split(s, ":")
 	if len(versions) > 2 {
-		return nil, fmt.Errorf("invalid version format: %s", s)
+		return nil, fmt.Errorf("%w format: %s", ErrInvalidVersion, s)
 	}
 
 	if len(versions) > 1 {
@@ -55,7 +106,7 @@ func NewCompleteVersion(s string) (*CompleteVersion, error) {
 	// handle possible rel
 	versions = strings.Split(versions[len(versions)-1], "-")
 	if len(versions) > 2 {
-		return nil, fmt.Errorf("invalid version format: %s", s)
+		return nil, fmt.Errorf("%w format: %s", ErrInvalidVersion, s)
 	}
 
 	if len(versions) > 1 {
@@ -71,7 +122,7 @@ func NewCompleteVersion(s string) (*CompleteVersion, error) {
 		}, nil
 	}
 
-	return nil, fmt.Errorf("invalid version format: %s", s)
+	return nil, fmt.Errorf("%w format: %s", ErrInvalidVersion, s)
 }
 
 // Older returns true if a is older than the argument version
@@ -89,39 +140,16 @@ func (a *CompleteVersion) Equal(b *CompleteVersion) bool {
 	return a.cmp(b) == 0
 }
 
+// Compare returns -1 if a is older than b, 0 if they are equal and 1 if a
+// is newer. The pkgrels are only compared if both versions have one.
+func (a *CompleteVersion) Compare(b *CompleteVersion) int {
+	return int(a.cmp(b))
+}
+
 // Satisfies tests whether or not version fits inside the bounds specified by
 // dep
 func (version *CompleteVersion) Satisfies(dep *Dependency) bool {
-	var cmpMax int8
-	var cmpMin int8
-
-	if dep.MaxVer != nil {
-		cmpMax = version.cmp(dep.MaxVer)
-		if cmpMax == 1 {
-			return false
-		}
-
-		if cmpMax == 0 && dep.slt {
-			return false
-		}
-	}
-
-	if dep.MinVer != nil {
-		if dep.MaxVer == dep.MinVer {
-			cmpMin = cmpMax
-		} else {
-			cmpMin = version.cmp(dep.MinVer)
-		}
-		if cmpMin == -1 {
-			return false
-		}
-
-		if cmpMin == 0 && dep.sgt {
-			return false
-		}
-	}
-
-	return true
+	return dep.Range().Contains(version)
 }
 
 // Compare a to b:
@@ -160,6 +188,134 @@ func (a *CompleteVersion) cmp(b *CompleteVersion) int8 {
 	return 0
 }
 
+// MaxVersion returns the newest of versions, nil if there are none. Of
+// equal versions the first one is returned.
+func MaxVersion(versions []CompleteVersion) *CompleteVersion {
+	var newest *CompleteVersion
+	for i := range versions {
+		if newest == nil || versions[i].Newer(newest) {
+			newest = &versions[i]
+		}
+	}
+	return newest
+}
+
+// MinVersion returns the oldest of versions, nil if there are none. Of
+// equal versions the first one is returned.
+func MinVersion(versions []CompleteVersion) *CompleteVersion {
+	var oldest *CompleteVersion
+	for i := range versions {
+		if oldest == nil || versions[i].Older(oldest) {
+			oldest = &versions[i]
+		}
+	}
+	return oldest
+}
+
+// Latest returns the key of the newest version of versions, e.g. the
+// repository providing the newest version of a package. Of equal versions
+// the smallest key is returned, ok is false if versions is empty.
+func Latest(versions map[string]CompleteVersion) (key string, ok bool) {
+	var latest CompleteVersion
+	for k, version := range versions {
+		if !ok || version.Newer(&latest) || version.Equal(&latest) && k < key {
+			key, latest, ok = k, version, true
+		}
+	}
+	return key, ok
+}
+
+// SortVersions sorts versions from oldest to newest, keeping the order of
+// equal versions.
+func SortVersions(versions []CompleteVersion) {
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versions[i].Older(&versions[j])
+	})
+}
+
+// VerCmp compares the full versions a and b like pacman's vercmp, returning
+// -1 if a is older than b, 0 if they are equal and 1 if a is newer. Unlike
+// NewCompleteVersion it accepts any string, splitting it into epoch, version
+// and release the same way as libalpm.
+func VerCmp(a, b string) int {
+	if a == b {
+		return 0
+	}
+
+	epochA, verA, relA, okA := parseEVR(a)
+	epochB, verB, relB, okB := parseEVR(b)
+
+	if ret := rpmvercmp(epochA, epochB); ret != 0 {
+		return ret
+	}
+
+	if ret := rpmvercmp(verA, verB); ret != 0 || !okA || !okB {
+		return ret
+	}
+
+	return rpmvercmp(relA, relB)
+}
+
+// parseEVR splits the full version evr into epoch, version and release as
+// parseEVR in libalpm does. A missing epoch is "0", hasRelease reports
+// whether evr has a release at all.
+func parseEVR(evr string) (epoch, version, release Version, hasRelease bool) {
+	i := 0
+	for i < len(evr) && evr[i] >= '0' && evr[i] <= '9' {
+		i++
+	}
+
+	epoch, version = "0", Version(evr)
+	if i < len(evr) && evr[i] == ':' {
+		if i > 0 {
+			epoch = Version(evr[:i])
+		}
+		version = Version(evr[i+1:])
+	}
+
+	if j := strings.LastIndexByte(string(version), '-'); j >= 0 {
+		version, release, hasRelease = version[:j], version[j+1:], true
+	}
+
+	return epoch, version, release, hasRelease
+}
+
+// Segment is a numeric or alpha segment of a version, as compared by
+// rpmvercmp.
+type Segment struct {
+	Separator string // the non alphanumeric characters before the segment
+	Value     string
+	Numeric   bool
+}
+
+// Segments splits v into the numeric and alpha segments rpmvercmp compares
+// one by one, e.g. "1.0rc1" into 1, 0, rc and 1. Trailing separators are
+// left out.
+func (v Version) Segments() []Segment {
+	var segments []Segment
+	r := []rune(string(v))
+
+	for i := 0; i < len(r); {
+		start := i
+		for i < len(r) && !isAlphaNumeric(r[i]) {
+			i++
+		}
+		if i == len(r) {
+			break
+		}
+
+		segment := Segment{Separator: string(r[start:i]), Numeric: isDigit(r[i])}
+		start = i
+		for i < len(r) && (segment.Numeric && isDigit(r[i]) || !segment.Numeric && isAlpha(r[i])) {
+			i++
+		}
+		segment.Value = string(r[start:i])
+		segments = append(segments, segment)
+	}
+
+	return segments
+}
+
 // Compare alpha and numeric segments of two versions.
 // return 1: a is newer than b
 //        0: a and b are the same version
@@ -1,6 +1,10 @@
 package pkgbuild
 
-import "testing"
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
 
 // Test version comparison
 func TestVersionComparison(t *testing.T) {
@@ -99,7 +103,7 @@ func TestCompleteVersionComparison(t *testing.T) {
 	for _, o := range older {
 		if b, err := NewCompleteVersion(o); err != nil {
 			t.Errorf("%s fails to parse %v", o, err)
-		} else if a.Older(b) || !a.Newer(b) {
+		} else if a.Older(b) || !a.Newer(b) || a.Compare(b) != 1 {
 			t.Errorf("%s should be older than %s", o, a.String())
 		}
 	}
@@ -114,7 +118,7 @@ func TestCompleteVersionComparison(t *testing.T) {
 	for _, n := range newer {
 		if b, err := NewCompleteVersion(n); err != nil {
 			t.Errorf("%s fails to parse %v", n, err)
-		} else if a.Newer(b) || !a.Older(b) {
+		} else if a.Newer(b) || !a.Older(b) || a.Compare(b) != -1 {
 			t.Errorf("%s should be newer than %s", n, a.String())
 		}
 	}
@@ -127,13 +131,134 @@ func TestCompleteVersionComparison(t *testing.T) {
 	for _, n := range equal {
 		if b, err := NewCompleteVersion(n); err != nil {
 			t.Errorf("%s fails to parse %v", n, err)
-		} else if a.Newer(b) || a.Older(b) || !a.Equal(b) {
+		} else if a.Newer(b) || a.Older(b) || !a.Equal(b) || a.Compare(b) != 0 {
 			t.Errorf("%s should be equal to %s", n, a.String())
 		}
 	}
 
 }
 
+func TestSegments(t *testing.T) {
+	segments := map[Version][]Segment{
+		"1.0rc1": {{"", "1", true}, {".", "0", true}, {"", "rc", false}, {"", "1", true}},
+		"2.0.r5.g1234": {
+			{"", "2", true}, {".", "0", true}, {".", "r", false}, {"", "5", true},
+			{".", "g", false}, {"", "1234", true},
+		},
+		"1..0_": {{"", "1", true}, {"..", "0", true}},
+		"":      nil,
+	}
+
+	for v, expected := range segments {
+		if s := v.Segments(); !reflect.DeepEqual(s, expected) {
+			t.Errorf("expected segments of %s to be %v, got %v", v, expected, s)
+		}
+	}
+}
+
+func TestMaxVersion(t *testing.T) {
+	var versions []CompleteVersion
+	for _, v := range []string{"1.0-1", "1:0.5-1", "2.0-1", "1:0.5", "1.0-10"} {
+		version, err := NewCompleteVersion(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		versions = append(versions, *version)
+	}
+
+	if newest := MaxVersion(versions); newest != &versions[1] {
+		t.Errorf("expected max version 1:0.5-1, got %v", newest)
+	}
+	if oldest := MinVersion(versions); oldest != &versions[0] {
+		t.Errorf("expected min version 1.0-1, got %v", oldest)
+	}
+	if MaxVersion(nil) != nil || MinVersion(nil) != nil {
+		t.Error("expected no version for an empty slice")
+	}
+
+	repos := map[string]CompleteVersion{
+		"extra":     versions[2],
+		"community": versions[3],
+		"core":      versions[1],
+	}
+	if repo, ok := Latest(repos); !ok || repo != "community" {
+		t.Errorf("expected latest repo community, got %s", repo)
+	}
+	if _, ok := Latest(nil); ok {
+		t.Error("expected no latest version for an empty map")
+	}
+
+	SortVersions(versions)
+	var sorted []string
+	for _, version := range versions {
+		sorted = append(sorted, version.String())
+	}
+	if expected := []string{"1.0-1", "1.0-10", "2.0-1", "1:0.5-1", "1:0.5"}; !reflect.DeepEqual(sorted, expected) {
+		t.Errorf("expected %v, got %v", expected, sorted)
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	canonical := map[string][]string{
+		"1.0":     {"1.0", "0:1.0", "1.0-0", "0:1.0-0", "1.0-00"},
+		"1:1.0-2": {"1:1.0-2", "01:1.0-2"},
+		"1.0-0.1": {"1.0-0.1"},
+	}
+
+	for expected, versions := range canonical {
+		for _, v := range versions {
+			version, err := NewCompleteVersion(v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c := version.Canonical(); c != expected {
+				t.Errorf("expected canonical form of %s to be %s, got %s", v, expected, c)
+			}
+		}
+	}
+
+	version := &CompleteVersion{Version: "1.0", Pkgrel: "0"}
+	if version.Normalize().Pkgrel != "" || version.Pkgrel != "0" {
+		t.Error("Normalize should return a normalized copy")
+	}
+}
+
+func TestVersionJSON(t *testing.T) {
+	type versions struct {
+		Pkgver   Version          `json:"pkgver"`
+		Complete CompleteVersion  `json:"complete"`
+		Pointer  *CompleteVersion `json:"pointer"`
+	}
+
+	in := versions{
+		Pkgver:   "2.3",
+		Complete: CompleteVersion{Version: "2.3", Epoch: 1, Pkgrel: "4"},
+		Pointer:  &CompleteVersion{Version: "1.0"},
+	}
+
+	data, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"pkgver":"2.3","complete":"1:2.3-4","pointer":"1.0"}`; string(data) != expected {
+		t.Errorf("expected %s, got %s", expected, data)
+	}
+
+	var out versions
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Pkgver != in.Pkgver || out.Complete != in.Complete || *out.Pointer != *in.Pointer {
+		t.Errorf("expected %+v, got %+v", in, out)
+	}
+
+	for _, data := range []string{`{"pkgver":"2.3-1"}`, `{"complete":"1:2:3"}`, `{"pointer":"-1"}`} {
+		if err := json.Unmarshal([]byte(data), &out); err == nil {
+			t.Errorf("expected error for %s", data)
+		}
+	}
+}
+
 func TestCompleteVersionString(t *testing.T) {
 	str := "42:3.14-1"
 	version, _ := NewCompleteVersion(str)
@@ -216,3 +341,33 @@ func TestUnicode(t *testing.T) {
 		t.Errorf("%v should be %v", version, expected)
 	}
 }
+
+func TestVerCmp(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.5.0", "1.5.0", 0},
+		{"1.5.1", "1.5.0", 1},
+		{"1.5.1", "1.5", 1},
+		{"1.5-1", "1.5-2", -1},
+		{"1.5-2", "1.5", 0},
+		{"1.0a", "1.0", -1},
+		{"1.0rc1", "1.0", -1},
+		{"1:1.0", "2.0", 1},
+		{"0:1.0", "1.0", 0},
+		{"1:1.0-1", "1:2.0-1", -1},
+		{"2.0-1", "1:1.0-1", -1},
+		{"1.0-", "1.0-1", -1},
+		{"r100.abc", "r99.def", 1},
+	}
+
+	for _, test := range tests {
+		if ret := VerCmp(test.a, test.b); ret != test.expected {
+			t.Errorf("VerCmp(%q, %q) = %d, expected %d", test.a, test.b, ret, test.expected)
+		}
+		if ret := VerCmp(test.b, test.a); ret != -test.expected {
+			t.Errorf("VerCmp(%q, %q) = %d, expected %d", test.b, test.a, ret, -test.expected)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package pkgbuild
+
+import (
+	"errors"
+	"fmt"
+)
+
+// StopWalk is returned by a WalkFunc to stop WalkSRCINFO without an error,
+// e.g. once the needed fields are found.
+var StopWalk = errors.New("stop walk")
+
+// WalkFunc is called by WalkSRCINFO for every variable of a .SRCINFO file.
+// key is the variable as written e.g. "depends_x86_64", pkgname the
+// package of the section it's in, empty for the pkgbase section. For the
+// pkgname variable starting a section pkgname is its value.
+type WalkFunc func(key, value, pkgname string) error
+
+// WalkSRCINFO calls fn for every variable of the .SRCINFO content in
+// order, without building a PKGBUILD. This is cheaper than parsing when
+// only a few fields are needed. Values are not validated. WithLimits,
+// WithInterner and WithZeroCopy apply like when parsing, with WithFields fn
+// is only called for the given variables and the pkgbase and pkgname
+// starting the sections. Other options are ignored. Walking stops at the
+// first syntax error or error returned by fn, which is returned unless it's
+// StopWalk.
+func WalkSRCINFO(content []byte, fn WalkFunc, opts ...ParseOption) error {
+	config := newParseConfig(opts)
+	if err := config.checkInputSize(len(content)); err != nil {
+		return err
+	}
+
+	lexer := lex(content, config.zeroCopy)
+	lexer.maxTokenLength = config.limits.MaxTokenLength
+	lexer.fields = config.fields
+	lexer.interner = config.interner
+
+	var counts map[string]int
+	if config.limits.MaxArrayLength > 0 {
+		counts = make(map[string]int)
+	}
+
+	pkgname := ""
+	for {
+		token := lexer.nextItem()
+		switch {
+		case token.typ == itemEOF:
+			return nil
+		case token.typ == itemError:
+			return lexer.errorAt(errors.New(token.val))
+		case !token.typ.isVariable():
+			continue
+		}
+
+		if counts != nil {
+			counts[token.val]++
+			if counts[token.val] > config.limits.MaxArrayLength {
+				return lexer.errorAt(fmt.Errorf("%w: %s", ErrTooManyValues, token.val))
+			}
+		}
+
+		value := lexer.nextItem()
+		if value.typ == itemError {
+			return lexer.errorAt(errors.New(value.val))
+		}
+
+		if token.typ == itemPkgname {
+			pkgname = value.val
+		}
+
+		if err := fn(token.val, value.val, pkgname); err != nil {
+			if err == StopWalk {
+				return nil
+			}
+			return err
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package pkgbuild
+
+import (
+	"errors"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestWalkSRCINFO(t *testing.T) {
+	content, err := ioutil.ReadFile("./test_pkgbuilds/SRCINFO_linux")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// pkgdesc by section
+	pkgdescs := make(map[string]string)
+	err = WalkSRCINFO(content, func(key, value, pkgname string) error {
+		if key == "pkgdesc" {
+			pkgdescs[pkgname] = value
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"linux":         "The Linux kernel and modules",
+		"linux-headers": "Header files and scripts for building modules for Linux kernel",
+		"linux-docs":    "Kernel hackers manual - HTML documentation that comes with the Linux kernel",
+	}
+	if !reflect.DeepEqual(pkgdescs, expected) {
+		t.Errorf("expected %v, got %v", expected, pkgdescs)
+	}
+
+	var keys []string
+	err = WalkSRCINFO(content, func(key, value, pkgname string) error {
+		keys = append(keys, key+"="+value)
+		if key == "pkgver" {
+			return StopWalk
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(keys, []string{"pkgbase=linux", "pkgver=3.18.5"}) {
+		t.Errorf("unexpected keys %v", keys)
+	}
+
+	errFoo := errors.New("foo")
+	if err = WalkSRCINFO(content, func(key, value, pkgname string) error { return errFoo }); err != errFoo {
+		t.Errorf("expected error of the callback, got %v", err)
+	}
+
+	err = WalkSRCINFO([]byte("pkgbase = foo\n=bar\n"), func(key, value, pkgname string) error { return nil })
+	if _, ok := err.(*ParseError); !ok {
+		t.Errorf("expected a ParseError, got %v", err)
+	}
+
+	err = WalkSRCINFO([]byte("pkgbase = foo\narch = a\narch = b\n"), func(key, value, pkgname string) error { return nil },
+		WithLimits(Limits{MaxArrayLength: 1}))
+	if err == nil {
+		t.Error("expected error for too many values")
+	}
+}
+
+func BenchmarkWalkSRCINFO(b *testing.B) {
+	content, err := ioutil.ReadFile("./test_pkgbuilds/SRCINFO_linux")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		WalkSRCINFO(content, func(key, value, pkgname string) error { return nil })
+	}
+}
@@ -0,0 +1,325 @@
+package pkgbuild
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// WatchOp is the kind of change of a WatchEvent.
+type WatchOp int
+
+// Watch operations
+const (
+	WatchAdded WatchOp = iota
+	WatchUpdated
+	WatchRemoved
+)
+
+func (op WatchOp) String() string {
+	switch op {
+	case WatchAdded:
+		return "added"
+	case WatchUpdated:
+		return "updated"
+	case WatchRemoved:
+		return "removed"
+	}
+	return "unknown"
+}
+
+// WatchEvent is a change of a package directory seen by a Watcher.
+type WatchEvent struct {
+	Op   WatchOp
+	Dir  string    // package directory
+	File string    // .SRCINFO or PKGBUILD file parsed
+	Old  *PKGBUILD // nil if added
+	New  *PKGBUILD // nil if removed or if parsing failed
+	Err  error     // parse error of File
+}
+
+// watchedDir is the state of a package directory known to a Watcher.
+type watchedDir struct {
+	file    string
+	modTime time.Time
+	size    int64
+	pkgb    *PKGBUILD // last successfully parsed
+}
+
+// Watcher watches the package directories below Root, directories
+// containing a .SRCINFO or a PKGBUILD file, and reports the packages added,
+// updated or removed. The .SRCINFO is preferred if a directory has both.
+// PKGBUILDs are evaluated without running bash as by WithEmbeddedEvaluation,
+// unless Makepkg is set.
+//
+// Files are compared by modification time and size. On Linux Run scans
+// again when inotify reports a change below Root. Elsewhere, with Poll set
+// or if the notifications fail, e.g. when running out of inotify watches on
+// a large tree, it polls and scans every Interval instead.
+type Watcher struct {
+	Root     string
+	Interval time.Duration // between scans of Run when polling, one second by default
+	Options  []ParseOption // used for parsing the files
+
+	// Poll makes Run poll even if file system notifications are
+	// available, e.g. for network file systems not delivering them.
+	Poll bool
+
+	// Makepkg makes the Watcher parse PKGBUILDs by running makepkg, which
+	// executes them, see ParsePKGBUILD. Only set it for trusted PKGBUILDs
+	// or with WithSandbox in Options.
+	Makepkg bool
+
+	dirs map[string]*watchedDir
+}
+
+// NewWatcher returns a Watcher for root, parsing the files with opts.
+func NewWatcher(root string, opts ...ParseOption) *Watcher {
+	return &Watcher{Root: root, Options: opts}
+}
+
+// Scan looks for changes since the last scan and returns them ordered by
+// directory. All packages are reported as added by the first scan. The
+// progress of checking the directories is reported to the ProgressFunc of
+// WithProgress in Options. If ctx is done before the scan finishes, the
+// changes found so far are reported again by the next scan.
+func (w *Watcher) Scan(ctx context.Context) ([]WatchEvent, error) {
+	files, unreadable, err := w.packageFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	// the new state, kept only if the scan finishes
+	next := make(map[string]*watchedDir, len(files))
+
+	var events []WatchEvent
+	for dir, watched := range w.dirs {
+		if _, ok := files[dir]; !ok {
+			if below(dir, unreadable) {
+				// unknown until it can be read again
+				next[dir] = watched
+				continue
+			}
+			events = append(events, WatchEvent{Op: WatchRemoved, Dir: dir, File: watched.file, Old: watched.pkgb})
+		}
+	}
+
+	dirs := make([]string, 0, len(files))
+	for dir := range files {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	progress := newParseConfig(w.Options).progress
+	for i, dir := range dirs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		file := files[dir]
+		progress.report(i, len(dirs), file)
+
+		watched, ok := w.dirs[dir]
+		info, err := os.Stat(file)
+		if err != nil {
+			// removed since listing it, reported by the next scan
+			if ok {
+				next[dir] = watched
+			}
+			continue
+		}
+
+		if ok && watched.file == file && watched.modTime.Equal(info.ModTime()) && watched.size == info.Size() {
+			next[dir] = watched
+			continue
+		}
+
+		event := WatchEvent{Op: WatchAdded, Dir: dir, File: file}
+		current := &watchedDir{file: file, modTime: info.ModTime(), size: info.Size()}
+		if ok {
+			event.Op = WatchUpdated
+			event.Old = watched.pkgb
+			current.pkgb = watched.pkgb
+		}
+		next[dir] = current
+
+		event.New, event.Err = w.parse(ctx, file)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if event.Err == nil {
+			current.pkgb = event.New
+			if event.Op == WatchUpdated && event.Old != nil && Equal(event.Old, event.New) {
+				// e.g. only touched
+				continue
+			}
+		}
+		events = append(events, event)
+	}
+	progress.report(len(dirs), len(dirs), "")
+
+	w.dirs = next
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Dir < events[j].Dir
+	})
+	return events, nil
+}
+
+// notifier waits for changes of the directories below a root reported by
+// the file system.
+type notifier interface {
+	watch(root string) error
+	wait(ctx context.Context) error
+	close() error
+}
+
+// notifyDelay is the time Run waits after a notification before scanning,
+// so the notifications of a file being written result in a single scan.
+const notifyDelay = 100 * time.Millisecond
+
+// Run scans for changes whenever notified or every Interval when polling,
+// see Watcher, and sends them to events until ctx is done, then it returns
+// the error of ctx. Errors of a scan other than parse errors are returned
+// immediately.
+func (w *Watcher) Run(ctx context.Context, events chan<- WatchEvent) error {
+	config := newParseConfig(w.Options)
+
+	var n notifier
+	if !w.Poll {
+		var err error
+		if n, err = newNotifier(); err != nil {
+			config.debugf("polling %s: %s", w.Root, err)
+		}
+	}
+	stopNotify := func(err error) {
+		config.debugf("polling %s: %s", w.Root, err)
+		n.close()
+		n = nil
+	}
+	defer func() {
+		if n != nil {
+			n.close()
+		}
+	}()
+
+	interval := w.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		// watched before scanning, so no change after the scan is missed
+		if n != nil {
+			if err := n.watch(w.Root); err != nil {
+				stopNotify(err)
+			}
+		}
+
+		changes, err := w.Scan(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, event := range changes {
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		if n != nil {
+			err := n.wait(ctx)
+			if err == nil {
+				select {
+				case <-time.After(notifyDelay):
+					continue
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			stopNotify(err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// packageFiles returns the file to parse of each package directory below
+// Root. Hidden directories like .git are skipped. Files and directories
+// removed while walking are skipped, the paths which can't be read for
+// other reasons are returned as unreadable. Only an error reading Root
+// itself is returned.
+func (w *Watcher) packageFiles() (files map[string]string, unreadable []string, err error) {
+	config := newParseConfig(w.Options)
+
+	files = make(map[string]string)
+	err = filepath.Walk(w.Root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			switch {
+			case path == w.Root:
+				return err
+			case !os.IsNotExist(err):
+				config.debugf("unable to read %s: %s", path, err)
+				unreadable = append(unreadable, path)
+			}
+			// skips the directory if it can't be read
+			return nil
+		}
+
+		if info.IsDir() {
+			if path != w.Root && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		dir := filepath.Dir(path)
+		switch info.Name() {
+		case ".SRCINFO":
+			files[dir] = path
+		case "PKGBUILD":
+			if _, ok := files[dir]; !ok {
+				files[dir] = path
+			}
+		}
+		return nil
+	})
+	return files, unreadable, err
+}
+
+// below returns true if path is one of paths or below one of them.
+func below(path string, paths []string) bool {
+	for _, p := range paths {
+		if path == p || strings.HasPrefix(path, p+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// parse parses the .SRCINFO or PKGBUILD file.
+func (w *Watcher) parse(ctx context.Context, file string) (*PKGBUILD, error) {
+	if filepath.Base(file) == "PKGBUILD" {
+		opts := w.Options
+		if !w.Makepkg {
+			opts = append(opts[:len(opts):len(opts)], WithEmbeddedEvaluation())
+		}
+		return ParsePKGBUILDContext(ctx, file, opts...)
+	}
+	return ParseSRCINFOContext(ctx, file, w.Options...)
+}
@@ -0,0 +1,101 @@
+package pkgbuild
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// inotifyMask selects the inotify events making the Watcher scan again.
+const inotifyMask = syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_DELETE_SELF |
+	syscall.IN_MODIFY | syscall.IN_CLOSE_WRITE | syscall.IN_ATTRIB |
+	syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_MOVE_SELF
+
+// inotifyNotifier is the notifier of Linux based on inotify.
+type inotifyNotifier struct {
+	file    *os.File
+	changed chan struct{}
+	err     chan error
+}
+
+// newNotifier returns an inotify instance without watches.
+func newNotifier() (notifier, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+
+	n := &inotifyNotifier{
+		// non-blocking so reads are interrupted by close
+		file:    os.NewFile(uintptr(fd), "inotify"),
+		changed: make(chan struct{}, 1),
+		err:     make(chan error, 1),
+	}
+	go n.read()
+	return n, nil
+}
+
+// read signals changed for every batch of events read until reading fails.
+// The events themselves aren't needed, the Watcher scans for the changes.
+func (n *inotifyNotifier) read() {
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		if _, err := n.file.Read(buf); err != nil {
+			n.err <- err
+			return
+		}
+
+		select {
+		case n.changed <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// watch adds watches for root and the directories below it, skipping hidden
+// ones like the Watcher. Adding a watch for a directory already watched
+// has no effect, the watches of removed directories are dropped by the
+// kernel.
+func (n *inotifyNotifier) watch(root string) error {
+	fd := int(n.file.Fd())
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// the directories the Watcher can't read are skipped by it too
+			if path == root {
+				return err
+			}
+			return nil
+		}
+
+		if !info.IsDir() {
+			return nil
+		}
+		if path != root && strings.HasPrefix(info.Name(), ".") {
+			return filepath.SkipDir
+		}
+
+		_, err = syscall.InotifyAddWatch(fd, path, inotifyMask)
+		switch err {
+		case nil, syscall.ENOENT, syscall.EACCES:
+			return nil
+		}
+		return os.NewSyscallError("inotify_add_watch", err)
+	})
+}
+
+func (n *inotifyNotifier) wait(ctx context.Context) error {
+	select {
+	case <-n.changed:
+		return nil
+	case err := <-n.err:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (n *inotifyNotifier) close() error {
+	return n.file.Close()
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+// +build !linux
+
+package pkgbuild
+
+import "errors"
+
+// newNotifier fails as only inotify of Linux is supported, the Watcher
+// polls instead.
+func newNotifier() (notifier, error) {
+	return nil, errors.New("file system notifications are only supported on Linux")
+}
@@ -0,0 +1,278 @@
+package pkgbuild
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestWatcherScan(t *testing.T) {
+	root, err := ioutil.TempDir("", "gopkgbuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	srcinfo := func(pkgver string) []byte {
+		return []byte("pkgbase = foo\n\tpkgver = " + pkgver + "\n\tpkgrel = 1\n\tarch = any\n\npkgname = foo\n")
+	}
+	write := func(path string, content []byte, mtime time.Time) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	foo := filepath.Join(root, "foo", ".SRCINFO")
+	now := time.Now()
+	write(foo, srcinfo("1.0"), now)
+	write(filepath.Join(root, ".git", "bar", ".SRCINFO"), srcinfo("1.0"), now)
+
+	w := NewWatcher(root)
+	scan := func() []WatchEvent {
+		events, err := w.Scan(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return events
+	}
+
+	events := scan()
+	if len(events) != 1 || events[0].Op != WatchAdded || events[0].File != foo || events[0].New.Pkgver != "1.0" {
+		t.Fatalf("expected foo to be added, got %+v", events)
+	}
+
+	if events = scan(); len(events) != 0 {
+		t.Errorf("expected no changes, got %+v", events)
+	}
+
+	// touched without changes
+	write(foo, srcinfo("1.0"), now.Add(time.Second))
+	if events = scan(); len(events) != 0 {
+		t.Errorf("expected no changes, got %+v", events)
+	}
+
+	write(foo, srcinfo("2.0"), now.Add(2*time.Second))
+	events = scan()
+	if len(events) != 1 || events[0].Op != WatchUpdated || events[0].Old.Pkgver != "1.0" || events[0].New.Pkgver != "2.0" {
+		t.Fatalf("expected foo to be updated to 2.0, got %+v", events)
+	}
+
+	write(foo, []byte("pkgname = foo\n"), now.Add(3*time.Second))
+	events = scan()
+	if len(events) != 1 || events[0].Err == nil || events[0].Old.Pkgver != "2.0" {
+		t.Fatalf("expected parse error, got %+v", events)
+	}
+
+	if err := os.RemoveAll(filepath.Dir(foo)); err != nil {
+		t.Fatal(err)
+	}
+	events = scan()
+	if len(events) != 1 || events[0].Op != WatchRemoved || events[0].Old.Pkgver != "2.0" {
+		t.Fatalf("expected foo to be removed, got %+v", events)
+	}
+}
+
+func TestWatcherRun(t *testing.T) {
+	// notified on Linux, polling elsewhere
+	t.Run("notify", func(t *testing.T) { testWatcherRun(t, false) })
+	t.Run("poll", func(t *testing.T) { testWatcherRun(t, true) })
+}
+
+// testWatcherRun tests that Run reports a package added below a new
+// directory.
+func testWatcherRun(t *testing.T, poll bool) {
+	root, err := ioutil.TempDir("", "gopkgbuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	w := NewWatcher(root)
+	w.Interval = 10 * time.Millisecond
+	w.Poll = poll
+	if !poll && runtime.GOOS == "linux" {
+		// only notifications can report the change in time
+		w.Interval = time.Hour
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := make(chan WatchEvent)
+	done := make(chan error)
+	go func() {
+		done <- w.Run(ctx, events)
+	}()
+
+	if err := os.Mkdir(filepath.Join(root, "foo"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	write := func(pkgver string) {
+		content := []byte("pkgbase = foo\n\tpkgver = " + pkgver + "\n\tpkgrel = 1\n\tarch = any\n\npkgname = foo\n")
+		// renamed in place so a scan never sees a partial file
+		tmp := filepath.Join(root, "foo", ".SRCINFO.tmp")
+		if err := ioutil.WriteFile(tmp, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(tmp, filepath.Join(root, "foo", ".SRCINFO")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, expected := range []struct {
+		op     WatchOp
+		pkgver Version
+	}{
+		{WatchAdded, "1.0"},
+		{WatchUpdated, "2.0"},
+	} {
+		write(string(expected.pkgver))
+
+		select {
+		case event := <-events:
+			if event.Op != expected.op || event.New == nil || event.New.Pkgver != expected.pkgver {
+				t.Errorf("expected foo %s to be %s, got %+v", expected.pkgver, expected.op, event)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for foo %s to be %s", expected.pkgver, expected.op)
+		}
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestWatcherPKGBUILD(t *testing.T) {
+	root, err := ioutil.TempDir("", "gopkgbuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	marker := filepath.Join(root, "executed")
+	content := "pkgname=foo\npkgver=1.0\npkgrel=1\narch=(any)\ntouch " + marker + "\n"
+	if err := os.Mkdir(filepath.Join(root, "foo"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, "foo", "PKGBUILD"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := NewWatcher(root).Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].New == nil || events[0].New.Pkgver != "1.0" {
+		t.Fatalf("expected foo to be added, got %+v", events)
+	}
+
+	// evaluated without running the PKGBUILD
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Error("expected the PKGBUILD not to be executed")
+	}
+}
+
+func TestWatcherScanCanceled(t *testing.T) {
+	root, err := ioutil.TempDir("", "gopkgbuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	for _, name := range []string{"bar", "foo"} {
+		content := []byte("pkgbase = " + name + "\n\tpkgver = 1.0\n\tpkgrel = 1\n\tarch = any\n\npkgname = " + name + "\n")
+		if err := os.Mkdir(filepath.Join(root, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(root, name, ".SRCINFO"), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := NewWatcher(root)
+	if _, err := w.Scan(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.RemoveAll(filepath.Join(root, "bar")); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, "foo", ".SRCINFO"), []byte("pkgbase = foo\n\tpkgver = 2.0\n\tpkgrel = 1\n\tarch = any\n\npkgname = foo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// canceled after listing the directories
+	ctx, cancel := context.WithCancel(context.Background())
+	w.Options = []ParseOption{WithProgress(func(done, total int, item string) {
+		cancel()
+	})}
+	if _, err := w.Scan(ctx); err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	w.Options = nil
+	events, err := w.Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].Op != WatchRemoved || events[0].Dir != filepath.Join(root, "bar") ||
+		events[1].Op != WatchUpdated || events[1].New.Pkgver != "2.0" {
+		t.Errorf("expected the changes to be reported after the canceled scan, got %+v", events)
+	}
+}
+
+func TestWatcherScanUnreadable(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("permissions aren't enforced for root")
+	}
+
+	root, err := ioutil.TempDir("", "gopkgbuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	dir := filepath.Join(root, "group", "foo")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := []byte("pkgbase = foo\n\tpkgver = 1.0\n\tpkgrel = 1\n\tarch = any\n\npkgname = foo\n")
+	if err := ioutil.WriteFile(filepath.Join(dir, ".SRCINFO"), content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w := NewWatcher(root)
+	if events, err := w.Scan(context.Background()); err != nil || len(events) != 1 {
+		t.Fatalf("expected foo to be added, got %+v, %v", events, err)
+	}
+
+	group := filepath.Join(root, "group")
+	if err := os.Chmod(group, 0); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(group, 0755)
+
+	events, err := w.Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 {
+		t.Errorf("expected no changes while unreadable, got %+v", events)
+	}
+}
+
+func TestWatcherScanMissingRoot(t *testing.T) {
+	if _, err := NewWatcher(filepath.Join(os.TempDir(), "gopkgbuild-missing")).Scan(context.Background()); !os.IsNotExist(err) {
+		t.Errorf("expected missing root error, got %v", err)
+	}
+}
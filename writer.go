@@ -0,0 +1,114 @@
+package pkgbuild
+
+import (
+	"io"
+	"sort"
+	"strings"
+)
+
+// scalarFields lists the variables written as scalars to a PKGBUILD.
+var scalarFields = map[string]bool{
+	"pkgbase":   true,
+	"pkgver":    true,
+	"pkgrel":    true,
+	"epoch":     true,
+	"pkgdesc":   true,
+	"url":       true,
+	"install":   true,
+	"changelog": true,
+}
+
+// WritePKGBUILD writes p to w as a PKGBUILD in the style of Format, with
+// the maintainers and contributors as header and functions like build()
+// after the variables. Values are written literally.
+func (p *PKGBUILD) WritePKGBUILD(w io.Writer, functions ...*Function) error {
+	return writePKGBUILD(w, p, func(name, value string) string {
+		return quoteWord(value)
+	}, functions)
+}
+
+// writePKGBUILD is WritePKGBUILD quoting the values of variables with
+// quote, so they can contain e.g. references to other variables.
+func writePKGBUILD(w io.Writer, p *PKGBUILD, quote func(name, value string) string, functions []*Function) error {
+	var b strings.Builder
+
+	for _, person := range p.Maintainers {
+		b.WriteString("# Maintainer: " + person.String() + "\n")
+	}
+	for _, person := range p.Contributors {
+		b.WriteString("# Contributor: " + person.String() + "\n")
+	}
+	if b.Len() > 0 {
+		b.WriteString("\n")
+	}
+
+	vars := p.Vars()
+	if pkgbase := vars["pkgbase"]; len(pkgbase) == 1 && len(p.Pkgnames) == 1 && pkgbase[0] == p.Pkgnames[0] {
+		delete(vars, "pkgbase")
+	}
+
+	for _, name := range sortedFields(vars) {
+		values := vars[name]
+		words := make([]string, 0, len(values))
+		for _, value := range values {
+			words = append(words, quote(name, value))
+		}
+
+		if scalarFields[name] || name == "pkgname" && len(values) == 1 {
+			b.WriteString(name + "=" + words[0] + "\n")
+		} else {
+			b.WriteString(name + "=(" + strings.Join(words, " ") + ")\n")
+		}
+	}
+
+	for _, function := range functions {
+		body := function.Body
+		if !strings.HasPrefix(body, "\n") {
+			body = "\n" + body
+		}
+		if !strings.HasSuffix(body, "\n") {
+			body += "\n"
+		}
+		b.WriteString("\n" + function.Name + "() {" + body + "}\n")
+	}
+
+	content, err := Format([]byte(b.String()))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(content)
+	return err
+}
+
+// sortedFields returns the names of vars in the canonical order of the
+// PKGBUILD variables, arch specific variables following their variable and
+// unknown ones last.
+func sortedFields(vars map[string][]string) []string {
+	rank := make(map[string]int, len(fieldOrder))
+	for i, name := range fieldOrder {
+		rank[name] = i
+	}
+
+	rankOf := func(name string) int {
+		if r, ok := rank[name]; ok {
+			return r
+		}
+		if i := strings.IndexByte(name, '_'); i > 0 && archFields[name[:i]] {
+			return rank[name[:i]]
+		}
+		return len(fieldOrder)
+	}
+
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		ri, rj := rankOf(names[i]), rankOf(names[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
@@ -0,0 +1,65 @@
+package pkgbuild
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWritePKGBUILD(t *testing.T) {
+	p, err := NewBuilder("foo").
+		Pkgver("1.0").
+		Pkgdesc("It's a foo").
+		Arch("x86_64").
+		Depends("glibc", "bar>=1.0").
+		Source("https://example.org/foo 1.0.tar.gz").
+		Checksums("sha256", "SKIP").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Maintainers = []Person{{Name: "Jane Doe", Email: "jane@example.org"}}
+	if err := p.addArchSpecific(itemDepends, "depends", "x86_64", "lib32-glibc"); err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	err = p.WritePKGBUILD(&b, &Function{Name: "package", Body: "\ninstall -Dm644 foo \"$pkgdir/foo\"\n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := b.String()
+
+	if !strings.HasPrefix(content, "# Maintainer: Jane Doe <jane@example.org>\n") {
+		t.Errorf("missing maintainer header:\n%s", content)
+	}
+	if strings.Contains(content, "pkgbase=") {
+		t.Errorf("pkgbase equal to pkgname written:\n%s", content)
+	}
+	if strings.Index(content, "depends_x86_64=") < strings.Index(content, "depends=") {
+		t.Errorf("depends_x86_64 written before depends:\n%s", content)
+	}
+
+	f, err := ParseAST(b.Bytes())
+	if err != nil {
+		t.Fatalf("written PKGBUILD not parsable: %s\n%s", err, content)
+	}
+	if !f.HasFunction("package") {
+		t.Errorf("package() missing:\n%s", content)
+	}
+
+	e := NewExpander(f)
+	for name, values := range map[string][]string{
+		"pkgname":        {"foo"},
+		"pkgver":         {"1.0"},
+		"pkgdesc":        {"It's a foo"},
+		"depends":        {"glibc", "bar>=1.0"},
+		"depends_x86_64": {"lib32-glibc"},
+		"source":         {"https://example.org/foo 1.0.tar.gz"},
+	} {
+		if got := e.Values(name); !reflect.DeepEqual(got, values) {
+			t.Errorf("%s: expected %q, got %q", name, values, got)
+		}
+	}
+}